@@ -1,92 +1,105 @@
 package main
 
 import (
-	"context"
-	"errors"
-	"log"
-	"net/http"
+	"flag"
+	"fmt"
+	"log/slog"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
-	"github.com/gin-gonic/gin"
-	"github.com/joho/godotenv" // <-- 1. ADD THIS IMPORT
+	"gorm.io/gorm"
 
+	"github.com/sujalbistaa/whispr/internal/config"
 	"github.com/sujalbistaa/whispr/internal/db"
-	routes "github.com/sujalbistaa/whispr/internal/http"
-	"github.com/sujalbistaa/whispr/internal/models"
-	"github.com/sujalbistaa/whispr/internal/ws"
+	"github.com/sujalbistaa/whispr/internal/logging"
 )
 
-func main() {
-	// 2. LOAD .env FILE
-	// This MUST be the first thing we do.
-	if err := godotenv.Load(); err != nil {
-		// We don't panic, but we log it. This allows running in production
-		// (where env vars are set directly) without a .env file.
-		log.Println("No .env file found, reading from environment")
-	}
+const usage = `Usage: server [command] [flags]
+
+Commands:
+  serve     Run the web server (default)
+  migrate   Apply pending database migrations and exit
+  seed      Insert fake posts and votes for local development
 
-	// 1. Initialize Database
-	database, err := db.Init()
+Run "server <command> -h" for command flags.
+`
+
+func main() {
+	// Every setting is read and validated here, so a bad value stops the
+	// process before anything starts, with all problems listed together.
+	cfg, err := config.Load()
 	if err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+		fatal("invalid configuration", err)
 	}
-
-	// 2. Run Migrations
-	log.Println("Running database migrations...")
-	if err := database.AutoMigrate(&models.Post{}, &models.Vote{}); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+	if err := logging.Setup(cfg.Log.Format, cfg.Log.Level); err != nil {
+		fatal("invalid logging configuration", err)
 	}
-	log.Println("Migrations complete.")
-
-	// 3. Initialize WebSocket Hub
-	hub := ws.NewHub()
-	go hub.Run() // Run the hub in a separate goroutine
-
-	// 4. Initialize Gin Router
-	router := gin.Default()
-
-	// 5. Setup Routes
-	// This is where the panic was happening. Now it will find the env var.
-	routes.SetupRoutes(router, database, hub)
-
-	// 6. Start Server with Graceful Shutdown
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	if !cfg.DotEnv {
+		slog.Info("no .env file found, reading from environment")
 	}
 
-	srv := &http.Server{
-		Addr:    ":" + port,
-		Handler: router,
+	// No command (or only flags) means serve, so "go run ./cmd/server"
+	// keeps working as before.
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && args[0] != "" && args[0][0] != '-' {
+		command, args = args[0], args[1:]
 	}
 
-	// Channel to listen for OS signals
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-
-	// Goroutine to start the server
-	go func() {
-		log.Printf("Server listening on :%s", port)
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			log.Fatalf("listen: %s\n", err)
+	switch command {
+	case "serve":
+		runServe(cfg, args)
+	case "migrate":
+		runMigrate(cfg, args)
+	case "seed":
+		runSeed(cfg, args)
+	case "help":
+		fmt.Print(usage)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+}
+
+// runMigrate applies migrations; a failure exits non-zero so deploy
+// pipelines can gate on it. With -status it only reports the versions.
+func runMigrate(cfg config.Config, args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	status := fs.Bool("status", false, "print the schema version and pending migrations without applying them")
+	fs.Parse(args)
+
+	database := openDB(cfg)
+	if *status {
+		version, err := db.Version(database)
+		if err != nil {
+			fatal("reading schema version", err)
 		}
-	}()
-
-	// Block until a signal is received
-	<-quit
-	log.Println("Shutting down server...")
-
-	// Create a context with a 5-second timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+		fmt.Printf("schema version %d, binary expects %d\n", version, db.LatestVersion())
+		for _, m := range db.Pending(version) {
+			fmt.Printf("pending: %04d %s\n", m.Version, m.Name)
+		}
+		return
+	}
+	migrate(database)
+}
 
-	// Attempt graceful shutdown
-	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+// openDB connects to DATABASE_URL or exits.
+func openDB(cfg config.Config) *gorm.DB {
+	database, err := db.Init(cfg.DatabaseURL, cfg.DB)
+	if err != nil {
+		fatal("initializing database", err)
 	}
+	return database
+}
 
-	log.Println("Server exiting")
-}
\ No newline at end of file
+func migrate(database *gorm.DB) {
+	slog.Info("running database migrations")
+	if err := db.Migrate(database); err != nil {
+		fatal("running migrations", err)
+	}
+	slog.Info("migrations complete", "version", db.LatestVersion())
+}
+
+// fatal logs err and exits; slog has no Fatal level.
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+
+	"github.com/sujalbistaa/whispr/internal/config"
+	"github.com/sujalbistaa/whispr/internal/seed"
+)
+
+// runSeed fills the database with demo data for local development.
+func runSeed(cfg config.Config, args []string) {
+	opts := seed.DefaultOptions()
+	fs := flag.NewFlagSet("seed", flag.ExitOnError)
+	fs.IntVar(&opts.Posts, "posts", opts.Posts, "number of posts to create")
+	fs.IntVar(&opts.MaxVotes, "max-votes", opts.MaxVotes, "upper bound on votes per post")
+	fs.DurationVar(&opts.Spread, "spread", opts.Spread, "backdate posts across this window")
+	fs.Float64Var(&opts.HiddenRatio, "hidden", opts.HiddenRatio, "fraction of posts created already hidden")
+	fs.Uint64Var(&opts.RandomSeed, "seed", 0, "random seed for reproducible data (0 = random)")
+	fs.BoolVar(&opts.Wipe, "wipe", false, "delete all posts and votes first")
+	fs.Parse(args)
+	if opts.HiddenRatio < 0 || opts.HiddenRatio > 1 {
+		fatal("invalid -hidden", fmt.Errorf("must be between 0 and 1, got %v", opts.HiddenRatio))
+	}
+
+	database := openDB(cfg)
+	migrate(database)
+
+	result, err := seed.Run(database, opts)
+	if err != nil {
+		fatal("seeding database", err)
+	}
+	slog.Info("seed complete", "posts", result.Posts, "votes", result.Votes)
+}
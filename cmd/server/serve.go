@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/sujalbistaa/whispr/internal/config"
+	"github.com/sujalbistaa/whispr/internal/server"
+)
+
+// runServe starts the HTTP server and blocks until SIGINT/SIGTERM.
+func runServe(cfg config.Config, args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	skipMigrate := fs.Bool("skip-migrate", false, "don't run migrations on startup even if MIGRATE_ON_START is set")
+	retentionDryRun := fs.Bool("retention-dry-run", false, "have the retention worker log what it would delete instead of deleting it")
+	fs.Parse(args)
+	cfg.Retention.DryRun = *retentionDryRun
+	if *skipMigrate {
+		cfg.MigrateOnStart = false
+	}
+	if cfg.Retention.DryRun && !cfg.Retention.Enabled() {
+		slog.Warn("-retention-dry-run has no effect without RETENTION_INTERVAL")
+	}
+
+	slog.Info("configuration", "config", cfg)
+
+	srv, err := server.New(cfg)
+	if err != nil {
+		fatal("starting server", err)
+	}
+
+	// Reload admin tokens on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := srv.ReloadTokens(); err != nil {
+				slog.Error("reloading admin tokens", "err", err)
+			}
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := srv.Run(ctx); err != nil {
+		fatal("server", err)
+	}
+}
@@ -0,0 +1,118 @@
+// Package archive keeps the number of posts in the feeds under a soft
+// cap. Once more than MaxVisible posts are up, a Worker archives the
+// oldest posts beyond the cap that are neither recently active nor
+// well-scored: they leave every feed but stay reachable by permalink and
+// through GET /archive/posts, and an admin can unarchive them.
+//
+// Archiving isn't moderation, so it broadcasts nothing and isn't
+// audited. Posts are archived by a conditional update of archived_at and
+// the count is taken again before each batch, so instances running the
+// worker side by side archive each post once and overshoot the cap by at
+// most a batch.
+package archive
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sujalbistaa/whispr/internal/metrics"
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+// Config configures a Worker.
+type Config struct {
+	Interval   time.Duration // Time between runs; 0 disables the worker
+	MaxVisible int           // Posts kept in the feeds; 0 disables the worker
+	KeepActive time.Duration // Posts active more recently are never archived
+	KeepScore  int           // Posts scoring at least this are never archived
+	BatchSize  int           // Posts archived per update
+}
+
+// Enabled reports whether the worker should run.
+func (c Config) Enabled() bool {
+	return c.Interval > 0 && c.MaxVisible > 0
+}
+
+// Worker archives posts on an interval.
+type Worker struct {
+	db  *gorm.DB
+	cfg Config
+}
+
+// New returns a worker for cfg; call Run to start it.
+func New(db *gorm.DB, cfg Config) *Worker {
+	return &Worker{db: db, cfg: cfg}
+}
+
+// Run archives immediately and then every Interval until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	slog.Info("archive worker started", "interval", w.cfg.Interval, "maxVisible", w.cfg.MaxVisible)
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		w.runAndLog(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			slog.Info("archive worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) runAndLog(ctx context.Context, now time.Time) {
+	start := time.Now()
+	archived, visible, err := w.Archive(ctx, now)
+	if err != nil && ctx.Err() == nil {
+		slog.Error("archiving posts failed", "archived", archived, "err", err)
+		return
+	}
+	attrs := []any{"archived", archived, "visible", visible, "maxVisible", w.cfg.MaxVisible, "duration", time.Since(start)}
+	if visible > int64(w.cfg.MaxVisible) {
+		// Everything left is active or well-scored; the cap is soft.
+		slog.Warn("posts over MAX_VISIBLE_POSTS can't be archived yet", attrs...)
+		return
+	}
+	slog.Info("archive run complete", attrs...)
+}
+
+// Archive archives the oldest posts beyond MaxVisible, as of now, that
+// weren't active within KeepActive and score below KeepScore. It returns
+// how many it archived and how many posts are left in the feeds. Hidden
+// posts don't count towards the cap.
+func (w *Worker) Archive(ctx context.Context, now time.Time) (archived, visible int64, err error) {
+	database := w.db.WithContext(ctx)
+	inactive := now.Add(-w.cfg.KeepActive)
+	for {
+		if err := database.Model(&models.Post{}).Where("archived_at IS NULL").Count(&visible).Error; err != nil {
+			return archived, visible, err
+		}
+		excess := visible - int64(w.cfg.MaxVisible)
+		if excess <= 0 {
+			return archived, visible, nil
+		}
+
+		var ids []uint
+		if err := database.Model(&models.Post{}).
+			Where("archived_at IS NULL AND last_activity_at < ? AND score < ?", inactive, w.cfg.KeepScore).
+			Order("created_at, id").Limit(int(min(excess, int64(w.cfg.BatchSize)))).
+			Pluck("id", &ids).Error; err != nil {
+			return archived, visible, err
+		}
+		if len(ids) == 0 {
+			return archived, visible, nil
+		}
+		// updated_at moves so cached feeds and their ETags change.
+		res := database.Model(&models.Post{}).Where("id IN ? AND archived_at IS NULL", ids).
+			UpdateColumns(map[string]any{"archived_at": now, "updated_at": now})
+		if res.Error != nil {
+			return archived, visible, res.Error
+		}
+		archived += res.RowsAffected
+		visible -= res.RowsAffected
+		metrics.PostsArchived.Add(float64(res.RowsAffected))
+	}
+}
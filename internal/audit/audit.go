@@ -0,0 +1,70 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"gorm.io/gorm"
+
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+// Actions recorded in the audit log.
+const (
+	ActionHidePost       = "post.hide"
+	ActionBanAdd         = "ban.add"
+	ActionBanRemove      = "ban.remove"
+	ActionAnnounce       = "announcement.create"
+	ActionExport         = "post.export"
+	ActionHideByKeyword  = "post.hide_by_keyword"
+	ActionMaintenance    = "maintenance.set"
+	ActionFlagSet        = "flag.set"
+	ActionHoldPost       = "post.hold"
+	ActionApprovePost    = "post.approve"
+	ActionBoardCreate    = "board.create"
+	ActionAPIKeyCreate   = "api_key.create"
+	ActionAPIKeyRevoke   = "api_key.revoke"
+	ActionContentWarning = "post.content_warning"
+	ActionUndoHide       = "post.undo_hide"
+	ActionUnarchive      = "post.unarchive"
+	ActionStrike         = "identity.strike"
+	ActionStrikeForgive  = "identity.strike_forgive"
+	ActionCooldown       = "identity.cooldown"
+	ActionStrikesClear   = "identity.strikes_clear"
+)
+
+// Target types recorded in the audit log.
+const (
+	TargetPost         = "post"
+	TargetBan          = "ban"
+	TargetAnnouncement = "announcement"
+	TargetMaintenance  = "maintenance"
+	TargetFlag         = "flag"
+	TargetBoard        = "board"
+	TargetAPIKey       = "api_key"
+	TargetIdentity     = "identity"
+)
+
+// ActorSpamFilter is recorded as the actor of posts the server holds for
+// moderation itself, in place of an admin token fingerprint.
+const ActorSpamFilter = "spam-filter"
+
+// Fingerprint returns a short, stable hash identifying an admin token.
+// The raw token is never stored.
+func Fingerprint(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Record writes an audit entry using the given handle.
+// Pass the transaction performing the action so the entry commits with it.
+func Record(tx *gorm.DB, actor, action, targetType string, targetID uint, metadata map[string]any) error {
+	entry := models.AuditLog{
+		Action:                action,
+		TargetType:            targetType,
+		TargetID:              targetID,
+		ActorTokenFingerprint: actor,
+		Metadata:              metadata,
+	}
+	return tx.Create(&entry).Error
+}
@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+)
+
+// Scope is what a public API key may do.
+type Scope string
+
+// ScopeRead keys may only make GET (and HEAD) requests.
+const ScopeRead Scope = "read"
+
+const (
+	// apiKeyPrefix marks whispr API keys, so a leaked one is recognizable.
+	apiKeyPrefix = "wk_"
+	// APIKeyDisplayLen is how much of a key is kept in the clear to tell
+	// keys apart in listings: the prefix and a few random characters.
+	APIKeyDisplayLen = len(apiKeyPrefix) + 8
+)
+
+// NewAPIKey returns a random API key and its hash. Only the hash is
+// stored; the key is shown to the admin once.
+func NewAPIKey() (key, hash string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", fmt.Errorf("generating API key: %w", err)
+	}
+	key = apiKeyPrefix + base64.RawURLEncoding.EncodeToString(raw)
+	return key, HashAPIKey(key), nil
+}
+
+// HashAPIKey returns the stored form of key. Keys carry 256 random bits,
+// so a plain SHA-256 can't be brute-forced and lookups stay one indexed
+// query.
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Role is the permission level attached to an admin token.
+type Role string
+
+const (
+	// RoleModerator can review content and hide posts.
+	RoleModerator Role = "moderator"
+	// RoleAdmin can do everything a moderator can, plus bans and configuration.
+	RoleAdmin Role = "admin"
+)
+
+// rank orders roles so that higher roles satisfy lower requirements.
+var rank = map[Role]int{
+	RoleModerator: 1,
+	RoleAdmin:     2,
+}
+
+// Allows reports whether r satisfies the required role.
+func (r Role) Allows(required Role) bool {
+	return rank[r] >= rank[required]
+}
+
+// ParseRole validates a role name.
+func ParseRole(s string) (Role, error) {
+	role := Role(strings.ToLower(strings.TrimSpace(s)))
+	if _, ok := rank[role]; !ok {
+		return "", fmt.Errorf("unknown role %q", s)
+	}
+	return role, nil
+}
+
+type tokenEntry struct {
+	token []byte
+	role  Role
+}
+
+// Sources names where admin tokens come from. Any combination may be set.
+type Sources struct {
+	Token      string // X_ADMIN_TOKEN: a single token with the admin role (legacy)
+	TokenFile  string // X_ADMIN_TOKEN_FILE: a file containing a single admin-role token
+	Tokens     string // X_ADMIN_TOKENS: comma-separated "role:token" pairs
+	TokensFile string // X_ADMIN_TOKENS_FILE: a file with one "role token" pair per line
+}
+
+// TokenStore holds the configured admin tokens and their roles.
+// Call Reload to pick up changes to the token files without restarting.
+type TokenStore struct {
+	mu      sync.RWMutex
+	sources Sources
+	tokens  []tokenEntry
+}
+
+// NewTokenStore loads tokens from sources.
+func NewTokenStore(sources Sources) (*TokenStore, error) {
+	s := &TokenStore{sources: sources}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload re-reads the token files and atomically swaps them in.
+// On error the previous tokens stay active.
+func (s *TokenStore) Reload() error {
+	tokens, err := loadTokens(s.sources)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.tokens = tokens
+	s.mu.Unlock()
+	return nil
+}
+
+// Len returns the number of configured tokens.
+func (s *TokenStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.tokens)
+}
+
+// Resolve returns the role for the supplied token.
+// Every configured token is compared in constant time.
+func (s *TokenStore) Resolve(supplied string) (Role, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var role Role
+	found := false
+	for _, entry := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(supplied), entry.token) == 1 && !found {
+			role, found = entry.role, true
+		}
+	}
+	return role, found
+}
+
+func loadTokens(src Sources) ([]tokenEntry, error) {
+	var tokens []tokenEntry
+
+	if token := src.Token; token != "" {
+		tokens = append(tokens, tokenEntry{token: []byte(token), role: RoleAdmin})
+	}
+
+	if path := src.TokenFile; path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("X_ADMIN_TOKEN_FILE: %w", err)
+		}
+		token := strings.TrimSpace(string(data))
+		if token == "" {
+			return nil, fmt.Errorf("X_ADMIN_TOKEN_FILE: %s is empty", path)
+		}
+		tokens = append(tokens, tokenEntry{token: []byte(token), role: RoleAdmin})
+	}
+
+	if raw := src.Tokens; raw != "" {
+		for _, pair := range strings.Split(raw, ",") {
+			roleName, token, ok := strings.Cut(strings.TrimSpace(pair), ":")
+			if !ok || token == "" {
+				return nil, fmt.Errorf("X_ADMIN_TOKENS: expected role:token, got %q", pair)
+			}
+			role, err := ParseRole(roleName)
+			if err != nil {
+				return nil, fmt.Errorf("X_ADMIN_TOKENS: %w", err)
+			}
+			tokens = append(tokens, tokenEntry{token: []byte(token), role: role})
+		}
+	}
+
+	if path := src.TokensFile; path != "" {
+		fileTokens, err := loadTokenFile(path)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, fileTokens...)
+	}
+
+	return tokens, nil
+}
+
+// loadTokenFile parses "role token" lines, skipping blanks and # comments.
+func loadTokenFile(path string) ([]tokenEntry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("X_ADMIN_TOKENS_FILE: %w", err)
+	}
+	defer f.Close()
+
+	var tokens []tokenEntry
+	scanner := bufio.NewScanner(f)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"role token\"", path, line)
+		}
+		role, err := ParseRole(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		tokens = append(tokens, tokenEntry{token: []byte(fields[1]), role: role})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("X_ADMIN_TOKENS_FILE: %w", err)
+	}
+	return tokens, nil
+}
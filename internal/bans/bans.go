@@ -0,0 +1,139 @@
+package bans
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+type entry struct {
+	id        uint
+	shadow    bool
+	prefix    netip.Prefix
+	expiresAt *time.Time
+}
+
+// Match describes the ban an address fell under.
+type Match struct {
+	ID     uint
+	Shadow bool
+}
+
+// List is an in-memory cache of the banned_ips table.
+// It loads lazily and reloads after Invalidate is called.
+type List struct {
+	db      *gorm.DB
+	mu      sync.RWMutex
+	entries []entry
+	loaded  bool
+}
+
+// NewList creates a ban list backed by the given database.
+func NewList(db *gorm.DB) *List {
+	return &List{db: db}
+}
+
+// ParsePrefix accepts a single IP ("10.0.0.1") or a CIDR ("10.0.0.0/8")
+// and returns it as a masked prefix. IPv4-mapped IPv6 prefixes
+// ("::ffff:10.0.0.0/104") are returned as the IPv4 prefix they cover,
+// since Lookup unmaps client addresses before matching.
+func ParsePrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		if prefix.Addr().Is4In6() {
+			if prefix.Bits() < 96 {
+				return netip.Prefix{}, fmt.Errorf("IPv4-mapped prefix %s must be /96 or longer", s)
+			}
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+// Invalidate forces the next lookup to reload bans from the database.
+func (l *List) Invalidate() {
+	l.mu.Lock()
+	l.loaded = false
+	l.mu.Unlock()
+}
+
+// Lookup returns the unexpired ban covering ip, if any.
+// Outright bans take precedence over shadow bans when both match.
+func (l *List) Lookup(ip string) (Match, bool, error) {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return Match{}, false, nil
+	}
+	addr = addr.Unmap()
+
+	entries, err := l.snapshot()
+	if err != nil {
+		return Match{}, false, err
+	}
+	now := time.Now()
+	var found Match
+	var ok bool
+	for _, e := range entries {
+		if e.expiresAt != nil && !e.expiresAt.After(now) {
+			continue
+		}
+		if !e.prefix.Contains(addr) {
+			continue
+		}
+		if !e.shadow {
+			return Match{ID: e.id}, true, nil
+		}
+		if !ok {
+			found, ok = Match{ID: e.id, Shadow: true}, true
+		}
+	}
+	return found, ok, nil
+}
+
+// snapshot returns the cached entries, reloading them if invalidated.
+func (l *List) snapshot() ([]entry, error) {
+	l.mu.RLock()
+	if l.loaded {
+		entries := l.entries
+		l.mu.RUnlock()
+		return entries, nil
+	}
+	l.mu.RUnlock()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.loaded {
+		return l.entries, nil
+	}
+
+	var rows []models.BannedIP
+	if err := l.db.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	entries := make([]entry, 0, len(rows))
+	for _, row := range rows {
+		prefix, err := ParsePrefix(row.CIDR)
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry{id: row.ID, shadow: row.Shadow, prefix: prefix, expiresAt: row.ExpiresAt})
+	}
+	l.entries = entries
+	l.loaded = true
+	return entries, nil
+}
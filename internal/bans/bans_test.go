@@ -0,0 +1,140 @@
+package bans_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sujalbistaa/whispr/internal/bans"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+func TestParsePrefix(t *testing.T) {
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"10.0.0.1", "10.0.0.1/32"},
+		{" 10.0.0.1 ", "10.0.0.1/32"},
+		{"::ffff:10.0.0.1", "10.0.0.1/32"},
+		{"2001:db8::1", "2001:db8::1/128"},
+		{"10.1.2.3/8", "10.0.0.0/8"},
+		{"2001:db8::1/32", "2001:db8::/32"},
+		{"::ffff:10.0.0.0/104", "10.0.0.0/8"},
+		{"::ffff:10.1.2.3/120", "10.1.2.0/24"},
+		{"::ffff:0.0.0.0/96", "0.0.0.0/0"},
+	} {
+		got, err := bans.ParsePrefix(tc.in)
+		if err != nil {
+			t.Errorf("ParsePrefix(%q): %v", tc.in, err)
+			continue
+		}
+		if got.String() != tc.want {
+			t.Errorf("ParsePrefix(%q) = %s, want %s", tc.in, got, tc.want)
+		}
+	}
+
+	for _, in := range []string{"", "10.0.0", "10.0.0.0/33", "not-an-ip", "::ffff:0.0.0.0/80"} {
+		if got, err := bans.ParsePrefix(in); err == nil {
+			t.Errorf("ParsePrefix(%q) = %s, want an error", in, got)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	database := testutil.NewDB(t)
+	past := time.Now().Add(-time.Minute)
+	future := time.Now().Add(time.Hour)
+	rows := []models.BannedIP{
+		{CIDR: "10.0.0.0/8"},
+		{CIDR: "192.168.1.0/24", Shadow: true},
+		{CIDR: "192.168.1.7/32"},
+		{CIDR: "172.16.0.0/12", ExpiresAt: &past},
+		{CIDR: "172.16.5.0/24", Shadow: true, ExpiresAt: &future},
+		{CIDR: "2001:db8::/32"},
+	}
+	for i := range rows {
+		if err := database.Create(&rows[i]).Error; err != nil {
+			t.Fatalf("creating ban %s: %v", rows[i].CIDR, err)
+		}
+	}
+	list := bans.NewList(database)
+
+	for _, tc := range []struct {
+		ip     string
+		want   *bans.Match
+		reason string
+	}{
+		{"10.200.3.4", &bans.Match{ID: rows[0].ID}, "inside a /8"},
+		{"::ffff:10.200.3.4", &bans.Match{ID: rows[0].ID}, "IPv4-mapped client"},
+		{"11.0.0.1", nil, "outside every range"},
+		{"192.168.1.8", &bans.Match{ID: rows[1].ID, Shadow: true}, "shadow range"},
+		{"192.168.1.7", &bans.Match{ID: rows[2].ID}, "outright ban wins over shadow"},
+		{"172.16.9.9", nil, "expired ban"},
+		{"172.16.5.1", &bans.Match{ID: rows[4].ID, Shadow: true}, "unexpired inside expired"},
+		{"2001:db8:1::1", &bans.Match{ID: rows[5].ID}, "IPv6 range"},
+		{"garbage", nil, "unparseable address"},
+	} {
+		got, ok, err := list.Lookup(tc.ip)
+		if err != nil {
+			t.Fatalf("Lookup(%s): %v", tc.ip, err)
+		}
+		switch {
+		case tc.want == nil && ok:
+			t.Errorf("Lookup(%s) (%s) = %+v, want no match", tc.ip, tc.reason, got)
+		case tc.want != nil && (!ok || got != *tc.want):
+			t.Errorf("Lookup(%s) (%s) = %+v, %v, want %+v", tc.ip, tc.reason, got, ok, *tc.want)
+		}
+	}
+}
+
+func TestLookupMappedPrefix(t *testing.T) {
+	database := testutil.NewDB(t)
+	prefix, err := bans.ParsePrefix("::ffff:10.0.0.0/104")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ban := models.BannedIP{CIDR: prefix.String()}
+	if err := database.Create(&ban).Error; err != nil {
+		t.Fatal(err)
+	}
+	list := bans.NewList(database)
+	for _, ip := range []string{"10.1.2.3", "::ffff:10.1.2.3"} {
+		if _, ok, _ := list.Lookup(ip); !ok {
+			t.Errorf("Lookup(%s) didn't match %s", ip, ban.CIDR)
+		}
+	}
+}
+
+func TestLookupExpiresWithoutReload(t *testing.T) {
+	database := testutil.NewDB(t)
+	soon := time.Now().Add(200 * time.Millisecond)
+	if err := database.Create(&models.BannedIP{CIDR: "10.0.0.1/32", ExpiresAt: &soon}).Error; err != nil {
+		t.Fatal(err)
+	}
+	list := bans.NewList(database)
+	if _, ok, _ := list.Lookup("10.0.0.1"); !ok {
+		t.Fatal("ban didn't match before expiring")
+	}
+	time.Sleep(time.Until(soon) + 10*time.Millisecond)
+	if _, ok, _ := list.Lookup("10.0.0.1"); ok {
+		t.Error("ban still matched after expiring")
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	database := testutil.NewDB(t)
+	list := bans.NewList(database)
+	if _, ok, _ := list.Lookup("10.0.0.1"); ok {
+		t.Fatal("matched with no bans")
+	}
+	if err := database.Create(&models.BannedIP{CIDR: "10.0.0.1/32"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := list.Lookup("10.0.0.1"); ok {
+		t.Error("matched a ban before Invalidate; the list should be cached")
+	}
+	list.Invalidate()
+	if _, ok, _ := list.Lookup("10.0.0.1"); !ok {
+		t.Error("didn't match a ban after Invalidate")
+	}
+}
@@ -0,0 +1,90 @@
+// Package challenge makes posting cost the client something, to slow down
+// scripted spam: either a hashcash-style proof of work solved in the
+// browser, or a CAPTCHA token checked with hCaptcha or Cloudflare
+// Turnstile. Clients fetch what to solve from GET /api/challenge and send
+// the solution with the post in the X-Challenge header.
+package challenge
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Mode selects the kind of challenge.
+type Mode string
+
+const (
+	ModeOff       Mode = "off"
+	ModePoW       Mode = "pow"
+	ModeHCaptcha  Mode = "hcaptcha"
+	ModeTurnstile Mode = "turnstile"
+)
+
+// Modes lists every supported mode.
+var Modes = []Mode{ModeOff, ModePoW, ModeHCaptcha, ModeTurnstile}
+
+// Config configures the challenge on post creation.
+type Config struct {
+	Mode Mode
+
+	// Proof of work.
+	Difficulty int           // Leading zero bits the hash must have
+	TTL        time.Duration // How long an issued challenge can be solved
+	MaxPending int           // Issued, unsolved challenges kept in memory
+
+	// CAPTCHA.
+	SiteKey   string // Handed to clients to render the widget
+	Secret    string
+	VerifyURL string // Provider's siteverify endpoint; empty uses the default
+}
+
+// Enabled reports whether posts need a solved challenge.
+func (c Config) Enabled() bool {
+	return c.Mode != "" && c.Mode != ModeOff
+}
+
+// Challenge tells a client what to solve.
+type Challenge struct {
+	Type Mode `json:"type"`
+
+	// Proof of work: find a nonce such that SHA-256("<id>:<nonce>") starts
+	// with Difficulty zero bits, then send "<id>:<nonce>".
+	ID         string     `json:"id,omitempty"`
+	Difficulty int        `json:"difficulty,omitempty"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+
+	// CAPTCHA: render the provider's widget with SiteKey and send the
+	// token it produces.
+	SiteKey string `json:"siteKey,omitempty"`
+}
+
+// Errors returned by Verifier.Verify.
+var (
+	ErrMissing     = errors.New("challenge: no solution")
+	ErrInvalid     = errors.New("challenge: invalid solution")
+	ErrExpired     = errors.New("challenge: expired or already used")
+	ErrUnavailable = errors.New("challenge: verifier unavailable")
+)
+
+// Verifier issues challenges and checks their solutions. Implementations
+// must be safe for concurrent use.
+type Verifier interface {
+	// Issue returns a challenge for a client to solve.
+	Issue(ctx context.Context) (Challenge, error)
+	// Verify checks a solution sent by the client at remoteIP. A solution
+	// is accepted once; replaying it fails with ErrExpired.
+	Verify(ctx context.Context, solution, remoteIP string) error
+}
+
+// New returns the verifier for cfg.Mode. Proof-of-work challenges are kept
+// in store; CAPTCHA modes don't use it.
+func New(cfg Config, store Store) Verifier {
+	switch cfg.Mode {
+	case ModePoW:
+		return NewPoW(store, cfg.Difficulty, cfg.TTL)
+	case ModeHCaptcha, ModeTurnstile:
+		return NewCaptcha(cfg.Mode, cfg.SiteKey, cfg.Secret, cfg.VerifyURL)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package challenge_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/sujalbistaa/whispr/internal/challenge"
+)
+
+const difficulty = 8
+
+// solve finds a nonce for c the way a client would.
+func solve(t *testing.T, c challenge.Challenge) string {
+	t.Helper()
+	for nonce := 0; ; nonce++ {
+		solution := c.ID + ":" + strconv.Itoa(nonce)
+		if challenge.LeadingZeroBits(sha256.Sum256([]byte(solution))) >= c.Difficulty {
+			return solution
+		}
+	}
+}
+
+// wrong returns a solution for c that doesn't have the work done.
+func wrong(t *testing.T, c challenge.Challenge) string {
+	t.Helper()
+	for nonce := 0; ; nonce++ {
+		solution := c.ID + ":" + strconv.Itoa(nonce)
+		if challenge.LeadingZeroBits(sha256.Sum256([]byte(solution))) < c.Difficulty {
+			return solution
+		}
+	}
+}
+
+// stores returns each Store implementation with a way to move its clock
+// past a TTL.
+func stores(t *testing.T) map[string]struct {
+	store challenge.Store
+	age   func(time.Duration)
+} {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return map[string]struct {
+		store challenge.Store
+		age   func(time.Duration)
+	}{
+		"memory": {challenge.NewMemoryStore(100), time.Sleep},
+		"redis":  {challenge.NewRedisStore(client, "challenge:"), mr.FastForward},
+	}
+}
+
+func TestPoW(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			pow := challenge.NewPoW(s.store, difficulty, time.Minute)
+			c, err := pow.Issue(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Type != challenge.ModePoW || c.ID == "" || c.Difficulty != difficulty || c.ExpiresAt == nil {
+				t.Fatalf("issued %+v", c)
+			}
+
+			for _, tc := range []struct {
+				solution string
+				want     error
+			}{
+				{"", challenge.ErrMissing},
+				{c.ID, challenge.ErrInvalid},
+				{c.ID + ":", challenge.ErrInvalid},
+				{":" + "123", challenge.ErrInvalid},
+				// Wrong work doesn't burn the challenge.
+				{wrong(t, c), challenge.ErrInvalid},
+			} {
+				if err := pow.Verify(ctx, tc.solution, ""); !errors.Is(err, tc.want) {
+					t.Errorf("Verify(%q) = %v, want %v", tc.solution, err, tc.want)
+				}
+			}
+
+			solution := solve(t, c)
+			if err := pow.Verify(ctx, solution, ""); err != nil {
+				t.Fatalf("Verify(solution) = %v", err)
+			}
+			if err := pow.Verify(ctx, solution, ""); !errors.Is(err, challenge.ErrExpired) {
+				t.Errorf("replayed solution: %v, want ErrExpired", err)
+			}
+
+			// Work done for an ID the server never issued.
+			forged := solve(t, challenge.Challenge{ID: "0123456789abcdef", Difficulty: difficulty})
+			if err := pow.Verify(ctx, forged, ""); !errors.Is(err, challenge.ErrExpired) {
+				t.Errorf("unissued ID: %v, want ErrExpired", err)
+			}
+		})
+	}
+}
+
+func TestPoWExpiry(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			const ttl = 50 * time.Millisecond
+			pow := challenge.NewPoW(s.store, difficulty, ttl)
+			c, err := pow.Issue(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			solution := solve(t, c)
+			s.age(2 * ttl)
+			if err := pow.Verify(ctx, solution, ""); !errors.Is(err, challenge.ErrExpired) {
+				t.Errorf("solution after the TTL: %v, want ErrExpired", err)
+			}
+		})
+	}
+}
+
+// TestPoWConcurrentReplay sends one solution many times at once: exactly
+// one may get through.
+func TestPoWConcurrentReplay(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			pow := challenge.NewPoW(s.store, difficulty, time.Minute)
+			c, err := pow.Issue(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			solution := solve(t, c)
+			var accepted atomic.Int32
+			var wg sync.WaitGroup
+			for i := 0; i < 20; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					switch err := pow.Verify(ctx, solution, ""); {
+					case err == nil:
+						accepted.Add(1)
+					case !errors.Is(err, challenge.ErrExpired):
+						t.Errorf("Verify: %v", err)
+					}
+				}()
+			}
+			wg.Wait()
+			if n := accepted.Load(); n != 1 {
+				t.Errorf("%d of 20 concurrent submissions accepted, want 1", n)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreFull(t *testing.T) {
+	ctx := context.Background()
+	store := challenge.NewMemoryStore(2)
+	store.Put(ctx, "a", 20*time.Millisecond)
+	store.Put(ctx, "b", time.Minute)
+	if err := store.Put(ctx, "c", time.Minute); !errors.Is(err, challenge.ErrFull) {
+		t.Fatalf("third Put: %v, want ErrFull", err)
+	}
+	// Expired challenges make room once swept.
+	time.Sleep(30 * time.Millisecond)
+	if err := store.Put(ctx, "c", time.Minute); err != nil {
+		t.Errorf("Put after one expired: %v", err)
+	}
+	pow := challenge.NewPoW(store, difficulty, time.Minute)
+	if _, err := pow.Issue(ctx); !errors.Is(err, challenge.ErrUnavailable) {
+		t.Errorf("Issue on a full store: %v, want ErrUnavailable", err)
+	}
+}
+
+func TestCaptcha(t *testing.T) {
+	var got map[string]string
+	var reply string
+	status := http.StatusOK
+	siteverify := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		got = map[string]string{}
+		for key := range r.PostForm {
+			got[key] = r.PostForm.Get(key)
+		}
+		w.WriteHeader(status)
+		w.Write([]byte(reply))
+	}))
+	defer siteverify.Close()
+	ctx := context.Background()
+	captcha := challenge.NewCaptcha(challenge.ModeTurnstile, "site-key", "secret", siteverify.URL)
+
+	c, err := captcha.Issue(ctx)
+	if err != nil || c.Type != challenge.ModeTurnstile || c.SiteKey != "site-key" {
+		t.Errorf("Issue = %+v, %v", c, err)
+	}
+	if err := captcha.Verify(ctx, "", "203.0.113.9"); !errors.Is(err, challenge.ErrMissing) {
+		t.Errorf("no token: %v, want ErrMissing", err)
+	}
+
+	for _, tc := range []struct {
+		reply  any
+		status int
+		want   error
+	}{
+		{map[string]any{"success": true}, http.StatusOK, nil},
+		{map[string]any{"success": false, "error-codes": []string{"timeout-or-duplicate"}}, http.StatusOK, challenge.ErrExpired},
+		{map[string]any{"success": false, "error-codes": []string{"invalid-input-response"}}, http.StatusOK, challenge.ErrInvalid},
+		{map[string]any{}, http.StatusInternalServerError, challenge.ErrUnavailable},
+	} {
+		body, _ := json.Marshal(tc.reply)
+		reply, status = string(body), tc.status
+		err := captcha.Verify(ctx, "the-token", "203.0.113.9")
+		if tc.want == nil && err != nil || tc.want != nil && !errors.Is(err, tc.want) {
+			t.Errorf("siteverify %d %s: %v, want %v", tc.status, body, err, tc.want)
+		}
+		if got["secret"] != "secret" || got["response"] != "the-token" || got["remoteip"] != "203.0.113.9" {
+			t.Errorf("siteverify got form %v", got)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package challenge
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/bits"
+	"strings"
+	"time"
+)
+
+// maxNonceLen bounds the nonce a client may send; real solutions need a
+// handful of digits.
+const maxNonceLen = 64
+
+// PoW issues hashcash-style challenges. Each one is a random ID stored
+// until it expires or is redeemed, so a solution works exactly once.
+type PoW struct {
+	store      Store
+	difficulty int
+	ttl        time.Duration
+}
+
+// NewPoW returns a verifier whose challenges need difficulty leading zero
+// bits and can be solved for ttl.
+func NewPoW(store Store, difficulty int, ttl time.Duration) *PoW {
+	return &PoW{store: store, difficulty: difficulty, ttl: ttl}
+}
+
+func (p *PoW) Issue(ctx context.Context) (Challenge, error) {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	id := hex.EncodeToString(buf)
+	if err := p.store.Put(ctx, id, p.ttl); err != nil {
+		return Challenge{}, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	expires := time.Now().Add(p.ttl).UTC()
+	return Challenge{Type: ModePoW, ID: id, Difficulty: p.difficulty, ExpiresAt: &expires}, nil
+}
+
+// Verify checks the work before redeeming the challenge, so a wrong
+// answer doesn't burn it.
+func (p *PoW) Verify(ctx context.Context, solution, _ string) error {
+	if solution == "" {
+		return ErrMissing
+	}
+	id, nonce, ok := strings.Cut(solution, ":")
+	if !ok || id == "" || nonce == "" || len(nonce) > maxNonceLen {
+		return ErrInvalid
+	}
+	if LeadingZeroBits(sha256.Sum256([]byte(solution))) < p.difficulty {
+		return ErrInvalid
+	}
+	taken, err := p.store.Take(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	if !taken {
+		return ErrExpired
+	}
+	return nil
+}
+
+// LeadingZeroBits counts the zero bits at the start of sum.
+func LeadingZeroBits(sum [sha256.Size]byte) int {
+	n := 0
+	for _, b := range sum {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
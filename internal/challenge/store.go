@@ -0,0 +1,89 @@
+package challenge
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrFull is returned by MemoryStore.Put when too many challenges are
+// pending.
+var ErrFull = errors.New("challenge: too many pending challenges")
+
+// Store keeps issued challenges until they are redeemed or expire.
+type Store interface {
+	// Put records id for ttl.
+	Put(ctx context.Context, id string, ttl time.Duration) error
+	// Take removes id and reports whether it was there and unexpired. It
+	// must be atomic: of two concurrent Takes of the same id, one wins.
+	Take(ctx context.Context, id string) (bool, error)
+}
+
+// MemoryStore keeps challenges in this process. Replicas don't share it;
+// use RedisStore when running more than one.
+type MemoryStore struct {
+	mu      sync.Mutex
+	expires map[string]time.Time
+	max     int
+}
+
+// NewMemoryStore returns a store holding at most max pending challenges.
+func NewMemoryStore(max int) *MemoryStore {
+	return &MemoryStore{expires: make(map[string]time.Time), max: max}
+}
+
+func (s *MemoryStore) Put(_ context.Context, id string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if len(s.expires) >= s.max {
+		// Only sweep when full, so issuing stays O(1) in the common case.
+		for k, exp := range s.expires {
+			if !now.Before(exp) {
+				delete(s.expires, k)
+			}
+		}
+		if len(s.expires) >= s.max {
+			return ErrFull
+		}
+	}
+	s.expires[id] = now.Add(ttl)
+	return nil
+}
+
+func (s *MemoryStore) Take(_ context.Context, id string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	exp, ok := s.expires[id]
+	if !ok {
+		return false, nil
+	}
+	delete(s.expires, id)
+	return time.Now().Before(exp), nil
+}
+
+// RedisStore keeps challenges in Redis with their TTL, shared by every
+// replica.
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisStore returns a store keeping challenges under prefix.
+func NewRedisStore(client *redis.Client, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+func (s *RedisStore) Put(ctx context.Context, id string, ttl time.Duration) error {
+	return s.client.Set(ctx, s.prefix+id, 1, ttl).Err()
+}
+
+// Take relies on DEL reporting how many keys it removed: only one caller
+// sees 1, and Redis has already dropped expired keys.
+func (s *RedisStore) Take(ctx context.Context, id string) (bool, error) {
+	n, err := s.client.Del(ctx, s.prefix+id).Result()
+	return n == 1, err
+}
@@ -0,0 +1,31 @@
+package config
+
+import (
+	"time"
+
+	"github.com/sujalbistaa/whispr/internal/archive"
+)
+
+const (
+	defaultArchiveInterval   = time.Hour
+	defaultArchiveKeepActive = 7 * 24 * time.Hour
+	defaultArchiveKeepScore  = 10
+	defaultArchiveBatchSize  = 500
+)
+
+// archive reads MAX_VISIBLE_POSTS, which enables the archive worker, with
+// ARCHIVE_INTERVAL, ARCHIVE_KEEP_ACTIVE, ARCHIVE_KEEP_SCORE and
+// ARCHIVE_BATCH_SIZE.
+func (l *loader) archive() archive.Config {
+	cfg := archive.Config{
+		Interval:   l.duration("ARCHIVE_INTERVAL", defaultArchiveInterval),
+		MaxVisible: l.nonNegativeInt("MAX_VISIBLE_POSTS", 0),
+		KeepActive: l.duration("ARCHIVE_KEEP_ACTIVE", defaultArchiveKeepActive),
+		KeepScore:  l.nonNegativeInt("ARCHIVE_KEEP_SCORE", defaultArchiveKeepScore),
+		BatchSize:  l.positiveInt("ARCHIVE_BATCH_SIZE", defaultArchiveBatchSize),
+	}
+	if cfg.MaxVisible > 0 && cfg.Interval == 0 {
+		l.failf("ARCHIVE_INTERVAL", "must be positive when MAX_VISIBLE_POSTS is set")
+	}
+	return cfg
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sujalbistaa/whispr/internal/challenge"
+)
+
+const (
+	defaultChallengeDifficulty = 16
+	defaultChallengeTTL        = 2 * time.Minute
+	defaultChallengeMaxPending = 100_000
+
+	// Beyond this a browser takes minutes per post.
+	maxChallengeDifficulty = 32
+)
+
+// challenge reads CHALLENGE_MODE (off, pow, hcaptcha or turnstile) with
+// CHALLENGE_DIFFICULTY, CHALLENGE_TTL and CHALLENGE_MAX_PENDING for proof
+// of work, or CAPTCHA_SITE_KEY, CAPTCHA_SECRET and CAPTCHA_VERIFY_URL for
+// the CAPTCHA providers.
+func (l *loader) challenge() challenge.Config {
+	cfg := challenge.Config{
+		Mode:       challenge.Mode(strings.ToLower(l.string("CHALLENGE_MODE", string(challenge.ModeOff)))),
+		Difficulty: l.positiveInt("CHALLENGE_DIFFICULTY", defaultChallengeDifficulty),
+		TTL:        l.duration("CHALLENGE_TTL", defaultChallengeTTL),
+		MaxPending: l.positiveInt("CHALLENGE_MAX_PENDING", defaultChallengeMaxPending),
+		SiteKey:    l.string("CAPTCHA_SITE_KEY", ""),
+		Secret:     os.Getenv("CAPTCHA_SECRET"),
+		VerifyURL:  l.string("CAPTCHA_VERIFY_URL", ""),
+	}
+	switch cfg.Mode {
+	case challenge.ModePoW:
+		if cfg.Difficulty > maxChallengeDifficulty {
+			l.failf("CHALLENGE_DIFFICULTY", "must be at most %d bits, got %d", maxChallengeDifficulty, cfg.Difficulty)
+		}
+		if cfg.TTL <= 0 {
+			l.failf("CHALLENGE_TTL", "must be positive")
+		}
+	case challenge.ModeHCaptcha, challenge.ModeTurnstile:
+		if cfg.SiteKey == "" || cfg.Secret == "" {
+			l.failf("CHALLENGE_MODE", "%s needs CAPTCHA_SITE_KEY and CAPTCHA_SECRET", cfg.Mode)
+		}
+		if cfg.VerifyURL != "" {
+			if u, err := url.Parse(cfg.VerifyURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				l.failf("CAPTCHA_VERIFY_URL", "expected an absolute http(s) URL, got %q", cfg.VerifyURL)
+			}
+		}
+	default:
+		if !slices.Contains(challenge.Modes, cfg.Mode) {
+			l.failf("CHALLENGE_MODE", "expected one of %v, got %q", challenge.Modes, cfg.Mode)
+		}
+	}
+	return cfg
+}
@@ -0,0 +1,596 @@
+// Package config loads the server's settings from the environment (and an
+// optional .env file) into one typed struct, validated up front so
+// misconfiguration fails at startup with every problem listed at once.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/sujalbistaa/whispr/internal/archive"
+	"github.com/sujalbistaa/whispr/internal/auth"
+	"github.com/sujalbistaa/whispr/internal/challenge"
+	"github.com/sujalbistaa/whispr/internal/contentpolicy"
+	"github.com/sujalbistaa/whispr/internal/contentwarning"
+	"github.com/sujalbistaa/whispr/internal/db"
+	"github.com/sujalbistaa/whispr/internal/eventlog"
+	"github.com/sujalbistaa/whispr/internal/flags"
+	"github.com/sujalbistaa/whispr/internal/history"
+	"github.com/sujalbistaa/whispr/internal/karma"
+	"github.com/sujalbistaa/whispr/internal/push"
+	"github.com/sujalbistaa/whispr/internal/reporting"
+	"github.com/sujalbistaa/whispr/internal/retention"
+	"github.com/sujalbistaa/whispr/internal/simhash"
+	"github.com/sujalbistaa/whispr/internal/strikes"
+	"github.com/sujalbistaa/whispr/internal/tracing"
+	"github.com/sujalbistaa/whispr/internal/webhook"
+)
+
+// Config is the complete server configuration.
+type Config struct {
+	Port        string
+	DatabaseURL string
+	DB          db.Options
+	DBHealth    db.HealthCheck
+	MetricsAddr string // Separate /metrics listener; empty serves it on Port
+	AdminAddr   string // Separate listener for the admin API, /metrics and /debug; empty serves them on Port
+	PublicURL   string // Base URL for absolute links; empty uses the request host
+	FrontendDir string // Serve the UI from disk instead of the embedded copy
+
+	TrustedProxies     []string
+	CompressionMinSize int // Bytes; -1 disables compression
+	MaxBodyBytes       int64
+	ShutdownDrainDelay time.Duration
+	MaintenanceMode    string        // "off", "readonly" or "full" at startup
+	MigrateOnStart     bool          // Apply pending migrations before serving
+	VoterHashSecret    string        // Keys Vote.VoterHash; random per process when empty
+	FeedCacheFresh     time.Duration // How long a cached feed is served between writes; 0 disables the cache
+	FeedCacheTTL       time.Duration // How long the last good feed is served while the database is down
+	SlowRequest        time.Duration // Requests taking longer are logged; 0 disables
+	RequestTimeout     time.Duration // Deadline on each request's context, and so its queries; 0 disables
+
+	Log       Log
+	Server    Server
+	TLS       TLS
+	CORS      CORS
+	Headers   SecurityHeaders
+	RateLimit RateLimit
+	Mirror    Mirror
+	Admin     auth.Sources
+	Webhooks  webhook.Config
+	Retention retention.Config
+	History   history.Config
+	Karma     karma.Config
+	Archive   archive.Config
+	Creator   CreatorHash
+	Tracing   tracing.Config
+	Sentry    reporting.SentryConfig
+	Challenge challenge.Config
+	Content   contentpolicy.Config
+	Warnings  contentwarning.Config
+	Spam      simhash.Config
+	Strikes   strikes.Config
+	Push      push.Config
+	EventLog  eventlog.Config
+	Features  map[flags.Flag]bool // FEATURE_* overrides of flag defaults
+
+	// DotEnv reports whether a .env file was loaded.
+	DotEnv bool
+}
+
+// Log configures the process-wide logger.
+type Log struct {
+	Format string // "text" or "json"
+	Level  slog.Level
+}
+
+// Server holds the http.Server limits that protect against slow or
+// oversized requests.
+type Server struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+}
+
+// Apply copies the limits onto srv.
+func (s Server) Apply(srv *http.Server) {
+	srv.ReadHeaderTimeout = s.ReadHeaderTimeout
+	srv.ReadTimeout = s.ReadTimeout
+	srv.WriteTimeout = s.WriteTimeout
+	srv.IdleTimeout = s.IdleTimeout
+	srv.MaxHeaderBytes = s.MaxHeaderBytes
+}
+
+// Defaults for settings that aren't covered by their own section.
+const (
+	defaultPort               = "8080"
+	defaultDatabaseURL        = "sqlite://whispr.db"
+	defaultCompressionMinSize = 1024
+	defaultMaxBodyBytes       = 64 << 10
+	defaultDBConnectAttempts  = 10
+	defaultDBConnectTimeout   = time.Minute
+	defaultSQLiteJournalMode  = "WAL"
+	defaultSQLiteSynchronous  = "NORMAL"
+	defaultSQLiteBusyTimeout  = 5 * time.Second
+	defaultDBHealthInterval   = 5 * time.Second
+	defaultDBHealthFailures   = 2
+	defaultFeedCacheFresh     = 3 * time.Second
+	defaultFeedCacheTTL       = 5 * time.Minute
+	defaultSlowRequest        = time.Second
+	defaultRequestTimeout     = 10 * time.Second
+	defaultSlowQuery          = 200 * time.Millisecond
+	defaultRetentionDays      = 30
+	defaultRetentionBatchSize = 500
+	defaultHistoryInterval    = 15 * time.Minute
+	defaultHistoryMaxAge      = 7 * 24 * time.Hour
+	defaultHistoryBatchSize   = 500
+	defaultKarmaInterval      = 24 * time.Hour
+	defaultKarmaSettleAfter   = 24 * time.Hour
+	defaultKarmaBatchSize     = 500
+	defaultServiceName        = "whispr"
+
+	defaultReadHeaderTimeout = 5 * time.Second
+	defaultReadTimeout       = 15 * time.Second
+	defaultWriteTimeout      = 30 * time.Second
+	defaultIdleTimeout       = 2 * time.Minute
+	defaultMaxHeaderBytes    = 32 << 10
+)
+
+// Load reads .env (if present) and the environment, applies defaults and
+// validates the result. The returned error joins every problem found.
+func Load() (Config, error) {
+	// Variables already set in the environment win over .env.
+	dotEnvErr := godotenv.Load()
+
+	l := &loader{}
+	cfg := Config{
+		Port:        l.string("PORT", defaultPort),
+		DatabaseURL: l.string("DATABASE_URL", defaultDatabaseURL),
+		MetricsAddr: l.string("METRICS_ADDR", ""),
+		AdminAddr:   l.string("ADMIN_ADDR", ""),
+		PublicURL:   strings.TrimRight(l.string("PUBLIC_URL", ""), "/"),
+		FrontendDir: l.string("FRONTEND_DIR", ""),
+
+		TrustedProxies:     l.list("TRUSTED_PROXIES"),
+		MaxBodyBytes:       int64(l.positiveInt("MAX_BODY_BYTES", defaultMaxBodyBytes)),
+		ShutdownDrainDelay: l.duration("SHUTDOWN_DRAIN_DELAY", 0),
+		MaintenanceMode:    strings.ToLower(l.string("MAINTENANCE_MODE", "off")),
+		MigrateOnStart:     l.bool("MIGRATE_ON_START", false),
+		VoterHashSecret:    os.Getenv("VOTER_HASH_SECRET"),
+		SlowRequest:        l.duration("SLOW_REQUEST_THRESHOLD", defaultSlowRequest),
+		RequestTimeout:     l.duration("REQUEST_TIMEOUT", defaultRequestTimeout),
+		Features:           l.features(),
+
+		Log: Log{
+			Format: strings.ToLower(l.string("LOG_FORMAT", "text")),
+			Level:  l.logLevel("LOG_LEVEL"),
+		},
+		Server: Server{
+			ReadHeaderTimeout: l.duration("HTTP_READ_HEADER_TIMEOUT", defaultReadHeaderTimeout),
+			ReadTimeout:       l.duration("HTTP_READ_TIMEOUT", defaultReadTimeout),
+			WriteTimeout:      l.duration("HTTP_WRITE_TIMEOUT", defaultWriteTimeout),
+			IdleTimeout:       l.duration("HTTP_IDLE_TIMEOUT", defaultIdleTimeout),
+			MaxHeaderBytes:    l.positiveInt("HTTP_MAX_HEADER_BYTES", defaultMaxHeaderBytes),
+		},
+		TLS:       l.tls(),
+		CORS:      l.cors("CORS_ORIGIN"),
+		RateLimit: l.rateLimit(),
+		Admin: auth.Sources{
+			Token:      os.Getenv("X_ADMIN_TOKEN"),
+			TokenFile:  l.string("X_ADMIN_TOKEN_FILE", ""),
+			Tokens:     os.Getenv("X_ADMIN_TOKENS"),
+			TokensFile: l.string("X_ADMIN_TOKENS_FILE", ""),
+		},
+		DBHealth: db.HealthCheck{
+			Interval: l.duration("DB_HEALTH_INTERVAL", defaultDBHealthInterval),
+			Failures: l.positiveInt("DB_HEALTH_FAILURES", defaultDBHealthFailures),
+		},
+		FeedCacheFresh: l.duration("FEED_CACHE_FRESH", defaultFeedCacheFresh),
+		FeedCacheTTL:   l.duration("FEED_CACHE_TTL", defaultFeedCacheTTL),
+		Webhooks:       l.webhooks(),
+		Challenge:      l.challenge(),
+		Content:        l.contentPolicy(),
+		Warnings:       l.contentWarnings(),
+		Spam:           l.spam(),
+		Strikes:        l.strikes(),
+		Push:           l.push(),
+		EventLog:       l.eventLog(),
+		Archive:        l.archive(),
+		Retention: retention.Config{
+			Interval:  l.duration("RETENTION_INTERVAL", 0),
+			MaxAge:    time.Duration(l.positiveInt("RETENTION_DAYS", defaultRetentionDays)) * 24 * time.Hour,
+			BatchSize: l.positiveInt("RETENTION_BATCH_SIZE", defaultRetentionBatchSize),
+		},
+		History: history.Config{
+			Interval:  l.duration("HISTORY_INTERVAL", defaultHistoryInterval),
+			MaxAge:    l.duration("HISTORY_MAX_AGE", defaultHistoryMaxAge),
+			BatchSize: l.positiveInt("HISTORY_BATCH_SIZE", defaultHistoryBatchSize),
+		},
+		Karma: karma.Config{
+			Interval:    l.duration("KARMA_INTERVAL", defaultKarmaInterval),
+			SettleAfter: l.duration("KARMA_SETTLE_AFTER", defaultKarmaSettleAfter),
+			BatchSize:   l.positiveInt("KARMA_BATCH_SIZE", defaultKarmaBatchSize),
+		},
+		Tracing: tracing.Config{
+			Endpoint:    l.string("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+			ServiceName: l.string("OTEL_SERVICE_NAME", defaultServiceName),
+		},
+		Sentry: reporting.SentryConfig{
+			DSN:         os.Getenv("SENTRY_DSN"),
+			Environment: l.string("SENTRY_ENVIRONMENT", ""),
+		},
+		DotEnv: dotEnvErr == nil,
+	}
+
+	cfg.Mirror = l.mirror(cfg.RateLimit.RedisURL)
+	cfg.Headers = l.securityHeaders(cfg.TLS)
+	cfg.Creator = l.creatorHash(cfg.VoterHashSecret)
+	if cfg.History.Enabled() && cfg.History.MaxAge < cfg.History.Interval {
+		l.failf("HISTORY_MAX_AGE", "must be at least HISTORY_INTERVAL (%s), got %s", cfg.History.Interval, cfg.History.MaxAge)
+	}
+
+	switch raw := l.string("COMPRESSION_MIN_SIZE", ""); raw {
+	case "":
+		cfg.CompressionMinSize = defaultCompressionMinSize
+	case "off":
+		cfg.CompressionMinSize = -1
+	default:
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			l.failf("COMPRESSION_MIN_SIZE", "expected a byte count or \"off\", got %q", raw)
+		}
+		cfg.CompressionMinSize = n
+	}
+
+	if port, err := strconv.Atoi(cfg.Port); err != nil || port < 1 || port > 65535 {
+		l.failf("PORT", "expected a port number, got %q", cfg.Port)
+	}
+	if !strings.HasPrefix(cfg.DatabaseURL, "postgres://") && !strings.HasPrefix(cfg.DatabaseURL, "mysql://") && !strings.HasPrefix(cfg.DatabaseURL, "sqlite://") {
+		l.failf("DATABASE_URL", "must start with postgres://, mysql:// or sqlite://")
+	}
+	cfg.DB = l.db(cfg.DatabaseURL)
+	if cfg.PublicURL != "" {
+		if u, err := url.Parse(cfg.PublicURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			l.failf("PUBLIC_URL", "expected an absolute http(s) URL, got %q", cfg.PublicURL)
+		}
+	}
+	switch cfg.MaintenanceMode {
+	case "off", "readonly", "full":
+	default:
+		l.failf("MAINTENANCE_MODE", "expected off, readonly or full, got %q", cfg.MaintenanceMode)
+	}
+	if cfg.Log.Format != "text" && cfg.Log.Format != "json" {
+		l.failf("LOG_FORMAT", "unknown format %q", cfg.Log.Format)
+	}
+
+	return cfg, errors.Join(l.errs...)
+}
+
+// LogValue renders the configuration for the startup log with secrets
+// redacted.
+func (c Config) LogValue() slog.Value {
+	return slog.GroupValue(
+		slog.String("port", c.Port),
+		slog.String("database", redactURL(c.DatabaseURL)),
+		slog.Group("db",
+			slog.Int("connectAttempts", c.DB.Connect.Attempts),
+			slog.Duration("connectTimeout", c.DB.Connect.Timeout),
+			slog.Int("maxOpenConns", c.DB.Pool.MaxOpenConns),
+			slog.Int("maxIdleConns", c.DB.Pool.MaxIdleConns),
+			slog.Duration("connMaxLifetime", c.DB.Pool.ConnMaxLifetime),
+			slog.Duration("connMaxIdleTime", c.DB.Pool.ConnMaxIdleTime),
+			slog.String("sqliteJournalMode", c.DB.SQLite.JournalMode),
+			slog.String("sqliteSynchronous", c.DB.SQLite.Synchronous),
+			slog.Duration("sqliteBusyTimeout", c.DB.SQLite.BusyTimeout),
+			slog.Duration("slowQuery", c.DB.SlowQuery),
+		),
+		slog.String("metricsAddr", c.MetricsAddr),
+		slog.String("adminAddr", c.AdminAddr),
+		slog.String("publicURL", c.PublicURL),
+		slog.String("frontendDir", c.FrontendDir),
+		slog.String("trustedProxies", strings.Join(c.TrustedProxies, ",")),
+		slog.Int("compressionMinSize", c.CompressionMinSize),
+		slog.Int64("maxBodyBytes", c.MaxBodyBytes),
+		slog.Duration("shutdownDrainDelay", c.ShutdownDrainDelay),
+		slog.String("maintenanceMode", c.MaintenanceMode),
+		slog.Bool("migrateOnStart", c.MigrateOnStart),
+		slog.Bool("voterHashSecret", c.VoterHashSecret != ""),
+		slog.Duration("slowRequest", c.SlowRequest),
+		slog.Duration("requestTimeout", c.RequestTimeout),
+		slog.Any("features", c.Features),
+		slog.String("logFormat", c.Log.Format),
+		slog.String("logLevel", c.Log.Level.String()),
+		slog.Group("server",
+			slog.Duration("readHeaderTimeout", c.Server.ReadHeaderTimeout),
+			slog.Duration("readTimeout", c.Server.ReadTimeout),
+			slog.Duration("writeTimeout", c.Server.WriteTimeout),
+			slog.Duration("idleTimeout", c.Server.IdleTimeout),
+			slog.Int("maxHeaderBytes", c.Server.MaxHeaderBytes),
+		),
+		slog.Group("tls",
+			slog.String("certFile", c.TLS.CertFile),
+			slog.String("autocertDomains", strings.Join(c.TLS.AutocertDomains, ",")),
+			slog.String("autocertCacheDir", c.TLS.AutocertCacheDir),
+			slog.String("redirectAddr", c.TLS.RedirectAddr),
+		),
+		slog.String("cors", c.CORS.String()),
+		slog.Group("headers",
+			slog.String("scriptSrc", strings.Join(c.Headers.ScriptSrc, " ")),
+			slog.String("styleSrc", strings.Join(c.Headers.StyleSrc, " ")),
+			slog.String("connectSrc", strings.Join(c.Headers.ConnectSrc, " ")),
+			slog.String("reportURI", c.Headers.ReportURI),
+			slog.Bool("reportOnly", c.Headers.ReportOnly),
+			slog.Duration("hstsMaxAge", c.Headers.HSTSMaxAge),
+			slog.String("referrerPolicy", c.Headers.ReferrerPolicy),
+			slog.String("permissionsPolicy", c.Headers.PermissionsPolicy),
+		),
+		slog.String("rateLimits", c.RateLimit.String()),
+		slog.String("redis", redactURL(c.RateLimit.RedisURL)),
+		slog.Group("mirror",
+			slog.Bool("readOnly", c.Mirror.ReadOnly),
+			slog.String("primaryURL", c.Mirror.PrimaryURL),
+			slog.String("channel", c.Mirror.Channel),
+		),
+		slog.Group("admin",
+			slog.Bool("token", c.Admin.Token != ""),
+			slog.String("tokenFile", c.Admin.TokenFile),
+			slog.Int("tokens", countList(c.Admin.Tokens)),
+			slog.String("tokensFile", c.Admin.TokensFile),
+		),
+		slog.Group("webhooks",
+			slog.Int("endpoints", len(c.Webhooks.Endpoints)),
+			slog.Bool("signed", c.Webhooks.Secret != ""),
+			slog.Int("queueSize", c.Webhooks.QueueSize),
+			slog.Int("maxAttempts", c.Webhooks.MaxAttempts),
+		),
+		slog.Group("dbHealth",
+			slog.Duration("interval", c.DBHealth.Interval),
+			slog.Int("failures", c.DBHealth.Failures),
+		),
+		slog.Group("feedCache",
+			slog.Duration("fresh", c.FeedCacheFresh),
+			slog.Duration("ttl", c.FeedCacheTTL),
+		),
+		slog.Group("retention",
+			slog.Duration("interval", c.Retention.Interval),
+			slog.Duration("maxAge", c.Retention.MaxAge),
+			slog.Int("batchSize", c.Retention.BatchSize),
+		),
+		slog.Group("history",
+			slog.Duration("interval", c.History.Interval),
+			slog.Duration("maxAge", c.History.MaxAge),
+			slog.Int("batchSize", c.History.BatchSize),
+		),
+		slog.Group("karma",
+			slog.Duration("interval", c.Karma.Interval),
+			slog.Duration("settleAfter", c.Karma.SettleAfter),
+			slog.Int("batchSize", c.Karma.BatchSize),
+		),
+		slog.Group("creatorHash",
+			slog.Bool("enabled", c.Creator.Enabled),
+			slog.Bool("salt", c.Creator.Salt != ""),
+		),
+		slog.Group("archive",
+			slog.Int("maxVisible", c.Archive.MaxVisible),
+			slog.Duration("interval", c.Archive.Interval),
+			slog.Duration("keepActive", c.Archive.KeepActive),
+			slog.Int("keepScore", c.Archive.KeepScore),
+			slog.Int("batchSize", c.Archive.BatchSize),
+		),
+		slog.Group("tracing",
+			slog.String("endpoint", c.Tracing.Endpoint),
+			slog.String("serviceName", c.Tracing.ServiceName),
+		),
+		slog.Group("sentry",
+			slog.Bool("dsn", c.Sentry.DSN != ""),
+			slog.String("environment", c.Sentry.Environment),
+		),
+		slog.Group("contentPolicy",
+			slog.String("action", string(c.Content.Action)),
+			slog.Any("kinds", c.Content.Kinds),
+			slog.String("allowDomains", strings.Join(c.Content.AllowDomains, ",")),
+		),
+		slog.Group("contentWarnings",
+			slog.Bool("auto", c.Warnings.Auto),
+			slog.Any("keywords", keywordCounts(c.Warnings.Keywords)),
+		),
+		slog.Group("strikes",
+			slog.Duration("window", c.Strikes.Window),
+			slog.String("escalation", strikeLevels(c.Strikes.Levels)),
+		),
+		slog.Group("challenge",
+			slog.String("mode", string(c.Challenge.Mode)),
+			slog.Int("difficulty", c.Challenge.Difficulty),
+			slog.Duration("ttl", c.Challenge.TTL),
+			slog.String("siteKey", c.Challenge.SiteKey),
+			slog.Bool("secret", c.Challenge.Secret != ""),
+		),
+		slog.Group("spam",
+			slog.Duration("window", c.Spam.Window),
+			slog.Int("maxDistance", c.Spam.MaxDistance),
+		),
+		slog.Group("push",
+			slog.Bool("enabled", c.Push.Enabled()),
+			slog.String("subject", c.Push.Subject),
+			slog.Int("threshold", c.Push.Threshold),
+			slog.String("allowedHosts", strings.Join(c.Push.AllowedHosts, ",")),
+			slog.Int("queueSize", c.Push.QueueSize),
+			slog.Int("maxAttempts", c.Push.MaxAttempts),
+			slog.Duration("ttl", c.Push.TTL),
+		),
+		slog.Group("eventLog",
+			slog.String("path", c.EventLog.Path),
+			slog.Int64("rotateBytes", c.EventLog.MaxBytes),
+			slog.String("fsync", string(c.EventLog.Sync)),
+			slog.Duration("flushInterval", c.EventLog.FlushInterval),
+			slog.Int("queueSize", c.EventLog.QueueSize),
+		),
+	)
+}
+
+// db reads the connection settings; pool defaults depend on the database.
+func (l *loader) db(dbURL string) db.Options {
+	def := db.DefaultPool(dbURL)
+	opts := db.Options{
+		Connect: db.Retry{
+			Attempts: l.positiveInt("DB_CONNECT_ATTEMPTS", defaultDBConnectAttempts),
+			Timeout:  l.duration("DB_CONNECT_TIMEOUT", defaultDBConnectTimeout),
+		},
+		Pool: db.Pool{
+			MaxOpenConns:    l.positiveInt("DB_MAX_OPEN_CONNS", def.MaxOpenConns),
+			MaxIdleConns:    l.nonNegativeInt("DB_MAX_IDLE_CONNS", def.MaxIdleConns),
+			ConnMaxLifetime: l.duration("DB_CONN_MAX_LIFETIME", def.ConnMaxLifetime),
+			ConnMaxIdleTime: l.duration("DB_CONN_MAX_IDLE_TIME", def.ConnMaxIdleTime),
+		},
+		SQLite: db.SQLite{
+			JournalMode: strings.ToUpper(l.string("SQLITE_JOURNAL_MODE", defaultSQLiteJournalMode)),
+			Synchronous: strings.ToUpper(l.string("SQLITE_SYNCHRONOUS", defaultSQLiteSynchronous)),
+			BusyTimeout: l.duration("SQLITE_BUSY_TIMEOUT", defaultSQLiteBusyTimeout),
+		},
+		SlowQuery: l.duration("SLOW_QUERY_THRESHOLD", defaultSlowQuery),
+	}
+	if opts.Pool.MaxIdleConns > opts.Pool.MaxOpenConns {
+		l.failf("DB_MAX_IDLE_CONNS", "must not exceed DB_MAX_OPEN_CONNS (%d), got %d", opts.Pool.MaxOpenConns, opts.Pool.MaxIdleConns)
+	}
+	switch opts.SQLite.JournalMode {
+	case "WAL", "DELETE", "TRUNCATE", "PERSIST", "MEMORY", "OFF":
+	default:
+		l.failf("SQLITE_JOURNAL_MODE", "expected WAL, DELETE, TRUNCATE, PERSIST, MEMORY or OFF, got %q", opts.SQLite.JournalMode)
+	}
+	switch opts.SQLite.Synchronous {
+	case "OFF", "NORMAL", "FULL", "EXTRA":
+	default:
+		l.failf("SQLITE_SYNCHRONOUS", "expected OFF, NORMAL, FULL or EXTRA, got %q", opts.SQLite.Synchronous)
+	}
+	return opts
+}
+
+// redactURL hides the password in a connection URL.
+func redactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil || u.User == nil {
+		return raw
+	}
+	return u.Redacted()
+}
+
+func countList(raw string) int {
+	n := 0
+	for _, part := range strings.Split(raw, ",") {
+		if strings.TrimSpace(part) != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// --- Parsing helpers ---
+
+// loader reads variables and collects errors instead of stopping at the
+// first one.
+type loader struct {
+	errs []error
+}
+
+func (l *loader) failf(key, format string, args ...any) {
+	l.errs = append(l.errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+}
+
+// string returns the trimmed value of key, or def when unset or blank.
+func (l *loader) string(key, def string) string {
+	if raw := strings.TrimSpace(os.Getenv(key)); raw != "" {
+		return raw
+	}
+	return def
+}
+
+// list splits a comma-separated value, dropping blank entries.
+func (l *loader) list(key string) []string {
+	var out []string
+	for _, entry := range strings.Split(os.Getenv(key), ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			out = append(out, entry)
+		}
+	}
+	return out
+}
+
+func (l *loader) bool(key string, def bool) bool {
+	raw := l.string(key, "")
+	if raw == "" {
+		return def
+	}
+	v, err := strconv.ParseBool(raw)
+	if err != nil {
+		l.failf(key, "expected true or false, got %q", raw)
+		return def
+	}
+	return v
+}
+
+func (l *loader) positiveInt(key string, def int) int {
+	raw := l.string(key, "")
+	if raw == "" {
+		return def
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n <= 0 {
+		l.failf(key, "expected a positive integer, got %q", raw)
+		return def
+	}
+	return n
+}
+
+func (l *loader) nonNegativeInt(key string, def int) int {
+	raw := l.string(key, "")
+	if raw == "" {
+		return def
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 0 {
+		l.failf(key, "expected a non-negative integer, got %q", raw)
+		return def
+	}
+	return n
+}
+
+// duration parses a Go duration such as 5s or 2m; 0 is allowed.
+func (l *loader) duration(key string, def time.Duration) time.Duration {
+	raw := l.string(key, "")
+	if raw == "" {
+		return def
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < 0 {
+		l.failf(key, "expected a non-negative duration like 30s, got %q", raw)
+		return def
+	}
+	return d
+}
+
+func (l *loader) logLevel(key string) slog.Level {
+	switch raw := strings.ToLower(l.string(key, "info")); raw {
+	case "info":
+		return slog.LevelInfo
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		l.failf(key, "unknown level %q", raw)
+		return slog.LevelInfo
+	}
+}
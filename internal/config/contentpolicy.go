@@ -0,0 +1,34 @@
+package config
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/sujalbistaa/whispr/internal/contentpolicy"
+)
+
+// contentPolicy reads CONTENT_POLICY (off, reject or redact),
+// CONTENT_POLICY_KINDS (url, email, phone and handle by default) and
+// CONTENT_POLICY_ALLOW_DOMAINS.
+func (l *loader) contentPolicy() contentpolicy.Config {
+	cfg := contentpolicy.Config{
+		Action:       contentpolicy.Action(strings.ToLower(l.string("CONTENT_POLICY", string(contentpolicy.ActionOff)))),
+		Kinds:        contentpolicy.Kinds,
+		AllowDomains: l.list("CONTENT_POLICY_ALLOW_DOMAINS"),
+	}
+	if !slices.Contains(contentpolicy.Actions, cfg.Action) {
+		l.failf("CONTENT_POLICY", "expected one of %v, got %q", contentpolicy.Actions, cfg.Action)
+	}
+	if names := l.list("CONTENT_POLICY_KINDS"); len(names) > 0 {
+		cfg.Kinds = nil
+		for _, name := range names {
+			kind := contentpolicy.Kind(strings.ToLower(name))
+			if !slices.Contains(contentpolicy.Kinds, kind) {
+				l.failf("CONTENT_POLICY_KINDS", "unknown kind %q (supported: %v)", name, contentpolicy.Kinds)
+				continue
+			}
+			cfg.Kinds = append(cfg.Kinds, kind)
+		}
+	}
+	return cfg
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/sujalbistaa/whispr/internal/contentwarning"
+)
+
+// contentWarnings reads CONTENT_WARNING_AUTO and, for each warning,
+// CONTENT_WARNING_KEYWORDS_<WARNING> (e.g. CONTENT_WARNING_KEYWORDS_SELF_HARM):
+// comma-separated phrases that replace the defaults for that warning.
+func (l *loader) contentWarnings() contentwarning.Config {
+	cfg := contentwarning.Config{
+		Auto:     l.bool("CONTENT_WARNING_AUTO", true),
+		Keywords: make(map[contentwarning.Warning][]string, len(contentwarning.Warnings)),
+	}
+	for _, w := range contentwarning.Warnings {
+		keywords := l.list("CONTENT_WARNING_KEYWORDS_" + strings.ToUpper(string(w)))
+		if len(keywords) == 0 {
+			keywords = contentwarning.DefaultKeywords[w]
+		}
+		cfg.Keywords[w] = keywords
+	}
+	return cfg
+}
+
+// keywordCounts summarizes keyword lists for the startup log, which
+// needn't repeat every phrase.
+func keywordCounts(keywords map[contentwarning.Warning][]string) map[contentwarning.Warning]int {
+	counts := make(map[contentwarning.Warning]int, len(keywords))
+	for w, phrases := range keywords {
+		counts[w] = len(phrases)
+	}
+	return counts
+}
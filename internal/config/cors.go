@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// CORS is the parsed CORS_ORIGIN setting.
+type CORS struct {
+	// AnyOrigin reflects every request origin back. Credentials are
+	// allowed, so a literal "*" can never be sent; meant for local dev.
+	AnyOrigin bool
+	// Origins are exact origins ("https://whispr.example.edu") or a single
+	// leading subdomain wildcard ("https://*.netlify.app").
+	Origins []string
+}
+
+// String summarizes the policy for the startup log.
+func (c CORS) String() string {
+	if c.AnyOrigin {
+		return "any origin (reflected, with credentials)"
+	}
+	return strings.Join(c.Origins, ", ")
+}
+
+// cors reads a comma-separated list of origins. "*" (the default) allows
+// any origin and can't be combined with others.
+func (l *loader) cors(key string) CORS {
+	var c CORS
+	entries := l.list(key)
+	if len(entries) == 0 {
+		entries = []string{"*"}
+	}
+	for _, origin := range entries {
+		if origin == "*" {
+			c.AnyOrigin = true
+			continue
+		}
+		normalized, err := normalizeOrigin(origin)
+		if err != nil {
+			l.failf(key, "%v", err)
+			continue
+		}
+		c.Origins = append(c.Origins, normalized)
+	}
+	if c.AnyOrigin && len(c.Origins) > 0 {
+		l.failf(key, "\"*\" can't be combined with specific origins")
+	}
+	return c
+}
+
+// normalizeOrigin checks that origin is scheme://host[:port] and returns it
+// in the lowercase form browsers send in the Origin header.
+func normalizeOrigin(origin string) (string, error) {
+	u, err := url.Parse(strings.TrimSuffix(origin, "/"))
+	if err != nil {
+		return "", fmt.Errorf("invalid origin %q: %v", origin, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid origin %q: scheme must be http or https", origin)
+	}
+	if u.Host == "" || u.User != nil || u.Path != "" || u.RawQuery != "" || u.Fragment != "" {
+		return "", fmt.Errorf("invalid origin %q: expected scheme://host[:port] with no path", origin)
+	}
+	host := strings.ToLower(u.Host)
+	if n := strings.Count(host, "*"); n > 1 || (n == 1 && !strings.HasPrefix(host, "*.")) {
+		return "", fmt.Errorf("invalid origin %q: only a single leading \"*.\" wildcard is supported", origin)
+	}
+	return strings.ToLower(u.Scheme) + "://" + host, nil
+}
@@ -0,0 +1,42 @@
+package config_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sujalbistaa/whispr/internal/config"
+)
+
+func TestCORSOrigins(t *testing.T) {
+	t.Setenv("CORS_ORIGIN", "HTTPS://Whispr.Example.edu/, https://*.netlify.app")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"https://whispr.example.edu", "https://*.netlify.app"}
+	if cfg.CORS.AnyOrigin || !reflect.DeepEqual(cfg.CORS.Origins, want) {
+		t.Errorf("CORS = %+v, want origins %v", cfg.CORS, want)
+	}
+}
+
+func TestCORSMalformed(t *testing.T) {
+	for _, value := range []string{
+		"*, https://whispr.example.edu",
+		"whispr.example.edu",
+		"ftp://whispr.example.edu",
+		"https://whispr.example.edu/app",
+		"https://a.*.example.edu",
+	} {
+		t.Run(value, func(t *testing.T) {
+			t.Setenv("CORS_ORIGIN", value)
+			_, err := config.Load()
+			if err == nil {
+				t.Fatalf("Load accepted CORS_ORIGIN=%q", value)
+			}
+			if !strings.Contains(err.Error(), "CORS_ORIGIN") {
+				t.Errorf("error %q doesn't name CORS_ORIGIN", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package config
+
+import "os"
+
+// minCreatorHashSalt is the shortest CREATOR_HASH_SALT accepted. IPv4 has
+// few enough addresses that a short salt could be brute-forced along
+// with them.
+const minCreatorHashSalt = 16
+
+// CreatorHash configures Post.CreatorHash, the salted hash of the IP that
+// created each post, which moderators use to find posts from one source.
+type CreatorHash struct {
+	Enabled bool
+	Salt    string // Keys the hash; required when Enabled
+}
+
+// creatorHash reads CREATOR_HASH, which turns the hash on, and
+// CREATOR_HASH_SALT, which it then requires. The salt must differ from
+// voterSecret, or creator hashes would match the voter hashes of votes
+// and identities.
+func (l *loader) creatorHash(voterSecret string) CreatorHash {
+	cfg := CreatorHash{
+		Enabled: l.bool("CREATOR_HASH", false),
+		Salt:    os.Getenv("CREATOR_HASH_SALT"),
+	}
+	if cfg.Enabled && len(cfg.Salt) < minCreatorHashSalt {
+		l.failf("CREATOR_HASH_SALT", "must be at least %d characters when CREATOR_HASH is on", minCreatorHashSalt)
+	} else if cfg.Enabled && cfg.Salt == voterSecret {
+		l.failf("CREATOR_HASH_SALT", "must differ from VOTER_HASH_SECRET")
+	}
+	return cfg
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sujalbistaa/whispr/internal/eventlog"
+)
+
+const (
+	defaultEventLogRotateMB      = 100
+	defaultEventLogFlushInterval = time.Second
+	defaultEventLogQueueSize     = 1024
+)
+
+// eventLog reads EVENT_LOG_PATH, which enables the event log, with
+// EVENT_LOG_ROTATE_MB, EVENT_LOG_FSYNC (always, interval or never),
+// EVENT_LOG_FLUSH_INTERVAL and EVENT_LOG_QUEUE_SIZE.
+func (l *loader) eventLog() eventlog.Config {
+	cfg := eventlog.Config{
+		Path:          l.string("EVENT_LOG_PATH", ""),
+		MaxBytes:      int64(l.positiveInt("EVENT_LOG_ROTATE_MB", defaultEventLogRotateMB)) << 20,
+		Sync:          eventlog.Sync(strings.ToLower(l.string("EVENT_LOG_FSYNC", string(eventlog.SyncInterval)))),
+		FlushInterval: l.duration("EVENT_LOG_FLUSH_INTERVAL", defaultEventLogFlushInterval),
+		QueueSize:     l.positiveInt("EVENT_LOG_QUEUE_SIZE", defaultEventLogQueueSize),
+	}
+	if strings.Contains(cfg.Path, "://") {
+		l.failf("EVENT_LOG_PATH", "expected a local file path; ship rotated files to object storage separately")
+	}
+	if !slices.Contains(eventlog.Syncs, cfg.Sync) {
+		l.failf("EVENT_LOG_FSYNC", "expected one of %v, got %q", eventlog.Syncs, cfg.Sync)
+	}
+	if cfg.FlushInterval <= 0 {
+		l.failf("EVENT_LOG_FLUSH_INTERVAL", "must be positive")
+	}
+	return cfg
+}
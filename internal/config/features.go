@@ -0,0 +1,36 @@
+package config
+
+import (
+	"os"
+	"strings"
+
+	"github.com/sujalbistaa/whispr/internal/flags"
+)
+
+// featurePrefix starts the variable overriding a flag's default, e.g.
+// FEATURE_REACTIONS=true.
+const featurePrefix = "FEATURE_"
+
+// features reads a FEATURE_<NAME> override for every flag. A FEATURE_
+// variable naming no flag is an error, so a typo doesn't silently leave
+// a feature in its default state.
+func (l *loader) features() map[flags.Flag]bool {
+	overrides := make(map[flags.Flag]bool)
+	for _, def := range flags.Definitions {
+		key := featurePrefix + strings.ToUpper(string(def.Name))
+		if l.string(key, "") != "" {
+			overrides[def.Name] = l.bool(key, def.Default)
+		}
+	}
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		name, ok := strings.CutPrefix(key, featurePrefix)
+		if !ok {
+			continue
+		}
+		if _, known := flags.Lookup(flags.Flag(strings.ToLower(name))); !known {
+			l.failf(key, "unknown feature flag %q", strings.ToLower(name))
+		}
+	}
+	return overrides
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Default Content-Security-Policy sources, which allow the embedded
+// frontend's CDN scripts and styles. Alpine.js needs 'unsafe-eval'.
+var (
+	defaultCSPScriptSrc  = []string{"'self'", "'unsafe-inline'", "'unsafe-eval'", "cdn.jsdelivr.net", "cdn.tailwindcss.com"}
+	defaultCSPStyleSrc   = []string{"'self'", "'unsafe-inline'", "cdn.tailwindcss.com"}
+	defaultCSPConnectSrc = []string{"'self'", "ws:", "wss:"}
+)
+
+const (
+	defaultReferrerPolicy    = "strict-origin-when-cross-origin"
+	defaultPermissionsPolicy = "camera=(), microphone=(), geolocation=()"
+
+	// DefaultCSPReportURI is the built-in report collector, which a
+	// report-only policy reports to unless CSP_REPORT_URI says otherwise.
+	DefaultCSPReportURI = "/api/v1/csp-report"
+)
+
+// SecurityHeaders configures the security headers sent with every
+// response. Empty policies are left out.
+type SecurityHeaders struct {
+	// CSP sources, each a full list including 'self' if wanted, so a
+	// deployment that self-hosts its assets can drop the CDNs.
+	ScriptSrc  []string
+	StyleSrc   []string
+	ConnectSrc []string
+	// ReportURI is where browsers send CSP violations; empty for nowhere.
+	ReportURI string
+	// ReportOnly sends the CSP as Content-Security-Policy-Report-Only, so
+	// violations are reported but not blocked.
+	ReportOnly bool
+	// HSTSMaxAge is the Strict-Transport-Security max-age; zero omits the
+	// header. Only sent when PORT serves HTTPS.
+	HSTSMaxAge        time.Duration
+	ReferrerPolicy    string
+	PermissionsPolicy string
+}
+
+// securityHeaders reads CSP_SCRIPT_SRC, CSP_STYLE_SRC and CSP_CONNECT_SRC
+// (space- or comma-separated sources), CSP_REPORT_URI, CSP_REPORT_ONLY,
+// HSTS_MAX_AGE, which needs TLS, REFERRER_POLICY and PERMISSIONS_POLICY.
+// "off" turns off CSP_REPORT_URI and the two policies.
+func (l *loader) securityHeaders(tls TLS) SecurityHeaders {
+	h := SecurityHeaders{
+		ScriptSrc:         l.cspSources("CSP_SCRIPT_SRC", defaultCSPScriptSrc),
+		StyleSrc:          l.cspSources("CSP_STYLE_SRC", defaultCSPStyleSrc),
+		ConnectSrc:        l.cspSources("CSP_CONNECT_SRC", defaultCSPConnectSrc),
+		ReportOnly:        l.bool("CSP_REPORT_ONLY", false),
+		HSTSMaxAge:        l.duration("HSTS_MAX_AGE", 0),
+		ReferrerPolicy:    l.headerValue("REFERRER_POLICY", defaultReferrerPolicy),
+		PermissionsPolicy: l.headerValue("PERMISSIONS_POLICY", defaultPermissionsPolicy),
+	}
+	reportDefault := ""
+	if h.ReportOnly {
+		reportDefault = DefaultCSPReportURI
+	}
+	h.ReportURI = l.headerValue("CSP_REPORT_URI", reportDefault)
+	if h.ReportURI != "" {
+		u, err := url.Parse(h.ReportURI)
+		relative := err == nil && u.Scheme == "" && u.Host == "" && strings.HasPrefix(u.Path, "/")
+		absolute := err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+		if (!relative && !absolute) || strings.ContainsAny(h.ReportURI, "; ") {
+			l.failf("CSP_REPORT_URI", "expected a path or an absolute http(s) URL, got %q", h.ReportURI)
+		}
+	}
+	if h.HSTSMaxAge > 0 && !tls.Enabled() {
+		l.failf("HSTS_MAX_AGE", "needs TLS_CERT_FILE or AUTOCERT_DOMAINS")
+	}
+	return h
+}
+
+// cspSources splits key into CSP sources, or returns def when unset.
+func (l *loader) cspSources(key string, def []string) []string {
+	raw := l.string(key, "")
+	if raw == "" {
+		return def
+	}
+	sources := strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+	for _, source := range sources {
+		if strings.Contains(source, ";") {
+			l.failf(key, "sources can't contain \";\", got %q", source)
+		}
+	}
+	return sources
+}
+
+// headerValue returns key, def when unset, or "" when "off".
+func (l *loader) headerValue(key, def string) string {
+	raw := l.string(key, def)
+	if raw == "off" {
+		return ""
+	}
+	if strings.ContainsAny(raw, "\r\n") {
+		l.failf(key, "can't contain line breaks")
+	}
+	return raw
+}
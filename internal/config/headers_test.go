@@ -0,0 +1,30 @@
+package config_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sujalbistaa/whispr/internal/config"
+)
+
+func TestSecurityHeadersMalformed(t *testing.T) {
+	for _, tc := range []struct{ key, value string }{
+		{"HSTS_MAX_AGE", "8760h"}, // Without TLS
+		{"CSP_REPORT_URI", "reports.example.edu"},
+		{"CSP_REPORT_URI", "javascript:alert(1)"},
+		{"CSP_REPORT_URI", "/csp; script-src *"},
+		{"CSP_SCRIPT_SRC", "'self' *;"},
+		{"REFERRER_POLICY", "no-referrer\r\nX-Injected: 1"},
+	} {
+		t.Run(tc.key+"="+tc.value, func(t *testing.T) {
+			t.Setenv(tc.key, tc.value)
+			_, err := config.Load()
+			if err == nil {
+				t.Fatalf("Load accepted %s=%q", tc.key, tc.value)
+			}
+			if !strings.Contains(err.Error(), tc.key) {
+				t.Errorf("error %q doesn't name %s", err, tc.key)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package config
+
+import "net/url"
+
+// Mirror configures read-only mirrors: instances that serve reads from a
+// replica database and leave writes to the primary.
+type Mirror struct {
+	// ReadOnly registers only the GET routes and /ws, answers every write
+	// with 405 and runs no migrations or background jobs.
+	ReadOnly bool
+	// PrimaryURL is where a mirror's 405s send writers; optional.
+	PrimaryURL string
+	// Channel is the Redis channel the primary publishes its WebSocket
+	// broadcasts on and mirrors relay to their clients; empty disables.
+	Channel string
+}
+
+// mirror reads READ_ONLY, PRIMARY_URL and REDIS_BROADCAST_CHANNEL, which
+// needs REDIS_URL.
+func (l *loader) mirror(redisURL string) Mirror {
+	m := Mirror{
+		ReadOnly:   l.bool("READ_ONLY", false),
+		PrimaryURL: l.string("PRIMARY_URL", ""),
+		Channel:    l.string("REDIS_BROADCAST_CHANNEL", ""),
+	}
+	if m.PrimaryURL != "" {
+		if u, err := url.Parse(m.PrimaryURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			l.failf("PRIMARY_URL", "expected an absolute http(s) URL, got %q", m.PrimaryURL)
+		}
+	}
+	if m.Channel != "" && redisURL == "" {
+		l.failf("REDIS_BROADCAST_CHANNEL", "needs REDIS_URL")
+	}
+	return m
+}
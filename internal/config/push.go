@@ -0,0 +1,60 @@
+package config
+
+import (
+	"encoding/base64"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sujalbistaa/whispr/internal/push"
+)
+
+const (
+	defaultPushThreshold   = 50
+	defaultPushQueueSize   = 64
+	defaultPushMaxAttempts = 5
+	defaultPushTimeout     = 10 * time.Second
+	defaultPushTTL         = 24 * time.Hour
+)
+
+// push reads VAPID_PUBLIC_KEY and VAPID_PRIVATE_KEY, which enable Web
+// Push, with VAPID_SUBJECT, PUSH_THRESHOLD, PUSH_ALLOWED_HOSTS,
+// PUSH_QUEUE_SIZE, PUSH_MAX_ATTEMPTS, PUSH_TIMEOUT and PUSH_TTL.
+func (l *loader) push() push.Config {
+	cfg := push.Config{
+		PublicKey:    l.string("VAPID_PUBLIC_KEY", ""),
+		PrivateKey:   strings.TrimSpace(os.Getenv("VAPID_PRIVATE_KEY")),
+		Subject:      l.string("VAPID_SUBJECT", ""),
+		Threshold:    l.positiveInt("PUSH_THRESHOLD", defaultPushThreshold),
+		AllowedHosts: push.DefaultHosts,
+		QueueSize:    l.positiveInt("PUSH_QUEUE_SIZE", defaultPushQueueSize),
+		MaxAttempts:  l.positiveInt("PUSH_MAX_ATTEMPTS", defaultPushMaxAttempts),
+		Timeout:      l.duration("PUSH_TIMEOUT", defaultPushTimeout),
+		TTL:          l.duration("PUSH_TTL", defaultPushTTL),
+	}
+	if hosts := l.list("PUSH_ALLOWED_HOSTS"); len(hosts) > 0 {
+		cfg.AllowedHosts = nil
+		for _, h := range hosts {
+			cfg.AllowedHosts = append(cfg.AllowedHosts, strings.ToLower(strings.TrimPrefix(h, ".")))
+		}
+	}
+	if (cfg.PublicKey == "") != (cfg.PrivateKey == "") {
+		l.failf("VAPID_PUBLIC_KEY", "VAPID_PUBLIC_KEY and VAPID_PRIVATE_KEY must be set together")
+		return cfg
+	}
+	if !cfg.Enabled() {
+		return cfg
+	}
+	if key, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(cfg.PublicKey, "=")); err != nil || len(key) != 65 {
+		l.failf("VAPID_PUBLIC_KEY", "expected an uncompressed P-256 public key in URL-safe base64")
+	}
+	if key, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(cfg.PrivateKey, "=")); err != nil || len(key) != 32 {
+		// Don't echo the key.
+		l.failf("VAPID_PRIVATE_KEY", "expected a P-256 private key in URL-safe base64")
+	}
+	if u, err := url.Parse(cfg.Subject); err != nil || (u.Scheme != "mailto" && u.Scheme != "https") || (u.Opaque == "" && u.Host == "") {
+		l.failf("VAPID_SUBJECT", "expected a mailto: or https: URL push services can reach you at, got %q", cfg.Subject)
+	}
+	return cfg
+}
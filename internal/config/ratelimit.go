@@ -0,0 +1,316 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/sujalbistaa/whispr/internal/bans"
+)
+
+// Routes that accept a rate limit override, keyed as "METHOD /path".
+// Versioned and legacy paths share one limiter, keyed by the legacy path.
+const (
+	RouteCreatePost = "POST /api/posts"
+	RouteVote       = "POST /api/posts/:id/vote"
+	RouteStats      = "GET /api/stats"
+	RouteStream     = "GET /api/posts/stream"
+	RouteChallenge  = "GET /api/challenge"
+	RoutePush       = "POST /api/push/subscribe"
+	RouteCSPReport  = "POST /api/csp-report"
+)
+
+const (
+	// Per-IP post creation: 1 request every 3 seconds.
+	defaultPostRPS   = 1.0 / 3.0
+	defaultPostBurst = 1
+
+	// Per-IP public stats: the landing page needs one request per visit.
+	defaultStatsRPS   = 1
+	defaultStatsBurst = 5
+
+	// Per-IP post stream: each request reads the whole posts table.
+	defaultStreamRPS   = 1.0 / 60.0
+	defaultStreamBurst = 2
+
+	// Per-IP challenges: one per post, plus retries.
+	defaultChallengeRPS   = 1
+	defaultChallengeBurst = 5
+
+	// Per-IP push subscriptions: a browser subscribes once, and again
+	// when its push service rotates the endpoint.
+	defaultPushRPS   = 1.0 / 60.0
+	defaultPushBurst = 5
+
+	// Per-IP CSP reports: a page can break several rules at once.
+	defaultCSPReportRPS   = 1
+	defaultCSPReportBurst = 20
+
+	// Per API key, across every route: enough for a bot mirroring the
+	// feed, which would trip the per-IP limits.
+	defaultAPIKeyRPS   = 5
+	defaultAPIKeyBurst = 50
+
+	// Per-client posts in any 24 hours.
+	defaultPostQuota = 10
+
+	// defaultIdleTTL is how long an idle visitor's bucket is kept.
+	defaultIdleTTL = 10 * time.Minute
+
+	// Global POST ceilings, sized so SQLite's single writer isn't swamped.
+	defaultGlobalMaxInFlight = 32
+	defaultGlobalRPS         = 50
+	defaultGlobalBurst       = 100
+)
+
+// RouteLimit is a token bucket configuration for one route.
+type RouteLimit struct {
+	RPS   float64 `json:"rps"`
+	Burst int     `json:"burst"`
+}
+
+// RateLimit holds the per-IP rate limits applied to write routes.
+// Default applies to post creation; Routes overrides individual routes.
+// Visitors idle for longer than IdleTTL are forgotten.
+// When RedisURL is set, buckets are shared through Redis and FailOpen
+// decides what happens while Redis is unreachable.
+// Clients inside Allowlist are never limited.
+// PostQuota caps each client's posts in any 24 hours; zero disables.
+// APIKey is the bucket each API key gets in place of its IP's.
+// The Global* fields cap POST traffic across all clients; zero disables.
+type RateLimit struct {
+	Default   RouteLimit            `json:"default"`
+	Routes    map[string]RouteLimit `json:"routes"`
+	IdleTTL   time.Duration         `json:"idleTtl"`
+	RedisURL  string                `json:"-"`
+	Backend   string                `json:"backend"`
+	FailOpen  bool                  `json:"failOpen"`
+	Allowlist []netip.Prefix        `json:"allowlist"`
+	PostQuota int                   `json:"postQuota"`
+	APIKey    RouteLimit            `json:"apiKey"`
+
+	GlobalMaxInFlight int     `json:"globalMaxInFlight"`
+	GlobalRPS         float64 `json:"globalRps"`
+	GlobalBurst       int     `json:"globalBurst"`
+}
+
+// For returns the limit for a route and whether it should be limited at all.
+// Post creation, public stats, the post stream, challenges, push
+// subscriptions and CSP reports are always limited; other routes only
+// when overridden.
+func (rc RateLimit) For(route string) (RouteLimit, bool) {
+	if limit, ok := rc.Routes[route]; ok {
+		return limit, true
+	}
+	switch route {
+	case RouteCreatePost:
+		return rc.Default, true
+	case RouteStats:
+		return RouteLimit{RPS: defaultStatsRPS, Burst: defaultStatsBurst}, true
+	case RouteStream:
+		return RouteLimit{RPS: defaultStreamRPS, Burst: defaultStreamBurst}, true
+	case RouteChallenge:
+		return RouteLimit{RPS: defaultChallengeRPS, Burst: defaultChallengeBurst}, true
+	case RoutePush:
+		return RouteLimit{RPS: defaultPushRPS, Burst: defaultPushBurst}, true
+	case RouteCSPReport:
+		return RouteLimit{RPS: defaultCSPReportRPS, Burst: defaultCSPReportBurst}, true
+	}
+	return RouteLimit{}, false
+}
+
+// String renders the config for startup logs.
+func (rc RateLimit) String() string {
+	parts := []string{
+		"backend " + rc.Backend,
+		fmt.Sprintf("default=%g/s burst %d", rc.Default.RPS, rc.Default.Burst),
+		"idle ttl " + rc.IdleTTL.String(),
+	}
+	if rc.Backend == "redis" {
+		parts = append(parts, fmt.Sprintf("fail open %t", rc.FailOpen))
+	}
+	parts = append(parts, fmt.Sprintf("daily post quota %d", rc.PostQuota))
+	parts = append(parts, fmt.Sprintf("api keys %g/s burst %d", rc.APIKey.RPS, rc.APIKey.Burst))
+	parts = append(parts, fmt.Sprintf("global max in-flight %d, global %g/s burst %d", rc.GlobalMaxInFlight, rc.GlobalRPS, rc.GlobalBurst))
+	if len(rc.Allowlist) > 0 {
+		allow := make([]string, len(rc.Allowlist))
+		for i, prefix := range rc.Allowlist {
+			allow[i] = prefix.String()
+		}
+		parts = append(parts, "allowlist "+strings.Join(allow, " "))
+	}
+	routes := make([]string, 0, len(rc.Routes))
+	for route := range rc.Routes {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	for _, route := range routes {
+		limit := rc.Routes[route]
+		parts = append(parts, fmt.Sprintf("%s=%g/s burst %d", route, limit.RPS, limit.Burst))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// rateLimit reads POST_RATE_RPS, POST_RATE_BURST, POST_DAILY_QUOTA,
+// RATE_LIMIT_IDLE_TTL, RATE_LIMIT_ROUTES, REDIS_URL, RATE_LIMIT_FAIL_OPEN,
+// RATE_LIMIT_ALLOWLIST, GLOBAL_MAX_INFLIGHT_POSTS, GLOBAL_POST_RPS,
+// GLOBAL_POST_BURST and API_KEY_RATE_LIMIT ("rps:burst").
+// RATE_LIMIT_ROUTES is a comma-separated list of "METHOD /path=rps:burst"
+// entries, e.g. "POST /api/v1/posts/:id/vote=2:5".
+func (l *loader) rateLimit() RateLimit {
+	rc := RateLimit{
+		Default:  RouteLimit{RPS: defaultPostRPS, Burst: defaultPostBurst},
+		Routes:   map[string]RouteLimit{},
+		IdleTTL:  defaultIdleTTL,
+		RedisURL: l.string("REDIS_URL", ""),
+		Backend:  "memory",
+		FailOpen: l.bool("RATE_LIMIT_FAIL_OPEN", true),
+
+		PostQuota: l.nonNegativeInt("POST_DAILY_QUOTA", defaultPostQuota),
+		APIKey:    RouteLimit{RPS: defaultAPIKeyRPS, Burst: defaultAPIKeyBurst},
+
+		GlobalMaxInFlight: l.nonNegativeInt("GLOBAL_MAX_INFLIGHT_POSTS", defaultGlobalMaxInFlight),
+		GlobalRPS:         defaultGlobalRPS,
+		GlobalBurst:       defaultGlobalBurst,
+	}
+	if rc.RedisURL != "" {
+		rc.Backend = "redis"
+		if _, err := redis.ParseURL(rc.RedisURL); err != nil {
+			l.failf("REDIS_URL", "%v", err)
+		}
+	}
+
+	if raw := l.string("POST_RATE_RPS", ""); raw != "" {
+		if rps, err := parseRPS(raw); err != nil {
+			l.failf("POST_RATE_RPS", "%v", err)
+		} else {
+			rc.Default.RPS = rps
+		}
+	}
+	if raw := l.string("POST_RATE_BURST", ""); raw != "" {
+		if burst, err := parseBurst(raw); err != nil {
+			l.failf("POST_RATE_BURST", "%v", err)
+		} else {
+			rc.Default.Burst = burst
+		}
+	}
+
+	for _, entry := range l.list("RATE_LIMIT_ALLOWLIST") {
+		prefix, err := bans.ParsePrefix(entry)
+		if err != nil {
+			l.failf("RATE_LIMIT_ALLOWLIST", "invalid IP or CIDR %q", entry)
+			continue
+		}
+		rc.Allowlist = append(rc.Allowlist, prefix)
+	}
+
+	if raw := l.string("GLOBAL_POST_RPS", ""); raw != "" {
+		rps, err := strconv.ParseFloat(raw, 64)
+		if err != nil || math.IsNaN(rps) || math.IsInf(rps, 0) || rps < 0 {
+			l.failf("GLOBAL_POST_RPS", "expected a non-negative number, got %q", raw)
+		} else {
+			rc.GlobalRPS = rps
+		}
+	}
+	if raw := l.string("GLOBAL_POST_BURST", ""); raw != "" {
+		if burst, err := parseBurst(raw); err != nil {
+			l.failf("GLOBAL_POST_BURST", "%v", err)
+		} else {
+			rc.GlobalBurst = burst
+		}
+	}
+
+	if raw := l.string("API_KEY_RATE_LIMIT", ""); raw != "" {
+		if limit, err := parseLimit(raw); err != nil {
+			l.failf("API_KEY_RATE_LIMIT", "%v", err)
+		} else {
+			rc.APIKey = limit
+		}
+	}
+
+	if raw := l.string("RATE_LIMIT_IDLE_TTL", ""); raw != "" {
+		ttl, err := time.ParseDuration(raw)
+		if err != nil || ttl <= 0 {
+			l.failf("RATE_LIMIT_IDLE_TTL", "expected a positive duration like 10m, got %q", raw)
+		} else {
+			rc.IdleTTL = ttl
+		}
+	}
+
+	for _, entry := range l.list("RATE_LIMIT_ROUTES") {
+		route, limit, err := parseRouteLimit(entry)
+		if err != nil {
+			l.failf("RATE_LIMIT_ROUTES", "%v", err)
+			continue
+		}
+		rc.Routes[route] = limit
+	}
+
+	return rc
+}
+
+// parseRouteLimit parses one "METHOD /path=rps:burst" override.
+func parseRouteLimit(entry string) (string, RouteLimit, error) {
+	route, value, ok := strings.Cut(entry, "=")
+	if !ok {
+		return "", RouteLimit{}, fmt.Errorf("expected \"METHOD /path=rps:burst\", got %q", entry)
+	}
+	route = strings.Replace(strings.Join(strings.Fields(route), " "), " /api/v1/", " /api/", 1)
+	switch route {
+	case RouteCreatePost, RouteVote, RouteStats, RouteStream, RouteChallenge, RoutePush, RouteCSPReport:
+	default:
+		return "", RouteLimit{}, fmt.Errorf("unsupported route %q (supported: %q, %q, %q, %q, %q, %q, %q)", route, RouteCreatePost, RouteVote, RouteStats, RouteStream, RouteChallenge, RoutePush, RouteCSPReport)
+	}
+	limit, err := parseLimit(value)
+	if err != nil {
+		return "", RouteLimit{}, fmt.Errorf("%q: %w", route, err)
+	}
+	return route, limit, nil
+}
+
+// parseLimit parses "rps:burst".
+func parseLimit(value string) (RouteLimit, error) {
+	rpsRaw, burstRaw, ok := strings.Cut(value, ":")
+	if !ok {
+		return RouteLimit{}, fmt.Errorf("expected rps:burst, got %q", value)
+	}
+	rps, err := parseRPS(rpsRaw)
+	if err != nil {
+		return RouteLimit{}, err
+	}
+	burst, err := parseBurst(burstRaw)
+	if err != nil {
+		return RouteLimit{}, err
+	}
+	return RouteLimit{RPS: rps, Burst: burst}, nil
+}
+
+// parseRPS parses a positive, finite rate. ParseFloat also accepts "NaN"
+// and "Inf", which no comparison would catch later.
+func parseRPS(raw string) (float64, error) {
+	rps, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil || math.IsNaN(rps) || math.IsInf(rps, 0) {
+		return 0, fmt.Errorf("invalid rate %q", raw)
+	}
+	if rps <= 0 {
+		return 0, fmt.Errorf("rate must be positive, got %g", rps)
+	}
+	return rps, nil
+}
+
+func parseBurst(raw string) (int, error) {
+	burst, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil {
+		return 0, fmt.Errorf("invalid burst %q", raw)
+	}
+	if burst <= 0 {
+		return 0, fmt.Errorf("burst must be positive, got %d", burst)
+	}
+	return burst, nil
+}
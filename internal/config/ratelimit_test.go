@@ -0,0 +1,76 @@
+package config_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sujalbistaa/whispr/internal/config"
+)
+
+func TestRateLimitMalformed(t *testing.T) {
+	for _, tc := range []struct {
+		key, value string
+	}{
+		{"POST_RATE_RPS", "fast"},
+		{"POST_RATE_RPS", "0"},
+		{"POST_RATE_RPS", "-1"},
+		{"POST_RATE_RPS", "NaN"},
+		{"POST_RATE_RPS", "Inf"},
+		{"POST_RATE_RPS", "+Inf"},
+		{"POST_RATE_RPS", "-inf"},
+		{"POST_RATE_RPS", "1e400"},
+		{"POST_RATE_BURST", "0"},
+		{"POST_RATE_BURST", "-3"},
+		{"POST_RATE_BURST", "1.5"},
+		{"POST_RATE_BURST", "lots"},
+		{"GLOBAL_POST_RPS", "-1"},
+		{"GLOBAL_POST_RPS", "NaN"},
+		{"GLOBAL_POST_RPS", "Inf"},
+		{"API_KEY_RATE_LIMIT", "5"},
+		{"API_KEY_RATE_LIMIT", "NaN:5"},
+		{"API_KEY_RATE_LIMIT", "5:0"},
+		{"RATE_LIMIT_ROUTES", "POST /api/v1/posts/:id/vote"},
+		{"RATE_LIMIT_ROUTES", "POST /api/v1/posts/:id/vote=+Inf:5"},
+		{"RATE_LIMIT_ROUTES", "GET /api/v1/posts=1:5"},
+		{"RATE_LIMIT_IDLE_TTL", "10"},
+		{"RATE_LIMIT_IDLE_TTL", "-1m"},
+	} {
+		t.Run(tc.key+"="+tc.value, func(t *testing.T) {
+			t.Setenv(tc.key, tc.value)
+			_, err := config.Load()
+			if err == nil {
+				t.Fatalf("Load accepted %s=%q", tc.key, tc.value)
+			}
+			if !strings.Contains(err.Error(), tc.key) {
+				t.Errorf("error %q doesn't name %s", err, tc.key)
+			}
+		})
+	}
+}
+
+func TestRateLimitFromEnv(t *testing.T) {
+	t.Setenv("POST_RATE_RPS", " 0.5 ")
+	t.Setenv("POST_RATE_BURST", "3")
+	t.Setenv("GLOBAL_POST_RPS", "0")
+	t.Setenv("RATE_LIMIT_ROUTES", "POST /api/v1/posts/:id/vote=2:5, GET /api/stats=0.1:1")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rl := cfg.RateLimit
+	if rl.Default != (config.RouteLimit{RPS: 0.5, Burst: 3}) {
+		t.Errorf("Default = %+v, want 0.5/s burst 3", rl.Default)
+	}
+	if rl.GlobalRPS != 0 {
+		t.Errorf("GlobalRPS = %g, want 0 (off)", rl.GlobalRPS)
+	}
+	if limit, ok := rl.For(config.RouteVote); !ok || limit != (config.RouteLimit{RPS: 2, Burst: 5}) {
+		t.Errorf("For(vote) = %+v, %v; want 2/s burst 5 under the legacy key", limit, ok)
+	}
+	if limit, _ := rl.For(config.RouteStats); limit != (config.RouteLimit{RPS: 0.1, Burst: 1}) {
+		t.Errorf("For(stats) = %+v, want 0.1/s burst 1", limit)
+	}
+	if limit, _ := rl.For(config.RouteCreatePost); limit != rl.Default {
+		t.Errorf("For(create) = %+v, want the default", limit)
+	}
+}
@@ -0,0 +1,25 @@
+package config
+
+import (
+	"time"
+
+	"github.com/sujalbistaa/whispr/internal/simhash"
+)
+
+const (
+	defaultSpamWindow   = time.Hour
+	defaultSpamDistance = 4
+)
+
+// spam reads SPAM_SIMILARITY_WINDOW, how far back new posts are compared
+// against for near-duplicates (0 disables), and SPAM_SIMILARITY_DISTANCE.
+func (l *loader) spam() simhash.Config {
+	cfg := simhash.Config{
+		Window:      l.duration("SPAM_SIMILARITY_WINDOW", defaultSpamWindow),
+		MaxDistance: l.nonNegativeInt("SPAM_SIMILARITY_DISTANCE", defaultSpamDistance),
+	}
+	if cfg.MaxDistance > simhash.MaxDistance {
+		l.failf("SPAM_SIMILARITY_DISTANCE", "must be at most %d bits, got %d", simhash.MaxDistance, cfg.MaxDistance)
+	}
+	return cfg
+}
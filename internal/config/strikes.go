@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sujalbistaa/whispr/internal/strikes"
+)
+
+const (
+	defaultStrikeWindow = 7 * 24 * time.Hour
+	// Three hidden posts in a week allow one post per 10 minutes for a
+	// day; five allow one an hour for three days.
+	defaultStrikeEscalation = "3:10m:24h,5:1h:72h"
+)
+
+// strikes reads STRIKE_WINDOW, how long strikes count from the first
+// one, and STRIKE_ESCALATION, a comma-separated list of
+// "strikes:cooldown:for" levels with rising strike counts, or "off".
+func (l *loader) strikes() strikes.Config {
+	cfg := strikes.Config{Window: l.duration("STRIKE_WINDOW", defaultStrikeWindow)}
+	if cfg.Window == 0 {
+		l.failf("STRIKE_WINDOW", "must be positive")
+	}
+	raw := l.string("STRIKE_ESCALATION", defaultStrikeEscalation)
+	if strings.EqualFold(raw, "off") {
+		return cfg
+	}
+	for _, entry := range strings.Split(raw, ",") {
+		level, err := parseStrikeLevel(strings.TrimSpace(entry))
+		if err != nil {
+			l.failf("STRIKE_ESCALATION", "%v", err)
+			continue
+		}
+		if n := len(cfg.Levels); n > 0 && level.Strikes <= cfg.Levels[n-1].Strikes {
+			l.failf("STRIKE_ESCALATION", "strike counts must rise, got %d after %d", level.Strikes, cfg.Levels[n-1].Strikes)
+			continue
+		}
+		cfg.Levels = append(cfg.Levels, level)
+	}
+	return cfg
+}
+
+// parseStrikeLevel parses "strikes:cooldown:for", e.g. "3:10m:24h".
+func parseStrikeLevel(entry string) (strikes.Level, error) {
+	parts := strings.Split(entry, ":")
+	if len(parts) != 3 {
+		return strikes.Level{}, fmt.Errorf("expected strikes:cooldown:for, got %q", entry)
+	}
+	n, err := strconv.Atoi(parts[0])
+	if err != nil || n <= 0 {
+		return strikes.Level{}, fmt.Errorf("expected a positive strike count in %q", entry)
+	}
+	cooldown, err := time.ParseDuration(parts[1])
+	if err != nil || cooldown < time.Second {
+		return strikes.Level{}, fmt.Errorf("expected a cooldown of at least 1s in %q", entry)
+	}
+	lasts, err := time.ParseDuration(parts[2])
+	if err != nil || lasts <= 0 {
+		return strikes.Level{}, fmt.Errorf("expected a positive duration for in %q", entry)
+	}
+	return strikes.Level{Strikes: n, Cooldown: cooldown, For: lasts}, nil
+}
+
+// strikeLevels summarizes the escalation schedule for the startup log.
+func strikeLevels(levels []strikes.Level) string {
+	entries := make([]string, len(levels))
+	for i, level := range levels {
+		entries[i] = fmt.Sprintf("%d:%s:%s", level.Strikes, level.Cooldown, level.For)
+	}
+	return strings.Join(entries, ",")
+}
@@ -0,0 +1,75 @@
+package config
+
+import "os"
+
+const (
+	defaultAutocertCacheDir = "autocert-cache"
+	defaultTLSRedirectAddr  = ":80"
+)
+
+// TLS configures HTTPS on PORT, for deployments without a proxy in front
+// to terminate it. Either a static certificate or Let's Encrypt (autocert)
+// can be used, not both.
+type TLS struct {
+	CertFile string
+	KeyFile  string
+
+	AutocertDomains  []string // Hosts to request certificates for
+	AutocertCacheDir string   // Where issued certificates are kept across restarts
+	AutocertEmail    string   // Contact for expiry notices; optional
+
+	// RedirectAddr is a plain-HTTP listener that redirects to HTTPS and,
+	// with autocert, answers the HTTP-01 challenges. Empty disables it.
+	RedirectAddr string
+}
+
+// Enabled reports whether PORT serves HTTPS.
+func (t TLS) Enabled() bool {
+	return t.CertFile != "" || t.Autocert()
+}
+
+// Autocert reports whether certificates come from Let's Encrypt.
+func (t TLS) Autocert() bool {
+	return len(t.AutocertDomains) > 0
+}
+
+// tls reads TLS_CERT_FILE and TLS_KEY_FILE, or AUTOCERT_DOMAINS with
+// AUTOCERT_CACHE_DIR and AUTOCERT_EMAIL, plus TLS_REDIRECT_ADDR, which
+// defaults to :80 with autocert (for HTTP-01) and is off otherwise. "off"
+// turns it off explicitly; autocert then relies on TLS-ALPN-01, which
+// only works with PORT=443.
+func (l *loader) tls() TLS {
+	t := TLS{
+		CertFile:         l.string("TLS_CERT_FILE", ""),
+		KeyFile:          l.string("TLS_KEY_FILE", ""),
+		AutocertDomains:  l.list("AUTOCERT_DOMAINS"),
+		AutocertCacheDir: l.string("AUTOCERT_CACHE_DIR", defaultAutocertCacheDir),
+		AutocertEmail:    l.string("AUTOCERT_EMAIL", ""),
+	}
+	redirectDefault := ""
+	if t.Autocert() {
+		redirectDefault = defaultTLSRedirectAddr
+	}
+	if t.RedirectAddr = l.string("TLS_REDIRECT_ADDR", redirectDefault); t.RedirectAddr == "off" {
+		t.RedirectAddr = ""
+	}
+
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		l.failf("TLS_CERT_FILE", "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	}
+	if t.RedirectAddr != "" && !t.Enabled() {
+		l.failf("TLS_REDIRECT_ADDR", "needs TLS_CERT_FILE or AUTOCERT_DOMAINS")
+	}
+	if t.CertFile != "" && t.Autocert() {
+		l.failf("AUTOCERT_DOMAINS", "can't be combined with TLS_CERT_FILE")
+	}
+	for _, file := range []struct{ key, path string }{{"TLS_CERT_FILE", t.CertFile}, {"TLS_KEY_FILE", t.KeyFile}} {
+		if file.path == "" {
+			continue
+		}
+		if _, err := os.Stat(file.path); err != nil {
+			l.failf(file.key, "%v", err)
+		}
+	}
+	return t
+}
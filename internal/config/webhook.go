@@ -0,0 +1,61 @@
+package config
+
+import (
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sujalbistaa/whispr/internal/webhook"
+)
+
+const (
+	defaultWebhookQueueSize   = 256
+	defaultWebhookMaxAttempts = 5
+	defaultWebhookTimeout     = 10 * time.Second
+)
+
+// webhooks reads WEBHOOKS, a semicolon-separated list of endpoints, each a
+// URL optionally followed by a space and comma-separated events:
+//
+//	https://discord.com/api/webhooks/1/abc new_post,post_hidden; https://hooks.example.edu/whispr
+//
+// plus WEBHOOK_SECRET, WEBHOOK_QUEUE_SIZE, WEBHOOK_MAX_ATTEMPTS and
+// WEBHOOK_TIMEOUT.
+func (l *loader) webhooks() webhook.Config {
+	cfg := webhook.Config{
+		Secret:      os.Getenv("WEBHOOK_SECRET"),
+		QueueSize:   l.positiveInt("WEBHOOK_QUEUE_SIZE", defaultWebhookQueueSize),
+		MaxAttempts: l.positiveInt("WEBHOOK_MAX_ATTEMPTS", defaultWebhookMaxAttempts),
+		Timeout:     l.duration("WEBHOOK_TIMEOUT", defaultWebhookTimeout),
+	}
+	for _, entry := range strings.Split(os.Getenv("WEBHOOKS"), ";") {
+		fields := strings.Fields(entry)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) > 2 {
+			l.failf("WEBHOOKS", "expected \"URL [event,event]\", got %q", strings.TrimSpace(entry))
+			continue
+		}
+		endpoint := webhook.Endpoint{URL: fields[0]}
+		if u, err := url.Parse(endpoint.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			// Don't echo the URL: it usually contains a secret.
+			l.failf("WEBHOOKS", "entry %d: expected an absolute http(s) URL", len(cfg.Endpoints)+1)
+			continue
+		}
+		if len(fields) == 2 {
+			for _, name := range strings.Split(fields[1], ",") {
+				event := webhook.Event(strings.TrimSpace(name))
+				if !slices.Contains(webhook.Events, event) {
+					l.failf("WEBHOOKS", "unknown event %q (supported: %v)", event, webhook.Events)
+					continue
+				}
+				endpoint.Events = append(endpoint.Events, event)
+			}
+		}
+		cfg.Endpoints = append(cfg.Endpoints, endpoint)
+	}
+	return cfg
+}
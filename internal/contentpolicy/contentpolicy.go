@@ -0,0 +1,220 @@
+// Package contentpolicy finds contact details in post content: links,
+// email addresses, phone numbers and social media handles, including the
+// usual ways of writing them to slip past a filter ("example[.]com",
+// "john dot doe at gmail"). Posts are anonymous, so these are almost
+// always spam, doxxing or phishing. Links and addresses on allowed
+// domains pass.
+package contentpolicy
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kind is a kind of contact detail.
+type Kind string
+
+const (
+	URL    Kind = "url"
+	Email  Kind = "email"
+	Phone  Kind = "phone"
+	Handle Kind = "handle" // @name, as on Instagram
+)
+
+// Kinds lists every kind, in the order overlapping matches are resolved:
+// an email address contains a domain and an @, and a URL can contain
+// digits.
+var Kinds = []Kind{Email, URL, Phone, Handle}
+
+// Action is what happens to a post with matches.
+type Action string
+
+const (
+	ActionOff    Action = "off"
+	ActionReject Action = "reject"
+	ActionRedact Action = "redact"
+)
+
+// Actions lists every action.
+var Actions = []Action{ActionOff, ActionReject, ActionRedact}
+
+// Placeholder replaces each match when redacting.
+const Placeholder = "[removed]"
+
+// Config configures the policy applied to new posts.
+type Config struct {
+	Action       Action
+	Kinds        []Kind   // What to look for
+	AllowDomains []string // Hosts whose links and addresses pass, with their subdomains
+}
+
+// Enabled reports whether posts are checked.
+func (c Config) Enabled() bool {
+	return c.Action != "" && c.Action != ActionOff && len(c.Kinds) > 0
+}
+
+// Match is one contact detail found in a text.
+type Match struct {
+	Kind       Kind
+	Start, End int // Byte offsets
+	Text       string
+}
+
+// Building blocks. Dots and "at" signs may be written out or bracketed;
+// a bare "." may not be surrounded by spaces, or every sentence break
+// would look like a domain.
+const (
+	label   = `[a-z0-9](?:[a-z0-9-]*[a-z0-9])?`
+	dot     = `(?:\.|\s*[\[({]\s*(?:\.|dot)\s*[\])}]\s*)`
+	wordDot = `\s+dot\s+`
+	at      = `(?:\s*@\s*|\s*[\[({]\s*(?:@|at)\s*[\])}]\s*|\s+at\s+)`
+
+	// Top-level domains a bare domain must end in to count. Anything
+	// with a scheme or www. counts regardless.
+	tlds = `com|net|org|edu|gov|io|co|me|ly|gg|info|biz|xyz|app|dev|link|site|online|shop|store|live|tv|us|uk|in|np|ru|cn|de|fr|au|ca`
+	// Spelled-out dots only before the unambiguous ones, so "a dot in
+	// the middle" isn't a domain.
+	wordTLDs = `com|net|org|edu|io`
+	// Free mail providers, enough on their own after a spelled-out "at".
+	providers = `gmail|yahoo|hotmail|outlook|icloud|protonmail|proton`
+
+	domain     = label + `(?:` + dot + label + `)*` + dot + `(?:` + tlds + `)\b`
+	wordDomain = label + `(?:` + wordDot + label + `)*` + wordDot + `(?:` + wordTLDs + `)\b`
+)
+
+var patterns = map[Kind]*regexp.Regexp{
+	Email: regexp.MustCompile(`(?i)\b[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}\b` +
+		`|\b[a-z0-9_%+-]+(?:(?:` + dot + `|` + wordDot + `)[a-z0-9_%+-]+)*` + at +
+		`(?:` + domain + `|` + wordDomain + `|(?:` + providers + `)\b)`),
+	URL: regexp.MustCompile(`(?i)\b(?:https?|hxxps?|ftp)://[^\s<>"]+` +
+		`|\bwww` + dot + `[^\s<>"]+` +
+		`|\b(?:` + domain + `|` + wordDomain + `)(?:/[^\s<>"]*)?`),
+	Phone: regexp.MustCompile(`(?:\+\s?)?\(?\b\d(?:[\s.()/-]{0,2}\d){7,14}\b`),
+	// The first group is the match; what precedes it only rules out the
+	// middle of a word.
+	Handle: regexp.MustCompile(`(?i)(?:^|[^\w@.])(@[a-z0-9_](?:[a-z0-9_.]*[a-z0-9_])?)`),
+}
+
+// Matches that look like phone numbers but are dates or year ranges.
+var notPhone = regexp.MustCompile(`^(?:\d{4}[-/.]\d{1,2}[-/.]\d{1,2}|\d{1,2}[-/.]\d{1,2}[-/.]\d{4}|(?:19|20)\d\d\s*-\s*(?:19|20)\d\d)$`)
+
+// normalizeDots rewrites obfuscated dots and "at"s so hosts can be
+// compared with the allowlist.
+var normalizeDots = strings.NewReplacer(
+	"[.]", ".", "(.)", ".", "{.}", ".", "[dot]", ".", "(dot)", ".", "{dot}", ".", " dot ", ".",
+	"[@]", "@", "(@)", "@", "{@}", "@", "[at]", "@", "(at)", "@", "{at}", "@", " at ", "@",
+)
+
+// Policy finds contact details of the configured kinds.
+type Policy struct {
+	kinds []Kind
+	allow []string
+}
+
+// New returns a policy looking for kinds, letting links and addresses on
+// allowDomains through.
+func New(kinds []Kind, allowDomains []string) *Policy {
+	p := &Policy{}
+	for _, k := range Kinds {
+		for _, want := range kinds {
+			if k == want {
+				p.kinds = append(p.kinds, k)
+			}
+		}
+	}
+	for _, d := range allowDomains {
+		p.allow = append(p.allow, strings.ToLower(strings.TrimPrefix(d, ".")))
+	}
+	return p
+}
+
+// Find returns the contact details in text, in order and without
+// overlaps.
+func (p *Policy) Find(text string) []Match {
+	var matches []Match
+	overlaps := func(start, end int) bool {
+		for _, m := range matches {
+			if start < m.End && m.Start < end {
+				return true
+			}
+		}
+		return false
+	}
+	for _, kind := range p.kinds {
+		for _, loc := range patterns[kind].FindAllStringSubmatchIndex(text, -1) {
+			start, end := loc[0], loc[1]
+			if len(loc) > 2 {
+				start, end = loc[2], loc[3]
+			}
+			if kind == URL {
+				// Sentence punctuation after a link isn't part of it.
+				end = start + len(strings.TrimRight(text[start:end], ".,;:!?)'\""))
+			}
+			m := Match{Kind: kind, Start: start, End: end, Text: strings.TrimSpace(text[start:end])}
+			if overlaps(start, end) || p.allowed(m) {
+				continue
+			}
+			if kind == Phone && notPhone.MatchString(m.Text) {
+				continue
+			}
+			matches = append(matches, m)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Start < matches[j].Start })
+	return matches
+}
+
+// Redact replaces every match in text with Placeholder.
+func (p *Policy) Redact(text string) (string, []Match) {
+	matches := p.Find(text)
+	if len(matches) == 0 {
+		return text, nil
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(text[last:m.Start])
+		b.WriteString(Placeholder)
+		last = m.End
+	}
+	b.WriteString(text[last:])
+	return b.String(), matches
+}
+
+// allowed reports whether m is a link or address on an allowed domain.
+func (p *Policy) allowed(m Match) bool {
+	if m.Kind == Phone || m.Kind == Handle || len(p.allow) == 0 {
+		return false
+	}
+	host := normalizeDots.Replace(strings.ToLower(m.Text))
+	if m.Kind == Email {
+		_, host, _ = strings.Cut(host, "@")
+	} else if _, rest, ok := strings.Cut(host, "://"); ok {
+		host = rest
+	}
+	if i := strings.IndexAny(host, "/?#:"); i >= 0 {
+		host = host[:i]
+	}
+	host = strings.TrimSpace(host)
+	for _, d := range p.allow {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// KindsOf returns the distinct kinds among matches, in Kinds order.
+func KindsOf(matches []Match) []Kind {
+	var kinds []Kind
+	for _, k := range Kinds {
+		for _, m := range matches {
+			if m.Kind == k {
+				kinds = append(kinds, k)
+				break
+			}
+		}
+	}
+	return kinds
+}
@@ -0,0 +1,94 @@
+package contentpolicy_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sujalbistaa/whispr/internal/contentpolicy"
+)
+
+// found is a match as the table writes it.
+type found struct {
+	kind contentpolicy.Kind
+	text string
+}
+
+func TestFind(t *testing.T) {
+	p := contentpolicy.New(contentpolicy.Kinds, []string{"tu.edu.np", "example.org"})
+	for _, tc := range []struct {
+		text string
+		want []found
+	}{
+		// Plain
+		{"mail me at john.doe@gmail.com tonight", []found{{contentpolicy.Email, "john.doe@gmail.com"}}},
+		{"see https://evil.example.com/login?next=1.", []found{{contentpolicy.URL, "https://evil.example.com/login?next=1"}}},
+		{"go to www.spam.ru now", []found{{contentpolicy.URL, "www.spam.ru"}}},
+		{"cheap stuff: shop.xyz!", []found{{contentpolicy.URL, "shop.xyz"}}},
+		{"call +977 9841234567", []found{{contentpolicy.Phone, "+977 9841234567"}}},
+		{"text 984-123-4567 after 5", []found{{contentpolicy.Phone, "984-123-4567"}}},
+		{"dm @sneaky_user on insta", []found{{contentpolicy.Handle, "@sneaky_user"}}},
+
+		// Obfuscated
+		{"write to john dot doe at gmail", []found{{contentpolicy.Email, "john dot doe at gmail"}}},
+		{"john [at] example [dot] com", []found{{contentpolicy.Email, "john [at] example [dot] com"}}},
+		{"jane(at)mail(dot)net please", []found{{contentpolicy.Email, "jane(at)mail(dot)net"}}},
+		{"visit example[.]com", []found{{contentpolicy.URL, "example[.]com"}}},
+		{"hxxps://phish.io/x", []found{{contentpolicy.URL, "hxxps://phish.io/x"}}},
+		{"write sales at shop.xyz", []found{{contentpolicy.Email, "sales at shop.xyz"}}},
+		{"spam dot com is the place", []found{{contentpolicy.URL, "spam dot com"}}},
+		{"9841 234 567", []found{{contentpolicy.Phone, "9841 234 567"}}},
+
+		// Several at once, in order
+		{"@me or me@site.com or 9841234567", []found{
+			{contentpolicy.Handle, "@me"},
+			{contentpolicy.Email, "me@site.com"},
+			{contentpolicy.Phone, "9841234567"},
+		}},
+
+		// Allowed domains and their subdomains
+		{"results at https://tu.edu.np/results", nil},
+		{"ask registrar@exam.tu.edu.np", nil},
+		{"docs on example[.]org", nil},
+		{"but not on example.org.evil.com", []found{{contentpolicy.URL, "example.org.evil.com"}}},
+		{"nor notexample.org", []found{{contentpolicy.URL, "notexample.org"}}},
+
+		// Ordinary text
+		{"The exam is on 2024-05-12 at 10am.", nil},
+		{"It ran 2019 - 2023. Then it stopped.", nil},
+		{"I'm at home. Dot your i's.", nil},
+		{"a dot in the middle of nowhere", nil},
+		{"meet me at the library", nil},
+		{"score was 3-2", nil},
+		{"email@ is not an address", nil},
+	} {
+		var got []found
+		for _, m := range p.Find(tc.text) {
+			got = append(got, found{m.Kind, m.Text})
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("Find(%q) = %v, want %v", tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestFindOnlyConfiguredKinds(t *testing.T) {
+	p := contentpolicy.New([]contentpolicy.Kind{contentpolicy.Phone}, nil)
+	matches := p.Find("me@site.com, www.site.com, @me and 9841234567")
+	if len(matches) != 1 || matches[0].Kind != contentpolicy.Phone {
+		t.Errorf("Find = %+v, want only the phone number", matches)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	p := contentpolicy.New(contentpolicy.Kinds, nil)
+	got, matches := p.Redact("write john dot doe at gmail or call 9841234567.")
+	if want := "write [removed] or call [removed]."; got != want {
+		t.Errorf("Redact = %q, want %q", got, want)
+	}
+	if kinds := contentpolicy.KindsOf(matches); !reflect.DeepEqual(kinds, []contentpolicy.Kind{contentpolicy.Email, contentpolicy.Phone}) {
+		t.Errorf("KindsOf = %v", kinds)
+	}
+	if got, matches := p.Redact("nothing to see here"); got != "nothing to see here" || matches != nil {
+		t.Errorf("Redact of clean text = %q, %v", got, matches)
+	}
+}
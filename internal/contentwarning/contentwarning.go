@@ -0,0 +1,142 @@
+// Package contentwarning tags posts on sensitive topics so clients can
+// blur them behind a tap. Authors pick a warning themselves; when they
+// don't, a keyword heuristic applies one.
+//
+// The keywords are matched against the post, not the reader's language:
+// the default lists mix English, romanized Nepali and Devanagari, so a
+// post is tagged the same whatever Accept-Language its author sent.
+package contentwarning
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Warning is a content warning. The empty Warning means none.
+type Warning string
+
+const (
+	SelfHarm       Warning = "self_harm"
+	Assault        Warning = "assault"
+	EatingDisorder Warning = "eating_disorder"
+	Substances     Warning = "substances"
+)
+
+// Warnings lists every warning, in the order the heuristic tries them.
+var Warnings = []Warning{SelfHarm, Assault, EatingDisorder, Substances}
+
+// Parse returns the warning named s, or none for "". It reports false for
+// an unknown name.
+func Parse(s string) (Warning, bool) {
+	if s == "" {
+		return "", true
+	}
+	for _, w := range Warnings {
+		if string(w) == s {
+			return w, true
+		}
+	}
+	return "", false
+}
+
+// Names lists every warning for messages, comma-separated.
+func Names() string {
+	names := make([]string, len(Warnings))
+	for i, w := range Warnings {
+		names[i] = string(w)
+	}
+	return strings.Join(names, ", ")
+}
+
+// DefaultKeywords are the phrases each warning is applied for unless
+// configured otherwise. They are deliberately narrow: a missed warning can
+// be added by a moderator, while a wrong one hides an ordinary post.
+var DefaultKeywords = map[Warning][]string{
+	SelfHarm: {
+		"suicide", "suicidal", "kill myself", "killing myself", "end my life", "self harm", "self-harm",
+		"cutting myself", "want to die", "overdose",
+		"aatmahatya", "atmahatya", "marna man lagyo", "marna mann lagyo",
+		"आत्महत्या", "मर्न मन लाग्यो",
+	},
+	Assault: {
+		"rape", "raped", "sexual assault", "sexually assaulted", "molested", "molestation", "groped",
+		"balatkar", "yon hinsa",
+		"बलात्कार", "यौन हिंसा", "यौन दुर्व्यवहार",
+	},
+	EatingDisorder: {
+		"anorexia", "anorexic", "bulimia", "bulimic", "purging", "binge eating", "starving myself",
+	},
+	Substances: {
+		"relapse", "relapsed", "heroin", "cocaine", "meth", "brown sugar",
+		"लागुऔषध",
+	},
+}
+
+// Config configures the heuristic.
+type Config struct {
+	Auto     bool                 // Apply warnings to posts whose authors didn't
+	Keywords map[Warning][]string // Phrases for each warning
+}
+
+// Detector applies the keyword heuristic. The zero value never matches.
+type Detector struct {
+	keywords map[Warning][]string // Normalized, each padded with spaces
+}
+
+// New returns a detector for keywords, which need not be normalized.
+// Warnings missing from keywords are never applied by the heuristic.
+func New(keywords map[Warning][]string) *Detector {
+	d := &Detector{keywords: make(map[Warning][]string, len(keywords))}
+	for w, phrases := range keywords {
+		for _, phrase := range phrases {
+			if normalized := normalize(phrase); strings.TrimSpace(normalized) != "" {
+				d.keywords[w] = append(d.keywords[w], normalized)
+			}
+		}
+	}
+	return d
+}
+
+// Detect returns the first warning in Warnings with a keyword in content,
+// or none. Keywords match whole words, ignoring case and punctuation, so
+// "scrape" isn't "rape".
+func (d *Detector) Detect(content string) Warning {
+	if d == nil || len(d.keywords) == 0 {
+		return ""
+	}
+	text := normalize(content)
+	for _, w := range Warnings {
+		for _, keyword := range d.keywords[w] {
+			if strings.Contains(text, keyword) {
+				return w
+			}
+		}
+	}
+	return ""
+}
+
+// normalize lowercases s and turns every run of characters that aren't
+// letters, digits or combining marks into one space, with a space at each
+// end. Marks are kept for Devanagari's vowel signs, and zero-width
+// joiners, which only change how a conjunct is drawn, are dropped.
+func normalize(s string) string {
+	var b strings.Builder
+	b.Grow(len(s) + 2)
+	b.WriteByte(' ')
+	space := true
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case unicode.Is(unicode.Cf, r):
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r):
+			b.WriteRune(r)
+			space = false
+		case !space:
+			b.WriteByte(' ')
+			space = true
+		}
+	}
+	if !space {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
@@ -0,0 +1,94 @@
+package contentwarning_test
+
+import (
+	"testing"
+
+	"github.com/sujalbistaa/whispr/internal/contentwarning"
+)
+
+func TestDetectDefaults(t *testing.T) {
+	d := contentwarning.New(contentwarning.DefaultKeywords)
+	for _, tc := range []struct {
+		content string
+		want    contentwarning.Warning
+	}{
+		// English, any case or punctuation.
+		{"some days I just want to die honestly", contentwarning.SelfHarm},
+		{"Thinking about SUICIDE again...", contentwarning.SelfHarm},
+		{"She was sexually   assaulted at the party", contentwarning.Assault},
+		{"recovering from anorexia, day 40", contentwarning.EatingDisorder},
+		{"relapsed last night. again.", contentwarning.Substances},
+		// Romanized Nepali and Devanagari.
+		{"aaja ta marna man lagyo yaar", contentwarning.SelfHarm},
+		{"hostel ma balatkar ko kura sunera dar lagyo", contentwarning.Assault},
+		{"साथीले आत्महत्या गर्ने कुरा गर्‍यो", contentwarning.SelfHarm},
+		{"यौन हिंसा को बारेमा कसैले बोल्दैन", contentwarning.Assault},
+		{"कलेज नजिक लागुऔषध बेच्छन्", contentwarning.Substances},
+		// A zero-width joiner only changes how a conjunct is drawn.
+		{"आत्‍महत्या", contentwarning.SelfHarm},
+		// The first warning in Warnings wins.
+		{"an overdose of heroin", contentwarning.SelfHarm},
+		{"relapse after the rape", contentwarning.Assault},
+		// Whole words only.
+		{"I scrape by on instant noodles", ""},
+		{"the grape harvest was great", ""},
+		{"methodology exam tomorrow", ""},
+		{"therapist says I'm doing better", ""},
+		{"", ""},
+	} {
+		if got := d.Detect(tc.content); got != tc.want {
+			t.Errorf("Detect(%q) = %q, want %q", tc.content, got, tc.want)
+		}
+	}
+}
+
+func TestDetectConfigured(t *testing.T) {
+	d := contentwarning.New(map[contentwarning.Warning][]string{
+		contentwarning.Substances: {"  Weed!! ", "", "..."},
+	})
+	for _, tc := range []struct {
+		content string
+		want    contentwarning.Warning
+	}{
+		{"anyone got weed?", contentwarning.Substances},
+		{"pulling weeds in the garden", ""},
+		// Only the configured warnings apply.
+		{"I want to die", ""},
+		{"something harmless", ""},
+	} {
+		if got := d.Detect(tc.content); got != tc.want {
+			t.Errorf("Detect(%q) = %q, want %q", tc.content, got, tc.want)
+		}
+	}
+
+	var off *contentwarning.Detector
+	if got := off.Detect("suicide"); got != "" {
+		t.Errorf("nil Detector found %q", got)
+	}
+	if got := (&contentwarning.Detector{}).Detect("suicide"); got != "" {
+		t.Errorf("zero Detector found %q", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		want contentwarning.Warning
+		ok   bool
+	}{
+		{"", "", true},
+		{"self_harm", contentwarning.SelfHarm, true},
+		{"assault", contentwarning.Assault, true},
+		{"eating_disorder", contentwarning.EatingDisorder, true},
+		{"substances", contentwarning.Substances, true},
+		{"Self_Harm", "", false},
+		{"violence", "", false},
+	} {
+		if got, ok := contentwarning.Parse(tc.name); got != tc.want || ok != tc.ok {
+			t.Errorf("Parse(%q) = %q, %v; want %q, %v", tc.name, got, ok, tc.want, tc.ok)
+		}
+	}
+	if got, want := contentwarning.Names(), "self_harm, assault, eating_disorder, substances"; got != want {
+		t.Errorf("Names() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// Options are the connection settings for Init.
+type Options struct {
+	Connect   Retry
+	Pool      Pool
+	SQLite    SQLite
+	SlowQuery time.Duration // Queries taking longer are logged; 0 disables
+}
+
+// SQLite holds the PRAGMAs set on every SQLite connection. WAL lets reads
+// proceed while a write is in progress, and the busy timeout makes a
+// writer wait for the lock instead of failing with "database is locked".
+type SQLite struct {
+	JournalMode string // journal_mode, e.g. WAL
+	Synchronous string // synchronous; NORMAL is durable enough with WAL
+	BusyTimeout time.Duration
+}
+
+// Retry bounds how long Init waits for the database to accept
+// connections, e.g. while it starts alongside the server.
+type Retry struct {
+	Attempts int           // Tries in total, including the first
+	Timeout  time.Duration // Deadline across all tries; 0 means none
+}
+
+const (
+	// pingTimeout bounds one connection attempt so a hung TCP handshake
+	// can't stall startup.
+	pingTimeout    = 5 * time.Second
+	initialBackoff = 500 * time.Millisecond
+	maxBackoff     = 10 * time.Second
+)
+
+// connect opens a database/sql pool for a network database and pings it,
+// retrying with exponential backoff and jitter until it answers or retry is
+// used up. The last error is returned only after the final attempt. The
+// pool is handed to the gorm dialector, which queries the server as soon as
+// it's opened, so this has to succeed first.
+func connect(driverName, dsn string, retry Retry) (*sql.DB, error) {
+	conn, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	if retry.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, retry.Timeout)
+		defer cancel()
+	}
+	fail := func(attempts int, err error) (*sql.DB, error) {
+		conn.Close()
+		return nil, fmt.Errorf("connecting to database (%d attempts): %w", attempts, err)
+	}
+
+	backoff := initialBackoff
+	for attempt := 1; ; attempt++ {
+		pingCtx, cancel := context.WithTimeout(ctx, pingTimeout)
+		err := conn.PingContext(pingCtx)
+		cancel()
+		if err == nil {
+			return conn, nil
+		}
+		if attempt >= retry.Attempts || ctx.Err() != nil {
+			return fail(attempt, err)
+		}
+
+		// Jitter keeps replicas that start together from retrying in
+		// lockstep.
+		wait := backoff/2 + rand.N(backoff/2+1)
+		slog.Warn("database not reachable, retrying", "attempt", attempt, "of", retry.Attempts, "in", wait, "err", err)
+		select {
+		case <-ctx.Done():
+			return fail(attempt, err)
+		case <-time.After(wait):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// Pool sizes the connection pool. Zero durations mean no limit.
+type Pool struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// DefaultPool returns the pool settings for the database dbURL names.
+// SQLite allows one writer at a time, so it gets a single connection that
+// is never recycled (recycling would drop an in-memory database). Managed
+// Postgres and MySQL servers close idle connections after a few minutes, so
+// those are recycled before that happens.
+func DefaultPool(dbURL string) Pool {
+	if strings.HasPrefix(dbURL, "sqlite://") {
+		return Pool{MaxOpenConns: 1, MaxIdleConns: 1}
+	}
+	return Pool{
+		MaxOpenConns:    100,
+		MaxIdleConns:    10,
+		ConnMaxLifetime: 30 * time.Minute,
+		ConnMaxIdleTime: 5 * time.Minute,
+	}
+}
+
+func (p Pool) apply(conn *sql.DB) {
+	conn.SetMaxOpenConns(p.MaxOpenConns)
+	conn.SetMaxIdleConns(p.MaxIdleConns)
+	conn.SetConnMaxLifetime(p.ConnMaxLifetime)
+	conn.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+}
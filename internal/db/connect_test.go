@@ -0,0 +1,83 @@
+package db_test
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/gorm"
+
+	"github.com/sujalbistaa/whispr/internal/config"
+	"github.com/sujalbistaa/whispr/internal/db"
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+// openSQLiteFile opens a new SQLite file database with the settings from
+// the environment, closed when the test ends.
+func openSQLiteFile(t *testing.T) *gorm.DB {
+	t.Helper()
+	t.Setenv("DATABASE_URL", "sqlite://"+filepath.Join(t.TempDir(), "whispr.db"))
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := db.Init(cfg.DatabaseURL, cfg.DB)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := conn.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return conn
+}
+
+// TestSQLiteConcurrentWrites writes 50 posts at once, each with an audit
+// row in the same transaction, over several connections to one file.
+// With the driver defaults (rollback journal, no busy timeout) most of
+// them fail with "database is locked".
+func TestSQLiteConcurrentWrites(t *testing.T) {
+	t.Setenv("DB_MAX_OPEN_CONNS", "8")
+	t.Setenv("DB_MAX_IDLE_CONNS", "8")
+	conn := openSQLiteFile(t)
+	if err := db.Migrate(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	for pragma, want := range map[string]string{"journal_mode": "wal", "busy_timeout": "5000", "synchronous": "1"} {
+		var got string
+		if err := conn.Raw("PRAGMA " + pragma).Scan(&got).Error; err != nil || !strings.EqualFold(got, want) {
+			t.Errorf("PRAGMA %s = %q (%v), want %q", pragma, got, err, want)
+		}
+	}
+
+	const writers = 50
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				post := models.Post{Content: fmt.Sprintf("written by writer %d", i)}
+				if err := tx.Create(&post).Error; err != nil {
+					return err
+				}
+				return tx.Create(&models.AuditLog{Action: "create_post", TargetType: "post", TargetID: post.ID, ActorTokenFingerprint: "test"}).Error
+			})
+			if err != nil {
+				t.Errorf("writer %d: %v", i, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var posts, audits int64
+	conn.Model(&models.Post{}).Count(&posts)
+	conn.Model(&models.AuditLog{}).Count(&audits)
+	if posts != writers || audits != writers {
+		t.Errorf("stored %d posts and %d audit rows, want %d of each", posts, audits, writers)
+	}
+}
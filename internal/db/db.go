@@ -1,62 +1,72 @@
 package db
 
 import (
-	"log"
-	"os"
+	"fmt"
+	"log/slog"
 	"strings"
 
 	"github.com/glebarez/sqlite" // <-- This is the new, correct driver
+	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
 	// "gorm.io/driver/sqlite" // This old one is not used
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 )
 
-// Init initializes and returns a GORM database connection.
-// It reads the DATABASE_URL environment variable.
-func Init() (*gorm.DB, error) {
-	dbURL := os.Getenv("DATABASE_URL")
-
-	// Default to local SQLite if no URL is provided
-	if dbURL == "" {
-		dbURL = "sqlite://whispr.db"
-		log.Println("DATABASE_URL not set, defaulting to 'sqlite://whispr.db'")
-	}
-
+// Init initializes and returns a GORM database connection for dbURL
+// (DATABASE_URL), which must start with postgres://, mysql:// or sqlite://.
+// For Postgres and MySQL it waits, per opts.Connect, until the server
+// accepts connections; a SQLite file has nothing to wait for.
+func Init(dbURL string, opts Options) (*gorm.DB, error) {
 	var dialector gorm.Dialector
 
 	if strings.HasPrefix(dbURL, "postgres://") {
-		// Use Postgres
-		dsn := strings.TrimPrefix(dbURL, "postgres://")
-		dialector = postgres.Open(dsn)
-		log.Println("Connecting to PostgreSQL database...")
+		// Use Postgres; pgx takes the URL as is
+		slog.Info("connecting to PostgreSQL database")
+		conn, err := connect("pgx", dbURL, opts.Connect)
+		if err != nil {
+			return nil, err
+		}
+		dialector = postgres.New(postgres.Config{Conn: conn})
+	} else if strings.HasPrefix(dbURL, "mysql://") {
+		// Use MySQL or MariaDB
+		dsn, err := mysqlDSN(dbURL)
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("connecting to MySQL database")
+		conn, err := connect("mysql", dsn, opts.Connect)
+		if err != nil {
+			return nil, err
+		}
+		dialector = mysql.New(mysql.Config{Conn: conn})
 	} else if strings.HasPrefix(dbURL, "sqlite://") {
 		// Use SQLite
 		dsn := strings.TrimPrefix(dbURL, "sqlite://")
 		// Use the NEW driver's Open function
-		dialector = sqlite.Open(dsn) // <-- This line uses the new driver
-		log.Println("Connecting to SQLite database at", dsn)
+		dialector = sqlite.Open(sqliteDSN(dsn, opts.SQLite)) // <-- This line uses the new driver
+		slog.Info("connecting to SQLite database", "path", dsn)
 	} else {
-		log.Fatalf("Invalid DATABASE_URL prefix. Must start with 'postgres://' or 'sqlite://'")
+		return nil, fmt.Errorf("invalid DATABASE_URL prefix: must start with 'postgres://', 'mysql://' or 'sqlite://'")
 	}
 
 	// Open the database connection
 	db, err := gorm.Open(dialector, &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent), // Be quiet by default
+		Logger: slowQueryLogger{threshold: opts.SlowQuery}, // Quiet apart from slow queries
+		// Report constraint violations as gorm.ErrDuplicatedKey and friends
+		// whatever the driver
+		TranslateError: true,
 	})
 
 	if err != nil {
 		return nil, err
 	}
 
-	// Optional: Configure connection pooling
 	sqlDB, err := db.DB()
 	if err != nil {
 		return nil, err
 	}
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
+	opts.Pool.apply(sqlDB)
 
-	log.Println("Database connection established.")
+	slog.Info("database connection established")
 	return db, nil
 }
\ No newline at end of file
@@ -0,0 +1,176 @@
+package db_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	gormmysql "gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/sujalbistaa/whispr/internal/db"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+func TestIsUniqueViolation(t *testing.T) {
+	database := testutil.NewDB(t)
+	post := models.Post{Content: "voted on twice"}
+	if err := database.Create(&post).Error; err != nil {
+		t.Fatal(err)
+	}
+	voter := "same-voter"
+	if err := database.Create(&models.Vote{PostID: post.ID, VoterHash: &voter, Value: 1}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// Through gorm, which translates the error, and straight to the
+	// driver, which doesn't.
+	translated := database.Create(&models.Vote{PostID: post.ID, VoterHash: &voter, Value: -1}).Error
+	raw := database.Exec("INSERT INTO votes (post_id, voter_hash, value, created_at) VALUES (?, ?, ?, CURRENT_TIMESTAMP)", post.ID, voter, -1).Error
+	notNull := database.Exec("INSERT INTO votes (post_id, voter_hash, created_at) VALUES (?, ?, CURRENT_TIMESTAMP)", post.ID, "other-voter").Error
+
+	for _, tc := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"duplicate vote through gorm", translated, true},
+		{"duplicate vote through the driver", raw, true},
+		{"wrapped", fmt.Errorf("recording vote: %w", raw), true},
+		{"gorm.ErrDuplicatedKey", gorm.ErrDuplicatedKey, true},
+		{"PostgreSQL unique_violation", fmt.Errorf("insert: %w", &pgconn.PgError{Code: "23505"}), true},
+		{"MySQL ER_DUP_ENTRY", &mysql.MySQLError{Number: 1062}, true},
+		{"NOT NULL constraint", notNull, false},
+		{"PostgreSQL foreign_key_violation", &pgconn.PgError{Code: "23503"}, false},
+		{"MySQL deadlock", &mysql.MySQLError{Number: 1213}, false},
+		{"not found", gorm.ErrRecordNotFound, false},
+		{"other", errors.New("connection reset"), false},
+		{"nil", nil, false},
+	} {
+		if tc.want && tc.err == nil {
+			t.Errorf("%s: no error to check", tc.name)
+			continue
+		}
+		if got := db.IsUniqueViolation(tc.err); got != tc.want {
+			t.Errorf("%s: IsUniqueViolation(%v) = %v, want %v", tc.name, tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestIsSerializationFailure(t *testing.T) {
+	// With no busy timeout, a second writer fails at once instead of
+	// waiting for the first one's transaction to end.
+	t.Setenv("SQLITE_BUSY_TIMEOUT", "0s")
+	t.Setenv("DB_MAX_OPEN_CONNS", "2")
+	conn := openSQLiteFile(t)
+	if err := db.Migrate(conn); err != nil {
+		t.Fatal(err)
+	}
+	tx := conn.Begin()
+	if err := tx.Create(&models.Post{Content: "holding the write lock"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	busy := conn.Create(&models.Post{Content: "waiting for it"}).Error
+	tx.Rollback()
+
+	for _, tc := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"SQLite busy", busy, true},
+		{"wrapped", fmt.Errorf("creating post: %w", busy), true},
+		{"PostgreSQL serialization_failure", fmt.Errorf("commit: %w", &pgconn.PgError{Code: "40001"}), true},
+		{"PostgreSQL deadlock_detected", &pgconn.PgError{Code: "40P01"}, true},
+		{"MySQL deadlock", &mysql.MySQLError{Number: 1213}, true},
+		{"PostgreSQL unique_violation", &pgconn.PgError{Code: "23505"}, false},
+		{"MySQL ER_DUP_ENTRY", &mysql.MySQLError{Number: 1062}, false},
+		{"gorm.ErrDuplicatedKey", gorm.ErrDuplicatedKey, false},
+		{"other", errors.New("connection reset"), false},
+		{"nil", nil, false},
+	} {
+		if tc.want && tc.err == nil {
+			t.Errorf("%s: no error to check", tc.name)
+			continue
+		}
+		if got := db.IsSerializationFailure(tc.err); got != tc.want {
+			t.Errorf("%s: IsSerializationFailure(%v) = %v, want %v", tc.name, tc.err, got, tc.want)
+		}
+	}
+}
+
+// dryRun opens dialector without connecting, for rendering SQL.
+func dryRun(t *testing.T, dialector gorm.Dialector) *gorm.DB {
+	t.Helper()
+	conn, err := gorm.Open(dialector, &gorm.Config{DryRun: true, DisableAutomaticPing: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func TestLockForUpdate(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		conn *gorm.DB
+		lock bool
+	}{
+		{"sqlite", testutil.NewDB(t), false},
+		{"postgres", dryRun(t, postgres.New(postgres.Config{DSN: "host=localhost"})), true},
+		{"mysql", dryRun(t, gormmysql.New(gormmysql.Config{DSN: "whispr@tcp(localhost)/whispr", SkipInitializeWithVersion: true})), true},
+	} {
+		sql := tc.conn.ToSQL(func(tx *gorm.DB) *gorm.DB {
+			return tx.Scopes(db.LockForUpdate).Where("id = ?", 1).Find(&models.Post{})
+		})
+		if strings.HasSuffix(sql, " FOR UPDATE") != tc.lock {
+			t.Errorf("%s: %s, want FOR UPDATE only where the database has row locks", tc.name, sql)
+		}
+	}
+
+	// On SQLite it is safe to use in a transaction that then writes.
+	database := testutil.NewDB(t)
+	post := models.Post{Content: "locked then updated"}
+	if err := database.Create(&post).Error; err != nil {
+		t.Fatal(err)
+	}
+	err := database.Transaction(func(tx *gorm.DB) error {
+		var locked models.Post
+		if err := tx.Scopes(db.LockForUpdate).First(&locked, post.ID).Error; err != nil {
+			return err
+		}
+		return tx.Model(&locked).Update("score", locked.Score+1).Error
+	})
+	if err != nil {
+		t.Fatalf("locking a post on SQLite: %v", err)
+	}
+}
+
+func TestCastText(t *testing.T) {
+	database := testutil.NewDB(t)
+	post := models.Post{Content: "cast me"}
+	if err := database.Create(&post).Error; err != nil {
+		t.Fatal(err)
+	}
+	var got string
+	if err := database.Model(&models.Post{}).Select(db.CastText(database, "id")).Where("id = ?", post.ID).Scan(&got).Error; err != nil {
+		t.Fatal(err)
+	}
+	if want := fmt.Sprint(post.ID); got != want {
+		t.Errorf("CAST(id) = %q, want %q", got, want)
+	}
+	var typ string
+	database.Raw("SELECT typeof(" + db.CastText(database, "id") + ") FROM posts").Scan(&typ)
+	if typ != "text" {
+		t.Errorf("typeof(%s) = %q, want text", db.CastText(database, "id"), typ)
+	}
+
+	mysqlConn := dryRun(t, gormmysql.New(gormmysql.Config{DSN: "whispr@tcp(localhost)/whispr", SkipInitializeWithVersion: true}))
+	if got := db.CastText(mysqlConn, "id"); got != "CAST(id AS CHAR)" {
+		t.Errorf("MySQL CastText = %q, want CAST(id AS CHAR)", got)
+	}
+}
@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// HealthCheck configures a Health monitor.
+type HealthCheck struct {
+	Interval time.Duration // Time between pings; 0 disables the monitor
+	Failures int           // Consecutive failed pings before the database counts as down
+}
+
+// Enabled reports whether the monitor should run.
+func (c HealthCheck) Enabled() bool {
+	return c.Interval > 0
+}
+
+// Health pings the database in the background so handlers can tell it is
+// down without waiting on a query of their own. A nil *Health always
+// reports the database up.
+type Health struct {
+	conn *gorm.DB
+	cfg  HealthCheck
+
+	mu       sync.RWMutex
+	down     bool
+	since    time.Time
+	failures int
+}
+
+// NewHealth returns a monitor for conn; call Run to start it.
+func NewHealth(conn *gorm.DB, cfg HealthCheck) *Health {
+	return &Health{conn: conn, cfg: cfg, since: time.Now()}
+}
+
+// Down reports whether the database is currently considered unreachable.
+func (h *Health) Down() bool {
+	if h == nil {
+		return false
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.down
+}
+
+// Since returns when the current state began.
+func (h *Health) Since() time.Time {
+	if h == nil {
+		return time.Time{}
+	}
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.since
+}
+
+// Run pings every Interval until ctx is done.
+func (h *Health) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.Check(ctx)
+		}
+	}
+}
+
+// Check pings the database once and updates the state. The database goes
+// down after Failures consecutive failures and comes back on the first
+// success.
+func (h *Health) Check(ctx context.Context) {
+	err := h.ping(ctx)
+	if ctx.Err() != nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err == nil {
+		h.failures = 0
+		if h.down {
+			slog.Info("database reachable again; leaving read-only mode", "downFor", time.Since(h.since))
+			h.down, h.since = false, time.Now()
+		}
+		return
+	}
+	h.failures++
+	if !h.down && h.failures >= h.cfg.Failures {
+		h.down, h.since = true, time.Now()
+		slog.Error("database unreachable; serving read-only", "failures", h.failures, "err", err)
+	}
+}
+
+func (h *Health) ping(ctx context.Context) error {
+	sqlDB, err := h.conn.DB()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, min(pingTimeout, h.cfg.Interval))
+	defer cancel()
+	return sqlDB.PingContext(ctx)
+}
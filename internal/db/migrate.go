@@ -0,0 +1,109 @@
+package db
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is one numbered schema change. Migrations are applied in
+// order, each in its own transaction, and recorded in schema_migrations.
+// Once a migration has shipped, never edit it: add a new one instead, in
+// a migrate_NNNN_name.go file, and list it in migrations.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+}
+
+var migrations = []Migration{
+	{Version: 1, Name: "initial schema", Up: migrateInitialSchema},
+	{Version: 2, Name: "feed indexes", Up: migrateFeedIndexes},
+	{Version: 3, Name: "vote voter hash", Up: migrateVoteVoterHash},
+	{Version: 4, Name: "post hidden_at", Up: migratePostHiddenAt},
+	{Version: 5, Name: "post score ledger", Up: migratePostScoreLedger},
+	{Version: 6, Name: "post author hash", Up: migratePostAuthorHash},
+	{Version: 7, Name: "post fingerprint", Up: migratePostFingerprint},
+	{Version: 8, Name: "post lang", Up: migratePostLang},
+	{Version: 9, Name: "push subscriptions", Up: migratePushSubscriptions},
+	{Version: 10, Name: "boards", Up: migrateBoards},
+	{Version: 11, Name: "bookmarks", Up: migrateBookmarks},
+	{Version: 12, Name: "score snapshots", Up: migrateScoreSnapshots},
+	{Version: 13, Name: "identities", Up: migrateIdentities},
+	{Version: 14, Name: "api keys", Up: migrateAPIKeys},
+	{Version: 15, Name: "post content warning", Up: migratePostContentWarning},
+	{Version: 16, Name: "activity indexes", Up: migrateActivityIndexes},
+	{Version: 17, Name: "identity strikes", Up: migrateIdentityStrikes},
+	{Version: 18, Name: "post last activity", Up: migratePostLastActivity},
+	{Version: 19, Name: "vote id", Up: migrateVoteID},
+	{Version: 20, Name: "post archived_at", Up: migratePostArchivedAt},
+	{Version: 21, Name: "post creator hash", Up: migratePostCreatorHash},
+}
+
+func init() {
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version <= migrations[i-1].Version {
+			panic(fmt.Sprintf("db: migration %d is listed after %d", migrations[i].Version, migrations[i-1].Version))
+		}
+	}
+}
+
+// schemaMigration is a row of schema_migrations.
+type schemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+// LatestVersion is the schema version this binary expects.
+func LatestVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// Version returns the highest migration applied to db, or 0 for a
+// database that has never been migrated.
+func Version(db *gorm.DB) (int, error) {
+	if !db.Migrator().HasTable(&schemaMigration{}) {
+		return 0, nil
+	}
+	var version int
+	err := db.Model(&schemaMigration{}).Select("COALESCE(MAX(version), 0)").Scan(&version).Error
+	return version, err
+}
+
+// Migrate applies every migration newer than the database's version.
+// A failed migration is rolled back and stops the run; the ones before it
+// stay applied.
+func Migrate(db *gorm.DB) error {
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return fmt.Errorf("creating schema_migrations: %w", err)
+	}
+	current, err := Version(db)
+	if err != nil {
+		return fmt.Errorf("reading schema version: %w", err)
+	}
+	for _, m := range Pending(current) {
+		slog.Info("applying migration", "version", m.Version, "name", m.Name)
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %04d (%s): %w", m.Version, m.Name, err)
+		}
+	}
+	return nil
+}
+
+// Pending lists the migrations newer than version, oldest first.
+func Pending(version int) []Migration {
+	i := sort.Search(len(migrations), func(i int) bool { return migrations[i].Version > version })
+	return migrations[i:]
+}
@@ -0,0 +1,60 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrateInitialSchema creates the tables as they were when versioned
+// migrations were introduced. It uses frozen copies of the models, so
+// later model changes can't alter what it does; on databases that were
+// set up by the old AutoMigrate-on-boot it only fills in anything
+// missing.
+func migrateInitialSchema(tx *gorm.DB) error {
+	type vote struct {
+		ID        uint `gorm:"primarykey"`
+		PostID    uint `gorm:"not null;index"`
+		Value     int  `gorm:"not null"`
+		CreatedAt time.Time
+		DeletedAt gorm.DeletedAt `gorm:"index"`
+	}
+	type post struct {
+		ID           uint   `gorm:"primarykey"`
+		Content      string `gorm:"not null"`
+		Score        int    `gorm:"not null;default:0"`
+		Hidden       bool   `gorm:"not null;default:false"`
+		ShadowBanned bool   `gorm:"not null;default:false;index"`
+		ShadowBanID  *uint  `gorm:"index"`
+		CreatedAt    time.Time
+		UpdatedAt    time.Time
+		Votes        []vote `gorm:"foreignKey:PostID"`
+	}
+	type auditLog struct {
+		ID                    uint      `gorm:"primarykey"`
+		Action                string    `gorm:"not null;index"`
+		TargetType            string    `gorm:"not null;index:idx_audit_target"`
+		TargetID              uint      `gorm:"index:idx_audit_target"`
+		ActorTokenFingerprint string    `gorm:"not null"`
+		Metadata              string    `gorm:"type:text"`
+		CreatedAt             time.Time `gorm:"index"`
+	}
+	type bannedIP struct {
+		ID        uint   `gorm:"primarykey"`
+		CIDR      string `gorm:"size:64;not null;uniqueIndex"`
+		Reason    string
+		Shadow    bool       `gorm:"not null;default:false"`
+		ExpiresAt *time.Time `gorm:"index"`
+		CreatedAt time.Time
+	}
+	type announcement struct {
+		ID        uint      `gorm:"primarykey"`
+		Message   string    `gorm:"not null"`
+		Level     string    `gorm:"not null;default:info"`
+		ExpiresAt time.Time `gorm:"not null;index"`
+		CreatedAt time.Time
+	}
+
+	// The types are named so gorm derives the original table names.
+	return tx.AutoMigrate(&post{}, &vote{}, &auditLog{}, &bannedIP{}, &announcement{})
+}
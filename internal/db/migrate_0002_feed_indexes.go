@@ -0,0 +1,41 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrateFeedIndexes adds indexes matching the feed queries, which filter
+// on hidden and sort by recency or score, and the per-post vote lookups.
+// Without them every feed request sorts the whole posts table.
+func migrateFeedIndexes(tx *gorm.DB) error {
+	type post struct {
+		Hidden    bool      `gorm:"index:idx_posts_feed,priority:1;index:idx_posts_trending,priority:1"`
+		Score     int       `gorm:"index:idx_posts_trending,priority:2,sort:desc"`
+		CreatedAt time.Time `gorm:"index:idx_posts_feed,priority:2,sort:desc;index:idx_posts_trending,priority:3,sort:desc"`
+	}
+	type vote struct {
+		PostID    uint      `gorm:"index:idx_votes_post_created,priority:1"`
+		CreatedAt time.Time `gorm:"index:idx_votes_post_created,priority:2"`
+	}
+
+	indexes := []struct {
+		model any
+		name  string
+	}{
+		{&post{}, "idx_posts_feed"},
+		{&post{}, "idx_posts_trending"},
+		{&vote{}, "idx_votes_post_created"},
+	}
+	migrator := tx.Migrator()
+	for _, idx := range indexes {
+		if migrator.HasIndex(idx.model, idx.name) {
+			continue
+		}
+		if err := migrator.CreateIndex(idx.model, idx.name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package db
+
+import "gorm.io/gorm"
+
+// migrateVoteVoterHash records who cast each vote, as a keyed hash of the
+// client, and makes (post_id, voter_hash) unique so concurrent requests
+// can't both count. Existing votes keep a NULL hash, which the index
+// ignores.
+func migrateVoteVoterHash(tx *gorm.DB) error {
+	type vote struct {
+		PostID    uint    `gorm:"uniqueIndex:idx_votes_post_voter,priority:1"`
+		VoterHash *string `gorm:"size:64;uniqueIndex:idx_votes_post_voter,priority:2"`
+	}
+
+	migrator := tx.Migrator()
+	if !migrator.HasColumn(&vote{}, "VoterHash") {
+		if err := migrator.AddColumn(&vote{}, "VoterHash"); err != nil {
+			return err
+		}
+	}
+	if !migrator.HasIndex(&vote{}, "idx_votes_post_voter") {
+		return migrator.CreateIndex(&vote{}, "idx_votes_post_voter")
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migratePostHiddenAt replaces posts.hidden with hidden_at, a soft-delete
+// timestamp gorm filters out of every query, and hidden_by, the moderator
+// who hid the post. Posts hidden before this only know they were hidden,
+// so updated_at stands in for when. The feed indexes move to hidden_at.
+func migratePostHiddenAt(tx *gorm.DB) error {
+	type post struct {
+		HiddenAt  *time.Time `gorm:"index:idx_posts_feed,priority:1;index:idx_posts_trending,priority:1"`
+		HiddenBy  string     `gorm:"size:64"`
+		Score     int        `gorm:"index:idx_posts_trending,priority:2,sort:desc"`
+		CreatedAt time.Time  `gorm:"index:idx_posts_feed,priority:2,sort:desc;index:idx_posts_trending,priority:3,sort:desc"`
+	}
+
+	migrator := tx.Migrator()
+	for _, column := range []string{"HiddenAt", "HiddenBy"} {
+		if err := migrator.AddColumn(&post{}, column); err != nil {
+			return err
+		}
+	}
+	if err := tx.Exec("UPDATE posts SET hidden_at = updated_at WHERE hidden = ?", true).Error; err != nil {
+		return err
+	}
+	for _, name := range []string{"idx_posts_feed", "idx_posts_trending"} {
+		if err := migrator.DropIndex(&post{}, name); err != nil {
+			return err
+		}
+		if err := migrator.CreateIndex(&post{}, name); err != nil {
+			return err
+		}
+	}
+	// Not migrator.DropColumn: on SQLite that rebuilds the table and loses
+	// its indexes. All three databases support dropping an unindexed column.
+	return tx.Exec("ALTER TABLE posts DROP COLUMN hidden").Error
+}
@@ -0,0 +1,19 @@
+package db
+
+import "gorm.io/gorm"
+
+// migratePostScoreLedger makes a post's score the sum of its votes. Posts
+// used to start at 1 for the author's implied upvote without a vote row
+// for it, so the author's upvote is recorded for every existing post
+// first, with a NULL voter hash like other pre-hash votes. Scores that
+// drifted from the ledger any other way are corrected too.
+func migratePostScoreLedger(tx *gorm.DB) error {
+	if err := tx.Exec(`INSERT INTO votes (post_id, value, created_at)
+		SELECT id, 1, created_at FROM posts`).Error; err != nil {
+		return err
+	}
+	return tx.Exec(`UPDATE posts SET score = (
+		SELECT COALESCE(SUM(value), 0) FROM votes
+		WHERE votes.post_id = posts.id AND votes.deleted_at IS NULL
+	)`).Error
+}
@@ -0,0 +1,29 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migratePostAuthorHash records who wrote each post, as the same keyed
+// hash of the client that votes carry, and indexes it with the creation
+// time for the daily posting quota. Existing posts keep a NULL hash and
+// don't count towards anyone's quota.
+func migratePostAuthorHash(tx *gorm.DB) error {
+	type post struct {
+		AuthorHash *string   `gorm:"size:64;index:idx_posts_author_created,priority:1"`
+		CreatedAt  time.Time `gorm:"index:idx_posts_author_created,priority:2"`
+	}
+
+	migrator := tx.Migrator()
+	if !migrator.HasColumn(&post{}, "AuthorHash") {
+		if err := migrator.AddColumn(&post{}, "AuthorHash"); err != nil {
+			return err
+		}
+	}
+	if !migrator.HasIndex(&post{}, "idx_posts_author_created") {
+		return migrator.CreateIndex(&post{}, "idx_posts_author_created")
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migratePostFingerprint adds the SimHash fingerprint of each post and its
+// six bands, each indexed with the creation time so a new post is only
+// compared against recent posts sharing a band with it. Existing posts
+// keep NULLs and are never matched.
+func migratePostFingerprint(tx *gorm.DB) error {
+	type post struct {
+		Fingerprint *int64
+		Band0       *int32    `gorm:"column:fingerprint_band0;index:idx_posts_fingerprint_band0,priority:1"`
+		Band1       *int32    `gorm:"column:fingerprint_band1;index:idx_posts_fingerprint_band1,priority:1"`
+		Band2       *int32    `gorm:"column:fingerprint_band2;index:idx_posts_fingerprint_band2,priority:1"`
+		Band3       *int32    `gorm:"column:fingerprint_band3;index:idx_posts_fingerprint_band3,priority:1"`
+		Band4       *int32    `gorm:"column:fingerprint_band4;index:idx_posts_fingerprint_band4,priority:1"`
+		Band5       *int32    `gorm:"column:fingerprint_band5;index:idx_posts_fingerprint_band5,priority:1"`
+		CreatedAt   time.Time `gorm:"index:idx_posts_fingerprint_band0,priority:2;index:idx_posts_fingerprint_band1,priority:2;index:idx_posts_fingerprint_band2,priority:2;index:idx_posts_fingerprint_band3,priority:2;index:idx_posts_fingerprint_band4,priority:2;index:idx_posts_fingerprint_band5,priority:2"`
+	}
+
+	migrator := tx.Migrator()
+	for _, column := range []string{"Fingerprint", "Band0", "Band1", "Band2", "Band3", "Band4", "Band5"} {
+		if !migrator.HasColumn(&post{}, column) {
+			if err := migrator.AddColumn(&post{}, column); err != nil {
+				return err
+			}
+		}
+	}
+	for band := 0; band < 6; band++ {
+		name := fmt.Sprintf("idx_posts_fingerprint_band%d", band)
+		if !migrator.HasIndex(&post{}, name) {
+			if err := migrator.CreateIndex(&post{}, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
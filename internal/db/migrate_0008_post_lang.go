@@ -0,0 +1,17 @@
+package db
+
+import "gorm.io/gorm"
+
+// migratePostLang adds the detected language of each post. Existing posts
+// are undetermined, which every language filter includes.
+func migratePostLang(tx *gorm.DB) error {
+	type post struct {
+		Lang string `gorm:"size:8;not null;default:und"`
+	}
+
+	migrator := tx.Migrator()
+	if migrator.HasColumn(&post{}, "Lang") {
+		return nil
+	}
+	return migrator.AddColumn(&post{}, "Lang")
+}
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migratePushSubscriptions adds the browsers subscribed to Web Push and
+// when each post was announced to them as trending. Existing posts
+// haven't been, so one already over the threshold is announced on its
+// next vote.
+func migratePushSubscriptions(tx *gorm.DB) error {
+	type pushSubscription struct {
+		ID        uint   `gorm:"primarykey"`
+		Endpoint  string `gorm:"size:512;not null;uniqueIndex"`
+		P256dh    string `gorm:"size:128;not null"`
+		Auth      string `gorm:"size:64;not null"`
+		CreatedAt time.Time
+	}
+	type post struct {
+		NotifiedAt *time.Time
+	}
+
+	// The type is named so gorm derives the table name push_subscriptions.
+	if err := tx.AutoMigrate(&pushSubscription{}); err != nil {
+		return err
+	}
+	migrator := tx.Migrator()
+	if migrator.HasColumn(&post{}, "NotifiedAt") {
+		return nil
+	}
+	return migrator.AddColumn(&post{}, "NotifiedAt")
+}
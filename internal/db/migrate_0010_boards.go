@@ -0,0 +1,54 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrateBoards adds boards and puts every existing post on the default
+// board, "general", which must get ID 1: new posts' board_id defaults to
+// it. The board indexes lead with board_id so each board's feed reads
+// only its own posts.
+func migrateBoards(tx *gorm.DB) error {
+	type board struct {
+		ID        uint   `gorm:"primarykey"`
+		Slug      string `gorm:"size:64;not null;uniqueIndex"`
+		Name      string `gorm:"size:100;not null"`
+		CreatedAt time.Time
+	}
+	type post struct {
+		BoardID   uint           `gorm:"not null;default:1;index:idx_posts_board_feed,priority:1;index:idx_posts_board_trending,priority:1"`
+		HiddenAt  gorm.DeletedAt `gorm:"index:idx_posts_board_feed,priority:2;index:idx_posts_board_trending,priority:2"`
+		Score     int            `gorm:"index:idx_posts_board_trending,priority:3,sort:desc"`
+		CreatedAt time.Time      `gorm:"index:idx_posts_board_feed,priority:3,sort:desc;index:idx_posts_board_trending,priority:4,sort:desc"`
+	}
+
+	// The type is named so gorm derives the table name boards.
+	if err := tx.AutoMigrate(&board{}); err != nil {
+		return err
+	}
+	general := board{Slug: "general", Name: "General"}
+	if err := tx.Where(board{Slug: general.Slug}).FirstOrCreate(&general).Error; err != nil {
+		return err
+	}
+	if general.ID != 1 {
+		return fmt.Errorf("default board has ID %d, want 1", general.ID)
+	}
+
+	migrator := tx.Migrator()
+	if !migrator.HasColumn(&post{}, "BoardID") {
+		if err := migrator.AddColumn(&post{}, "BoardID"); err != nil {
+			return err
+		}
+	}
+	for _, name := range []string{"idx_posts_board_feed", "idx_posts_board_trending"} {
+		if !migrator.HasIndex(&post{}, name) {
+			if err := migrator.CreateIndex(&post{}, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrateBookmarks adds the posts clients saved, one row per client and
+// post.
+func migrateBookmarks(tx *gorm.DB) error {
+	type bookmark struct {
+		ID         uint   `gorm:"primarykey"`
+		PostID     uint   `gorm:"not null;index;uniqueIndex:idx_bookmarks_client_post,priority:2"`
+		ClientHash string `gorm:"size:64;not null;uniqueIndex:idx_bookmarks_client_post,priority:1"`
+		CreatedAt  time.Time
+	}
+
+	// The type is named so gorm derives the table name bookmarks.
+	return tx.AutoMigrate(&bookmark{})
+}
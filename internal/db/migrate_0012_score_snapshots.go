@@ -0,0 +1,21 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrateScoreSnapshots adds the score history of posts, one row per post
+// and snapshot interval.
+func migrateScoreSnapshots(tx *gorm.DB) error {
+	type scoreSnapshot struct {
+		ID      uint      `gorm:"primarykey"`
+		PostID  uint      `gorm:"not null;uniqueIndex:idx_score_snapshots_post_taken,priority:1"`
+		Score   int       `gorm:"not null"`
+		TakenAt time.Time `gorm:"not null;index;uniqueIndex:idx_score_snapshots_post_taken,priority:2"`
+	}
+
+	// The type is named so gorm derives the table name score_snapshots.
+	return tx.AutoMigrate(&scoreSnapshot{})
+}
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrateIdentities adds the karma and posting streaks of anonymous
+// clients, and marks which posts the karma job has settled. Existing posts
+// start unsettled, so the first run credits them.
+func migrateIdentities(tx *gorm.DB) error {
+	type identity struct {
+		ID         uint      `gorm:"primarykey"`
+		ClientHash string    `gorm:"size:64;not null;uniqueIndex"`
+		Karma      int       `gorm:"not null;default:0"`
+		Streak     int       `gorm:"not null;default:0"`
+		BestStreak int       `gorm:"not null;default:0"`
+		LastPostOn time.Time `gorm:"not null"`
+		CreatedAt  time.Time
+		UpdatedAt  time.Time
+	}
+	type post struct {
+		KarmaAt *time.Time `gorm:"index"`
+	}
+
+	// The type is named so gorm derives the table name identities.
+	if err := tx.AutoMigrate(&identity{}); err != nil {
+		return err
+	}
+	migrator := tx.Migrator()
+	if !migrator.HasColumn(&post{}, "KarmaAt") {
+		if err := migrator.AddColumn(&post{}, "KarmaAt"); err != nil {
+			return err
+		}
+	}
+	if !migrator.HasIndex(&post{}, "KarmaAt") {
+		return migrator.CreateIndex(&post{}, "KarmaAt")
+	}
+	return nil
+}
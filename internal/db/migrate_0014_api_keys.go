@@ -0,0 +1,23 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrateAPIKeys adds hashed API keys for read-only integrations.
+func migrateAPIKeys(tx *gorm.DB) error {
+	type apiKey struct {
+		ID        uint   `gorm:"primarykey"`
+		Label     string `gorm:"size:100;not null"`
+		Scope     string `gorm:"size:16;not null"`
+		Prefix    string `gorm:"size:16;not null"`
+		KeyHash   string `gorm:"size:64;not null;uniqueIndex"`
+		RevokedAt *time.Time
+		CreatedAt time.Time
+	}
+
+	// The type is named so gorm derives the table name api_keys.
+	return tx.AutoMigrate(&apiKey{})
+}
@@ -0,0 +1,17 @@
+package db
+
+import "gorm.io/gorm"
+
+// migratePostContentWarning adds the content warning of each post.
+// Existing posts have none; the heuristic only runs on new ones.
+func migratePostContentWarning(tx *gorm.DB) error {
+	type post struct {
+		ContentWarning string `gorm:"size:32;not null;default:''"`
+	}
+
+	migrator := tx.Migrator()
+	if migrator.HasColumn(&post{}, "ContentWarning") {
+		return nil
+	}
+	return migrator.AddColumn(&post{}, "ContentWarning")
+}
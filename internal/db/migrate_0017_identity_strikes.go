@@ -0,0 +1,32 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrateIdentityStrikes adds the moderation strikes of each client and
+// the posting cooldown they escalate to. Existing clients start clean.
+func migrateIdentityStrikes(tx *gorm.DB) error {
+	type identity struct {
+		Strikes         int `gorm:"not null;default:0"`
+		StrikesSince    *time.Time
+		CooldownSeconds int        `gorm:"not null;default:0"`
+		CooldownUntil   *time.Time `gorm:"index"`
+	}
+
+	migrator := tx.Migrator()
+	for _, field := range []string{"Strikes", "StrikesSince", "CooldownSeconds", "CooldownUntil"} {
+		if migrator.HasColumn(&identity{}, field) {
+			continue
+		}
+		if err := migrator.AddColumn(&identity{}, field); err != nil {
+			return err
+		}
+	}
+	if !migrator.HasIndex(&identity{}, "CooldownUntil") {
+		return migrator.CreateIndex(&identity{}, "CooldownUntil")
+	}
+	return nil
+}
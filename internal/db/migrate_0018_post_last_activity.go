@@ -0,0 +1,32 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migratePostLastActivity adds when each post last saw activity, for the
+// active feed, which orders each board by it. Existing posts start at
+// their creation time; votes cast before the migration don't bump them.
+func migratePostLastActivity(tx *gorm.DB) error {
+	type post struct {
+		BoardID        uint           `gorm:"index:idx_posts_board_active,priority:1"`
+		HiddenAt       gorm.DeletedAt `gorm:"index:idx_posts_board_active,priority:2"`
+		LastActivityAt time.Time      `gorm:"index:idx_posts_board_active,priority:3,sort:desc"`
+	}
+
+	migrator := tx.Migrator()
+	if !migrator.HasColumn(&post{}, "LastActivityAt") {
+		if err := migrator.AddColumn(&post{}, "LastActivityAt"); err != nil {
+			return err
+		}
+	}
+	if err := tx.Exec("UPDATE posts SET last_activity_at = created_at WHERE last_activity_at IS NULL").Error; err != nil {
+		return err
+	}
+	if !migrator.HasIndex(&post{}, "idx_posts_board_active") {
+		return migrator.CreateIndex(&post{}, "idx_posts_board_active")
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package db
+
+import "gorm.io/gorm"
+
+// migrateVoteID adds the client-generated ID a vote may carry, which makes
+// retrying it safe. The index is unique; NULLs, for votes without one,
+// don't collide.
+func migrateVoteID(tx *gorm.DB) error {
+	type vote struct {
+		VoteID *string `gorm:"size:36;uniqueIndex:idx_votes_vote_id"`
+	}
+
+	migrator := tx.Migrator()
+	if !migrator.HasColumn(&vote{}, "VoteID") {
+		if err := migrator.AddColumn(&vote{}, "VoteID"); err != nil {
+			return err
+		}
+	}
+	if !migrator.HasIndex(&vote{}, "idx_votes_vote_id") {
+		return migrator.CreateIndex(&vote{}, "idx_votes_vote_id")
+	}
+	return nil
+}
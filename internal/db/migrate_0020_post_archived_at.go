@@ -0,0 +1,26 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migratePostArchivedAt adds when each post was archived by the archive
+// job, which keeps it out of the feeds. Existing posts are unarchived.
+func migratePostArchivedAt(tx *gorm.DB) error {
+	type post struct {
+		ArchivedAt *time.Time `gorm:"index"`
+	}
+
+	migrator := tx.Migrator()
+	if !migrator.HasColumn(&post{}, "ArchivedAt") {
+		if err := migrator.AddColumn(&post{}, "ArchivedAt"); err != nil {
+			return err
+		}
+	}
+	if !migrator.HasIndex(&post{}, "ArchivedAt") {
+		return migrator.CreateIndex(&post{}, "ArchivedAt")
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package db
+
+import "gorm.io/gorm"
+
+// migratePostCreatorHash adds the salted hash of the IP that created each
+// post, for moderators. Existing posts have none: their IPs were never
+// stored.
+func migratePostCreatorHash(tx *gorm.DB) error {
+	type post struct {
+		CreatorHash *string `gorm:"size:64;index"`
+	}
+
+	migrator := tx.Migrator()
+	if !migrator.HasColumn(&post{}, "CreatorHash") {
+		if err := migrator.AddColumn(&post{}, "CreatorHash"); err != nil {
+			return err
+		}
+	}
+	if !migrator.HasIndex(&post{}, "CreatorHash") {
+		return migrator.CreateIndex(&post{}, "CreatorHash")
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package db_test
+
+import (
+	"testing"
+
+	"github.com/sujalbistaa/whispr/internal/db"
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+// TestMigrateChain runs every migration against a fresh SQLite file.
+func TestMigrateChain(t *testing.T) {
+	conn := openSQLiteFile(t)
+
+	if version, err := db.Version(conn); err != nil || version != 0 {
+		t.Fatalf("fresh database: version %d (%v), want 0", version, err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := db.Migrate(conn); err != nil {
+			t.Fatalf("Migrate (run %d): %v", i+1, err)
+		}
+		if version, err := db.Version(conn); err != nil || version != db.LatestVersion() {
+			t.Fatalf("after run %d: version %d (%v), want %d", i+1, version, err, db.LatestVersion())
+		}
+	}
+	if pending := db.Pending(db.LatestVersion()); len(pending) != 0 {
+		t.Errorf("Pending(latest) = %v, want none", pending)
+	}
+
+	var applied int64
+	if err := conn.Table("schema_migrations").Count(&applied).Error; err != nil || applied != int64(len(db.Pending(0))) {
+		t.Errorf("schema_migrations has %d rows (%v), want one per migration (%d)", applied, err, len(db.Pending(0)))
+	}
+	for _, model := range []any{&models.Post{}, &models.Vote{}, &models.Board{}, &models.Identity{}, &models.APIKey{}, &models.AuditLog{}} {
+		if !conn.Migrator().HasTable(model) {
+			t.Errorf("no table for %T", model)
+		}
+	}
+	for _, column := range []string{"hidden_at", "fingerprint_band0", "lang", "content_warning", "last_activity_at", "archived_at", "creator_hash"} {
+		if !conn.Migrator().HasColumn(&models.Post{}, column) {
+			t.Errorf("posts has no %s column", column)
+		}
+	}
+}
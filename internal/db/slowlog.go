@@ -0,0 +1,50 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm/logger"
+
+	"github.com/sujalbistaa/whispr/internal/logging"
+)
+
+// slowQueryLogger is the GORM logger. It is silent apart from queries
+// slower than threshold (0 disables them too), which are logged as
+// warnings with their SQL. Parameters are dropped by ParamsFilter, so the
+// SQL keeps its placeholders and post content never reaches the log.
+type slowQueryLogger struct {
+	threshold time.Duration
+}
+
+func (l slowQueryLogger) LogMode(logger.LogLevel) logger.Interface      { return l }
+func (l slowQueryLogger) Info(context.Context, string, ...interface{})  {}
+func (l slowQueryLogger) Warn(context.Context, string, ...interface{})  {}
+func (l slowQueryLogger) Error(context.Context, string, ...interface{}) {}
+
+func (l slowQueryLogger) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	if l.threshold <= 0 {
+		return
+	}
+	elapsed := time.Since(begin)
+	if elapsed < l.threshold {
+		return
+	}
+	sql, rows := fc()
+	// Queries made WithContext from a request carry its request ID.
+	args := []any{
+		"duration_ms", float64(elapsed.Microseconds()) / 1000,
+		"threshold_ms", l.threshold.Milliseconds(),
+		"rows", rows,
+		"sql", sql,
+	}
+	if err != nil {
+		args = append(args, "err", err)
+	}
+	logging.FromContext(ctx).Warn("slow query", args...)
+}
+
+// ParamsFilter keeps query parameters out of Trace's SQL.
+func (l slowQueryLogger) ParamsFilter(_ context.Context, sql string, _ ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
@@ -0,0 +1,362 @@
+// Package eventlog appends the messages broadcast to WebSocket clients to
+// a local file, one JSON record per line, for offline analysis without
+// querying the main tables. Records are queued without blocking the
+// caller and written by a background goroutine through a buffered writer;
+// the file is rotated by size, and Walk reads the rotated files and the
+// current one back in order.
+package eventlog
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sujalbistaa/whispr/internal/metrics"
+	"github.com/sujalbistaa/whispr/internal/supervisor"
+)
+
+// Sync is when written records are fsynced to disk.
+type Sync string
+
+const (
+	SyncAlways   Sync = "always"   // After every record
+	SyncInterval Sync = "interval" // Every FlushInterval
+	SyncNever    Sync = "never"    // Left to the operating system
+)
+
+// Syncs lists every fsync policy.
+var Syncs = []Sync{SyncAlways, SyncInterval, SyncNever}
+
+// maxRecord is the longest line Walk reads.
+const maxRecord = 16 << 20
+
+// rotatedSuffix formats the time a file was rotated, appended to Path. It
+// sorts in time order.
+const rotatedSuffix = "20060102T150405.000000000Z"
+
+// Config configures a Log.
+type Config struct {
+	Path          string        // File appended to; empty disables the log
+	MaxBytes      int64         // Size at which the file is rotated
+	Sync          Sync          // When records are fsynced
+	FlushInterval time.Duration // How often buffered records are written out
+	QueueSize     int           // Pending records before new ones are dropped
+}
+
+// Enabled reports whether a path is configured.
+func (c Config) Enabled() bool {
+	return c.Path != ""
+}
+
+// Record is one line of the log.
+type Record struct {
+	Time  time.Time       `json:"time"`
+	Type  string          `json:"type"`
+	Board string          `json:"board,omitempty"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// Log appends records to a file in the background. A nil *Log is valid
+// and drops everything, so callers don't need to check whether the log is
+// configured.
+type Log struct {
+	cfg   Config
+	queue chan []byte
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	dropped atomic.Uint64
+
+	// Owned by the writer goroutine.
+	file     *os.File
+	buf      *bufio.Writer
+	size     int64
+	buffered int // Records in buf
+}
+
+// New opens cfg.Path for appending and starts the writer under sup, or
+// returns nil when no path is configured. Call Close on shutdown.
+func New(cfg Config, sup *supervisor.Supervisor) (*Log, error) {
+	if !cfg.Enabled() {
+		return nil, nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	l := &Log{
+		cfg:    cfg,
+		queue:  make(chan []byte, cfg.QueueSize),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	if err := l.open(); err != nil {
+		cancel()
+		return nil, err
+	}
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		sup.Run(l.ctx, "eventlog", l.run)
+	}()
+	return l, nil
+}
+
+// Append queues a record of a broadcast message. It never blocks: when the
+// queue is full or the message can't be encoded, the record is dropped
+// and counted.
+func (l *Log) Append(typ, board string, data any) {
+	if l == nil {
+		return
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		l.drop("encoding event log record", "type", typ, "err", err)
+		return
+	}
+	line, err := json.Marshal(Record{Time: time.Now().UTC(), Type: typ, Board: board, Data: raw})
+	if err != nil {
+		l.drop("encoding event log record", "type", typ, "err", err)
+		return
+	}
+	select {
+	case l.queue <- append(line, '\n'):
+	default:
+		l.drop("event log queue full, dropping record", "type", typ)
+	}
+}
+
+// Dropped returns how many records were dropped since New.
+func (l *Log) Dropped() uint64 {
+	if l == nil {
+		return 0
+	}
+	return l.dropped.Load()
+}
+
+// Close writes out the records still queued, syncs unless the policy is
+// SyncNever, and closes the file.
+func (l *Log) Close() {
+	if l == nil {
+		return
+	}
+	l.cancel()
+	l.wg.Wait()
+}
+
+func (l *Log) drop(msg string, args ...any) {
+	l.dropped.Add(1)
+	metrics.EventLogDropped.Inc()
+	slog.Warn(msg, args...)
+}
+
+func (l *Log) run(ctx context.Context) {
+	ticker := time.NewTicker(l.cfg.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case line := <-l.queue:
+			l.write(line)
+		case <-ticker.C:
+			l.flush(l.cfg.Sync == SyncInterval)
+		case <-ctx.Done():
+			for {
+				select {
+				case line := <-l.queue:
+					l.write(line)
+				default:
+					l.flush(l.cfg.Sync != SyncNever)
+					if l.file != nil {
+						l.file.Close()
+					}
+					return
+				}
+			}
+		}
+	}
+}
+
+// write appends line, rotating first if it would take the file past
+// MaxBytes. After a failure the file is reopened for the next record.
+func (l *Log) write(line []byte) {
+	if l.size > 0 && l.size+int64(len(line)) > l.cfg.MaxBytes {
+		if err := l.rotate(); err != nil {
+			slog.Error("rotating event log", "path", l.cfg.Path, "err", err)
+		}
+	}
+	if l.file == nil {
+		if err := l.open(); err != nil {
+			l.drop("opening event log, dropping record", "path", l.cfg.Path, "err", err)
+			return
+		}
+	}
+	if _, err := l.buf.Write(line); err != nil {
+		l.fail(err)
+		return
+	}
+	l.size += int64(len(line))
+	l.buffered++
+	if l.cfg.Sync == SyncAlways {
+		l.flush(true)
+	}
+}
+
+// flush writes out buffered records, and fsyncs them with sync.
+func (l *Log) flush(sync bool) {
+	if l.file == nil || (l.buf.Buffered() == 0 && !sync) {
+		return
+	}
+	if err := l.buf.Flush(); err != nil {
+		l.fail(err)
+		return
+	}
+	l.buffered = 0
+	if sync {
+		if err := l.file.Sync(); err != nil {
+			slog.Error("syncing event log", "path", l.cfg.Path, "err", err)
+		}
+	}
+}
+
+// fail drops the buffered records after a write error and closes the
+// file, to be reopened by the next write. Some of them may have reached
+// the file; Walk skips the one cut short.
+func (l *Log) fail(err error) {
+	n := max(l.buffered, 1)
+	l.dropped.Add(uint64(n))
+	metrics.EventLogDropped.Add(float64(n))
+	slog.Error("writing event log, dropping buffered records", "path", l.cfg.Path, "records", n, "err", err)
+	l.file.Close()
+	l.file, l.buf, l.buffered = nil, nil, 0
+}
+
+// rotate closes the current file and renames it with the time as a
+// suffix. The next write opens a new one.
+func (l *Log) rotate() error {
+	l.flush(l.cfg.Sync != SyncNever)
+	if l.file == nil {
+		return nil
+	}
+	l.file.Close()
+	l.file, l.buf = nil, nil
+	rotated := l.cfg.Path + "." + time.Now().UTC().Format(rotatedSuffix)
+	if err := os.Rename(l.cfg.Path, rotated); err != nil {
+		return err
+	}
+	slog.Info("rotated event log", "path", rotated, "bytes", l.size)
+	l.size = 0
+	return nil
+}
+
+// open opens Path for appending. A record cut short by a crash is ended
+// with a newline, so the next one starts on a line of its own.
+func (l *Log) open() error {
+	file, err := os.OpenFile(l.cfg.Path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0o640)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	l.file, l.buf, l.size = file, bufio.NewWriter(file), info.Size()
+	if l.size > 0 {
+		last := make([]byte, 1)
+		if _, err := file.ReadAt(last, l.size-1); err != nil {
+			file.Close()
+			l.file, l.buf = nil, nil
+			return err
+		}
+		if last[0] != '\n' {
+			l.buf.WriteByte('\n')
+			l.size++
+		}
+	}
+	return nil
+}
+
+// Files returns the log files for path oldest first: the rotated ones,
+// then path itself if it exists.
+func Files(path string) ([]string, error) {
+	rotated, err := filepath.Glob(globEscape(path) + ".*")
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, name := range rotated {
+		if _, err := time.Parse(rotatedSuffix, name[len(path)+1:]); err == nil {
+			files = append(files, name)
+		}
+	}
+	sort.Strings(files)
+	if _, err := os.Stat(path); err == nil {
+		files = append(files, path)
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Walk calls fn with every record logged at path, oldest first, stopping
+// at the first error fn returns. Records cut short by a crash mid-write
+// are skipped; any other malformed line is an error.
+func Walk(path string, fn func(Record) error) error {
+	files, err := Files(path)
+	if err != nil {
+		return err
+	}
+	for _, name := range files {
+		if err := walkFile(name, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func walkFile(name string, fn func(Record) error) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, maxRecord)
+	for line := 1; scanner.Scan(); line++ {
+		var record Record
+		err := json.NewDecoder(bytes.NewReader(scanner.Bytes())).Decode(&record)
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%s:%d: %w", name, line, err)
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// globEscape quotes the glob metacharacters in path.
+func globEscape(path string) string {
+	var escaped []byte
+	for i := 0; i < len(path); i++ {
+		switch path[i] {
+		case '*', '?', '[', '\\':
+			escaped = append(escaped, '\\')
+		}
+		escaped = append(escaped, path[i])
+	}
+	return string(escaped)
+}
@@ -0,0 +1,141 @@
+// Package flags gates risky features so they can be rolled out, and
+// rolled back, without a deploy. Every flag is declared in Definitions
+// with its default; FEATURE_<NAME> overrides it at startup and admins can
+// flip it at runtime. Runtime changes live in this process only.
+package flags
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+)
+
+// Flag names a feature.
+type Flag string
+
+// The features behind flags.
+const (
+	Reactions Flag = "reactions"
+	Polls     Flag = "polls"
+)
+
+// Definition declares a flag.
+type Definition struct {
+	Name        Flag
+	Default     bool
+	Description string
+}
+
+// Definitions lists every flag. Add new ones here.
+var Definitions = []Definition{
+	{Reactions, false, "Emoji reactions on posts"},
+	{Polls, false, "Posts with a poll attached"},
+}
+
+// ErrUnknown is returned by Set for a flag that isn't in Definitions.
+var ErrUnknown = errors.New("flags: unknown flag")
+
+// Lookup returns the definition of name.
+func Lookup(name Flag) (Definition, bool) {
+	for _, def := range Definitions {
+		if def.Name == name {
+			return def, true
+		}
+	}
+	return Definition{}, false
+}
+
+// State is a flag's current value, for admin views.
+type State struct {
+	Name        Flag   `json:"name"`
+	Enabled     bool   `json:"enabled"`
+	Default     bool   `json:"default"`
+	Description string `json:"description"`
+}
+
+// Set holds the current value of every flag. It is read on every gated
+// request and written by admins, so access is guarded by a lock.
+type Set struct {
+	mu     sync.RWMutex
+	values map[Flag]bool
+}
+
+// New starts every flag at its default, except those in overrides
+// (FEATURE_* from the config). Unknown names are ignored.
+func New(overrides map[Flag]bool) *Set {
+	s := &Set{values: make(map[Flag]bool, len(Definitions))}
+	for _, def := range Definitions {
+		s.values[def.Name] = def.Default
+		if v, ok := overrides[def.Name]; ok {
+			s.values[def.Name] = v
+		}
+	}
+	return s
+}
+
+// Enabled reports whether f is on. A nil Set and an unknown flag are
+// both off.
+func (s *Set) Enabled(f Flag) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.values[f]
+}
+
+// Set switches f and reports whether anything changed.
+func (s *Set) Set(f Flag, enabled bool) (bool, error) {
+	if _, ok := Lookup(f); !ok {
+		return false, ErrUnknown
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values[f] == enabled {
+		return false, nil
+	}
+	s.values[f] = enabled
+	return true, nil
+}
+
+// States returns every flag, sorted by name.
+func (s *Set) States() []State {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	states := make([]State, 0, len(Definitions))
+	for _, def := range Definitions {
+		states = append(states, State{Name: def.Name, Enabled: s.values[def.Name], Default: def.Default, Description: def.Description})
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
+
+// Values returns every flag's value by name.
+func (s *Set) Values() map[Flag]bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	values := make(map[Flag]bool, len(s.values))
+	for name, v := range s.values {
+		values[name] = v
+	}
+	return values
+}
+
+type setKey struct{}
+
+// WithSet returns a copy of ctx carrying s.
+func WithSet(ctx context.Context, s *Set) context.Context {
+	return context.WithValue(ctx, setKey{}, s)
+}
+
+// Enabled reports whether f is on for the request behind ctx. Without a
+// Set in ctx the flag's default applies, so code outside a request still
+// gets a sensible answer.
+func Enabled(ctx context.Context, f Flag) bool {
+	if s, ok := ctx.Value(setKey{}).(*Set); ok {
+		return s.Enabled(f)
+	}
+	def, _ := Lookup(f)
+	return def.Default
+}
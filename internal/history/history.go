@@ -0,0 +1,139 @@
+// Package history records each post's score over time for charts. Every
+// Interval a snapshot is taken of the posts whose score may have changed
+// since the last one, and snapshots older than MaxAge are deleted.
+//
+// Snapshot times are truncated to the interval and unique per post, so
+// every instance can run the worker: whichever takes a bucket first
+// writes it, and the others' inserts are ignored.
+package history
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+// Config configures a Worker.
+type Config struct {
+	Interval  time.Duration // Time between snapshots; 0 disables the worker
+	MaxAge    time.Duration // How long snapshots are kept
+	BatchSize int           // Posts read and snapshots written per query
+}
+
+// Enabled reports whether the worker should run.
+func (c Config) Enabled() bool {
+	return c.Interval > 0
+}
+
+// Worker takes snapshots on an interval.
+type Worker struct {
+	db  *gorm.DB
+	cfg Config
+}
+
+// New returns a worker for cfg; call Run to start it.
+func New(db *gorm.DB, cfg Config) *Worker {
+	return &Worker{db: db, cfg: cfg}
+}
+
+// Run snapshots immediately and then every Interval until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	slog.Info("score history worker started", "interval", w.cfg.Interval, "maxAge", w.cfg.MaxAge)
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		w.runAndLog(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			slog.Info("score history worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) runAndLog(ctx context.Context, now time.Time) {
+	start := time.Now()
+	taken, err := w.Snapshot(ctx, now)
+	if err != nil && ctx.Err() == nil {
+		slog.Error("taking score snapshots failed", "snapshots", taken, "err", err)
+		return
+	}
+	trimmed, err := w.Trim(ctx, now)
+	if err != nil && ctx.Err() == nil {
+		slog.Error("trimming score snapshots failed", "trimmed", trimmed, "err", err)
+		return
+	}
+	slog.Info("score snapshots taken", "snapshots", taken, "trimmed", trimmed, "duration", time.Since(start))
+}
+
+// Snapshot records the score of every visible post updated since the
+// start of the previous bucket, stamped with the bucket now falls in, and
+// returns how many snapshots it wrote. Votes bump a post's updated_at, so
+// posts nobody voted on are skipped. Posts are read in batches keyed on
+// id.
+func (w *Worker) Snapshot(ctx context.Context, now time.Time) (int64, error) {
+	bucket := now.UTC().Truncate(w.cfg.Interval)
+	since := bucket.Add(-w.cfg.Interval)
+	db := w.db.WithContext(ctx)
+
+	var total int64
+	var last uint
+	for {
+		var posts []models.Post
+		if err := db.Select("id", "score").Where("id > ? AND updated_at >= ?", last, since).
+			Order("id").Limit(w.cfg.BatchSize).Find(&posts).Error; err != nil {
+			return total, err
+		}
+		if len(posts) == 0 {
+			return total, nil
+		}
+		snapshots := make([]models.ScoreSnapshot, len(posts))
+		for i, post := range posts {
+			snapshots[i] = models.ScoreSnapshot{PostID: post.ID, Score: post.Score, TakenAt: bucket}
+		}
+		// Another instance may have taken this bucket already.
+		res := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&snapshots)
+		if res.Error != nil {
+			return total, res.Error
+		}
+		total += res.RowsAffected
+		if len(posts) < w.cfg.BatchSize {
+			return total, nil
+		}
+		last = posts[len(posts)-1].ID
+	}
+}
+
+// Trim deletes snapshots taken more than MaxAge before now, a batch at a
+// time, and returns how many it deleted.
+func (w *Worker) Trim(ctx context.Context, now time.Time) (int64, error) {
+	db := w.db.WithContext(ctx)
+	cutoff := now.Add(-w.cfg.MaxAge)
+	var total int64
+	for {
+		// IDs are selected first because MySQL can't delete from a table
+		// it's also selecting from with a LIMIT.
+		var ids []uint
+		if err := db.Model(&models.ScoreSnapshot{}).Where("taken_at < ?", cutoff).
+			Order("id").Limit(w.cfg.BatchSize).Pluck("id", &ids).Error; err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+		res := db.Where("id IN ?", ids).Delete(&models.ScoreSnapshot{})
+		if res.Error != nil {
+			return total, res.Error
+		}
+		total += res.RowsAffected
+		if len(ids) < w.cfg.BatchSize {
+			return total, nil
+		}
+	}
+}
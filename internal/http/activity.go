@@ -0,0 +1,121 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/sujalbistaa/whispr/internal/db"
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+const (
+	defaultActivityDays = 7
+	maxActivityDays     = 31
+	// activityMaxAge is how long a window that has fully elapsed may be
+	// cached: its counts only change if posts or votes are purged.
+	activityMaxAge = 24 * time.Hour
+)
+
+// ActivityBucket counts what happened in one hour or day, starting at
+// Start in UTC.
+type ActivityBucket struct {
+	Start time.Time `json:"start"`
+	Posts int64     `json:"posts"` // Hidden posts included
+	Votes int64     `json:"votes"` // Retracted votes included
+}
+
+// Activity is the response to GET /admin/activity, with a bucket for
+// every hour or day from From up to Until, empty ones included.
+type Activity struct {
+	Granularity db.Bucket        `json:"granularity"`
+	From        time.Time        `json:"from"`
+	Until       time.Time        `json:"until"`
+	Buckets     []ActivityBucket `json:"buckets"`
+}
+
+// GetActivity counts posts created and votes cast per UTC hour or day over
+// the last days days, or the days before until. Buckets are UTC so
+// daylight saving time never makes one 23 or 25 hours long; clients shift
+// them into local time. A window that ended before the current bucket
+// began is cacheable.
+func (e *Env) GetActivity(c *gin.Context) {
+	granularity := db.Bucket(c.DefaultQuery("granularity", string(db.BucketHour)))
+	step := time.Hour
+	switch granularity {
+	case db.BucketHour:
+	case db.BucketDay:
+		step = 24 * time.Hour
+	default:
+		respondError(c, ErrBadRequest("query.invalid_granularity"))
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(defaultActivityDays)))
+	if err != nil || days < 1 || days > maxActivityDays {
+		respondError(c, ErrBadRequest("query.invalid_days", "max", maxActivityDays))
+		return
+	}
+	now := time.Now().UTC()
+	until := now.Truncate(step).Add(step)
+	if raw := c.Query("until"); raw != "" {
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			respondError(c, ErrBadRequest("query.invalid_timestamp", "param", "until"))
+			return
+		}
+		// Round up so the bucket holding until is counted in full.
+		until = t.UTC().Add(step - 1).Truncate(step)
+	}
+	from := until.AddDate(0, 0, -days)
+
+	database := e.DB.WithContext(c.Request.Context())
+	posts, err := countByBucket(database.Model(&models.Post{}), granularity, from, until)
+	if err != nil {
+		requestLogger(c).Error("counting post activity", "err", err)
+		respondError(c, ErrInternal("activity.fetch_failed"))
+		return
+	}
+	votes, err := countByBucket(database.Model(&models.Vote{}), granularity, from, until)
+	if err != nil {
+		requestLogger(c).Error("counting vote activity", "err", err)
+		respondError(c, ErrInternal("activity.fetch_failed"))
+		return
+	}
+
+	activity := Activity{Granularity: granularity, From: from, Until: until, Buckets: []ActivityBucket{}}
+	for start := from; start.Before(until); start = start.Add(step) {
+		key := start.Format(time.RFC3339)
+		activity.Buckets = append(activity.Buckets, ActivityBucket{Start: start, Posts: posts[key], Votes: votes[key]})
+	}
+	if !until.After(now.Truncate(step)) {
+		c.Header("Cache-Control", "private, max-age="+strconv.Itoa(int(activityMaxAge.Seconds())))
+	} else {
+		c.Header("Cache-Control", "private, no-store")
+	}
+	c.JSON(http.StatusOK, activity)
+}
+
+// countByBucket counts the rows of query created in [from, until), soft
+// deleted ones included, grouped in the database by bucket. The result is
+// keyed by each bucket's start in RFC 3339; empty buckets are missing.
+func countByBucket(query *gorm.DB, bucket db.Bucket, from, until time.Time) (map[string]int64, error) {
+	var rows []struct {
+		Bucket string
+		Count  int64
+	}
+	err := query.Unscoped().
+		Select(db.TruncateTime(query, "created_at", bucket)+" AS bucket, COUNT(*) AS count").
+		Where("created_at >= ? AND created_at < ?", from, until).
+		Group("bucket").Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Bucket] = row.Count
+	}
+	return counts, nil
+}
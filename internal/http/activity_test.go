@@ -0,0 +1,152 @@
+package http_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	routes "github.com/sujalbistaa/whispr/internal/http"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+func getActivity(t *testing.T, ts *testutil.TestServer, query url.Values) (routes.Activity, *http.Response) {
+	t.Helper()
+	resp, err := ts.Client().Do(ts.AdminRequest(t, http.MethodGet, "/api/v1/admin/activity?"+query.Encode(), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var activity routes.Activity
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /admin/activity?%s: status %d", query.Encode(), resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&activity); err != nil {
+		t.Fatal(err)
+	}
+	return activity, resp
+}
+
+// checkBuckets fails unless activity has n buckets step apart, with the
+// counts in want, keyed by start in RFC 3339, and zero everywhere else.
+func checkBuckets(t *testing.T, activity routes.Activity, n int, step time.Duration, want map[string][2]int64) {
+	t.Helper()
+	if len(activity.Buckets) != n {
+		t.Fatalf("%d buckets, want %d", len(activity.Buckets), n)
+	}
+	seen := 0
+	for i, bucket := range activity.Buckets {
+		if i > 0 {
+			if gap := bucket.Start.Sub(activity.Buckets[i-1].Start); gap != step {
+				t.Errorf("bucket %s starts %s after the one before, want %s", bucket.Start, gap, step)
+			}
+		}
+		key := bucket.Start.UTC().Format(time.RFC3339)
+		counts, ok := want[key]
+		if ok {
+			seen++
+		}
+		if bucket.Posts != counts[0] || bucket.Votes != counts[1] {
+			t.Errorf("bucket %s: %d posts, %d votes; want %d, %d", key, bucket.Posts, bucket.Votes, counts[0], counts[1])
+		}
+	}
+	if seen != len(want) {
+		t.Errorf("only %d of the %d expected buckets were returned: %+v", seen, len(want), activity.Buckets)
+	}
+}
+
+// TestActivityFallBack seeds the repeated hour of New York's return to
+// standard time, which is two UTC hours and so two buckets.
+func TestActivityFallBack(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	edt := time.Date(2025, time.November, 2, 1, 30, 0, 0, ny) // 05:30Z, the first 1:30
+	est := edt.Add(time.Hour)                                 // 06:30Z, 1:30 again
+	if edt.Hour() != est.Hour() {
+		t.Fatalf("%s and %s should show the same local hour", edt, est)
+	}
+	seed := func(at time.Time, posts, votes int) {
+		for i := 0; i < posts; i++ {
+			seedPost(t, ts, models.Post{CreatedAt: at.UTC()})
+		}
+		if votes > 0 {
+			post := seedPost(t, ts, models.Post{CreatedAt: at.Add(-48 * time.Hour).UTC()})
+			seedVotes(t, ts, post.ID, votes, at.UTC())
+		}
+	}
+	seed(edt.Add(-time.Hour), 1, 0)                                      // 00:30 EDT
+	seed(edt, 2, 1)                                                      // 01:30 EDT
+	seed(est, 1, 0)                                                      // 01:30 EST
+	seed(est.Add(-15*time.Minute), 0, 2)                                 // 01:15 EST
+	seed(est.Add(time.Hour), 3, 0)                                       // 02:30 EST
+	seed(time.Date(2025, time.November, 2, 12, 0, 0, 0, time.UTC), 5, 5) // At until: excluded
+
+	activity, resp := getActivity(t, ts, url.Values{"granularity": {"hour"}, "days": {"1"}, "until": {"2025-11-02T12:00:00Z"}})
+	checkBuckets(t, activity, 24, time.Hour, map[string][2]int64{
+		"2025-11-02T04:00:00Z": {1, 0},
+		"2025-11-02T05:00:00Z": {2, 1},
+		"2025-11-02T06:00:00Z": {1, 2},
+		"2025-11-02T07:00:00Z": {3, 0},
+	})
+	if !activity.From.Equal(time.Date(2025, time.November, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("from %s, want 2025-11-01T12:00:00Z", activity.From)
+	}
+	if got := resp.Header.Get("Cache-Control"); got != "private, max-age=86400" {
+		t.Errorf("elapsed window Cache-Control %q, want private, max-age=86400", got)
+	}
+}
+
+// TestActivitySpringForward seeds either side of New York's missing hour,
+// an hour apart in UTC, and the day around it, which is 24 UTC hours.
+func TestActivitySpringForward(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, at := range []time.Time{
+		time.Date(2026, time.March, 7, 23, 30, 0, 0, ny), // 04:30Z on the 8th
+		time.Date(2026, time.March, 8, 1, 30, 0, 0, ny),  // 06:30Z, EST
+		time.Date(2026, time.March, 8, 3, 30, 0, 0, ny),  // 07:30Z, EDT
+		time.Date(2026, time.March, 8, 23, 30, 0, 0, ny), // 03:30Z on the 9th
+	} {
+		seedPost(t, ts, models.Post{CreatedAt: at.UTC()})
+	}
+
+	hourly, _ := getActivity(t, ts, url.Values{"granularity": {"hour"}, "days": {"1"}, "until": {"2026-03-08T12:00:00Z"}})
+	checkBuckets(t, hourly, 24, time.Hour, map[string][2]int64{
+		"2026-03-08T04:00:00Z": {1, 0},
+		"2026-03-08T06:00:00Z": {1, 0},
+		"2026-03-08T07:00:00Z": {1, 0},
+	})
+
+	// until is rounded up to the end of its day.
+	daily, _ := getActivity(t, ts, url.Values{"granularity": {"day"}, "days": {"3"}, "until": {"2026-03-09T18:00:00Z"}})
+	checkBuckets(t, daily, 3, 24*time.Hour, map[string][2]int64{
+		"2026-03-07T00:00:00Z": {0, 0},
+		"2026-03-08T00:00:00Z": {3, 0},
+		"2026-03-09T00:00:00Z": {1, 0},
+	})
+}
+
+func TestActivityParams(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	activity, resp := getActivity(t, ts, url.Values{})
+	if activity.Granularity != "hour" || len(activity.Buckets) != 7*24 {
+		t.Errorf("defaults: %s with %d buckets, want hour with %d", activity.Granularity, len(activity.Buckets), 7*24)
+	}
+	if got := resp.Header.Get("Cache-Control"); got != "private, no-store" {
+		t.Errorf("window up to now: Cache-Control %q, want private, no-store", got)
+	}
+	for _, query := range []string{"granularity=week", "days=0", "days=32", "days=seven", "until=yesterday"} {
+		if status, code := errorCode(t, ts, ts.AdminRequest(t, http.MethodGet, "/api/v1/admin/activity?"+query, nil)); status != http.StatusBadRequest || code != routes.CodeBadRequest {
+			t.Errorf("?%s: %d %s, want 400 %s", query, status, code, routes.CodeBadRequest)
+		}
+	}
+}
@@ -0,0 +1,640 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/sujalbistaa/whispr/internal/audit"
+	"github.com/sujalbistaa/whispr/internal/bans"
+	"github.com/sujalbistaa/whispr/internal/db"
+	"github.com/sujalbistaa/whispr/internal/flags"
+	"github.com/sujalbistaa/whispr/internal/metrics"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/strikes"
+	"github.com/sujalbistaa/whispr/internal/webhook"
+)
+
+// --- Admin Payloads ---
+
+// AdminStats is the activity overview returned to moderators.
+// "Today" counts start at the `since` time, which defaults to midnight UTC.
+type AdminStats struct {
+	Board         string        `json:"board,omitempty"` // Slug of the board counted; empty for every board
+	Since         time.Time     `json:"since"`
+	PostsToday    int64         `json:"postsToday"`
+	PostsThisWeek int64         `json:"postsThisWeek"`
+	VotesToday    int64         `json:"votesToday"`
+	HiddenPosts   int64         `json:"hiddenPosts"`
+	WSConnections int           `json:"wsConnections"`
+	TopPosts      []models.Post `json:"topPosts"`
+	DBPool        DBPoolStats   `json:"dbPool"`
+	Flags         []flags.State `json:"flags"`
+}
+
+// DBPoolStats shows whether the database pool is exhausted: requests
+// waiting for a connection add to WaitCount and WaitMS.
+type DBPoolStats struct {
+	MaxOpen           int   `json:"maxOpen"`
+	Open              int   `json:"open"`
+	InUse             int   `json:"inUse"`
+	Idle              int   `json:"idle"`
+	WaitCount         int64 `json:"waitCount"`
+	WaitMS            int64 `json:"waitMs"`
+	MaxIdleClosed     int64 `json:"maxIdleClosed"`
+	MaxIdleTimeClosed int64 `json:"maxIdleTimeClosed"`
+	MaxLifetimeClosed int64 `json:"maxLifetimeClosed"`
+}
+
+// AuditPage is a single page of audit log entries.
+type AuditPage struct {
+	Entries []models.AuditLog `json:"entries"`
+	Page    int               `json:"page"`
+	Limit   int               `json:"limit"`
+	Total   int64             `json:"total"`
+}
+
+// CreateBanInput is the body accepted by CreateBan.
+type CreateBanInput struct {
+	IP        string     `json:"ip" binding:"required"` // Single IP or CIDR range
+	Reason    string     `json:"reason" binding:"max=500"`
+	Shadow    bool       `json:"shadow"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+}
+
+// AnnounceInput is the body accepted by CreateAnnouncement.
+type AnnounceInput struct {
+	Message    string `json:"message" binding:"required,min=1,max=500"`
+	Level      string `json:"level" binding:"omitempty,oneof=info warning critical"`
+	TTLSeconds int    `json:"ttlSeconds" binding:"required,min=1,max=604800"` // Up to one week
+}
+
+// HideByKeywordInput is the body accepted by HideByKeyword.
+type HideByKeywordInput struct {
+	Phrase string `json:"phrase" binding:"required,min=4,max=200"`
+}
+
+const (
+	defaultAuditLimit = 50
+	maxAuditLimit     = 200
+)
+
+// --- Admin Handlers ---
+
+// GetAdminStats returns aggregate activity counts for moderators.
+// Accepts an optional RFC3339 `since` query parameter, and `board` to
+// count one board.
+func (e *Env) GetAdminStats(c *gin.Context) {
+	board, ok := e.optionalBoard(c)
+	if !ok {
+		return
+	}
+	inBoard := func(db *gorm.DB) *gorm.DB {
+		if board.ID == 0 {
+			return db
+		}
+		return db.Where("posts.board_id = ?", board.ID)
+	}
+	now := time.Now().UTC()
+	since := now.Truncate(24 * time.Hour)
+	if raw := c.Query("since"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			respondError(c, ErrBadRequest("query.invalid_timestamp", "param", "since"))
+			return
+		}
+		since = parsed.UTC()
+	}
+
+	stats := AdminStats{Board: board.Slug, Since: since, TopPosts: []models.Post{}}
+
+	if err := e.DB.WithContext(c.Request.Context()).Model(&models.Post{}).Scopes(inBoard).Where("created_at >= ?", since).Count(&stats.PostsToday).Error; err != nil {
+		requestLogger(c).Error("counting posts", "err", err)
+		respondError(c, ErrInternal("stats.fetch_failed"))
+		return
+	}
+	if err := e.DB.WithContext(c.Request.Context()).Model(&models.Post{}).Scopes(inBoard).Where("created_at >= ?", now.AddDate(0, 0, -7)).Count(&stats.PostsThisWeek).Error; err != nil {
+		requestLogger(c).Error("counting weekly posts", "err", err)
+		respondError(c, ErrInternal("stats.fetch_failed"))
+		return
+	}
+	votes := e.DB.WithContext(c.Request.Context()).Model(&models.Vote{}).Where("votes.created_at >= ?", since)
+	if board.ID != 0 {
+		votes = votes.Joins("JOIN posts ON posts.id = votes.post_id").Scopes(inBoard)
+	}
+	if err := votes.Count(&stats.VotesToday).Error; err != nil {
+		requestLogger(c).Error("counting votes", "err", err)
+		respondError(c, ErrInternal("stats.fetch_failed"))
+		return
+	}
+	if err := e.DB.WithContext(c.Request.Context()).Unscoped().Model(&models.Post{}).Scopes(inBoard).Where("hidden_at IS NOT NULL").Count(&stats.HiddenPosts).Error; err != nil {
+		requestLogger(c).Error("counting hidden posts", "err", err)
+		respondError(c, ErrInternal("stats.fetch_failed"))
+		return
+	}
+	if err := e.DB.WithContext(c.Request.Context()).Scopes(inBoard).Where("shadow_banned = ? AND created_at >= ?", false, since).Order("score desc, created_at desc").Limit(5).Find(&stats.TopPosts).Error; err != nil {
+		requestLogger(c).Error("fetching top posts", "err", err)
+		respondError(c, ErrInternal("stats.fetch_failed"))
+		return
+	}
+	stats.WSConnections = e.Hub.ClientCount()
+	if board.ID != 0 {
+		stats.WSConnections = e.Hub.RoomCount(board.Slug)
+	}
+	stats.Flags = e.Flags.States()
+	if sqlDB, err := e.DB.DB(); err == nil {
+		pool := sqlDB.Stats()
+		stats.DBPool = DBPoolStats{
+			MaxOpen:           pool.MaxOpenConnections,
+			Open:              pool.OpenConnections,
+			InUse:             pool.InUse,
+			Idle:              pool.Idle,
+			WaitCount:         pool.WaitCount,
+			WaitMS:            pool.WaitDuration.Milliseconds(),
+			MaxIdleClosed:     pool.MaxIdleClosed,
+			MaxIdleTimeClosed: pool.MaxIdleTimeClosed,
+			MaxLifetimeClosed: pool.MaxLifetimeClosed,
+		}
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// GetAuditLog lists audit entries, newest first.
+// Supports `page`, `limit`, `action`, `targetType` and `targetId` query parameters.
+func (e *Env) GetAuditLog(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		respondError(c, ErrBadRequest("query.invalid_page"))
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultAuditLimit)))
+	if err != nil || limit < 1 || limit > maxAuditLimit {
+		respondError(c, ErrBadRequest("query.invalid_limit", "max", maxAuditLimit))
+		return
+	}
+
+	query := e.DB.WithContext(c.Request.Context()).Model(&models.AuditLog{})
+	if action := c.Query("action"); action != "" {
+		query = query.Where("action = ?", action)
+	}
+	if targetType := c.Query("targetType"); targetType != "" {
+		query = query.Where("target_type = ?", targetType)
+	}
+	if raw := c.Query("targetId"); raw != "" {
+		targetID, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			respondError(c, ErrBadRequest("audit.invalid_target_id"))
+			return
+		}
+		query = query.Where("target_id = ?", targetID)
+	}
+
+	query = query.Session(&gorm.Session{})
+
+	result := AuditPage{Entries: []models.AuditLog{}, Page: page, Limit: limit}
+	if err := query.Count(&result.Total).Error; err != nil {
+		requestLogger(c).Error("counting audit entries", "err", err)
+		respondError(c, ErrInternal("audit.fetch_failed"))
+		return
+	}
+	if err := query.Order("id desc").Offset((page - 1) * limit).Limit(limit).Find(&result.Entries).Error; err != nil {
+		requestLogger(c).Error("fetching audit entries", "err", err)
+		respondError(c, ErrInternal("audit.fetch_failed"))
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetBans lists all IP bans, including expired ones.
+// Pass `shadow=true` or `shadow=false` to list only one kind.
+func (e *Env) GetBans(c *gin.Context) {
+	query := e.DB.WithContext(c.Request.Context()).Order("created_at desc")
+	if raw := c.Query("shadow"); raw != "" {
+		shadow, err := strconv.ParseBool(raw)
+		if err != nil {
+			respondError(c, ErrBadRequest("query.invalid_shadow"))
+			return
+		}
+		query = query.Where("shadow = ?", shadow)
+	}
+
+	var rows []models.BannedIP
+	if err := query.Find(&rows).Error; err != nil {
+		requestLogger(c).Error("fetching bans", "err", err)
+		respondError(c, ErrInternal("ban.fetch_failed"))
+		return
+	}
+	c.JSON(http.StatusOK, rows)
+}
+
+// CreateBan bans a single IP or CIDR range.
+func (e *Env) CreateBan(c *gin.Context) {
+	var input CreateBanInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, ErrValidation(err))
+		return
+	}
+	prefix, err := bans.ParsePrefix(input.IP)
+	if err != nil {
+		respondError(c, ErrBadRequest("ban.invalid_cidr"))
+		return
+	}
+
+	ban := models.BannedIP{CIDR: prefix.String(), Reason: input.Reason, Shadow: input.Shadow, ExpiresAt: input.ExpiresAt}
+	err = e.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&ban).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, adminActor(c), audit.ActionBanAdd, audit.TargetBan, ban.ID, map[string]any{"cidr": ban.CIDR, "reason": ban.Reason, "shadow": ban.Shadow})
+	})
+	if db.IsUniqueViolation(err) {
+		respondError(c, ErrConflict("ban.exists"))
+		return
+	}
+	if err != nil {
+		requestLogger(c).Error("creating ban", "err", err)
+		respondError(c, ErrInternal("ban.create_failed"))
+		return
+	}
+	e.Bans.Invalidate()
+
+	c.JSON(http.StatusCreated, ban)
+}
+
+// DeleteBan lifts a ban by id.
+func (e *Env) DeleteBan(c *gin.Context) {
+	banID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, ErrBadRequest("ban.invalid_id"))
+		return
+	}
+
+	var ban models.BannedIP
+	err = e.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&ban, banID).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&ban).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, adminActor(c), audit.ActionBanRemove, audit.TargetBan, ban.ID, map[string]any{"cidr": ban.CIDR})
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, ErrNotFound("ban.not_found"))
+			return
+		}
+		requestLogger(c).Error("deleting ban", "err", err)
+		respondError(c, ErrInternal("ban.delete_failed"))
+		return
+	}
+	e.Bans.Invalidate()
+
+	c.JSON(http.StatusOK, gin.H{"message": "Ban removed"})
+}
+
+// AdminPost is a post as moderators see it, with the hash of the IP that
+// created it.
+type AdminPost struct {
+	models.Post
+	CreatorHash  *string `json:"creatorHash,omitempty"` // Absent for posts made while CREATOR_HASH was off
+	Hidden       bool    `json:"hidden"`
+	ShadowBanned bool    `json:"shadowBanned"`
+}
+
+// AdminPostPage is one page of GET /admin/posts.
+type AdminPostPage struct {
+	Posts []AdminPost `json:"posts"` // Newest first
+	Page  int         `json:"page"`
+	Limit int         `json:"limit"`
+	Total int64       `json:"total"`
+}
+
+// GetAdminPosts lists every post from the IP whose hash is ?creatorHash=,
+// newest first, hidden, shadow-banned and archived ones included. Takes
+// `page` and `limit` like the audit log. It 404s while CREATOR_HASH is
+// off.
+func (e *Env) GetAdminPosts(c *gin.Context) {
+	if e.creatorKey == nil {
+		respondError(c, ErrNotFound("post.creator_hash_disabled"))
+		return
+	}
+	creator := c.Query("creatorHash")
+	if !isCreatorHash(creator) {
+		respondError(c, ErrBadRequest("post.invalid_creator_hash"))
+		return
+	}
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		respondError(c, ErrBadRequest("query.invalid_page"))
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultAuditLimit)))
+	if err != nil || limit < 1 || limit > maxAuditLimit {
+		respondError(c, ErrBadRequest("query.invalid_limit", "max", maxAuditLimit))
+		return
+	}
+
+	query := e.DB.WithContext(c.Request.Context()).Unscoped().Model(&models.Post{}).
+		Where("creator_hash = ?", creator).Session(&gorm.Session{})
+	result := AdminPostPage{Posts: []AdminPost{}, Page: page, Limit: limit}
+	if err := query.Count(&result.Total).Error; err != nil {
+		requestLogger(c).Error("counting posts by creator", "err", err)
+		respondError(c, ErrInternal("post.list_failed"))
+		return
+	}
+	var posts []models.Post
+	if err := query.Order("created_at desc, id desc").Offset((page - 1) * limit).Limit(limit).Find(&posts).Error; err != nil {
+		requestLogger(c).Error("fetching posts by creator", "err", err)
+		respondError(c, ErrInternal("post.list_failed"))
+		return
+	}
+	for _, post := range posts {
+		result.Posts = append(result.Posts, AdminPost{Post: post, CreatorHash: post.CreatorHash, Hidden: post.HiddenAt.Valid, ShadowBanned: post.ShadowBanned})
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// isCreatorHash reports whether s has the form of Post.CreatorHash: a
+// hex-encoded SHA-256 HMAC.
+func isCreatorHash(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && (r < 'a' || r > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// ShadowBannedPost is a quarantined post as shown to moderators.
+type ShadowBannedPost struct {
+	models.Post
+	ShadowBanID *uint   `json:"shadowBanId"`
+	CreatorHash *string `json:"creatorHash,omitempty"`
+}
+
+// GetShadowBannedPosts lists posts quarantined by shadow bans, newest first.
+func (e *Env) GetShadowBannedPosts(c *gin.Context) {
+	var posts []models.Post
+	if err := e.DB.WithContext(c.Request.Context()).Unscoped().Where("shadow_banned = ?", true).Order("created_at desc").Limit(maxAuditLimit).Find(&posts).Error; err != nil {
+		requestLogger(c).Error("fetching shadow-banned posts", "err", err)
+		respondError(c, ErrInternal("post.list_failed"))
+		return
+	}
+	result := make([]ShadowBannedPost, 0, len(posts))
+	for _, post := range posts {
+		result = append(result, ShadowBannedPost{Post: post, ShadowBanID: post.ShadowBanID, CreatorHash: post.CreatorHash})
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// HeldPost is a post in the moderation queue, with the recent post it
+// nearly duplicates.
+type HeldPost struct {
+	models.Post
+	SimilarTo   uint    `json:"similarTo"`
+	Distance    int     `json:"distance"` // Bits between the two fingerprints
+	CreatorHash *string `json:"creatorHash,omitempty"`
+}
+
+// GetModerationQueue lists posts held as near-duplicates and not reviewed
+// yet, newest first.
+func (e *Env) GetModerationQueue(c *gin.Context) {
+	var posts []models.Post
+	if err := e.DB.WithContext(c.Request.Context()).Unscoped().Where("hidden_by = ?", audit.ActorSpamFilter).Order("created_at desc").Limit(maxAuditLimit).Find(&posts).Error; err != nil {
+		requestLogger(c).Error("fetching moderation queue", "err", err)
+		respondError(c, ErrInternal("moderation.queue_failed"))
+		return
+	}
+	ids := make([]uint, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+	var holds []models.AuditLog
+	if len(ids) > 0 {
+		err := e.DB.WithContext(c.Request.Context()).Where("action = ? AND target_type = ? AND target_id IN ?", audit.ActionHoldPost, audit.TargetPost, ids).Find(&holds).Error
+		if err != nil {
+			requestLogger(c).Error("fetching moderation queue", "err", err)
+			respondError(c, ErrInternal("moderation.queue_failed"))
+			return
+		}
+	}
+	reasons := make(map[uint]map[string]any, len(holds))
+	for _, hold := range holds {
+		reasons[hold.TargetID] = hold.Metadata
+	}
+	result := make([]HeldPost, 0, len(posts))
+	for _, post := range posts {
+		held := HeldPost{Post: post, CreatorHash: post.CreatorHash}
+		// Metadata comes back from JSON, so numbers are float64.
+		if n, ok := reasons[post.ID]["similarTo"].(float64); ok {
+			held.SimilarTo = uint(n)
+		}
+		if n, ok := reasons[post.ID]["distance"].(float64); ok {
+			held.Distance = int(n)
+		}
+		result = append(result, held)
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// ApproveHeldPost publishes a post from the moderation queue.
+func (e *Env) ApproveHeldPost(c *gin.Context) {
+	post, ok := e.reviewHeldPost(c, true)
+	if !ok {
+		return
+	}
+	e.invalidateFeeds()
+	if !post.ShadowBanned {
+		e.broadcastMessage(WsMessage{Type: "new_post", Data: post, Board: e.boardSlugOf(c.Request.Context(), post.BoardID)})
+		e.notify(c, webhook.EventNewPost, post)
+	}
+	c.JSON(http.StatusOK, post)
+}
+
+// RejectHeldPost takes a post out of the moderation queue, leaving it
+// hidden as if a moderator had hidden it.
+func (e *Env) RejectHeldPost(c *gin.Context) {
+	post, ok := e.reviewHeldPost(c, false)
+	if !ok {
+		return
+	}
+	metrics.PostsHidden.Inc()
+	c.JSON(http.StatusOK, post)
+}
+
+// reviewHeldPost approves or rejects the held post named by the id
+// parameter and records it in the audit log. Posts not in the queue,
+// including ones another moderator just reviewed, are not found.
+func (e *Env) reviewHeldPost(c *gin.Context, approve bool) (models.Post, bool) {
+	postID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, ErrBadRequest("post.invalid_id"))
+		return models.Post{}, false
+	}
+	action, updates := audit.ActionHidePost, map[string]any{"hidden_by": adminActor(c)}
+	if approve {
+		action, updates = audit.ActionApprovePost, map[string]any{"hidden_at": nil, "hidden_by": ""}
+	}
+
+	var post models.Post
+	err = e.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		res := tx.Unscoped().Model(&models.Post{}).Where("id = ? AND hidden_by = ?", postID, audit.ActorSpamFilter).Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return models.ErrPostNotFound
+		}
+		if err := tx.Unscoped().First(&post, postID).Error; err != nil {
+			return err
+		}
+		if err := audit.Record(tx, adminActor(c), action, audit.TargetPost, post.ID, map[string]any{"held": true}); err != nil {
+			return err
+		}
+		// Rejecting a held post is a moderator's call, so it counts as a
+		// strike; being held by the spam filter doesn't.
+		if approve || post.AuthorHash == nil {
+			return nil
+		}
+		return strikes.Add(tx, e.Config.Strikes, adminActor(c), *post.AuthorHash, post.ID, time.Now())
+	})
+	if errors.Is(err, models.ErrPostNotFound) {
+		respondError(c, ErrNotFound("post.not_found"))
+		return models.Post{}, false
+	}
+	if err != nil {
+		requestLogger(c).Error("reviewing held post", "err", err)
+		respondError(c, ErrInternal("moderation.review_failed"))
+		return models.Post{}, false
+	}
+	return post, true
+}
+
+// CreateAnnouncement replaces the active announcement and pushes it to every client.
+func (e *Env) CreateAnnouncement(c *gin.Context) {
+	var input AnnounceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, ErrValidation(err))
+		return
+	}
+	if input.Level == "" {
+		input.Level = "info"
+	}
+
+	announcement := models.Announcement{
+		Message:   input.Message,
+		Level:     input.Level,
+		ExpiresAt: time.Now().Add(time.Duration(input.TTLSeconds) * time.Second),
+	}
+	err := e.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&announcement).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, adminActor(c), audit.ActionAnnounce, audit.TargetAnnouncement, announcement.ID, map[string]any{"level": announcement.Level, "ttlSeconds": input.TTLSeconds})
+	})
+	if err != nil {
+		requestLogger(c).Error("creating announcement", "err", err)
+		respondError(c, ErrInternal("announcement.create_failed"))
+		return
+	}
+
+	msg := WsMessage{Type: "announcement", Data: announcement}
+	e.broadcastMessage(msg)
+
+	c.JSON(http.StatusCreated, announcement)
+}
+
+// ReloadTokens re-reads the admin token configuration.
+// Removed tokens stop working immediately.
+func (e *Env) ReloadTokens(c *gin.Context) {
+	if err := e.Tokens.Reload(); err != nil {
+		requestLogger(c).Error("reloading admin tokens", "err", err)
+		respondError(c, ErrInternal("admin.tokens_reload_failed", "error", err))
+		return
+	}
+	requestLogger(c).Info("admin tokens reloaded", "count", e.Tokens.Len(), "actor", adminActor(c))
+	c.JSON(http.StatusOK, gin.H{"tokens": e.Tokens.Len()})
+}
+
+// HideByKeyword hides every visible post containing a phrase (case-insensitive),
+// adding a strike against each author per post.
+// With ?dryRun=true it only reports the ids that would be hidden.
+func (e *Env) HideByKeyword(c *gin.Context) {
+	var input HideByKeywordInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, ErrValidation(err))
+		return
+	}
+	phrase := strings.TrimSpace(input.Phrase)
+	if len([]rune(phrase)) < 4 {
+		respondError(c, ErrBadRequest("moderation.phrase_too_short", "min", 4))
+		return
+	}
+	dryRun, _ := strconv.ParseBool(c.Query("dryRun"))
+
+	ids := []uint{}
+	var matched []struct {
+		ID, BoardID  uint
+		AuthorHash   *string
+		ShadowBanned bool
+	}
+	err := e.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Post{}).Scopes(db.ContainsFold("content", phrase)).Select("id", "board_id", "author_hash", "shadow_banned").Order("id").Find(&matched).Error; err != nil {
+			return err
+		}
+		for _, post := range matched {
+			ids = append(ids, post.ID)
+		}
+		if dryRun || len(ids) == 0 {
+			return nil
+		}
+		now := time.Now()
+		if err := tx.Model(&models.Post{}).Where("id IN ?", ids).Updates(map[string]any{"hidden_at": now, "hidden_by": adminActor(c)}).Error; err != nil {
+			return err
+		}
+		if err := audit.Record(tx, adminActor(c), audit.ActionHideByKeyword, audit.TargetPost, 0, map[string]any{"phrase": phrase, "ids": ids}); err != nil {
+			return err
+		}
+		for _, post := range matched {
+			if post.AuthorHash == nil {
+				continue
+			}
+			if err := strikes.Add(tx, e.Config.Strikes, adminActor(c), *post.AuthorHash, post.ID, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		requestLogger(c).Error("hiding posts by keyword", "err", err)
+		respondError(c, ErrInternal("moderation.hide_failed"))
+		return
+	}
+
+	if !dryRun && len(ids) > 0 {
+		e.invalidateFeeds()
+		metrics.PostsHidden.Add(float64(len(ids)))
+		// Held posts are already hidden, so only shadow-banned ones can
+		// match without ever having been public; announcing those would
+		// reveal them.
+		for _, post := range matched {
+			if post.ShadowBanned {
+				continue
+			}
+			e.broadcastMessage(WsMessage{Type: "delete", Data: gin.H{"id": post.ID}, Board: e.boardSlugOf(c.Request.Context(), post.BoardID)})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"ids": ids, "count": len(ids), "dryRun": dryRun})
+}
@@ -0,0 +1,277 @@
+package http_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sujalbistaa/whispr/internal/audit"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// seedPost stores post as given, bypassing CreatePost so its times,
+// score and state can be set.
+func seedPost(t *testing.T, ts *testutil.TestServer, post models.Post) models.Post {
+	t.Helper()
+	if post.Content == "" {
+		post.Content = "seeded post"
+	}
+	if post.LastActivityAt.IsZero() {
+		post.LastActivityAt = post.CreatedAt
+	}
+	if err := ts.DB.Create(&post).Error; err != nil {
+		t.Fatalf("seeding post: %v", err)
+	}
+	return post
+}
+
+// seedVotes stores n upvotes on post id cast at the given time.
+func seedVotes(t *testing.T, ts *testutil.TestServer, id uint, n int, at time.Time) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		voter := ts.ClientIP()
+		if err := ts.DB.Create(&models.Vote{PostID: id, VoterHash: &voter, Value: 1, CreatedAt: at}).Error; err != nil {
+			t.Fatalf("seeding vote: %v", err)
+		}
+	}
+}
+
+func getAdminStats(t *testing.T, ts *testutil.TestServer, query url.Values) (int, map[string]json.RawMessage) {
+	t.Helper()
+	status, body := ts.Do(t, ts.AdminRequest(t, http.MethodGet, "/api/v1/admin/stats?"+query.Encode(), nil))
+	var stats map[string]json.RawMessage
+	if status == http.StatusOK {
+		if err := json.Unmarshal(body, &stats); err != nil {
+			t.Fatalf("decoding stats %s: %v", body, err)
+		}
+	}
+	return status, stats
+}
+
+func statInt(t *testing.T, stats map[string]json.RawMessage, name string) int64 {
+	t.Helper()
+	var n int64
+	if err := json.Unmarshal(stats[name], &n); err != nil {
+		t.Fatalf("%s = %s: %v", name, stats[name], err)
+	}
+	return n
+}
+
+func TestAdminStatsAggregates(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	now := time.Now().UTC()
+	since := now.Add(-2 * time.Hour)
+
+	recent := seedPost(t, ts, models.Post{Score: 5, CreatedAt: now.Add(-time.Hour)})
+	best := seedPost(t, ts, models.Post{Score: 9, CreatedAt: now.Add(-30 * time.Minute)})
+	earlier := seedPost(t, ts, models.Post{Score: 50, CreatedAt: now.Add(-3 * time.Hour)})
+	seedPost(t, ts, models.Post{Score: 80, CreatedAt: now.AddDate(0, 0, -10)})
+	hidden := seedPost(t, ts, models.Post{Score: 100, CreatedAt: now.Add(-20 * time.Minute)})
+	seedPost(t, ts, models.Post{Score: 70, CreatedAt: now.Add(-10 * time.Minute), ShadowBanned: true})
+	if err := ts.DB.Delete(&models.Post{}, hidden.ID).Error; err != nil {
+		t.Fatalf("hiding post: %v", err)
+	}
+	seedVotes(t, ts, recent.ID, 3, now.Add(-time.Hour))
+	seedVotes(t, ts, hidden.ID, 1, now.Add(-15*time.Minute))
+	seedVotes(t, ts, earlier.ID, 2, now.Add(-3*time.Hour))
+	ts.DialWS(t)
+
+	status, stats := getAdminStats(t, ts, url.Values{"since": {since.Format(time.RFC3339)}})
+	if status != http.StatusOK {
+		t.Fatalf("status %d, want 200", status)
+	}
+	for name, want := range map[string]int64{
+		"postsToday":    3, // recent, best and the shadow-banned post; hidden posts don't count
+		"postsThisWeek": 4, // and earlier, but not the post from 10 days ago
+		"votesToday":    4, // votes on hidden posts count, earlier ones don't
+		"hiddenPosts":   1,
+		"wsConnections": 1,
+	} {
+		if got := statInt(t, stats, name); got != want {
+			t.Errorf("%s = %d, want %d", name, got, want)
+		}
+	}
+	var top []models.Post
+	if err := json.Unmarshal(stats["topPosts"], &top); err != nil {
+		t.Fatalf("decoding topPosts: %v", err)
+	}
+	if len(top) != 2 || top[0].ID != best.ID || top[1].ID != recent.ID {
+		t.Errorf("topPosts = %+v, want posts %d then %d", top, best.ID, recent.ID)
+	}
+}
+
+func TestAdminStatsTopPostsLimit(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	now := time.Now().UTC()
+	for score := 1; score <= 7; score++ {
+		seedPost(t, ts, models.Post{Score: score, CreatedAt: now.Add(-time.Duration(score) * time.Minute)})
+	}
+
+	_, stats := getAdminStats(t, ts, url.Values{"since": {now.Add(-time.Hour).Format(time.RFC3339)}})
+	var top []models.Post
+	if err := json.Unmarshal(stats["topPosts"], &top); err != nil {
+		t.Fatalf("decoding topPosts: %v", err)
+	}
+	if len(top) != 5 {
+		t.Fatalf("got %d top posts, want 5", len(top))
+	}
+	for i, post := range top {
+		if want := 7 - i; post.Score != want {
+			t.Errorf("topPosts[%d].score = %d, want %d", i, post.Score, want)
+		}
+	}
+}
+
+func TestAdminStatsBoard(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	now := time.Now().UTC()
+	board := models.Board{Slug: "campus", Name: "Campus"}
+	if err := ts.DB.Create(&board).Error; err != nil {
+		t.Fatalf("creating board: %v", err)
+	}
+	seedPost(t, ts, models.Post{CreatedAt: now.Add(-time.Minute)})
+	onBoard := seedPost(t, ts, models.Post{BoardID: board.ID, CreatedAt: now.Add(-time.Minute)})
+	seedVotes(t, ts, onBoard.ID, 2, now.Add(-time.Minute))
+
+	_, stats := getAdminStats(t, ts, url.Values{"board": {"campus"}, "since": {now.Add(-time.Hour).Format(time.RFC3339)}})
+	var slug string
+	json.Unmarshal(stats["board"], &slug)
+	if slug != "campus" {
+		t.Errorf("board = %q, want campus", slug)
+	}
+	if got := statInt(t, stats, "postsToday"); got != 1 {
+		t.Errorf("postsToday = %d, want 1", got)
+	}
+	if got := statInt(t, stats, "votesToday"); got != 2 {
+		t.Errorf("votesToday = %d, want 2", got)
+	}
+}
+
+func TestAdminStatsRejects(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	if status, _ := getAdminStats(t, ts, url.Values{"since": {"yesterday"}}); status != http.StatusBadRequest {
+		t.Errorf("since=yesterday: status %d, want 400", status)
+	}
+	if status, _ := ts.Do(t, ts.NewRequest(t, http.MethodGet, "/api/v1/admin/stats", nil)); status != http.StatusUnauthorized {
+		t.Errorf("without a token: status %d, want 401", status)
+	}
+}
+
+func hideByKeyword(t *testing.T, ts *testutil.TestServer, phrase string, dryRun bool) []uint {
+	t.Helper()
+	path := "/api/v1/admin/posts/hide-by-keyword"
+	if dryRun {
+		path += "?dryRun=true"
+	}
+	status, body := ts.Do(t, ts.AdminRequest(t, http.MethodPost, path, map[string]string{"phrase": phrase}))
+	if status != http.StatusOK {
+		t.Fatalf("POST %s: status %d: %s", path, status, body)
+	}
+	var result struct {
+		IDs    []uint `json:"ids"`
+		Count  int    `json:"count"`
+		DryRun bool   `json:"dryRun"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.DryRun != dryRun || result.Count != len(result.IDs) {
+		t.Errorf("result = %+v for dryRun=%v", result, dryRun)
+	}
+	return result.IDs
+}
+
+func TestHideByKeyword(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	now := time.Now().UTC()
+	public := seedPost(t, ts, models.Post{Content: "Selling CHEAP tickets here", CreatedAt: now})
+	banID := uint(1)
+	shadowed := seedPost(t, ts, models.Post{Content: "cheap tickets, honest", CreatedAt: now, ShadowBanned: true, ShadowBanID: &banID})
+	held := seedPost(t, ts, models.Post{Content: "cheap tickets again", CreatedAt: now, HiddenBy: audit.ActorSpamFilter})
+	if err := ts.DB.Delete(&models.Post{}, held.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	unrelated := seedPost(t, ts, models.Post{Content: "nothing to see", CreatedAt: now})
+	ws := ts.DialWS(t)
+
+	want := []uint{public.ID, shadowed.ID}
+	if got := hideByKeyword(t, ts, "cheap tickets", true); !slices.Equal(got, want) {
+		t.Errorf("dry run matched %v, want %v", got, want)
+	}
+	ws.ExpectNone(t, "delete", 200*time.Millisecond)
+	var visible int64
+	ts.DB.Model(&models.Post{}).Count(&visible)
+	if visible != 3 {
+		t.Fatalf("dry run hid posts: %d left, want 3", visible)
+	}
+	var audits int64
+	ts.DB.Model(&models.AuditLog{}).Where("action = ?", audit.ActionHideByKeyword).Count(&audits)
+	if audits != 0 {
+		t.Errorf("dry run wrote %d audit entries", audits)
+	}
+
+	if got := hideByKeyword(t, ts, "cheap tickets", false); !slices.Equal(got, want) {
+		t.Errorf("real run hid %v, want %v", got, want)
+	}
+	msg := ws.Expect(t, "delete", 0)
+	var deleted struct {
+		ID uint `json:"id"`
+	}
+	json.Unmarshal(msg.Data, &deleted)
+	if deleted.ID != public.ID {
+		t.Errorf("delete broadcast for post %d, want %d", deleted.ID, public.ID)
+	}
+	// The shadow-banned post was never public, so it's not announced.
+	ws.ExpectNone(t, "delete", 200*time.Millisecond)
+
+	var left []models.Post
+	ts.DB.Find(&left)
+	if len(left) != 1 || left[0].ID != unrelated.ID {
+		t.Errorf("visible after hiding: %v, want only %d", left, unrelated.ID)
+	}
+	var entry models.AuditLog
+	if err := ts.DB.Where("action = ?", audit.ActionHideByKeyword).First(&entry).Error; err != nil {
+		t.Fatalf("no audit entry: %v", err)
+	}
+	if ids, _ := entry.Metadata["ids"].([]any); len(ids) != len(want) {
+		t.Errorf("audit ids = %v, want %v", entry.Metadata["ids"], want)
+	}
+
+	if got := hideByKeyword(t, ts, "cheap tickets", false); len(got) != 0 {
+		t.Errorf("second run hid %v again", got)
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	now := time.Now().UTC()
+	for _, content := range []string{"=HYPERLINK(\"http://evil.test\")", "+1 for this", "-2 for that", "@SUM(A1)", "\tindented", "\rreturned", "plain, with a comma"} {
+		seedPost(t, ts, models.Post{Content: content, Score: -3, CreatedAt: now})
+	}
+
+	status, body := ts.Do(t, ts.AdminRequest(t, http.MethodGet, "/api/v1/admin/export?format=csv", nil))
+	if status != http.StatusOK {
+		t.Fatalf("GET /api/v1/admin/export: status %d: %s", status, body)
+	}
+	records, err := csv.NewReader(bytes.NewReader(body)).ReadAll()
+	if err != nil {
+		t.Fatalf("reading the CSV: %v", err)
+	}
+	var contents []string
+	for _, record := range records[1:] {
+		contents = append(contents, record[1])
+		if record[2] != "-3" {
+			t.Errorf("score cell %q, want -3 left as a number", record[2])
+		}
+	}
+	want := []string{"'=HYPERLINK(\"http://evil.test\")", "'+1 for this", "'-2 for that", "'@SUM(A1)", "'\tindented", "'\rreturned", "plain, with a comma"}
+	if !slices.Equal(contents, want) {
+		t.Errorf("content cells = %q, want %q", contents, want)
+	}
+}
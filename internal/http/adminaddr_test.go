@@ -0,0 +1,73 @@
+package http_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	routes "github.com/sujalbistaa/whispr/internal/http"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// TestAdminAddr checks that with ADMIN_ADDR set, the admin API, hiding
+// posts included, is served on the admin listener only.
+func TestAdminAddr(t *testing.T) {
+	t.Setenv("ADMIN_ADDR", "127.0.0.1:0")
+	ts := testutil.NewTestServer(t)
+	handler := ts.App.AdminHandler()
+	if handler == nil {
+		t.Fatal("ADMIN_ADDR is set but there is no admin listener")
+	}
+	admin := httptest.NewServer(handler)
+	t.Cleanup(admin.Close)
+
+	post := ts.CreatePost(t, "moderated from the admin port only")
+	for _, tc := range []struct{ method, path string }{
+		{http.MethodDelete, fmt.Sprintf("/api/v1/posts/%d", post.ID)},
+		{http.MethodDelete, fmt.Sprintf("/api/posts/%d", post.ID)},
+		{http.MethodDelete, fmt.Sprintf("/api/v1/admin/posts/%d", post.ID)},
+		{http.MethodGet, "/api/v1/admin/stats"},
+		{http.MethodGet, "/metrics"},
+		{http.MethodGet, "/debug/pprof/"},
+	} {
+		if status, code := errorCode(t, ts, ts.AdminRequest(t, tc.method, tc.path, nil)); status != http.StatusNotFound || code != routes.CodeNotFound {
+			t.Errorf("public %s %s: %d %s, want 404 %s", tc.method, tc.path, status, code, routes.CodeNotFound)
+		}
+	}
+	var stored models.Post
+	if err := ts.DB.First(&stored, post.ID).Error; err != nil {
+		t.Fatalf("post hidden through the public listener: %v", err)
+	}
+
+	req := ts.AdminRequest(t, http.MethodDelete, fmt.Sprintf("/api/v1/admin/posts/%d", post.ID), nil)
+	req.URL.Host = admin.Listener.Addr().String()
+	resp, err := admin.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("admin listener DELETE /api/v1/admin/posts/%d: status %d", post.ID, resp.StatusCode)
+	}
+	if err := ts.DB.First(&stored, post.ID).Error; err == nil {
+		t.Error("post still visible after hiding it on the admin listener")
+	}
+}
+
+// TestDeletePostPaths checks that on one listener both the admin path
+// and the original one hide posts.
+func TestDeletePostPaths(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	for _, path := range []string{"/api/v1/posts/%d", "/api/v1/admin/posts/%d", "/api/posts/%d"} {
+		post := ts.CreatePost(t, "hidden via "+path)
+		status, body := ts.Do(t, ts.AdminRequest(t, http.MethodDelete, fmt.Sprintf(path, post.ID), nil))
+		if status != http.StatusOK {
+			t.Errorf("DELETE %s: %d %s", path, status, body)
+		}
+		if status, code := errorCode(t, ts, ts.NewRequest(t, http.MethodDelete, fmt.Sprintf(path, post.ID), nil)); status != http.StatusUnauthorized {
+			t.Errorf("DELETE %s without a token: %d %s, want 401", path, status, code)
+		}
+	}
+}
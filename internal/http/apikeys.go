@@ -0,0 +1,145 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/sujalbistaa/whispr/internal/audit"
+	"github.com/sujalbistaa/whispr/internal/auth"
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+// CreateAPIKeyInput is the body of POST /admin/api-keys.
+type CreateAPIKeyInput struct {
+	Label string `json:"label" binding:"required,max=100"`
+	Scope string `json:"scope" binding:"omitempty,oneof=read"` // Defaults to read
+}
+
+// CreatedAPIKey is a new key as returned to the admin who created it, the
+// only time the key itself is shown.
+type CreatedAPIKey struct {
+	models.APIKey
+	Key string `json:"key"`
+}
+
+// APIKeyMiddleware authenticates callers that send an API key as
+// "Authorization: Bearer <key>" and limits them in the key's own bucket
+// instead of their IP's. Read-scoped keys get 403 on anything but GET and
+// HEAD. Requests without a key pass through untouched.
+//
+// Keys are looked up on every request, so revoking one takes effect on
+// every instance at once.
+func APIKeyMiddleware(database *gorm.DB, limiter RateLimiter, failOpen bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		scheme, supplied, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !ok || !strings.EqualFold(scheme, "Bearer") {
+			c.Next()
+			return
+		}
+
+		var key models.APIKey
+		err := database.WithContext(c.Request.Context()).
+			Where("key_hash = ? AND revoked_at IS NULL", auth.HashAPIKey(strings.TrimSpace(supplied))).
+			First(&key).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, ErrUnauthorized("api_key.invalid"))
+			return
+		}
+		if err != nil {
+			requestLogger(c).Error("looking up API key", "err", err)
+			respondError(c, ErrInternal("api_key.lookup_failed"))
+			return
+		}
+		if auth.Scope(key.Scope) == auth.ScopeRead && c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			respondError(c, ErrForbidden("api_key.read_only"))
+			return
+		}
+		c.Set(apiKeyKey, key)
+		if allowRequest(c, limiter, "key:"+strconv.FormatUint(uint64(key.ID), 10), failOpen) {
+			c.Next()
+		}
+	}
+}
+
+// GetAPIKeys lists every API key, newest first, revoked ones included.
+func (e *Env) GetAPIKeys(c *gin.Context) {
+	keys := []models.APIKey{}
+	if err := e.DB.WithContext(c.Request.Context()).Order("created_at desc").Find(&keys).Error; err != nil {
+		requestLogger(c).Error("listing API keys", "err", err)
+		respondError(c, ErrInternal("api_key.fetch_failed"))
+		return
+	}
+	c.JSON(http.StatusOK, keys)
+}
+
+// CreateAPIKey generates a key. The response is the only place the key
+// appears; only its hash is stored.
+func (e *Env) CreateAPIKey(c *gin.Context) {
+	var input CreateAPIKeyInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, ErrValidation(err))
+		return
+	}
+	if input.Scope == "" {
+		input.Scope = string(auth.ScopeRead)
+	}
+	plain, hash, err := auth.NewAPIKey()
+	if err != nil {
+		requestLogger(c).Error("creating API key", "err", err)
+		respondError(c, ErrInternal("api_key.create_failed"))
+		return
+	}
+
+	key := models.APIKey{Label: strings.TrimSpace(input.Label), Scope: input.Scope, Prefix: plain[:auth.APIKeyDisplayLen], KeyHash: hash}
+	err = e.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&key).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, adminActor(c), audit.ActionAPIKeyCreate, audit.TargetAPIKey, key.ID, map[string]any{"label": key.Label, "scope": key.Scope, "prefix": key.Prefix})
+	})
+	if err != nil {
+		requestLogger(c).Error("creating API key", "err", err)
+		respondError(c, ErrInternal("api_key.create_failed"))
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusCreated, CreatedAPIKey{APIKey: key, Key: plain})
+}
+
+// RevokeAPIKey stops a key from working. Revoking it again changes
+// nothing.
+func (e *Env) RevokeAPIKey(c *gin.Context) {
+	keyID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, ErrBadRequest("api_key.invalid_id"))
+		return
+	}
+
+	var key models.APIKey
+	err = e.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&key, keyID).Error; err != nil {
+			return err
+		}
+		res := tx.Model(&key).Where("revoked_at IS NULL").Update("revoked_at", time.Now())
+		if res.Error != nil || res.RowsAffected == 0 {
+			return res.Error
+		}
+		return audit.Record(tx, adminActor(c), audit.ActionAPIKeyRevoke, audit.TargetAPIKey, key.ID, map[string]any{"label": key.Label, "prefix": key.Prefix})
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, ErrNotFound("api_key.not_found"))
+			return
+		}
+		requestLogger(c).Error("revoking API key", "err", err)
+		respondError(c, ErrInternal("api_key.revoke_failed"))
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked"})
+}
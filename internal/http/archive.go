@@ -0,0 +1,115 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/sujalbistaa/whispr/internal/audit"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/store"
+)
+
+const (
+	defaultArchiveLimit = 20
+	maxArchiveLimit     = 100
+)
+
+// ArchivePage is one page of GET /archive/posts.
+type ArchivePage struct {
+	Posts []models.Post `json:"posts"` // Most recently archived first
+	Page  int           `json:"page"`
+	Limit int           `json:"limit"`
+	Total int64         `json:"total"`
+}
+
+// GetArchivedPosts returns a page of the posts the archive worker took
+// out of the feeds, across every board.
+func (e *Env) GetArchivedPosts(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		respondError(c, ErrBadRequest("query.invalid_page"))
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultArchiveLimit)))
+	if err != nil || limit < 1 || limit > maxArchiveLimit {
+		respondError(c, ErrBadRequest("query.invalid_limit", "max", maxArchiveLimit))
+		return
+	}
+
+	ctx := c.Request.Context()
+	viewer := e.viewer(c)
+	feed := store.FeedArchive.Page((page-1)*limit, limit)
+	posts, err := e.Posts.List(ctx, viewer, feed)
+	if err != nil {
+		requestLogger(c).Error("fetching archived posts", "err", err)
+		respondError(c, ErrInternal("post.list_failed"))
+		return
+	}
+	total, err := e.Posts.Count(ctx, viewer, feed)
+	if err != nil {
+		requestLogger(c).Error("counting archived posts", "err", err)
+		respondError(c, ErrInternal("post.list_failed"))
+		return
+	}
+	c.JSON(http.StatusOK, ArchivePage{Posts: posts, Page: page, Limit: limit, Total: total})
+}
+
+// errNotArchived means the post is already in the feeds.
+var errNotArchived = errors.New("post is not archived")
+
+// UnarchivePost puts an archived post back in the feeds, hidden posts
+// included, and records it in the audit log. The post counts as active
+// from now on, so the archive worker leaves it alone for
+// ARCHIVE_KEEP_ACTIVE. Like archiving, it broadcasts nothing.
+func (e *Env) UnarchivePost(c *gin.Context) {
+	postID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, ErrBadRequest("post.invalid_id"))
+		return
+	}
+
+	var post models.Post
+	err = e.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().First(&post, postID).Error; err != nil {
+			return err
+		}
+		if post.ArchivedAt == nil {
+			return errNotArchived
+		}
+		archivedAt := *post.ArchivedAt
+		now := time.Now()
+		res := tx.Unscoped().Model(&models.Post{}).Where("id = ? AND archived_at IS NOT NULL", post.ID).
+			Updates(map[string]any{"archived_at": nil, "last_activity_at": now})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errNotArchived
+		}
+		post = models.Post{}
+		if err := tx.Unscoped().First(&post, postID).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, adminActor(c), audit.ActionUnarchive, audit.TargetPost, post.ID, map[string]any{"archivedAt": archivedAt})
+	})
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		respondError(c, ErrNotFound("post.not_found"))
+		return
+	case errors.Is(err, errNotArchived):
+		respondError(c, ErrConflict("post.not_archived"))
+		return
+	case err != nil:
+		requestLogger(c).Error("unarchiving post", "post", postID, "err", err)
+		respondError(c, ErrInternal("post.unarchive_failed"))
+		return
+	}
+
+	e.invalidateFeeds()
+	c.JSON(http.StatusOK, post)
+}
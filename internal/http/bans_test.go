@@ -0,0 +1,65 @@
+package http_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+func createBan(t *testing.T, ts *testutil.TestServer, cidr string, shadow bool) {
+	t.Helper()
+	req := ts.AdminRequest(t, http.MethodPost, "/api/v1/admin/bans", map[string]any{"ip": cidr, "shadow": shadow})
+	if status, body := ts.Do(t, req); status != http.StatusCreated {
+		t.Fatalf("banning %s: status %d: %s", cidr, status, body)
+	}
+}
+
+func feedIDs(t *testing.T, ts *testutil.TestServer, ip string) map[uint]bool {
+	t.Helper()
+	status, body := ts.Do(t, ts.NewRequestFrom(t, ip, http.MethodGet, "/api/v1/posts", nil))
+	if status != http.StatusOK {
+		t.Fatalf("GET /api/v1/posts: status %d", status)
+	}
+	var posts []models.Post
+	if err := json.Unmarshal(body, &posts); err != nil {
+		t.Fatalf("decoding feed %s: %v", body, err)
+	}
+	seen := map[uint]bool{}
+	for _, post := range posts {
+		seen[post.ID] = true
+	}
+	return seen
+}
+
+func TestShadowBannedAuthorSeesOwnPost(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	createBan(t, ts, "10.250.0.0/16", true)
+	author, other := "10.250.0.1", ts.ClientIP()
+
+	var post models.Post
+	status, body := ts.Do(t, ts.NewRequestFrom(t, author, http.MethodPost, "/api/v1/posts", map[string]string{"content": "nobody else can read this"}))
+	if status != http.StatusCreated {
+		t.Fatalf("posting while shadow-banned: status %d: %s", status, body)
+	}
+	if err := json.Unmarshal(body, &post); err != nil {
+		t.Fatal(err)
+	}
+
+	if !feedIDs(t, ts, author)[post.ID] {
+		t.Error("the author's feed doesn't list their own post")
+	}
+	if feedIDs(t, ts, other)[post.ID] {
+		t.Error("another client's feed lists a shadow-banned post")
+	}
+	path := fmt.Sprintf("/api/v1/posts/%d", post.ID)
+	if status, _ := ts.Do(t, ts.NewRequestFrom(t, author, http.MethodGet, path, nil)); status != http.StatusOK {
+		t.Errorf("author GET %s: status %d, want 200", path, status)
+	}
+	if status, _ := ts.Do(t, ts.NewRequestFrom(t, other, http.MethodGet, path, nil)); status != http.StatusNotFound {
+		t.Errorf("other GET %s: status %d, want 404", path, status)
+	}
+}
@@ -0,0 +1,132 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// strictJSONError is a request body that encoding/json would accept but
+// bindJSON refuses. Field is empty for a fault of the body as a whole.
+type strictJSONError struct {
+	Rule  string // unknown, duplicate or trailing
+	Field string
+}
+
+func (e *strictJSONError) Error() string {
+	if e.Field == "" {
+		return "request body: " + e.Rule
+	}
+	return "request body: " + e.Rule + " field " + strconv.Quote(e.Field)
+}
+
+// bindJSON decodes the body into obj and validates it like ShouldBindJSON,
+// but strictly: fields obj doesn't have, a key repeated in an object, and
+// anything after the JSON value are refused rather than ignored, so a
+// typo like "contnet" is reported as such instead of as a missing
+// "content". The body is read up to MAX_BODY_BYTES whichever route it is
+// used on. Pass its errors to ErrValidation.
+func (e *Env) bindJSON(c *gin.Context, obj any) error {
+	if c.Request.Body == nil {
+		return io.EOF
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, e.Config.MaxBodyBytes))
+	if err != nil {
+		return err
+	}
+	if field := duplicateKey(body); field != "" {
+		return &strictJSONError{Rule: "duplicate", Field: field}
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(obj); err != nil {
+		// encoding/json has no type for this one.
+		if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			if field, err := strconv.Unquote(name); err == nil {
+				return &strictJSONError{Rule: "unknown", Field: field}
+			}
+		}
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return &strictJSONError{Rule: "trailing"}
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// duplicateKey returns the path, dotted like json.UnmarshalTypeError's
+// Field, of the first key repeated within an object in data, or "" if
+// there is none. Malformed JSON is left for the decoder to report.
+func duplicateKey(data []byte) string {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	field, _ := walkKeys(dec, "")
+	return field
+}
+
+func walkKeys(dec *json.Decoder, path string) (string, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return "", err
+	}
+	switch tok {
+	case json.Delim('{'):
+		seen := make(map[string]bool)
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return "", err
+			}
+			key, _ := tok.(string)
+			field := key
+			if path != "" {
+				field = path + "." + key
+			}
+			if seen[key] {
+				return field, nil
+			}
+			seen[key] = true
+			if dup, err := walkKeys(dec, field); dup != "" || err != nil {
+				return dup, err
+			}
+		}
+	case json.Delim('['):
+		for i := 0; dec.More(); i++ {
+			if dup, err := walkKeys(dec, path+"."+strconv.Itoa(i)); dup != "" || err != nil {
+				return dup, err
+			}
+		}
+	default:
+		return "", nil
+	}
+	_, err = dec.Token() // The closing delimiter
+	return "", err
+}
+
+// jsonType names the JSON type that decodes into t, for telling clients
+// what a field should have been.
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Pointer:
+		return jsonType(t.Elem())
+	default:
+		return "object"
+	}
+}
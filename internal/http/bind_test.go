@@ -0,0 +1,108 @@
+package http_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	routes "github.com/sujalbistaa/whispr/internal/http"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// apiError is the error envelope as a client sees it.
+type apiError struct {
+	Error struct {
+		Code    string          `json:"code"`
+		Message string          `json:"message"`
+		Details json.RawMessage `json:"details"` // Its shape depends on Code
+	} `json:"error"`
+}
+
+// fieldErrors decodes the details of a validation error.
+func (e apiError) fieldErrors(t *testing.T) []routes.FieldError {
+	t.Helper()
+	var fields []routes.FieldError
+	if len(e.Error.Details) > 0 {
+		if err := json.Unmarshal(e.Error.Details, &fields); err != nil {
+			t.Fatalf("details %s: %v", e.Error.Details, err)
+		}
+	}
+	return fields
+}
+
+// postRaw sends body to path unchanged and decodes the error it gets back.
+func postRaw(t *testing.T, ts *testutil.TestServer, path, body string) (int, apiError) {
+	t.Helper()
+	status, resp := ts.Do(t, ts.NewRequest(t, http.MethodPost, path, []byte(body)))
+	var apiErr apiError
+	if status >= 400 {
+		if err := json.Unmarshal(resp, &apiErr); err != nil {
+			t.Fatalf("POST %s: decoding %s: %v", path, resp, err)
+		}
+	}
+	return status, apiErr
+}
+
+func TestStrictBinding(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	post := ts.CreatePost(t, "a post to vote on")
+	votePath := fmt.Sprintf("/api/v1/posts/%d/vote", post.ID)
+
+	for _, tc := range []struct {
+		name, path, body string
+		want             routes.FieldError // Message isn't compared
+	}{
+		{"unknown field", "/api/v1/posts", `{"content":"hello there","contnet":"x"}`, routes.FieldError{Field: "contnet", Rule: "unknown"}},
+		{"unknown vote field", votePath, `{"value":1,"weight":10}`, routes.FieldError{Field: "weight", Rule: "unknown"}},
+		{"wrong type", "/api/v1/posts", `{"content":5}`, routes.FieldError{Field: "content", Rule: "type", Param: "string"}},
+		{"wrong vote type", votePath, `{"value":"up"}`, routes.FieldError{Field: "value", Rule: "type", Param: "number"}},
+		{"duplicate key", "/api/v1/posts", `{"content":"harmless","content":"sneaky"}`, routes.FieldError{Field: "content", Rule: "duplicate"}},
+		{"duplicate vote key", votePath, `{"value":1,"value":-1}`, routes.FieldError{Field: "value", Rule: "duplicate"}},
+	} {
+		status, apiErr := postRaw(t, ts, tc.path, tc.body)
+		if status != http.StatusBadRequest || apiErr.Error.Code != routes.CodeValidation {
+			t.Errorf("%s: status %d, code %q, want 400 %s", tc.name, status, apiErr.Error.Code, routes.CodeValidation)
+			continue
+		}
+		details := apiErr.fieldErrors(t)
+		if len(details) != 1 {
+			t.Errorf("%s: details %+v, want just %+v", tc.name, details, tc.want)
+			continue
+		}
+		if got := details[0]; got.Field != tc.want.Field || got.Rule != tc.want.Rule || got.Param != tc.want.Param || got.Message == "" {
+			t.Errorf("%s: detail %+v, want %+v with a message", tc.name, got, tc.want)
+		}
+	}
+
+	// Nothing was created or counted by the rejected requests.
+	var posts []models.Post
+	if err := ts.DB.Find(&posts).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 1 || posts[0].Score != post.Score {
+		t.Errorf("posts after the rejected requests = %+v, want just %d at %d", posts, post.ID, post.Score)
+	}
+}
+
+func TestStrictBindingTrailingData(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	status, apiErr := postRaw(t, ts, "/api/v1/posts", `{"content":"first"}{"content":"second"}`)
+	if status != http.StatusBadRequest || apiErr.Error.Code != routes.CodeValidation || len(apiErr.fieldErrors(t)) != 0 {
+		t.Errorf("trailing data: status %d, code %q, want 400 %s without details", status, apiErr.Error.Code, routes.CodeValidation)
+	}
+}
+
+func TestStrictBindingTooLarge(t *testing.T) {
+	t.Setenv("MAX_BODY_BYTES", "64")
+	ts := testutil.NewTestServer(t)
+	status, apiErr := postRaw(t, ts, "/api/v1/posts", `{"content":"`+strings.Repeat("a", 100)+`"}`)
+	if status != http.StatusRequestEntityTooLarge || apiErr.Error.Code != routes.CodeTooLarge {
+		t.Errorf("oversized body: status %d, code %q, want 413 %s", status, apiErr.Error.Code, routes.CodeTooLarge)
+	}
+	if status, _ := postRaw(t, ts, "/api/v1/posts", `{"content":"fits"}`); status != http.StatusCreated {
+		t.Errorf("small body: status %d, want 201", status)
+	}
+}
@@ -0,0 +1,190 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/sujalbistaa/whispr/internal/audit"
+	"github.com/sujalbistaa/whispr/internal/db"
+	"github.com/sujalbistaa/whispr/internal/logging"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/ws"
+)
+
+// boardSlug is what a board slug must look like: lowercase words of
+// letters and digits joined by hyphens.
+var boardSlug = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// CreateBoardInput is the body of POST /admin/boards.
+type CreateBoardInput struct {
+	Slug string `json:"slug" binding:"required,min=2,max=64"`
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+// boardCache remembers boards by slug and ID. Boards are never renamed or
+// deleted, so an entry can't go stale; a miss falls through to the
+// database, which picks up boards created on other replicas.
+type boardCache struct {
+	bySlug sync.Map // string -> models.Board
+	byID   sync.Map // uint -> models.Board
+}
+
+func (bc *boardCache) store(board models.Board) {
+	bc.bySlug.Store(board.Slug, board)
+	bc.byID.Store(board.ID, board)
+}
+
+// boardBySlug returns the board named slug, or ErrBoardNotFound.
+func (e *Env) boardBySlug(ctx context.Context, slug string) (models.Board, error) {
+	if board, ok := e.boards.bySlug.Load(slug); ok {
+		return board.(models.Board), nil
+	}
+	var board models.Board
+	err := e.DB.WithContext(ctx).Where("slug = ?", slug).First(&board).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return board, models.ErrBoardNotFound
+	}
+	if err != nil {
+		return board, err
+	}
+	e.boards.store(board)
+	return board, nil
+}
+
+// boardSlugOf returns the slug of board id for WebSocket rooms. Posts
+// always reference an existing board, so a failed lookup is a database
+// error; it is logged and the message goes to every room.
+func (e *Env) boardSlugOf(ctx context.Context, id uint) string {
+	if board, ok := e.boards.byID.Load(id); ok {
+		return board.(models.Board).Slug
+	}
+	var board models.Board
+	if err := e.DB.WithContext(ctx).First(&board, id).Error; err != nil {
+		logging.FromContext(ctx).Warn("looking up board", "board", id, "err", err)
+		return ""
+	}
+	e.boards.store(board)
+	return board.Slug
+}
+
+// requestBoard returns the board a request is scoped to: the :slug route
+// parameter, else the board query parameter, else the default board. An
+// unknown board is answered with 404.
+func (e *Env) requestBoard(c *gin.Context) (models.Board, bool) {
+	slug := c.Param("slug")
+	if slug == "" {
+		slug = c.Query("board")
+	}
+	if slug == "" {
+		slug = models.DefaultBoardSlug
+	}
+	board, err := e.boardBySlug(c.Request.Context(), slug)
+	if err != nil {
+		respondStoreError(c, err, "looking up board", "board.fetch_failed")
+		return board, false
+	}
+	return board, true
+}
+
+// optionalBoard is requestBoard for routes that cover every board unless
+// one is named; the ID is 0 when none is.
+func (e *Env) optionalBoard(c *gin.Context) (models.Board, bool) {
+	if c.Param("slug") == "" && c.Query("board") == "" {
+		return models.Board{}, true
+	}
+	return e.requestBoard(c)
+}
+
+// GetBoards lists every board, by slug.
+func (e *Env) GetBoards(c *gin.Context) {
+	boards := []models.Board{}
+	if err := e.DB.WithContext(c.Request.Context()).Order("slug").Find(&boards).Error; err != nil {
+		requestLogger(c).Error("listing boards", "err", err)
+		respondError(c, ErrInternal("board.fetch_failed"))
+		return
+	}
+	c.JSON(http.StatusOK, boards)
+}
+
+// CreateBoard adds a board.
+func (e *Env) CreateBoard(c *gin.Context) {
+	var input CreateBoardInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, ErrValidation(err))
+		return
+	}
+	if !boardSlug.MatchString(input.Slug) {
+		respondError(c, ErrBadRequest("board.invalid_slug"))
+		return
+	}
+
+	board := models.Board{Slug: input.Slug, Name: strings.TrimSpace(input.Name)}
+	err := e.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&board).Error; err != nil {
+			return err
+		}
+		return audit.Record(tx, adminActor(c), audit.ActionBoardCreate, audit.TargetBoard, board.ID, map[string]any{"slug": board.Slug, "name": board.Name})
+	})
+	if db.IsUniqueViolation(err) {
+		respondError(c, ErrConflict("board.exists"))
+		return
+	}
+	if err != nil {
+		requestLogger(c).Error("creating board", "err", err)
+		respondError(c, ErrInternal("board.create_failed"))
+		return
+	}
+	e.boards.store(board)
+
+	c.JSON(http.StatusCreated, board)
+}
+
+// ServeWS upgrades the request to a WebSocket receiving events for the
+// boards in ?board= (comma-separated or repeated), or the default board.
+// Messages for every board, such as announcements, always arrive. They are
+// JSON unless ?encoding=msgpack asks for MessagePack.
+func (e *Env) ServeWS(c *gin.Context) {
+	encoding, ok := ws.ParseEncoding(c.Query("encoding"))
+	if !ok {
+		respondError(c, ErrBadRequest("ws.invalid_encoding"))
+		return
+	}
+	slugs, ok := e.requestRooms(c)
+	if !ok {
+		return
+	}
+	// WebSockets outlive any write timeout.
+	clearDeadlines(c)
+	e.Hub.ServeRooms(c.Writer, c.Request, slugs, encoding)
+}
+
+// requestRooms returns the boards in ?board= (comma-separated or
+// repeated), or the default board, as WebSocket rooms. An unknown board is
+// answered with 404.
+func (e *Env) requestRooms(c *gin.Context) ([]string, bool) {
+	var slugs []string
+	for _, raw := range c.QueryArray("board") {
+		for _, slug := range strings.Split(raw, ",") {
+			if slug = strings.TrimSpace(slug); slug != "" {
+				slugs = append(slugs, slug)
+			}
+		}
+	}
+	if len(slugs) == 0 {
+		slugs = []string{models.DefaultBoardSlug}
+	}
+	for _, slug := range slugs {
+		if _, err := e.boardBySlug(c.Request.Context(), slug); err != nil {
+			respondStoreError(c, err, "looking up board", "board.fetch_failed")
+			return nil, false
+		}
+	}
+	return slugs, true
+}
@@ -0,0 +1,79 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+const (
+	defaultBookmarkLimit = 20
+	maxBookmarkLimit     = 100
+)
+
+// BookmarkPage is one page of GET /bookmarks.
+type BookmarkPage struct {
+	Posts []models.Post `json:"posts"` // Most recently saved first
+	Page  int           `json:"page"`
+	Limit int           `json:"limit"`
+	Total int64         `json:"total"`
+}
+
+// BookmarkPost saves a visible post for the caller. Saving it twice is
+// not an error.
+func (e *Env) BookmarkPost(c *gin.Context) {
+	postID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, ErrBadRequest("post.invalid_id"))
+		return
+	}
+	if err := e.Bookmarks.Bookmark(c.Request.Context(), e.viewer(c), uint(postID), e.voterHash(c)); err != nil {
+		respondStoreError(c, err, "saving bookmark", "bookmark.save_failed")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// UnbookmarkPost removes a post from the caller's bookmarks. Removing one
+// that isn't there succeeds too.
+func (e *Env) UnbookmarkPost(c *gin.Context) {
+	postID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, ErrBadRequest("post.invalid_id"))
+		return
+	}
+	if err := e.Bookmarks.Unbookmark(c.Request.Context(), uint(postID), e.voterHash(c)); err != nil {
+		requestLogger(c).Error("deleting bookmark", "err", err)
+		respondError(c, ErrInternal("bookmark.delete_failed"))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetBookmarks returns a page of the caller's saved posts. Posts hidden
+// since they were saved are left out.
+func (e *Env) GetBookmarks(c *gin.Context) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		respondError(c, ErrBadRequest("query.invalid_page"))
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultBookmarkLimit)))
+	if err != nil || limit < 1 || limit > maxBookmarkLimit {
+		respondError(c, ErrBadRequest("query.invalid_limit", "max", maxBookmarkLimit))
+		return
+	}
+
+	posts, total, err := e.Bookmarks.Bookmarks(c.Request.Context(), e.viewer(c), e.voterHash(c), (page-1)*limit, limit)
+	if err != nil {
+		requestLogger(c).Error("fetching bookmarks", "err", err)
+		respondError(c, ErrInternal("bookmark.list_failed"))
+		return
+	}
+	// Bookmarks are private to the client, so no shared cache may keep them.
+	c.Header("Cache-Control", "private, no-store")
+	c.JSON(http.StatusOK, BookmarkPage{Posts: posts, Page: page, Limit: limit, Total: total})
+}
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/sujalbistaa/whispr/internal/metrics"
+	"github.com/sujalbistaa/whispr/internal/ws"
+)
+
+// Broadcaster delivers messages to WebSocket clients. Handlers only send
+// through it, so they don't depend on a running hub, and an embedding
+// program can supply its own, e.g. to fan messages out across replicas.
+type Broadcaster interface {
+	Broadcast(msg WsMessage) error
+}
+
+// HubBroadcaster delivers messages to the clients of a local ws.Hub. It
+// never blocks: when the hub's queue is full the message is dropped and
+// counted in whispr_ws_broadcasts_dropped_total.
+type HubBroadcaster struct {
+	Hub *ws.Hub
+}
+
+// Broadcast encodes msg and queues it on the hub for the clients of
+// msg.Board, or all of them when it is empty.
+func (b HubBroadcaster) Broadcast(msg WsMessage) error {
+	jsonMsg, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if err := b.Hub.PublishTo(msg.Board, jsonMsg); err != nil {
+		metrics.WSBroadcastDropped.Inc()
+		return err
+	}
+	return nil
+}
+
+// redisPublishTimeout bounds how long a request waits on Redis to publish
+// a broadcast.
+const redisPublishTimeout = 500 * time.Millisecond
+
+// RedisBroadcaster delivers messages through Local and also publishes
+// them on a Redis channel, from which read-only mirrors relay them to
+// their own clients (see relayBroadcasts).
+type RedisBroadcaster struct {
+	Local   Broadcaster
+	Client  *redis.Client
+	Channel string
+}
+
+// Broadcast delivers msg locally, then publishes it. Either failing is
+// reported, but doesn't stop the other.
+func (b RedisBroadcaster) Broadcast(msg WsMessage) error {
+	localErr := b.Local.Broadcast(msg)
+	jsonMsg, err := json.Marshal(msg)
+	if err != nil {
+		return errors.Join(localErr, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), redisPublishTimeout)
+	defer cancel()
+	return errors.Join(localErr, b.Client.Publish(ctx, b.Channel, jsonMsg).Err())
+}
+
+// relayBroadcasts delivers the messages the primary publishes on channel
+// to the clients of hub until ctx is done, so a read-only mirror's
+// clients still get live updates. go-redis resubscribes after a dropped
+// connection; messages published meanwhile are lost.
+func relayBroadcasts(ctx context.Context, client *redis.Client, channel string, hub *ws.Hub) {
+	sub := client.Subscribe(ctx, channel)
+	defer sub.Close()
+	messages := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case m, ok := <-messages:
+			if !ok {
+				return
+			}
+			var msg WsMessage
+			if err := json.Unmarshal([]byte(m.Payload), &msg); err != nil {
+				slog.Warn("relaying broadcast: malformed message", "channel", channel, "err", err)
+				continue
+			}
+			if err := hub.PublishTo(msg.Board, []byte(m.Payload)); err != nil {
+				metrics.WSBroadcastDropped.Inc()
+			}
+		}
+	}
+}
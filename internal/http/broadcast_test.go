@@ -0,0 +1,94 @@
+package http_test
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	routes "github.com/sujalbistaa/whispr/internal/http"
+	"github.com/sujalbistaa/whispr/internal/metrics"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/server"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+	"github.com/sujalbistaa/whispr/internal/ws"
+)
+
+// onlyMessage fails the test unless exactly one message was broadcast,
+// and returns it.
+func onlyMessage(t *testing.T, sent *testutil.Broadcasts, action string) routes.WsMessage {
+	t.Helper()
+	msgs := sent.Messages()
+	sent.Reset()
+	if len(msgs) != 1 {
+		t.Fatalf("%s broadcast %d messages, want 1: %+v", action, len(msgs), msgs)
+	}
+	return msgs[0]
+}
+
+func TestBroadcasts(t *testing.T) {
+	sent := &testutil.Broadcasts{}
+	ts := testutil.NewTestServer(t, server.WithBroadcaster(sent))
+
+	post := ts.CreatePost(t, "announced to everyone")
+	msg := onlyMessage(t, sent, "creating a post")
+	created, ok := msg.Data.(models.Post)
+	if msg.Type != "new_post" || msg.Board != models.DefaultBoardSlug || !ok || created.ID != post.ID || created.Content != post.Content {
+		t.Errorf("create broadcast %+v, want new_post on %s with post %d", msg, models.DefaultBoardSlug, post.ID)
+	}
+
+	score := ts.Vote(t, post.ID, 1)
+	msg = onlyMessage(t, sent, "voting")
+	if want := (routes.WsMessage{Type: "vote", Data: gin.H{"id": post.ID, "score": score}, Board: models.DefaultBoardSlug}); !reflect.DeepEqual(msg, want) {
+		t.Errorf("vote broadcast %+v, want %+v", msg, want)
+	}
+
+	ts.Hide(t, post.ID)
+	msg = onlyMessage(t, sent, "hiding")
+	if want := (routes.WsMessage{Type: "delete", Data: gin.H{"id": post.ID}, Board: models.DefaultBoardSlug}); !reflect.DeepEqual(msg, want) {
+		t.Errorf("hide broadcast %+v, want %+v", msg, want)
+	}
+	ts.Hide(t, post.ID)
+	if msgs := sent.Messages(); len(msgs) != 0 {
+		t.Errorf("hiding a hidden post broadcast %+v, want nothing", msgs)
+	}
+}
+
+func TestBroadcastsSkipShadowBanned(t *testing.T) {
+	sent := &testutil.Broadcasts{}
+	ts := testutil.NewTestServer(t, server.WithBroadcaster(sent))
+	createBan(t, ts, "10.251.0.1", true)
+	sent.Reset()
+
+	status, _ := ts.Do(t, ts.NewRequestFrom(t, "10.251.0.1", http.MethodPost, "/api/v1/posts", map[string]string{"content": "nobody hears this"}))
+	if status != http.StatusCreated {
+		t.Fatalf("shadow-banned POST: status %d, want 201", status)
+	}
+	if msgs := sent.Messages(); len(msgs) != 0 {
+		t.Errorf("shadow-banned post broadcast %+v, want nothing", msgs)
+	}
+}
+
+// TestHubBroadcasterNeverBlocks sends to a hub that isn't running, so its
+// queue fills up, and checks the overflow is dropped and counted.
+func TestHubBroadcasterNeverBlocks(t *testing.T) {
+	b := routes.HubBroadcaster{Hub: ws.NewHub()}
+	before := counterValue(t, metrics.NameWSDropped, map[string]string{})
+	var dropped int
+	for i := 0; i < 10_000; i++ {
+		if err := b.Broadcast(routes.WsMessage{Type: "vote", Data: gin.H{"id": i}}); err != nil {
+			if !errors.Is(err, ws.ErrBroadcastFull) {
+				t.Fatalf("message %d: %v", i, err)
+			}
+			dropped++
+		}
+	}
+	if dropped == 0 {
+		t.Fatal("no message was dropped; is the hub's queue unbounded?")
+	}
+	if got := counterValue(t, metrics.NameWSDropped, map[string]string{}) - before; got != float64(dropped) {
+		t.Errorf("%s went up by %g, want %d", metrics.NameWSDropped, got, dropped)
+	}
+}
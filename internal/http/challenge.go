@@ -0,0 +1,57 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sujalbistaa/whispr/internal/challenge"
+)
+
+// challengeHeader carries the solution to a challenge with a new post.
+const challengeHeader = "X-Challenge"
+
+// ChallengeMiddleware rejects posts without a valid, unexpired, unused
+// solution to a challenge from GetChallenge. Clients that skip rate
+// limiting skip the challenge too.
+func ChallengeMiddleware(v challenge.Verifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool(rateLimitBypassKey) {
+			c.Next()
+			return
+		}
+		err := v.Verify(c.Request.Context(), c.GetHeader(challengeHeader), clientIP(c))
+		switch {
+		case err == nil:
+			c.Next()
+		case errors.Is(err, challenge.ErrUnavailable):
+			requestLogger(c).Error("verifying challenge", "err", err)
+			respondError(c, ErrUnavailable("challenge.unavailable"))
+		case errors.Is(err, challenge.ErrMissing):
+			respondError(c, ErrChallenge("challenge.required"))
+		case errors.Is(err, challenge.ErrExpired):
+			respondError(c, ErrChallenge("challenge.expired"))
+		default:
+			requestLogger(c).Debug("challenge failed", "err", err)
+			respondError(c, ErrChallenge("challenge.invalid"))
+		}
+	}
+}
+
+// GetChallenge issues a challenge to solve before posting, or reports
+// type "off" when posts don't need one.
+func (e *Env) GetChallenge(c *gin.Context) {
+	if e.Challenge == nil {
+		c.JSON(http.StatusOK, challenge.Challenge{Type: challenge.ModeOff})
+		return
+	}
+	ch, err := e.Challenge.Issue(c.Request.Context())
+	if err != nil {
+		requestLogger(c).Error("issuing challenge", "err", err)
+		respondError(c, ErrUnavailable("challenge.unavailable"))
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, ch)
+}
@@ -0,0 +1,211 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// uncompressedPaths are never compressed: WebSocket upgrades hijack the
+// connection and streaming endpoints must reach the client unbuffered.
+var uncompressedPaths = []string{"/ws"}
+
+var gzipWriters = sync.Pool{
+	New: func() any {
+		w, _ := gzip.NewWriterLevel(nil, gzip.DefaultCompression)
+		return w
+	},
+}
+
+// CompressionMiddleware gzips responses for clients that accept it; a
+// negative minSize disables it (COMPRESSION_MIN_SIZE=off).
+// Bodies are buffered until minSize bytes are written, so small responses
+// go out untouched; a Flush commits early so streamed responses keep
+// flowing. Only textual content types are compressed, and event streams,
+// WebSocket upgrades and uncompressedPaths are skipped entirely.
+func CompressionMiddleware(minSize int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if minSize < 0 || !acceptsGzip(c.GetHeader("Accept-Encoding")) || skipCompression(c.Request) {
+			c.Next()
+			return
+		}
+
+		w := &gzipResponseWriter{ResponseWriter: c.Writer, minSize: minSize}
+		c.Writer = w
+		c.Header("Vary", "Accept-Encoding")
+		defer w.finish()
+		c.Next()
+	}
+}
+
+func skipCompression(r *http.Request) bool {
+	if r.Method == http.MethodHead || r.Header.Get("Upgrade") != "" {
+		return true
+	}
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		return true
+	}
+	for _, path := range uncompressedPaths {
+		if r.URL.Path == path || strings.HasPrefix(r.URL.Path, path+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsGzip reports whether Accept-Encoding allows gzip (or "*").
+func acceptsGzip(header string) bool {
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding != "gzip" && coding != "*" {
+			continue
+		}
+		if q, ok := strings.CutPrefix(strings.ReplaceAll(params, " ", ""), "q="); ok {
+			if v, err := strconv.ParseFloat(q, 64); err == nil && v == 0 {
+				continue
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// compressibleType reports whether a Content-Type benefits from gzip.
+func compressibleType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case mediaType == "text/event-stream":
+		return false
+	case strings.HasPrefix(mediaType, "text/"),
+		mediaType == "application/json",
+		mediaType == "application/javascript",
+		mediaType == "application/xml",
+		mediaType == "image/svg+xml",
+		strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "+xml"):
+		return true
+	}
+	return false
+}
+
+// gzipResponseWriter buffers the start of a body to decide whether it is
+// worth compressing, then either gzips or passes through the rest.
+type gzipResponseWriter struct {
+	gin.ResponseWriter
+	minSize int
+
+	buf     bytes.Buffer
+	gz      *gzip.Writer
+	decided bool
+}
+
+func (w *gzipResponseWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buf.Write(data)
+		if w.buf.Len() < w.minSize {
+			return len(data), nil
+		}
+		if err := w.commit(); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.gz != nil {
+		return w.gz.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *gzipResponseWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Size counts still-buffered bytes too, so code checking c.Writer.Size()
+// sees that a body was started before the compression decision.
+func (w *gzipResponseWriter) Size() int {
+	if !w.decided {
+		if w.buf.Len() == 0 {
+			return w.ResponseWriter.Size()
+		}
+		return w.buf.Len()
+	}
+	return w.ResponseWriter.Size()
+}
+
+func (w *gzipResponseWriter) Written() bool {
+	return w.decided || w.buf.Len() > 0 || w.ResponseWriter.Written()
+}
+
+// Unwrap lets http.ResponseController reach the connection underneath.
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush commits to a decision so streamed responses aren't held back.
+func (w *gzipResponseWriter) Flush() {
+	if !w.decided {
+		if err := w.commit(); err != nil {
+			return
+		}
+	}
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// commit picks gzip or passthrough and writes out the buffered bytes.
+func (w *gzipResponseWriter) commit() error {
+	w.decided = true
+	header := w.Header()
+	status := w.Status()
+	if w.buf.Len() > 0 && header.Get("Content-Encoding") == "" &&
+		status != http.StatusNoContent && status != http.StatusNotModified &&
+		status != http.StatusPartialContent && header.Get("Content-Range") == "" &&
+		compressibleType(header.Get("Content-Type")) {
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+		w.gz = gzipWriters.Get().(*gzip.Writer)
+		w.gz.Reset(w.ResponseWriter)
+	}
+
+	data := w.buf.Bytes()
+	w.buf = bytes.Buffer{}
+	if len(data) == 0 {
+		return nil
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(data)
+	} else {
+		_, err = w.ResponseWriter.Write(data)
+	}
+	return err
+}
+
+// finish sends whatever is still buffered and closes the gzip stream.
+func (w *gzipResponseWriter) finish() {
+	if !w.decided {
+		// Below the threshold: send as-is.
+		w.decided = true
+		if w.buf.Len() > 0 {
+			w.ResponseWriter.Write(w.buf.Bytes())
+		}
+		return
+	}
+	if w.gz != nil {
+		w.gz.Close()
+		gzipWriters.Put(w.gz)
+		w.gz = nil
+	}
+}
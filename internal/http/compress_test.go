@@ -0,0 +1,136 @@
+package http_test
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	routes "github.com/sujalbistaa/whispr/internal/http"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// getGzip fetches path accepting gzip and returns the response with its
+// body decoded as sent.
+func getGzip(t *testing.T, ts *testutil.TestServer, path string) (*http.Response, []byte) {
+	t.Helper()
+	req := ts.NewRequest(t, http.MethodGet, path, nil)
+	// Set by hand, so the client leaves the body compressed.
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body io.Reader = resp.Body
+	if resp.Header.Get("Content-Encoding") == "gzip" {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		body = gz
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("GET %s: reading body: %v", path, err)
+	}
+	return resp, data
+}
+
+func TestCompression(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	post := ts.CreatePost(t, "short")
+
+	// One post is under the 1 KB threshold.
+	resp, body := getGzip(t, ts, fmt.Sprintf("/api/v1/posts/%d", post.ID))
+	if got := resp.Header.Get("Content-Encoding"); got != "" {
+		t.Errorf("small response: Content-Encoding %q, want none", got)
+	}
+	if !json.Valid(body) {
+		t.Errorf("small response isn't JSON: %s", body)
+	}
+
+	for i := 0; i < 20; i++ {
+		seedPost(t, ts, models.Post{Content: fmt.Sprintf("post number %d, padded out so the feed goes over the threshold", i), CreatedAt: time.Now()})
+	}
+	resp, body = getGzip(t, ts, "/api/v1/posts")
+	if got := resp.Header.Get("Content-Encoding"); got != "gzip" {
+		t.Errorf("feed: Content-Encoding %q, want gzip", got)
+	}
+	if got := resp.Header.Get("Vary"); !strings.Contains(got, "Accept-Encoding") {
+		t.Errorf("feed: Vary %q, want Accept-Encoding", got)
+	}
+	var posts []models.Post
+	if err := json.Unmarshal(body, &posts); err != nil || len(posts) != 21 {
+		t.Errorf("feed decompressed to %d posts (%v), want 21", len(posts), err)
+	}
+}
+
+func TestCompressionSkipsWebSocket(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	header := http.Header{"Accept-Encoding": {"gzip"}}
+	conn, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws", header)
+	if err != nil {
+		t.Fatalf("dialing /ws accepting gzip: %v", err)
+	}
+	defer conn.Close()
+	if got := resp.Header.Get("Content-Encoding"); got != "" {
+		t.Errorf("upgrade: Content-Encoding %q, want none", got)
+	}
+}
+
+// TestCompressionSkipsEventStreams serves an event stream big enough to
+// compress and checks the first event reaches the client uncompressed as
+// soon as it is flushed, whether or not the client asked for a stream.
+func TestCompressionSkipsEventStreams(t *testing.T) {
+	for _, accept := range []string{"text/event-stream", "*/*"} {
+		events := make(chan string)
+		engine := gin.New()
+		engine.Use(routes.CompressionMiddleware(16))
+		engine.GET("/events", func(c *gin.Context) {
+			c.Header("Content-Type", "text/event-stream")
+			for event := range events {
+				c.Writer.WriteString("data: " + event + "\n\n")
+				c.Writer.Flush()
+			}
+		})
+		server := httptest.NewServer(engine)
+
+		req, _ := http.NewRequest(http.MethodGet, server.URL+"/events", nil)
+		req.Header.Set("Accept", accept)
+		req.Header.Set("Accept-Encoding", "gzip")
+		done := make(chan *http.Response, 1)
+		go func() {
+			resp, err := server.Client().Do(req)
+			if err != nil {
+				t.Error(err)
+			}
+			done <- resp
+		}()
+		event := strings.Repeat("x", 64)
+		events <- event
+		resp := <-done
+		if resp == nil {
+			t.FailNow()
+		}
+		if got := resp.Header.Get("Content-Encoding"); got != "" {
+			t.Errorf("Accept %s: Content-Encoding %q, want none", accept, got)
+		}
+		line := make([]byte, len("data: ")+len(event))
+		if _, err := io.ReadFull(resp.Body, line); err != nil || string(line) != "data: "+event {
+			t.Errorf("Accept %s: first event %q (%v) didn't arrive as sent", accept, line, err)
+		}
+		close(events)
+		resp.Body.Close()
+		server.Close()
+	}
+}
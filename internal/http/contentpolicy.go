@@ -0,0 +1,27 @@
+package http
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sujalbistaa/whispr/internal/contentpolicy"
+)
+
+// applyContentPolicy rejects content with contact details in it, or
+// replaces them with a placeholder, per CONTENT_POLICY. A post that would
+// be left with nothing but placeholders is rejected either way.
+func (e *Env) applyContentPolicy(c *gin.Context, content string) (string, bool) {
+	action := e.Config.Content.Action
+	redacted, matches := e.Content.Redact(content)
+	if len(matches) == 0 {
+		return content, true
+	}
+	kinds := contentpolicy.KindsOf(matches)
+	requestLogger(c).Info("content policy matched", "action", action, "kinds", kinds)
+	if action == contentpolicy.ActionReject || strings.TrimSpace(strings.ReplaceAll(redacted, contentpolicy.Placeholder, "")) == "" {
+		respondError(c, ErrContentRejected(kinds))
+		return "", false
+	}
+	return redacted, true
+}
@@ -0,0 +1,79 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/sujalbistaa/whispr/internal/audit"
+	"github.com/sujalbistaa/whispr/internal/contentwarning"
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+// SetContentWarningInput is the body of PATCH
+// /admin/posts/:id/content-warning. An empty warning removes it.
+type SetContentWarningInput struct {
+	ContentWarning *string `json:"contentWarning" binding:"required"`
+}
+
+// SetContentWarning adds, changes or removes a post's content warning,
+// hidden posts included, and tells clients showing the post.
+func (e *Env) SetContentWarning(c *gin.Context) {
+	postID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, ErrBadRequest("post.invalid_id"))
+		return
+	}
+	var input SetContentWarningInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, ErrValidation(err))
+		return
+	}
+	warning, ok := contentwarning.Parse(*input.ContentWarning)
+	if !ok {
+		respondError(c, ErrBadRequest("post.invalid_content_warning", "allowed", contentwarning.Names()))
+		return
+	}
+
+	var post models.Post
+	var changed bool
+	err = e.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().First(&post, postID).Error; err != nil {
+			return err
+		}
+		previous := post.ContentWarning
+		if previous == string(warning) {
+			return nil
+		}
+		if err := tx.Unscoped().Model(&post).Update("content_warning", string(warning)).Error; err != nil {
+			return err
+		}
+		post.ContentWarning = string(warning)
+		changed = true
+		return audit.Record(tx, adminActor(c), audit.ActionContentWarning, audit.TargetPost, post.ID, map[string]any{"from": previous, "to": post.ContentWarning})
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, ErrNotFound("post.not_found"))
+			return
+		}
+		requestLogger(c).Error("setting content warning", "post", postID, "err", err)
+		respondError(c, ErrInternal("post.content_warning_failed"))
+		return
+	}
+
+	// Nothing changed, so there is nothing to announce.
+	if !changed {
+		c.JSON(http.StatusOK, post)
+		return
+	}
+	e.invalidateFeeds()
+	if !post.HiddenAt.Valid && !post.ShadowBanned {
+		payload := gin.H{"id": post.ID, "contentWarning": post.ContentWarning}
+		e.broadcastMessage(WsMessage{Type: "content_warning", Data: payload, Board: e.boardSlugOf(c.Request.Context(), post.BoardID)})
+	}
+	c.JSON(http.StatusOK, post)
+}
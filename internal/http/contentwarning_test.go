@@ -0,0 +1,119 @@
+package http_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sujalbistaa/whispr/internal/audit"
+	routes "github.com/sujalbistaa/whispr/internal/http"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+func TestContentWarningOnCreate(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	for _, tc := range []struct {
+		name     string
+		language string
+		input    map[string]string
+		want     string
+	}{
+		{"detected in English", "en", map[string]string{"content": "I keep thinking about suicide"}, "self_harm"},
+		// The keywords match the post, not the author's language.
+		{"detected whatever the Accept-Language", "ne", map[string]string{"content": "was molested on the bus today"}, "assault"},
+		{"detected in Devanagari", "en", map[string]string{"content": "आज फेरि मर्न मन लाग्यो"}, "self_harm"},
+		{"chosen by the author", "en", map[string]string{"content": "a long story about my week", "contentWarning": "eating_disorder"}, "eating_disorder"},
+		{"none", "ne", map[string]string{"content": "the canteen momo is back"}, ""},
+	} {
+		req := ts.NewRequest(t, http.MethodPost, "/api/v1/posts", tc.input)
+		req.Header.Set("Accept-Language", tc.language)
+		status, body := ts.Do(t, req)
+		var post models.Post
+		if status != http.StatusCreated || json.Unmarshal(body, &post) != nil {
+			t.Errorf("%s: status %d: %s", tc.name, status, body)
+			continue
+		}
+		if post.ContentWarning != tc.want {
+			t.Errorf("%s: contentWarning %q, want %q", tc.name, post.ContentWarning, tc.want)
+		}
+	}
+
+	status, code := errorCode(t, ts, ts.NewRequest(t, http.MethodPost, "/api/v1/posts", map[string]string{"content": "bad label", "contentWarning": "spoilers"}))
+	if status != http.StatusBadRequest || code != routes.CodeBadRequest {
+		t.Errorf("unknown contentWarning: %d %s, want 400 %s", status, code, routes.CodeBadRequest)
+	}
+}
+
+func TestSetContentWarning(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	ws := ts.DialWS(t)
+	post := ts.CreatePost(t, "an ordinary post, at first")
+	ws.Expect(t, "new_post", 0)
+	path := fmt.Sprintf("/api/v1/admin/posts/%d/content-warning", post.ID)
+
+	set := func(warning string) (int, models.Post) {
+		t.Helper()
+		status, body := ts.Do(t, ts.AdminRequest(t, http.MethodPatch, path, map[string]string{"contentWarning": warning}))
+		var updated models.Post
+		if status == http.StatusOK {
+			json.Unmarshal(body, &updated)
+		}
+		return status, updated
+	}
+	type broadcast struct {
+		ID             uint   `json:"id"`
+		ContentWarning string `json:"contentWarning"`
+	}
+	expect := func(want string) {
+		t.Helper()
+		var got broadcast
+		if err := json.Unmarshal(ws.Expect(t, "content_warning", 0).Data, &got); err != nil || got != (broadcast{post.ID, want}) {
+			t.Errorf("content_warning message %+v (%v), want %q on post %d", got, err, want, post.ID)
+		}
+	}
+
+	if status, updated := set("substances"); status != http.StatusOK || updated.ContentWarning != "substances" {
+		t.Fatalf("adding a warning: %d %+v", status, updated)
+	}
+	expect("substances")
+	var stored models.Post
+	ts.DB.First(&stored, post.ID)
+	if stored.ContentWarning != "substances" {
+		t.Errorf("stored contentWarning %q, want substances", stored.ContentWarning)
+	}
+
+	// Setting the same warning again changes and announces nothing.
+	if status, _ := set("substances"); status != http.StatusOK {
+		t.Errorf("setting the same warning: status %d", status)
+	}
+	ws.ExpectNone(t, "content_warning", 200*time.Millisecond)
+	if status, updated := set(""); status != http.StatusOK || updated.ContentWarning != "" {
+		t.Fatalf("removing the warning: %d %+v", status, updated)
+	}
+	expect("")
+
+	var entries []models.AuditLog
+	ts.DB.Where("action = ? AND target_id = ?", audit.ActionContentWarning, post.ID).Order("id").Find(&entries)
+	if len(entries) != 2 || entries[0].Metadata["to"] != "substances" || entries[1].Metadata["from"] != "substances" || entries[1].Metadata["to"] != "" {
+		t.Errorf("audit entries %+v, want one for adding and one for removing", entries)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		req    *http.Request
+		status int
+		code   string
+	}{
+		{"unknown warning", ts.AdminRequest(t, http.MethodPatch, path, map[string]string{"contentWarning": "spoilers"}), http.StatusBadRequest, routes.CodeBadRequest},
+		{"missing field", ts.AdminRequest(t, http.MethodPatch, path, map[string]string{}), http.StatusBadRequest, routes.CodeValidation},
+		{"missing post", ts.AdminRequest(t, http.MethodPatch, "/api/v1/admin/posts/9999/content-warning", map[string]string{"contentWarning": "assault"}), http.StatusNotFound, routes.CodeNotFound},
+		{"not an admin", ts.NewRequest(t, http.MethodPatch, path, map[string]string{"contentWarning": "assault"}), http.StatusUnauthorized, routes.CodeUnauthorized},
+	} {
+		if status, code := errorCode(t, ts, tc.req); status != tc.status || code != tc.code {
+			t.Errorf("%s: %d %s, want %d %s", tc.name, status, code, tc.status, tc.code)
+		}
+	}
+}
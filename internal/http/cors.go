@@ -0,0 +1,28 @@
+package http
+
+import (
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+
+	"github.com/sujalbistaa/whispr/internal/config"
+)
+
+// CORSMiddleware builds the CORS handler for policy. Disallowed origins get
+// a bare 403.
+func CORSMiddleware(policy config.CORS) gin.HandlerFunc {
+	corsConfig := cors.Config{
+		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Admin-Token", "X-Request-ID", challengeHeader},
+		ExposeHeaders:    []string{"Content-Length", "ETag", "X-Total-Count", "X-Request-ID", "X-Trace-ID", "X-Post-Quota-Limit", "X-Post-Quota-Remaining", "Deprecation", "Sunset", "Link"},
+		AllowCredentials: true,
+	}
+	if policy.AnyOrigin {
+		// Browsers reject "Access-Control-Allow-Origin: *" on credentialed
+		// requests, so echo the caller's origin instead.
+		corsConfig.AllowOriginFunc = func(string) bool { return true }
+	} else {
+		corsConfig.AllowOrigins = policy.Origins
+		corsConfig.AllowWildcard = true
+	}
+	return cors.New(corsConfig)
+}
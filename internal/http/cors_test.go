@@ -0,0 +1,72 @@
+package http_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// preflight sends a CORS preflight for a POST from origin.
+func preflight(t *testing.T, ts *testutil.TestServer, origin string) *http.Response {
+	t.Helper()
+	req := ts.NewRequest(t, http.MethodOptions, "/api/v1/posts", nil)
+	req.Header.Set("Origin", origin)
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "Content-Type")
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestCORSPreflight(t *testing.T) {
+	t.Setenv("CORS_ORIGIN", "https://whispr.example.edu, https://*.netlify.app")
+	ts := testutil.NewTestServer(t)
+
+	for _, origin := range []string{
+		"https://whispr.example.edu",
+		"https://deploy-preview-42--whispr.netlify.app",
+	} {
+		resp := preflight(t, ts, origin)
+		if resp.StatusCode != http.StatusNoContent {
+			t.Errorf("preflight from %s: status %d, want 204", origin, resp.StatusCode)
+		}
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != origin {
+			t.Errorf("preflight from %s: Allow-Origin %q", origin, got)
+		}
+		if got := resp.Header.Get("Access-Control-Allow-Credentials"); got != "true" {
+			t.Errorf("preflight from %s: Allow-Credentials %q, want true", origin, got)
+		}
+	}
+
+	for _, origin := range []string{
+		"https://evil.example.com",
+		"http://whispr.example.edu",
+		"https://netlify.app.evil.example.com",
+	} {
+		resp := preflight(t, ts, origin)
+		if resp.StatusCode != http.StatusForbidden {
+			t.Errorf("preflight from %s: status %d, want 403", origin, resp.StatusCode)
+		}
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("preflight from %s: Allow-Origin %q, want none", origin, got)
+		}
+	}
+}
+
+// TestCORSAnyOrigin checks the dev default echoes the origin rather than
+// sending "*", which browsers reject alongside credentials.
+func TestCORSAnyOrigin(t *testing.T) {
+	t.Setenv("CORS_ORIGIN", "*")
+	ts := testutil.NewTestServer(t)
+	resp := preflight(t, ts, "http://localhost:5173")
+	if resp.StatusCode != http.StatusNoContent {
+		t.Errorf("status %d, want 204", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "http://localhost:5173" {
+		t.Errorf("Allow-Origin %q, want the request origin", got)
+	}
+}
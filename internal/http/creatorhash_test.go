@@ -0,0 +1,69 @@
+package http_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	routes "github.com/sujalbistaa/whispr/internal/http"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// TestCreatorHashOnlyForAdmins checks that with CREATOR_HASH on the hash
+// is stored but reaches moderators alone: neither the key nor the value
+// is in anything an ordinary client gets.
+func TestCreatorHashOnlyForAdmins(t *testing.T) {
+	t.Setenv("CREATOR_HASH", "true")
+	t.Setenv("CREATOR_HASH_SALT", "a-salt-for-creator-hashes")
+	ts := testutil.NewTestServer(t)
+	ws := ts.DialWS(t)
+
+	status, created := ts.Do(t, ts.NewRequest(t, http.MethodPost, "/api/v1/posts", map[string]string{"content": "whose post is this?"}))
+	if status != http.StatusCreated {
+		t.Fatalf("POST /api/v1/posts: status %d: %s", status, created)
+	}
+	var post models.Post
+	if err := json.Unmarshal(created, &post); err != nil {
+		t.Fatal(err)
+	}
+	var stored models.Post
+	if err := ts.DB.First(&stored, post.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.CreatorHash == nil || *stored.CreatorHash == "" {
+		t.Fatal("no creator hash stored with CREATOR_HASH on")
+	}
+	hash := *stored.CreatorHash
+
+	public := map[string][]byte{"POST /api/v1/posts": created}
+	for _, path := range []string{"/api/v1/posts", fmt.Sprintf("/api/v1/posts/%d", post.ID)} {
+		status, body := ts.Do(t, ts.NewRequest(t, http.MethodGet, path, nil))
+		if status != http.StatusOK {
+			t.Fatalf("GET %s: status %d", path, status)
+		}
+		public["GET "+path] = body
+	}
+	msg := ws.Expect(t, "new_post", 5*time.Second)
+	public["WS new_post"] = msg.Data
+	for name, body := range public {
+		if bytes.Contains(body, []byte("creatorHash")) || bytes.Contains(body, []byte(hash)) {
+			t.Errorf("%s gives away the creator hash: %s", name, body)
+		}
+	}
+
+	status, body := ts.Do(t, ts.AdminRequest(t, http.MethodGet, "/api/v1/admin/posts?creatorHash="+hash, nil))
+	if status != http.StatusOK {
+		t.Fatalf("GET /api/v1/admin/posts: status %d: %s", status, body)
+	}
+	var page routes.AdminPostPage
+	if err := json.Unmarshal(body, &page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Posts) != 1 || page.Posts[0].ID != post.ID || page.Posts[0].CreatorHash == nil || *page.Posts[0].CreatorHash != hash {
+		t.Errorf("admin posts = %s, want post %d with creatorHash %s", body, post.ID, hash)
+	}
+}
@@ -0,0 +1,159 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sujalbistaa/whispr/internal/config"
+)
+
+// cspReportsLogged caps the violations logged per report request, since
+// a single Reporting API batch can carry any number.
+const cspReportsLogged = 10
+
+// securityHeader is one header SecurityHeadersMiddleware sends.
+type securityHeader struct{ name, value string }
+
+// securityHeaders builds the headers policy describes, in the order they
+// are sent. HSTS is only included when tls is set.
+func securityHeaders(policy config.SecurityHeaders, tls bool) []securityHeader {
+	csp := "Content-Security-Policy"
+	if policy.ReportOnly {
+		csp += "-Report-Only"
+	}
+	headers := []securityHeader{
+		{"X-Frame-Options", "DENY"},
+		{"X-Content-Type-Options", "nosniff"},
+		{csp, contentSecurityPolicy(policy)},
+	}
+	if tls && policy.HSTSMaxAge > 0 {
+		headers = append(headers, securityHeader{"Strict-Transport-Security", "max-age=" + strconv.FormatInt(int64(policy.HSTSMaxAge/time.Second), 10)})
+	}
+	if policy.ReferrerPolicy != "" {
+		headers = append(headers, securityHeader{"Referrer-Policy", policy.ReferrerPolicy})
+	}
+	if policy.PermissionsPolicy != "" {
+		headers = append(headers, securityHeader{"Permissions-Policy", policy.PermissionsPolicy})
+	}
+	return headers
+}
+
+// contentSecurityPolicy assembles the CSP from policy's sources. A
+// directive without sources is left out, so it falls back to
+// default-src.
+func contentSecurityPolicy(policy config.SecurityHeaders) string {
+	directives := []string{"default-src 'self'"}
+	for _, directive := range []struct {
+		name    string
+		sources []string
+	}{
+		{"script-src", policy.ScriptSrc},
+		{"style-src", policy.StyleSrc},
+		{"connect-src", policy.ConnectSrc},
+	} {
+		if len(directive.sources) > 0 {
+			directives = append(directives, directive.name+" "+strings.Join(directive.sources, " "))
+		}
+	}
+	if policy.ReportURI != "" {
+		directives = append(directives, "report-uri "+policy.ReportURI)
+	}
+	return strings.Join(directives, "; ") + ";"
+}
+
+// cspViolation is the part of a CSP violation report worth logging.
+type cspViolation struct {
+	Document, Blocked, Directive, Disposition, Source string
+	Line                                              int
+}
+
+// cspReportURI is a violation as report-uri sends it, in kebab case.
+type cspReportURI struct {
+	DocumentURI        string `json:"document-uri"`
+	BlockedURI         string `json:"blocked-uri"`
+	ViolatedDirective  string `json:"violated-directive"`
+	EffectiveDirective string `json:"effective-directive"`
+	Disposition        string `json:"disposition"`
+	SourceFile         string `json:"source-file"`
+	LineNumber         int    `json:"line-number"`
+}
+
+// cspReportTo is a violation as the Reporting API sends it, in camel
+// case.
+type cspReportTo struct {
+	DocumentURL        string `json:"documentURL"`
+	BlockedURL         string `json:"blockedURL"`
+	EffectiveDirective string `json:"effectiveDirective"`
+	Disposition        string `json:"disposition"`
+	SourceFile         string `json:"sourceFile"`
+	LineNumber         int    `json:"lineNumber"`
+}
+
+// parseCSPReports reads a report-uri body ({"csp-report": {...}}) or a
+// Reporting API batch ([{"type": "csp-violation", "body": {...}}]),
+// skipping reports of other types.
+func parseCSPReports(body []byte) ([]cspViolation, error) {
+	var single struct {
+		Report *cspReportURI `json:"csp-report"`
+	}
+	if err := json.Unmarshal(body, &single); err == nil {
+		if single.Report == nil {
+			return nil, errors.New(`missing "csp-report"`)
+		}
+		r := single.Report
+		directive := r.EffectiveDirective
+		if directive == "" {
+			directive = r.ViolatedDirective
+		}
+		return []cspViolation{{r.DocumentURI, r.BlockedURI, directive, r.Disposition, r.SourceFile, r.LineNumber}}, nil
+	}
+	var batch []struct {
+		Type string      `json:"type"`
+		Body cspReportTo `json:"body"`
+	}
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return nil, err
+	}
+	violations := make([]cspViolation, 0, len(batch))
+	for _, report := range batch {
+		if report.Type != "csp-violation" {
+			continue
+		}
+		r := report.Body
+		violations = append(violations, cspViolation{r.DocumentURL, r.BlockedURL, r.EffectiveDirective, r.Disposition, r.SourceFile, r.LineNumber})
+	}
+	return violations, nil
+}
+
+// ReportCSP logs the CSP violations browsers send to CSP_REPORT_URI and
+// answers 204. Reports are only logged, not stored, so mirrors accept
+// them too.
+func (e *Env) ReportCSP(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		respondError(c, ErrValidation(err))
+		return
+	}
+	violations, err := parseCSPReports(body)
+	if err != nil {
+		respondError(c, ErrBadRequest("csp.invalid_report"))
+		return
+	}
+	log := requestLogger(c)
+	for i, v := range violations {
+		if i == cspReportsLogged {
+			log.Warn("CSP violations not logged", "count", len(violations)-i)
+			break
+		}
+		log.Warn("CSP violation", "document", v.Document, "blocked", v.Blocked, "directive", v.Directive,
+			"disposition", v.Disposition, "source", v.Source, "line", v.Line)
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,230 @@
+package http_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sujalbistaa/whispr/internal/config"
+	routes "github.com/sujalbistaa/whispr/internal/http"
+	"github.com/sujalbistaa/whispr/internal/server"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// securityHeaderNames are the headers SecurityHeadersMiddleware may send.
+var securityHeaderNames = []string{
+	"X-Frame-Options", "X-Content-Type-Options", "Content-Security-Policy", "Content-Security-Policy-Report-Only",
+	"Strict-Transport-Security", "Referrer-Policy", "Permissions-Policy",
+}
+
+// fakeTLS points TLS_CERT_FILE and TLS_KEY_FILE at empty files, which is
+// enough for config.Load to treat TLS as on.
+func fakeTLS(t *testing.T) {
+	dir := t.TempDir()
+	for key, name := range map[string]string{"TLS_CERT_FILE": "cert.pem", "TLS_KEY_FILE": "key.pem"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, nil, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		t.Setenv(key, path)
+	}
+}
+
+// TestSecurityHeaders loads a few configurations and checks the exact
+// headers each sends; a missing entry means the header isn't sent.
+func TestSecurityHeaders(t *testing.T) {
+	const defaultCSP = "default-src 'self'; script-src 'self' 'unsafe-inline' 'unsafe-eval' cdn.jsdelivr.net cdn.tailwindcss.com; " +
+		"style-src 'self' 'unsafe-inline' cdn.tailwindcss.com; connect-src 'self' ws: wss:;"
+	always := map[string]string{"X-Frame-Options": "DENY", "X-Content-Type-Options": "nosniff"}
+	for _, tc := range []struct {
+		name string
+		env  map[string]string
+		tls  bool
+		want map[string]string
+	}{
+		{
+			name: "defaults",
+			want: map[string]string{
+				"Content-Security-Policy": defaultCSP,
+				"Referrer-Policy":         "strict-origin-when-cross-origin",
+				"Permissions-Policy":      "camera=(), microphone=(), geolocation=()",
+			},
+		},
+		{
+			name: "self-hosted",
+			env: map[string]string{
+				"CSP_SCRIPT_SRC":     "'self'",
+				"CSP_STYLE_SRC":      "'self', https://assets.example.edu",
+				"CSP_CONNECT_SRC":    "'self' wss://whispr.example.edu",
+				"CSP_REPORT_URI":     "https://reports.example.edu/csp",
+				"REFERRER_POLICY":    "no-referrer",
+				"PERMISSIONS_POLICY": "off",
+			},
+			want: map[string]string{
+				"Content-Security-Policy": "default-src 'self'; script-src 'self'; style-src 'self' https://assets.example.edu; " +
+					"connect-src 'self' wss://whispr.example.edu; report-uri https://reports.example.edu/csp;",
+				"Referrer-Policy": "no-referrer",
+			},
+		},
+		{
+			name: "report-only",
+			env:  map[string]string{"CSP_REPORT_ONLY": "true", "REFERRER_POLICY": "off"},
+			want: map[string]string{
+				"Content-Security-Policy-Report-Only": strings.TrimSuffix(defaultCSP, ";") + "; report-uri /api/v1/csp-report;",
+				"Permissions-Policy":                  "camera=(), microphone=(), geolocation=()",
+			},
+		},
+		{
+			name: "report-only elsewhere",
+			env:  map[string]string{"CSP_REPORT_ONLY": "true", "CSP_REPORT_URI": "/csp/reports", "CSP_SCRIPT_SRC": "'self'", "CSP_STYLE_SRC": "'self'", "CSP_CONNECT_SRC": "'self'"},
+			want: map[string]string{
+				"Content-Security-Policy-Report-Only": "default-src 'self'; script-src 'self'; style-src 'self'; connect-src 'self'; report-uri /csp/reports;",
+				"Referrer-Policy":                     "strict-origin-when-cross-origin",
+				"Permissions-Policy":                  "camera=(), microphone=(), geolocation=()",
+			},
+		},
+		{
+			name: "hsts",
+			env:  map[string]string{"HSTS_MAX_AGE": "8760h"},
+			tls:  true,
+			want: map[string]string{
+				"Content-Security-Policy":   defaultCSP,
+				"Strict-Transport-Security": "max-age=31536000",
+				"Referrer-Policy":           "strict-origin-when-cross-origin",
+				"Permissions-Policy":        "camera=(), microphone=(), geolocation=()",
+			},
+		},
+		{
+			name: "tls without hsts",
+			tls:  true,
+			want: map[string]string{
+				"Content-Security-Policy": defaultCSP,
+				"Referrer-Policy":         "strict-origin-when-cross-origin",
+				"Permissions-Policy":      "camera=(), microphone=(), geolocation=()",
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for key, value := range tc.env {
+				t.Setenv(key, value)
+			}
+			if tc.tls {
+				fakeTLS(t)
+			}
+			cfg, err := config.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			engine := gin.New()
+			engine.Use(routes.SecurityHeadersMiddleware(cfg.Headers, cfg.TLS.Enabled()))
+			engine.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			for _, name := range securityHeaderNames {
+				want, ok := always[name]
+				if !ok {
+					want = tc.want[name]
+				}
+				if got := w.Header().Get(name); got != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+// TestCSPReport posts both report formats to the collector and checks
+// what it logs.
+func TestCSPReport(t *testing.T) {
+	t.Setenv("CSP_REPORT_ONLY", "true")
+	logs := &logLines{}
+	ts := testutil.NewTestServer(t, server.WithLogger(logs.logger()))
+
+	report := func(contentType, body string) int {
+		req := ts.NewRequest(t, http.MethodPost, "/api/v1/csp-report", []byte(body))
+		req.Header.Set("Content-Type", contentType)
+		status, _ := ts.Do(t, req)
+		return status
+	}
+
+	// The server's own policy reports here.
+	req := ts.NewRequest(t, http.MethodGet, "/api/v1/posts", nil)
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if csp := resp.Header.Get("Content-Security-Policy-Report-Only"); !strings.HasSuffix(csp, "; report-uri /api/v1/csp-report;") {
+		t.Fatalf("Content-Security-Policy-Report-Only = %q, want a report-uri to the collector", csp)
+	}
+
+	status := report("application/csp-report", `{"csp-report": {
+		"document-uri": "https://whispr.example.edu/",
+		"blocked-uri": "https://evil.example.com/x.js",
+		"violated-directive": "script-src-elem",
+		"effective-directive": "script-src",
+		"disposition": "report",
+		"source-file": "https://whispr.example.edu/app.js",
+		"line-number": 12
+	}}`)
+	if status != http.StatusNoContent {
+		t.Fatalf("report-uri report: status %d, want 204", status)
+	}
+	lines := logs.withMsg(t, "CSP violation")
+	if len(lines) != 1 {
+		t.Fatalf("%d violations logged, want 1", len(lines))
+	}
+	for key, want := range map[string]any{
+		"document":    "https://whispr.example.edu/",
+		"blocked":     "https://evil.example.com/x.js",
+		"directive":   "script-src",
+		"disposition": "report",
+		"source":      "https://whispr.example.edu/app.js",
+		"line":        float64(12),
+		"level":       "WARN",
+	} {
+		if lines[0][key] != want {
+			t.Errorf("logged %s = %v, want %v", key, lines[0][key], want)
+		}
+	}
+
+	// A Reporting API batch of 12 violations and another report type logs
+	// the first ten and counts the rest.
+	var batch []string
+	for i := 0; i < 12; i++ {
+		batch = append(batch, fmt.Sprintf(`{"type": "csp-violation", "body": {"documentURL": "https://whispr.example.edu/", "blockedURL": "inline", "effectiveDirective": "style-src", "disposition": "enforce", "lineNumber": %d}}`, i+1))
+	}
+	batch = append(batch, `{"type": "deprecation", "body": {"id": "old-api"}}`)
+	if status := report("application/reports+json", "["+strings.Join(batch, ",")+"]"); status != http.StatusNoContent {
+		t.Fatalf("Reporting API batch: status %d, want 204", status)
+	}
+	lines = logs.withMsg(t, "CSP violation")
+	if len(lines) != 11 {
+		t.Fatalf("%d violations logged, want 11", len(lines))
+	}
+	if last := lines[10]; last["directive"] != "style-src" || last["blocked"] != "inline" || last["line"] != float64(10) {
+		t.Errorf("last batch violation logged as %v", last)
+	}
+	skipped := logs.withMsg(t, "CSP violations not logged")
+	if len(skipped) != 1 || skipped[0]["count"] != float64(2) {
+		t.Errorf("skipped violations logged as %v, want a count of 2", skipped)
+	}
+
+	for _, body := range []string{`not json`, `{"report": {}}`, `"csp-report"`} {
+		req := ts.NewRequest(t, http.MethodPost, "/api/v1/csp-report", []byte(body))
+		req.Header.Set("Content-Type", "application/csp-report")
+		if status, code := errorCode(t, ts, req); status != http.StatusBadRequest || code != "bad_request" {
+			t.Errorf("report %s: %d %q, want 400 bad_request", body, status, code)
+		}
+	}
+	if lines := logs.withMsg(t, "CSP violation"); len(lines) != 11 {
+		t.Errorf("%d violations logged after bad reports, want still 11", len(lines))
+	}
+}
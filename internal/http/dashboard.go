@@ -0,0 +1,143 @@
+package http
+
+import (
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/sujalbistaa/whispr/internal/audit"
+	"github.com/sujalbistaa/whispr/internal/auth"
+	"github.com/sujalbistaa/whispr/internal/metrics"
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+const (
+	// dashboardTTL is how long GET /admin/dashboard reuses its queries, so
+	// a dashboard left open by several moderators costs the database six
+	// queries per interval.
+	dashboardTTL = 15 * time.Second
+	// Items listed per dashboard section; totals are counted in full.
+	dashboardHeld   = 10
+	dashboardRising = 5
+	dashboardShadow = 10
+)
+
+// PostSection is a dashboard section: how many posts match, and the newest
+// of them.
+type PostSection struct {
+	Total int64         `json:"total"`
+	Posts []models.Post `json:"posts"`
+}
+
+// RisingPost is a post with the votes it received in the last hour.
+type RisingPost struct {
+	models.Post
+	RecentVotes int64 `json:"recentVotes"`
+}
+
+// Dashboard is the response to GET /admin/dashboard: the moderation
+// signals worth checking first, in one request. Sections the caller's role
+// can't see are left out.
+type Dashboard struct {
+	AutoHidden          PostSection  `json:"autoHidden"`   // Held by the spam filter in the last 24 hours and not reviewed yet
+	Rising              []RisingPost `json:"rising"`       // Most votes in the last hour
+	ShadowBanned        PostSection  `json:"shadowBanned"` // Quarantined by shadow bans and still up
+	WSConnections       int          `json:"wsConnections"`
+	RateLimitRejections *uint64      `json:"rateLimitRejections,omitempty"` // In the last hour, on this instance; admins only
+	AsOf                time.Time    `json:"asOf"`                          // When the post sections were queried
+}
+
+// dashboardCache holds the last post sections. Like publicStatsCache, the
+// lock is held while they are refreshed.
+type dashboardCache struct {
+	mu        sync.Mutex
+	dashboard Dashboard
+}
+
+// GetDashboard summarizes what needs a moderator's attention. The post
+// sections are cached for dashboardTTL; connection and rejection counts
+// are live.
+func (e *Env) GetDashboard(c *gin.Context) {
+	e.dashboard.mu.Lock()
+	dashboard := e.dashboard.dashboard
+	if time.Since(dashboard.AsOf) > dashboardTTL {
+		fresh, err := e.queryDashboard(c)
+		if err != nil {
+			e.dashboard.mu.Unlock()
+			requestLogger(c).Error("building dashboard", "err", err)
+			respondError(c, ErrInternal("dashboard.fetch_failed"))
+			return
+		}
+		e.dashboard.dashboard, dashboard = fresh, fresh
+	}
+	e.dashboard.mu.Unlock()
+
+	dashboard.WSConnections = e.Hub.ClientCount()
+	role, _ := c.Get(adminRoleKey)
+	if r, ok := role.(auth.Role); ok && r.Allows(auth.RoleAdmin) {
+		rejected := metrics.RateLimitedLastHour()
+		dashboard.RateLimitRejections = &rejected
+	}
+	c.Header("Cache-Control", "private, no-store")
+	c.JSON(http.StatusOK, dashboard)
+}
+
+func (e *Env) queryDashboard(c *gin.Context) (Dashboard, error) {
+	db := e.DB.WithContext(c.Request.Context())
+	now := time.Now()
+	dashboard := Dashboard{AsOf: now, Rising: []RisingPost{}}
+
+	held := db.Unscoped().Model(&models.Post{}).Where("hidden_by = ? AND hidden_at >= ?", audit.ActorSpamFilter, now.Add(-24*time.Hour))
+	if err := postSection(held, dashboardHeld, &dashboard.AutoHidden); err != nil {
+		return dashboard, err
+	}
+	shadow := db.Model(&models.Post{}).Where("shadow_banned = ?", true)
+	if err := postSection(shadow, dashboardShadow, &dashboard.ShadowBanned); err != nil {
+		return dashboard, err
+	}
+
+	var rising []struct {
+		PostID uint
+		Recent int64
+	}
+	err := db.Model(&models.Vote{}).Select("votes.post_id, COUNT(*) AS recent").
+		Joins("JOIN posts ON posts.id = votes.post_id AND posts.hidden_at IS NULL AND posts.shadow_banned = ?", false).
+		Where("votes.created_at >= ?", now.Add(-time.Hour)).
+		Group("votes.post_id").Order("recent desc, votes.post_id desc").Limit(dashboardRising).
+		Scan(&rising).Error
+	if err != nil || len(rising) == 0 {
+		return dashboard, err
+	}
+	ids := make([]uint, len(rising))
+	for i, row := range rising {
+		ids[i] = row.PostID
+	}
+	var posts []models.Post
+	if err := db.Where("id IN ?", ids).Find(&posts).Error; err != nil {
+		return dashboard, err
+	}
+	byID := make(map[uint]models.Post, len(posts))
+	for _, post := range posts {
+		byID[post.ID] = post
+	}
+	for _, row := range rising {
+		// A post hidden between the two queries is left out.
+		if post, ok := byID[row.PostID]; ok {
+			dashboard.Rising = append(dashboard.Rising, RisingPost{Post: post, RecentVotes: row.Recent})
+		}
+	}
+	return dashboard, nil
+}
+
+// postSection fills section with the number of posts query matches and the
+// newest limit of them.
+func postSection(query *gorm.DB, limit int, section *PostSection) error {
+	if err := query.Session(&gorm.Session{}).Count(&section.Total).Error; err != nil {
+		return err
+	}
+	section.Posts = []models.Post{}
+	return query.Session(&gorm.Session{}).Order("created_at desc").Limit(limit).Find(&section.Posts).Error
+}
@@ -0,0 +1,114 @@
+package http
+
+import (
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// processStart approximates when the server started, for uptime.
+var processStart = time.Now()
+
+// recentGCPauses is how many of the latest GC pauses GetRuntime reports.
+const recentGCPauses = 16
+
+// registerPprof mounts the net/http/pprof handlers on group, which must
+// already require authentication. The handlers are wired explicitly rather
+// than through the package's DefaultServeMux registration, which nothing
+// here serves.
+func registerPprof(group *gin.RouterGroup) {
+	// CPU profiles and traces stream for ?seconds=N, longer than the write
+	// timeout allows.
+	group.Use(func(c *gin.Context) {
+		clearDeadlines(c)
+		c.Next()
+	})
+	group.GET("/", gin.WrapF(pprof.Index))
+	group.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	group.GET("/profile", gin.WrapF(pprof.Profile))
+	group.GET("/symbol", gin.WrapF(pprof.Symbol))
+	group.POST("/symbol", gin.WrapF(pprof.Symbol))
+	group.GET("/trace", gin.WrapF(pprof.Trace))
+	// heap, goroutine, allocs, block, mutex and threadcreate
+	group.GET("/:profile", gin.WrapF(pprof.Index))
+}
+
+// RuntimeStats is a snapshot of the Go runtime for GetRuntime.
+type RuntimeStats struct {
+	StartedAt     time.Time `json:"startedAt"`
+	UptimeSeconds int64     `json:"uptimeSeconds"`
+	GoVersion     string    `json:"goVersion"`
+	Goroutines    int       `json:"goroutines"`
+	CPUs          int       `json:"cpus"`
+	GOMAXPROCS    int       `json:"gomaxprocs"`
+	Heap          HeapStats `json:"heap"`
+	GC            GCStats   `json:"gc"`
+}
+
+// HeapStats are in bytes, except Objects.
+type HeapStats struct {
+	Alloc      uint64 `json:"alloc"`
+	InUse      uint64 `json:"inUse"`
+	Idle       uint64 `json:"idle"`
+	Released   uint64 `json:"released"`
+	Sys        uint64 `json:"sys"` // Obtained from the OS for everything, not just the heap
+	Objects    uint64 `json:"objects"`
+	TotalAlloc uint64 `json:"totalAlloc"`
+}
+
+// GCStats summarizes garbage collection; pauses are in milliseconds.
+type GCStats struct {
+	Cycles         uint32     `json:"cycles"`
+	NextTarget     uint64     `json:"nextTarget"` // Heap size that triggers the next cycle
+	LastRun        *time.Time `json:"lastRun"`
+	PauseTotalMS   float64    `json:"pauseTotalMs"`
+	RecentPausesMS []float64  `json:"recentPausesMs"` // Newest first
+}
+
+// GetRuntime reports goroutine, heap and GC statistics, to tell whether
+// memory growth is worth a heap profile.
+func (e *Env) GetRuntime(c *gin.Context) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := RuntimeStats{
+		StartedAt:     processStart.UTC(),
+		UptimeSeconds: int64(time.Since(processStart).Seconds()),
+		GoVersion:     runtime.Version(),
+		Goroutines:    runtime.NumGoroutine(),
+		CPUs:          runtime.NumCPU(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+		Heap: HeapStats{
+			Alloc:      mem.HeapAlloc,
+			InUse:      mem.HeapInuse,
+			Idle:       mem.HeapIdle,
+			Released:   mem.HeapReleased,
+			Sys:        mem.Sys,
+			Objects:    mem.HeapObjects,
+			TotalAlloc: mem.TotalAlloc,
+		},
+		GC: GCStats{
+			Cycles:         mem.NumGC,
+			NextTarget:     mem.NextGC,
+			PauseTotalMS:   nsToMS(mem.PauseTotalNs),
+			RecentPausesMS: []float64{},
+		},
+	}
+	if mem.LastGC > 0 {
+		last := time.Unix(0, int64(mem.LastGC)).UTC()
+		stats.GC.LastRun = &last
+	}
+	// PauseNs is a circular buffer; the latest pause is at (NumGC+255)%256.
+	for i := uint32(0); i < min(mem.NumGC, recentGCPauses); i++ {
+		stats.GC.RecentPausesMS = append(stats.GC.RecentPausesMS, nsToMS(mem.PauseNs[(mem.NumGC-1-i)%uint32(len(mem.PauseNs))]))
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+func nsToMS(ns uint64) float64 {
+	return float64(ns) / float64(time.Millisecond)
+}
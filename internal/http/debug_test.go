@@ -0,0 +1,54 @@
+package http_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// pprofProfiles are the names the /debug/pprof/:profile route serves.
+var pprofProfiles = []string{"heap", "goroutine", "allocs", "block", "mutex", "threadcreate"}
+
+func TestPprofRequiresAdminToken(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+
+	var paths []string
+	for _, route := range ts.App.Handler().(*gin.Engine).Routes() {
+		if !strings.HasPrefix(route.Path, "/debug/pprof") {
+			continue
+		}
+		if prefix, ok := strings.CutSuffix(route.Path, ":profile"); ok {
+			for _, profile := range pprofProfiles {
+				paths = append(paths, route.Method+" "+prefix+profile)
+			}
+			continue
+		}
+		paths = append(paths, route.Method+" "+route.Path)
+	}
+	paths = append(paths, "GET /api/v1/admin/runtime")
+	if len(paths) < 10 {
+		t.Fatalf("only found %v; are the pprof routes registered?", paths)
+	}
+
+	for _, route := range paths {
+		method, path, _ := strings.Cut(route, " ")
+		if status, _ := ts.Do(t, ts.NewRequest(t, method, path, nil)); status != http.StatusUnauthorized {
+			t.Errorf("%s without a token: status %d, want 401", route, status)
+		}
+		req := ts.NewRequest(t, method, path, nil)
+		req.Header.Set("X-Admin-Token", "not-the-token")
+		if status, _ := ts.Do(t, req); status != http.StatusForbidden {
+			t.Errorf("%s with a wrong token: status %d, want 403", route, status)
+		}
+	}
+
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/cmdline", "/debug/pprof/heap", "/api/v1/admin/runtime"} {
+		if status, _ := ts.Do(t, ts.AdminRequest(t, http.MethodGet, path, nil)); status != http.StatusOK {
+			t.Errorf("GET %s with the admin token: status %d, want 200", path, status)
+		}
+	}
+}
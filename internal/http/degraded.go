@@ -0,0 +1,31 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sujalbistaa/whispr/internal/db"
+)
+
+// While the database is down (see db.Health) the server is degraded:
+// writes are refused up front instead of each failing on its own, and the
+// feeds are answered from the feed cache (see serveFeed).
+
+// DegradedMiddleware rejects writes with a 503 while health reports the
+// database down. It lets through the same requests as read-only
+// maintenance, plus admin routes, which report their own errors.
+func DegradedMiddleware(health *db.Health) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !health.Down() || maintenanceExempt(c.Request.URL.Path) || isGraphQLPath(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		respondError(c, ErrUnavailable("database.read_only"))
+	}
+}
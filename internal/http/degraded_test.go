@@ -0,0 +1,116 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	routes "github.com/sujalbistaa/whispr/internal/http"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/server"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// readyStatus returns the status field of /readyz.
+func readyStatus(t *testing.T, ts *testutil.TestServer) (int, string) {
+	t.Helper()
+	status, body := ts.Do(t, ts.NewRequest(t, http.MethodGet, "/readyz", nil))
+	var resp struct {
+		Status string `json:"status"`
+	}
+	json.Unmarshal(body, &resp)
+	return status, resp.Status
+}
+
+// TestDegradedMode takes the database away and brings it back. The
+// monitor is checked by hand rather than left to its ticker.
+func TestDegradedMode(t *testing.T) {
+	t.Setenv("DB_HEALTH_INTERVAL", "1h")
+	t.Setenv("DB_HEALTH_FAILURES", "1")
+	// Every feed request reads the database, so the stale copy is what
+	// answers while it's down.
+	t.Setenv("FEED_CACHE_FRESH", "0s")
+	failing := testutil.NewFailingDB(t)
+	ts := testutil.NewTestServer(t, server.WithDB(failing.DB))
+	health := ts.App.DBHealth()
+	ctx := context.Background()
+
+	post := ts.CreatePost(t, "read before the outage")
+	if status, _ := ts.Do(t, ts.NewRequest(t, http.MethodGet, "/api/v1/posts", nil)); status != http.StatusOK {
+		t.Fatalf("GET /api/v1/posts: status %d", status)
+	}
+
+	failing.SetDown(true)
+	health.Check(ctx)
+	if !health.Down() {
+		t.Fatal("health monitor didn't notice the database going down")
+	}
+
+	resp, err := ts.Client().Do(ts.NewRequest(t, http.MethodGet, "/api/v1/posts", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var posts []models.Post
+	json.NewDecoder(resp.Body).Decode(&posts)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(posts) != 1 || posts[0].ID != post.ID {
+		t.Errorf("feed while down: status %d, %d posts; want the cached post", resp.StatusCode, len(posts))
+	}
+	if resp.Header.Get("X-Whispr-Stale") == "" {
+		t.Error("feed while down has no X-Whispr-Stale header")
+	}
+
+	for _, req := range []*http.Request{
+		ts.NewRequest(t, http.MethodPost, "/api/v1/posts", map[string]string{"content": "written during the outage"}),
+		ts.NewRequest(t, http.MethodPost, "/api/v1/posts/1/vote", map[string]int{"value": 1}),
+	} {
+		if status, code := errorCode(t, ts, req); status != http.StatusServiceUnavailable || code != routes.CodeUnavailable {
+			t.Errorf("%s %s while down: %d %s, want 503 %s", req.Method, req.URL.Path, status, code, routes.CodeUnavailable)
+		}
+	}
+	if status, state := readyStatus(t, ts); status != http.StatusOK || state != "degraded" {
+		t.Errorf("/readyz while down: %d %q, want 200 degraded", status, state)
+	}
+
+	failing.SetDown(false)
+	health.Check(ctx)
+	if health.Down() {
+		t.Fatal("health monitor didn't notice the database coming back")
+	}
+	ts.CreatePost(t, "written after recovery")
+	resp, err = ts.Client().Do(ts.NewRequest(t, http.MethodGet, "/api/v1/posts", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	posts = nil
+	json.NewDecoder(resp.Body).Decode(&posts)
+	resp.Body.Close()
+	if len(posts) != 2 || resp.Header.Get("X-Whispr-Stale") != "" {
+		t.Errorf("feed after recovery: %d posts, X-Whispr-Stale %q; want 2 fresh posts", len(posts), resp.Header.Get("X-Whispr-Stale"))
+	}
+	if status, state := readyStatus(t, ts); status != http.StatusOK || state != "ok" {
+		t.Errorf("/readyz after recovery: %d %q, want 200 ok", status, state)
+	}
+}
+
+// TestDegradedFeedBeforeMonitor checks a failed feed read falls back to
+// the cache even before the monitor has marked the database down.
+func TestDegradedFeedBeforeMonitor(t *testing.T) {
+	t.Setenv("FEED_CACHE_FRESH", "0s")
+	failing := testutil.NewFailingDB(t)
+	ts := testutil.NewTestServer(t, server.WithDB(failing.DB))
+	ts.CreatePost(t, "cached in time")
+	ts.Do(t, ts.NewRequest(t, http.MethodGet, "/api/v1/posts", nil))
+
+	failing.SetDown(true)
+	resp, err := ts.Client().Do(ts.NewRequest(t, http.MethodGet, "/api/v1/posts", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("X-Whispr-Stale") == "" {
+		t.Errorf("feed after a failed read: status %d, X-Whispr-Stale %q; want a stale 200", resp.StatusCode, resp.Header.Get("X-Whispr-Stale"))
+	}
+	failing.SetDown(false)
+}
@@ -0,0 +1,116 @@
+package http_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sujalbistaa/whispr/internal/audit"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+type hideResult struct {
+	AlreadyHidden bool   `json:"alreadyHidden"`
+	UndoToken     string `json:"undoToken"`
+}
+
+func deletePost(t *testing.T, ts *testutil.TestServer, id uint) hideResult {
+	t.Helper()
+	status, body := ts.Do(t, ts.AdminRequest(t, http.MethodDelete, fmt.Sprintf("/api/v1/posts/%d", id), nil))
+	if status != http.StatusOK {
+		t.Errorf("DELETE post %d: status %d: %s", id, status, body)
+	}
+	var result hideResult
+	json.Unmarshal(body, &result)
+	return result
+}
+
+func countHides(t *testing.T, ts *testutil.TestServer, id uint) int64 {
+	t.Helper()
+	var n int64
+	ts.DB.Model(&models.AuditLog{}).Where("action = ? AND target_id = ?", audit.ActionHidePost, id).Count(&n)
+	return n
+}
+
+func TestDeletePostTwice(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	post := ts.CreatePost(t, "delete me twice")
+	ws := ts.DialWS(t)
+
+	if first := deletePost(t, ts, post.ID); first.AlreadyHidden || first.UndoToken == "" {
+		t.Errorf("first delete = %+v, want alreadyHidden false with an undo token", first)
+	}
+	msg := ws.Expect(t, "delete", 0)
+	var deleted struct {
+		ID uint `json:"id"`
+	}
+	json.Unmarshal(msg.Data, &deleted)
+	if deleted.ID != post.ID {
+		t.Errorf("delete broadcast for post %d, want %d", deleted.ID, post.ID)
+	}
+
+	if second := deletePost(t, ts, post.ID); !second.AlreadyHidden || second.UndoToken != "" {
+		t.Errorf("second delete = %+v, want alreadyHidden true without an undo token", second)
+	}
+	ws.ExpectNone(t, "delete", 200*time.Millisecond)
+	if n := countHides(t, ts, post.ID); n != 1 {
+		t.Errorf("%d hides audited, want 1", n)
+	}
+}
+
+func TestDeletePostConcurrently(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	post := ts.CreatePost(t, "everyone deletes me at once")
+	ws := ts.DialWS(t)
+
+	const moderators = 10
+	results := make(chan hideResult, moderators)
+	var wg sync.WaitGroup
+	for i := 0; i < moderators; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results <- deletePost(t, ts, post.ID)
+		}()
+	}
+	wg.Wait()
+	close(results)
+	hid := 0
+	for result := range results {
+		if !result.AlreadyHidden {
+			hid++
+		}
+	}
+	if hid != 1 {
+		t.Errorf("%d of %d deletes hid the post, want 1", hid, moderators)
+	}
+	ws.Expect(t, "delete", 0)
+	ws.ExpectNone(t, "delete", 200*time.Millisecond)
+	if n := countHides(t, ts, post.ID); n != 1 {
+		t.Errorf("%d hides audited, want 1", n)
+	}
+}
+
+func TestUndoDeleteTwice(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	post := ts.CreatePost(t, "hide me, then think better of it")
+	hidden := deletePost(t, ts, post.ID)
+	ws := ts.DialWS(t)
+
+	undo := func() int {
+		status, _ := ts.Do(t, ts.AdminRequest(t, http.MethodPost, fmt.Sprintf("/api/v1/admin/posts/%d/undo", post.ID), map[string]string{"undoToken": hidden.UndoToken}))
+		return status
+	}
+	if status := undo(); status != http.StatusOK {
+		t.Fatalf("undo: status %d, want 200", status)
+	}
+	ws.Expect(t, "new_post", 0)
+	if status := undo(); status != http.StatusGone {
+		t.Errorf("second undo: status %d, want 410", status)
+	}
+	ws.ExpectNone(t, "new_post", 200*time.Millisecond)
+}
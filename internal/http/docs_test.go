@@ -0,0 +1,44 @@
+package http_test
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	routes "github.com/sujalbistaa/whispr/internal/http"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// TestOpenAPIRoutes fails when a route is registered without being in
+// openapi.json, or the spec documents a route that isn't registered.
+func TestOpenAPIRoutes(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		adminAddr string
+	}{
+		{"one listener", ""},
+		{"separate admin listener", "127.0.0.1:0"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("ADMIN_ADDR", tc.adminAddr)
+			ts := testutil.NewTestServer(t)
+			registered := ts.App.Handler().(*gin.Engine).Routes()
+			if admin := ts.App.AdminHandler(); admin != nil {
+				registered = append(registered, admin.(*gin.Engine).Routes()...)
+			} else if tc.adminAddr != "" {
+				t.Fatal("ADMIN_ADDR is set but there is no admin listener")
+			}
+
+			undocumented, stale, err := routes.CheckOpenAPIRoutes(registered)
+			if err != nil {
+				t.Fatalf("parsing openapi.json: %v", err)
+			}
+			for _, route := range undocumented {
+				t.Errorf("%s is registered but missing from openapi.json", route)
+			}
+			for _, route := range stale {
+				t.Errorf("%s is in openapi.json but not registered", route)
+			}
+		})
+	}
+}
@@ -0,0 +1,355 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/sujalbistaa/whispr/internal/contentpolicy"
+	"github.com/sujalbistaa/whispr/internal/i18n"
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+// Error codes are part of the API contract; clients switch on them, so
+// never change the meaning of an existing code. Messages are for humans,
+// translated per Accept-Language, and may change at any time.
+const (
+	CodeBadRequest    = "bad_request"
+	CodeValidation    = "validation_failed"
+	CodeContent       = "content_rejected"
+	CodeUnauthorized  = "unauthorized"
+	CodeForbidden     = "forbidden"
+	CodeBanned        = "banned"
+	CodeChallenge     = "challenge_required"
+	CodeNotFound      = "not_found"
+	CodeConflict      = "conflict"
+	CodeGone          = "gone"
+	CodeReadOnly      = "read_only"
+	CodeTooLarge      = "payload_too_large"
+	CodeRateLimited   = "rate_limited"
+	CodeQuotaExceeded = "quota_exceeded"
+	CodeOverloaded    = "overloaded"
+	CodeUnavailable   = "unavailable"
+	CodeTimeout       = "timeout"
+	CodeMaintenance   = "maintenance"
+	CodeAdminDisabled = "admin_disabled"
+	CodeInternal      = "internal_error"
+)
+
+// APIError is the body of every error response, wrapped as {"error": ...}.
+// Message holds the English text until respondError translates it.
+type APIError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
+
+	key  string // Message catalog key, see internal/i18n/locales
+	args []any  // Placeholder name/value pairs for key
+}
+
+func (e *APIError) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// newError builds an APIError whose message is the catalog entry key.
+// args are alternating placeholder names and values.
+func newError(status int, code, key string, args ...any) *APIError {
+	return &APIError{
+		Status:  status,
+		Code:    code,
+		Message: i18n.Default.Message(i18n.Fallback, key, args...),
+		key:     key,
+		args:    args,
+	}
+}
+
+// localize returns a copy of e with its message, and those of any field
+// errors, in locale.
+func (e *APIError) localize(locale string) *APIError {
+	out := *e
+	if e.key != "" {
+		out.Message = i18n.Default.Message(locale, e.key, e.args...)
+	}
+	if fields, ok := e.Details.([]FieldError); ok {
+		localized := make([]FieldError, len(fields))
+		for i, fe := range fields {
+			fe.Message = fe.localize(locale)
+			localized[i] = fe
+		}
+		out.Details = localized
+	}
+	return &out
+}
+
+// FieldError describes one failed validation rule on a request body.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+
+	length bool // min and max count characters rather than compare values
+}
+
+func (fe FieldError) localize(locale string) string {
+	key := "validation.rule." + fe.Rule
+	if fe.length && (fe.Rule == "min" || fe.Rule == "max") {
+		key += ".string"
+	}
+	if !i18n.Default.Has(key) {
+		key = "validation.rule.default"
+	}
+	param := fe.Param
+	if fe.Rule == "oneof" {
+		param = strings.Join(strings.Fields(param), ", ")
+	}
+	return i18n.Default.Message(locale, key, "field", fe.Field, "param", param)
+}
+
+// ErrBadRequest is for malformed parameters other than the JSON body.
+func ErrBadRequest(key string, args ...any) *APIError {
+	return newError(http.StatusBadRequest, CodeBadRequest, key, args...)
+}
+
+// ErrValidation wraps a binding error; field-level failures are listed in
+// Details so clients can highlight the offending inputs. Decoder and
+// validator text is never passed through, since it can't be translated.
+func ErrValidation(err error) *APIError {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return ErrPayloadTooLarge(tooLarge.Limit)
+	}
+	var fieldErrs validator.ValidationErrors
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	var strictErr *strictJSONError
+	switch {
+	case errors.As(err, &fieldErrs):
+	case errors.As(err, &strictErr):
+		if strictErr.Field == "" {
+			return newError(http.StatusBadRequest, CodeValidation, "validation.trailing_data")
+		}
+		return fieldError(newError(http.StatusBadRequest, CodeValidation, "validation.failed"), FieldError{Field: strictErr.Field, Rule: strictErr.Rule})
+	case errors.As(err, &typeErr) && typeErr.Field == "":
+		return newError(http.StatusBadRequest, CodeValidation, "validation.wrong_body_type", "type", jsonType(typeErr.Type))
+	case errors.As(err, &typeErr):
+		apiErr := newError(http.StatusBadRequest, CodeValidation, "validation.wrong_type", "field", typeErr.Field)
+		return fieldError(apiErr, FieldError{Field: typeErr.Field, Rule: "type", Param: jsonType(typeErr.Type)})
+	case errors.As(err, &syntaxErr), errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF):
+		return newError(http.StatusBadRequest, CodeValidation, "validation.invalid_json")
+	default:
+		return newError(http.StatusBadRequest, CodeValidation, "validation.invalid_body")
+	}
+	details := make([]FieldError, 0, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		field := FieldError{Field: fe.Field(), Rule: fe.Tag(), Param: fe.Param(), length: fe.Kind() == reflect.String}
+		field.Message = field.localize(i18n.Fallback)
+		details = append(details, field)
+	}
+	apiErr := newError(http.StatusBadRequest, CodeValidation, "validation.failed")
+	apiErr.Details = details
+	return apiErr
+}
+
+// fieldError sets apiErr's Details to the single field error fe.
+func fieldError(apiErr *APIError, fe FieldError) *APIError {
+	fe.Message = fe.localize(i18n.Fallback)
+	apiErr.Details = []FieldError{fe}
+	return apiErr
+}
+
+// ErrContentRejected means the post contains contact details of kinds
+// that CONTENT_POLICY doesn't allow.
+func ErrContentRejected(kinds []contentpolicy.Kind) *APIError {
+	err := newError(http.StatusBadRequest, CodeContent, "post.contact_details")
+	err.Details = gin.H{"kinds": kinds}
+	return err
+}
+
+// ErrPayloadTooLarge means the request body exceeded limit bytes.
+func ErrPayloadTooLarge(limit int64) *APIError {
+	err := newError(http.StatusRequestEntityTooLarge, CodeTooLarge, "request.too_large")
+	err.Details = gin.H{"limit": limit}
+	return err
+}
+
+// ErrUnauthorized means credentials were required but missing.
+func ErrUnauthorized(key string, args ...any) *APIError {
+	return newError(http.StatusUnauthorized, CodeUnauthorized, key, args...)
+}
+
+// ErrForbidden means the credentials don't grant access.
+func ErrForbidden(key string, args ...any) *APIError {
+	return newError(http.StatusForbidden, CodeForbidden, key, args...)
+}
+
+// ErrBanned means the caller's IP is banned.
+func ErrBanned() *APIError {
+	return newError(http.StatusForbidden, CodeBanned, "ban.banned")
+}
+
+// ErrChallenge means the post lacks a valid solution to a challenge from
+// GET /api/challenge; fetch a new one and retry.
+func ErrChallenge(key string) *APIError {
+	return newError(http.StatusForbidden, CodeChallenge, key)
+}
+
+// ErrNotFound means the addressed resource doesn't exist or isn't visible.
+func ErrNotFound(key string, args ...any) *APIError {
+	return newError(http.StatusNotFound, CodeNotFound, key, args...)
+}
+
+// ErrConflict means the request clashes with existing state, such as a
+// duplicate.
+func ErrConflict(key string, args ...any) *APIError {
+	return newError(http.StatusConflict, CodeConflict, key, args...)
+}
+
+// ErrGone means the request named something that has expired or been
+// used up, such as an undo token.
+func ErrGone(key string, args ...any) *APIError {
+	return newError(http.StatusGone, CodeGone, key, args...)
+}
+
+// ErrReadOnly means the server is a read-only mirror; writes go to
+// primary, if known.
+func ErrReadOnly(primary string) *APIError {
+	err := newError(http.StatusMethodNotAllowed, CodeReadOnly, "request.read_only")
+	if primary != "" {
+		err.Details = gin.H{"primary": primary}
+	}
+	return err
+}
+
+// ErrRateLimited is a per-client 429; retryAfter is in seconds.
+func ErrRateLimited(retryAfter int) *APIError {
+	err := newError(http.StatusTooManyRequests, CodeRateLimited, "request.rate_limited")
+	err.Details = gin.H{"retryAfter": retryAfter}
+	return err
+}
+
+// ErrQuotaExceeded means the client has used its daily posts; one frees
+// up at resetAt, retryAfter seconds from now.
+func ErrQuotaExceeded(quota int, resetAt time.Time, retryAfter int) *APIError {
+	err := newError(http.StatusTooManyRequests, CodeQuotaExceeded, "post.quota_exceeded", "limit", quota)
+	err.Details = gin.H{"resetAt": resetAt.UTC(), "retryAfter": retryAfter}
+	return err
+}
+
+// ErrCooldown means the client is in a strike cooldown and may post again
+// at next, retryAfter seconds from now.
+func ErrCooldown(cooldown time.Duration, next time.Time, retryAfter int) *APIError {
+	err := newError(http.StatusTooManyRequests, CodeRateLimited, "post.cooldown", "minutes", int(math.Ceil(cooldown.Minutes())))
+	err.Details = gin.H{"resetAt": next.UTC(), "retryAfter": retryAfter}
+	return err
+}
+
+// ErrOverloaded is a server-wide 503; retryAfter is in seconds.
+func ErrOverloaded(retryAfter int) *APIError {
+	err := newError(http.StatusServiceUnavailable, CodeOverloaded, "request.overloaded")
+	err.Details = gin.H{"retryAfter": retryAfter}
+	return err
+}
+
+// ErrUnavailable means a dependency the request needs is down.
+func ErrUnavailable(key string, args ...any) *APIError {
+	return newError(http.StatusServiceUnavailable, CodeUnavailable, key, args...)
+}
+
+// ErrTimeout means the request ran past REQUEST_TIMEOUT and its queries
+// were canceled.
+func ErrTimeout() *APIError {
+	return newError(http.StatusServiceUnavailable, CodeTimeout, "request.timeout")
+}
+
+// ErrMaintenance means the request is blocked by the maintenance mode.
+func ErrMaintenance(mode MaintenanceMode) *APIError {
+	key := "maintenance.full"
+	if mode == MaintenanceReadOnly {
+		key = "maintenance.readonly"
+	}
+	err := newError(http.StatusServiceUnavailable, CodeMaintenance, key)
+	err.Details = gin.H{"mode": mode}
+	return err
+}
+
+// ErrAdminDisabled means no admin token is configured.
+func ErrAdminDisabled() *APIError {
+	return newError(http.StatusServiceUnavailable, CodeAdminDisabled, "admin.disabled")
+}
+
+// ErrInternal hides the underlying failure; log it before responding.
+func ErrInternal(key string, args ...any) *APIError {
+	return newError(http.StatusInternalServerError, CodeInternal, key, args...)
+}
+
+// sentinelErrors maps the sentinel errors in models to API errors.
+var sentinelErrors = []struct {
+	err error
+	api func() *APIError
+}{
+	{models.ErrPostNotFound, func() *APIError { return ErrNotFound("post.not_found") }},
+	{models.ErrVoteConflict, func() *APIError { return ErrConflict("vote.duplicate") }},
+	{models.ErrVoteIDReused, func() *APIError { return ErrConflict("vote.id_reused") }},
+	{models.ErrBoardNotFound, func() *APIError { return ErrNotFound("board.not_found") }},
+}
+
+// respondStoreError answers with the API error for a sentinel error in
+// err, however deeply wrapped. Any other error is logged as msg and hidden
+// behind ErrInternal(key).
+func respondStoreError(c *gin.Context, err error, msg, key string) {
+	for _, s := range sentinelErrors {
+		if errors.Is(err, s.err) {
+			respondError(c, s.api())
+			return
+		}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		requestLogger(c).Warn(msg, "err", err)
+		respondError(c, ErrTimeout())
+		return
+	}
+	requestLogger(c).Error(msg, "err", err)
+	respondError(c, ErrInternal(key))
+}
+
+// respondError aborts the chain with the error envelope, its message in
+// the best locale for the request's Accept-Language. An internal error
+// after the request timed out is answered with ErrTimeout, since the
+// deadline is what failed it.
+func respondError(c *gin.Context, err *APIError) {
+	if err.Status == http.StatusInternalServerError && errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+		err = ErrTimeout()
+	}
+	locale := i18n.Default.Negotiate(c.GetHeader("Accept-Language"))
+	c.Header("Content-Language", locale)
+	c.Writer.Header().Add("Vary", "Accept-Language")
+	c.AbortWithStatusJSON(err.Status, gin.H{"error": err.localize(locale)})
+}
+
+// Report validation failures by JSON field name rather than Go field name.
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return f.Name
+			}
+			return name
+		})
+	}
+}
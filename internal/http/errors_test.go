@@ -0,0 +1,121 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	routes "github.com/sujalbistaa/whispr/internal/http"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// errorCode sends req and returns the status and the envelope's code,
+// failing the test if the body isn't an error envelope.
+func errorCode(t *testing.T, ts *testutil.TestServer, req *http.Request) (int, string) {
+	t.Helper()
+	status, body := ts.Do(t, req)
+	var apiErr apiError
+	if err := json.Unmarshal(body, &apiErr); err != nil || apiErr.Error.Code == "" || apiErr.Error.Message == "" {
+		t.Fatalf("%s %s: status %d, body %s isn't an error envelope", req.Method, req.URL.Path, status, body)
+	}
+	return status, apiErr.Error.Code
+}
+
+func TestErrorCodes(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	post := ts.CreatePost(t, "something to get wrong")
+	voter := ts.ClientIP()
+	ts.VoteFrom(t, voter, post.ID, 1)
+	createBan(t, ts, "10.200.0.1", false)
+	missing := fmt.Sprintf("/api/v1/posts/%d", post.ID+1000)
+
+	wrongToken := ts.NewRequest(t, http.MethodDelete, fmt.Sprintf("/api/v1/posts/%d", post.ID), nil)
+	wrongToken.Header.Set("X-Admin-Token", "not-the-token")
+
+	for _, tc := range []struct {
+		name   string
+		req    *http.Request
+		status int
+		code   string
+	}{
+		{"missing post", ts.NewRequest(t, http.MethodGet, missing, nil), http.StatusNotFound, routes.CodeNotFound},
+		{"vote on a missing post", ts.NewRequest(t, http.MethodPost, missing+"/vote", map[string]int{"value": 1}), http.StatusNotFound, routes.CodeNotFound},
+		{"bad post id", ts.NewRequest(t, http.MethodGet, "/api/v1/posts/abc", nil), http.StatusBadRequest, routes.CodeBadRequest},
+		{"invalid JSON", ts.NewRequest(t, http.MethodPost, "/api/v1/posts", []byte(`{"content":`)), http.StatusBadRequest, routes.CodeValidation},
+		{"invalid vote", ts.NewRequest(t, http.MethodPost, fmt.Sprintf("/api/v1/posts/%d/vote", post.ID), map[string]int{"value": 2}), http.StatusBadRequest, routes.CodeValidation},
+		{"second vote", ts.NewRequestFrom(t, voter, http.MethodPost, fmt.Sprintf("/api/v1/posts/%d/vote", post.ID), map[string]int{"value": -1}), http.StatusConflict, routes.CodeConflict},
+		{"no admin token", ts.NewRequest(t, http.MethodDelete, fmt.Sprintf("/api/v1/posts/%d", post.ID), nil), http.StatusUnauthorized, routes.CodeUnauthorized},
+		{"wrong admin token", wrongToken, http.StatusForbidden, routes.CodeForbidden},
+		{"banned", ts.NewRequestFrom(t, "10.200.0.1", http.MethodPost, "/api/v1/posts", map[string]string{"content": "let me in"}), http.StatusForbidden, routes.CodeBanned},
+	} {
+		status, code := errorCode(t, ts, tc.req)
+		if status != tc.status || code != tc.code {
+			t.Errorf("%s: %d %s, want %d %s", tc.name, status, code, tc.status, tc.code)
+		}
+	}
+
+	// The limiter answers with the envelope too.
+	ip := ts.ClientIP()
+	ts.Do(t, ts.NewRequestFrom(t, ip, http.MethodPost, "/api/v1/posts", map[string]string{"content": "the one post allowed"}))
+	if status, code := errorCode(t, ts, ts.NewRequestFrom(t, ip, http.MethodPost, "/api/v1/posts", map[string]string{"content": "and one too many"})); status != http.StatusTooManyRequests || code != routes.CodeRateLimited {
+		t.Errorf("rate limited: %d %s, want 429 %s", status, code, routes.CodeRateLimited)
+	}
+}
+
+// TestErrorCodesIgnoreLanguage checks that the code stays put when the
+// message is translated.
+func TestErrorCodesIgnoreLanguage(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	messages := map[string]bool{}
+	for _, lang := range []string{"en", "ne"} {
+		req := ts.NewRequest(t, http.MethodGet, "/api/v1/posts/999999", nil)
+		req.Header.Set("Accept-Language", lang)
+		status, body := ts.Do(t, req)
+		var apiErr apiError
+		json.Unmarshal(body, &apiErr)
+		if status != http.StatusNotFound || apiErr.Error.Code != routes.CodeNotFound {
+			t.Errorf("Accept-Language %s: %d %s, want 404 %s", lang, status, apiErr.Error.Code, routes.CodeNotFound)
+		}
+		messages[apiErr.Error.Message] = true
+	}
+	if len(messages) != 2 {
+		t.Errorf("messages %v, want one per language", messages)
+	}
+}
+
+// TestStoreErrorsWrapped checks the sentinel errors map to their statuses
+// however they were wrapped on the way out of the store.
+func TestStoreErrorsWrapped(t *testing.T) {
+	for _, tc := range []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{models.ErrPostNotFound, http.StatusNotFound, routes.CodeNotFound},
+		{fmt.Errorf("voting: %w", models.ErrPostNotFound), http.StatusNotFound, routes.CodeNotFound},
+		{fmt.Errorf("in transaction: %w", fmt.Errorf("voting: %w", models.ErrVoteConflict)), http.StatusConflict, routes.CodeConflict},
+		{fmt.Errorf("voting: %w", models.ErrVoteIDReused), http.StatusConflict, routes.CodeConflict},
+		{errors.Join(errors.New("rollback failed"), models.ErrBoardNotFound), http.StatusNotFound, routes.CodeNotFound},
+		{fmt.Errorf("listing: %w", context.DeadlineExceeded), http.StatusServiceUnavailable, routes.CodeTimeout},
+		{errors.New(models.ErrPostNotFound.Error()), http.StatusInternalServerError, routes.CodeInternal},
+	} {
+		engine := gin.New()
+		engine.GET("/", func(c *gin.Context) {
+			routes.RespondStoreError(c, tc.err, "failed", "post.delete_failed")
+		})
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		var apiErr apiError
+		json.Unmarshal(rec.Body.Bytes(), &apiErr)
+		if rec.Code != tc.status || apiErr.Error.Code != tc.code {
+			t.Errorf("%v: %d %s, want %d %s", tc.err, rec.Code, apiErr.Error.Code, tc.status, tc.code)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package http
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sujalbistaa/whispr/internal/audit"
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+// exportFlushEvery controls how many rows are written between flushes.
+const exportFlushEvery = 500
+
+// csvFormulaPrefixes start a cell a spreadsheet would run as a formula.
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// ExportRow is a single post as written by ExportPosts.
+type ExportRow struct {
+	ID        uint      `json:"id"`
+	Content   string    `json:"content"`
+	Score     int       `json:"score"`
+	Hidden    bool      `json:"hidden"`
+	CreatedAt time.Time `json:"createdAt"`
+	Upvotes   int64     `json:"upvotes"`
+	Downvotes int64     `json:"downvotes"`
+}
+
+// ExportPosts streams posts with their vote counts as CSV or JSON.
+// Query parameters: format=csv|json, from/to (RFC3339), includeHidden.
+// Rows are read with a cursor and written incrementally, so memory use does
+// not grow with the size of the export.
+func (e *Env) ExportPosts(c *gin.Context) {
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		respondError(c, ErrBadRequest("export.invalid_format"))
+		return
+	}
+
+	// Large exports can take longer than the server's write timeout.
+	clearDeadlines(c)
+
+	ctx := c.Request.Context()
+	query := e.DB.WithContext(ctx).Model(&models.Post{}).
+		Select("posts.id, posts.content, posts.score, posts.hidden_at IS NOT NULL, posts.created_at, COALESCE(v.upvotes, 0), COALESCE(v.downvotes, 0)").
+		Joins("LEFT JOIN (SELECT post_id, SUM(CASE WHEN value > 0 THEN 1 ELSE 0 END) AS upvotes, SUM(CASE WHEN value < 0 THEN 1 ELSE 0 END) AS downvotes FROM votes WHERE deleted_at IS NULL GROUP BY post_id) v ON v.post_id = posts.id").
+		Order("posts.id")
+
+	for _, bound := range []struct{ param, clause string }{
+		{"from", "posts.created_at >= ?"},
+		{"to", "posts.created_at < ?"},
+	} {
+		raw := c.Query(bound.param)
+		if raw == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			respondError(c, ErrBadRequest("query.invalid_timestamp", "param", bound.param))
+			return
+		}
+		query = query.Where(bound.clause, t)
+	}
+	includeHidden, _ := strconv.ParseBool(c.Query("includeHidden"))
+	if includeHidden {
+		query = query.Unscoped()
+	} else {
+		query = query.Where("posts.shadow_banned = ?", false)
+	}
+
+	// Audit first: the open rows hold a connection, which is the only one
+	// on SQLite.
+	if err := audit.Record(e.DB, adminActor(c), audit.ActionExport, audit.TargetPost, 0, map[string]any{"format": format, "query": c.Request.URL.RawQuery}); err != nil {
+		requestLogger(c).Error("writing audit log", "err", err)
+	}
+
+	rows, err := query.Rows()
+	if err != nil {
+		requestLogger(c).Error("starting export", "err", err)
+		respondError(c, ErrInternal("export.failed"))
+		return
+	}
+	defer rows.Close()
+
+	filename := "whispr-posts-" + time.Now().UTC().Format("20060102-150405") + "." + format
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+	}
+	c.Status(http.StatusOK)
+
+	var csvWriter *csv.Writer
+	var jsonEncoder *json.Encoder
+	if format == "csv" {
+		csvWriter = csv.NewWriter(c.Writer)
+		if err := csvWriter.Write([]string{"id", "content", "score", "hidden", "created_at", "upvotes", "downvotes"}); err != nil {
+			requestLogger(c).Info("export aborted", "rows", 0, "err", err)
+			return
+		}
+	} else {
+		jsonEncoder = json.NewEncoder(c.Writer)
+		c.Writer.WriteString("[")
+	}
+
+	count := 0
+	for rows.Next() {
+		if ctx.Err() != nil {
+			requestLogger(c).Info("export canceled", "rows", count, "err", ctx.Err())
+			return
+		}
+		var row ExportRow
+		if err := rows.Scan(&row.ID, &row.Content, &row.Score, &row.Hidden, &row.CreatedAt, &row.Upvotes, &row.Downvotes); err != nil {
+			requestLogger(c).Error("scanning export row", "err", err)
+			return
+		}
+
+		var err error
+		if csvWriter != nil {
+			err = csvWriter.Write([]string{
+				strconv.FormatUint(uint64(row.ID), 10),
+				csvText(row.Content),
+				strconv.Itoa(row.Score),
+				strconv.FormatBool(row.Hidden),
+				row.CreatedAt.UTC().Format(time.RFC3339),
+				strconv.FormatInt(row.Upvotes, 10),
+				strconv.FormatInt(row.Downvotes, 10),
+			})
+		} else {
+			if count > 0 {
+				_, err = c.Writer.WriteString(",")
+			}
+			if err == nil {
+				err = jsonEncoder.Encode(row)
+			}
+		}
+		if err != nil {
+			// Most likely the client went away; the status is already sent.
+			requestLogger(c).Info("export aborted", "rows", count, "err", err)
+			return
+		}
+
+		count++
+		if count%exportFlushEvery == 0 {
+			if csvWriter != nil {
+				csvWriter.Flush()
+				if err := csvWriter.Error(); err != nil {
+					requestLogger(c).Info("export aborted", "rows", count, "err", err)
+					return
+				}
+			}
+			c.Writer.Flush()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		requestLogger(c).Error("reading export rows", "err", err)
+		return
+	}
+
+	if csvWriter != nil {
+		csvWriter.Flush()
+		err = csvWriter.Error()
+	} else {
+		_, err = c.Writer.WriteString("]\n")
+	}
+	if err != nil {
+		requestLogger(c).Info("export aborted", "rows", count, "err", err)
+		return
+	}
+	c.Writer.Flush()
+}
+
+// csvText makes text safe to open in a spreadsheet: a cell that would be
+// read as a formula is prefixed with ', which shows it as text instead.
+func csvText(text string) string {
+	if text != "" && strings.ContainsRune(csvFormulaPrefixes, rune(text[0])) {
+		return "'" + text
+	}
+	return text
+}
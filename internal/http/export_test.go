@@ -0,0 +1,7 @@
+package http
+
+// CheckOpenAPIRoutes exposes checkOpenAPIRoutes to the external tests.
+var CheckOpenAPIRoutes = checkOpenAPIRoutes
+
+// RespondStoreError exposes respondStoreError to the external tests.
+var RespondStoreError = respondStoreError
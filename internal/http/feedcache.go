@@ -0,0 +1,210 @@
+package http
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sujalbistaa/whispr/internal/metrics"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/store"
+)
+
+// FeedCache stores feed results between requests. memoryFeedCache keeps
+// them in this process; one backed by Redis would share them between
+// replicas without changes to the handlers.
+type FeedCache interface {
+	// Load returns the entry stored under key, however old.
+	Load(key string) (CachedFeed, bool)
+	// Store saves feed under key. version is what Version returned before
+	// the feed was read; if Invalidate ran since, the entry is stored
+	// stale, so a read that raced a write can't be served as current.
+	Store(key string, version uint64, feed CachedFeed)
+	// Version changes every time Invalidate is called.
+	Version() uint64
+	// Invalidate marks every entry stale. Stale entries are only served
+	// while the database can't be read.
+	Invalidate()
+}
+
+// CachedFeed is one stored feed result.
+type CachedFeed struct {
+	Posts []models.Post
+	ETag  string    // Empty if it couldn't be computed
+	At    time.Time // When it was read from the database
+	Stale bool      // Invalidated since it was read
+}
+
+type memoryFeedCache struct {
+	mu      sync.RWMutex
+	version uint64
+	entries map[string]memoryFeedEntry
+}
+
+type memoryFeedEntry struct {
+	feed    CachedFeed
+	version uint64
+}
+
+func newMemoryFeedCache() *memoryFeedCache {
+	return &memoryFeedCache{entries: make(map[string]memoryFeedEntry)}
+}
+
+func (m *memoryFeedCache) Load(key string) (CachedFeed, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	entry, ok := m.entries[key]
+	if !ok {
+		return CachedFeed{}, false
+	}
+	feed := entry.feed
+	feed.Stale = entry.version != m.version
+	return feed, true
+}
+
+func (m *memoryFeedCache) Store(key string, version uint64, feed CachedFeed) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.entries[key] = memoryFeedEntry{feed: feed, version: version}
+}
+
+func (m *memoryFeedCache) Version() uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.version
+}
+
+func (m *memoryFeedCache) Invalidate() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.version++
+}
+
+// invalidateFeeds is called after anything that changes what the feeds
+// show: a new visible post, a vote or a hide.
+func (e *Env) invalidateFeeds() {
+	e.feeds.Invalidate()
+}
+
+// serveFeed answers a feed request with the posts query selects. Results
+// are cached for FEED_CACHE_FRESH and shared by concurrent requests; a
+// write invalidates them at once. While the database can't be read, the
+// last result is served for up to FEED_CACHE_TTL, marked with
+// X-Whispr-Stale.
+//
+// The key is the feed name plus the query string, so parameters that
+// change the result get their own entries.
+func (e *Env) serveFeed(c *gin.Context, name string, feed store.Feed) {
+	// A shadow-banned viewer also sees their own posts, so their feed is
+	// neither cached nor shared.
+	if _, shadowBanned := e.viewerShadowBan(c); shadowBanned {
+		result, err := e.readFeed(c, feed)
+		if err != nil {
+			requestLogger(c).Error("fetching feed", "feed", name, "err", err)
+			respondError(c, ErrInternal("post.list_failed"))
+			return
+		}
+		respondFeed(c, result)
+		return
+	}
+
+	key := name
+	if c.Request.URL.RawQuery != "" {
+		key += "?" + c.Request.URL.Query().Encode()
+	}
+	cached, found := e.feeds.Load(key)
+	if found && !cached.Stale && time.Since(cached.At) < e.Config.FeedCacheFresh {
+		metrics.FeedCache("hit")
+		respondFeed(c, cached)
+		return
+	}
+	if found && e.DBHealth.Down() && e.serveStaleFeed(c, cached) {
+		return
+	}
+
+	// Requests that arrive after an invalidation start a new read rather
+	// than joining one that may predate the write.
+	version := e.feeds.Version()
+	result, err, _ := e.feedReads.Do(fmt.Sprintf("%s@%d", key, version), func() (any, error) {
+		result, err := e.readFeed(c, feed)
+		if err == nil {
+			e.feeds.Store(key, version, result)
+		}
+		return result, err
+	})
+	if err != nil {
+		if found && e.serveStaleFeed(c, cached) {
+			return
+		}
+		requestLogger(c).Error("fetching feed", "feed", name, "err", err)
+		respondError(c, ErrInternal("post.list_failed"))
+		return
+	}
+	metrics.FeedCache("miss")
+	respondFeed(c, result.(CachedFeed))
+}
+
+// readFeed loads a feed and its ETag for the caller. The read may be
+// shared with other requests, so it isn't canceled with the caller's.
+func (e *Env) readFeed(c *gin.Context, feed store.Feed) (CachedFeed, error) {
+	ctx := context.WithoutCancel(c.Request.Context())
+	viewer := e.viewer(c)
+	result := CachedFeed{ETag: e.feedETag(ctx, c, viewer, feed), At: time.Now()}
+	posts, err := e.Posts.List(ctx, viewer, feed)
+	result.Posts = posts
+	return result, err
+}
+
+// feedETag returns a weak ETag for the caller's view of feed. Any post,
+// vote or hide changes either the visible count or the latest updated_at,
+// so the pair identifies the feed without loading it. A feed that decays
+// also changes as votes age out of its window, so the current minute is
+// mixed in and its ETag is good for a minute at most. Errors return "" so
+// the response just isn't cacheable.
+func (e *Env) feedETag(ctx context.Context, c *gin.Context, viewer store.Viewer, feed store.Feed) string {
+	version, err := e.Posts.Version(ctx, viewer)
+	if err != nil {
+		requestLogger(c).Error("computing feed ETag", "err", err)
+		return ""
+	}
+	key := fmt.Sprintf("%d|%s", version.Count, version.Latest)
+	if feed.Decays() {
+		key += "|" + strconv.FormatInt(time.Now().Unix()/60, 10)
+	}
+	sum := sha256.Sum256([]byte(key))
+	return `W/"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// respondFeed sends feed, or 304 when If-None-Match already has its ETag.
+func respondFeed(c *gin.Context, feed CachedFeed) {
+	if feed.ETag != "" {
+		c.Header("ETag", feed.ETag)
+		c.Header("Cache-Control", "no-cache")
+		if etagMatches(c.GetHeader("If-None-Match"), feed.ETag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+	c.JSON(http.StatusOK, feed.Posts)
+}
+
+// serveStaleFeed sends feed if it is within FEED_CACHE_TTL and reports
+// whether it did. X-Whispr-Stale says when the data was read, so clients
+// can tell it may be behind.
+func (e *Env) serveStaleFeed(c *gin.Context, feed CachedFeed) bool {
+	if time.Since(feed.At) > e.Config.FeedCacheTTL {
+		return false
+	}
+	metrics.FeedCache("stale")
+	c.Header("X-Whispr-Stale", feed.At.UTC().Format(http.TimeFormat))
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, feed.Posts)
+	return true
+}
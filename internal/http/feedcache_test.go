@@ -0,0 +1,155 @@
+package http_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sujalbistaa/whispr/internal/metrics"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+func getFeed(t *testing.T, ts *testutil.TestServer, etag string) (int, string) {
+	t.Helper()
+	req := ts.NewRequest(t, http.MethodGet, "/api/v1/posts", nil)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode, resp.Header.Get("ETag")
+}
+
+func TestFeedETag(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	post := ts.CreatePost(t, "the first post in the feed")
+
+	status, etag := getFeed(t, ts, "")
+	if status != http.StatusOK || etag == "" {
+		t.Fatalf("first GET: status %d, ETag %q; want 200 with an ETag", status, etag)
+	}
+	if status, again := getFeed(t, ts, etag); status != http.StatusNotModified || again != etag {
+		t.Errorf("GET with If-None-Match: status %d, ETag %q; want 304 with %q", status, again, etag)
+	}
+	if status, _ := getFeed(t, ts, `"something-else"`); status != http.StatusOK {
+		t.Errorf("GET with a stale If-None-Match: status %d, want 200", status)
+	}
+
+	for _, write := range []struct {
+		name string
+		do   func()
+	}{
+		{"post", func() { ts.CreatePost(t, "a second post changes the feed") }},
+		{"vote", func() { ts.Vote(t, post.ID, 1) }},
+		{"hide", func() { ts.Hide(t, post.ID) }},
+	} {
+		name := write.name
+		write.do()
+		status, next := getFeed(t, ts, etag)
+		if status != http.StatusOK || next == etag {
+			t.Errorf("after a %s: status %d, ETag %q; want 200 with a new ETag", name, status, next)
+		}
+		if status, _ := getFeed(t, ts, next); status != http.StatusNotModified {
+			t.Errorf("after a %s: status %d with the new ETag, want 304", name, status)
+		}
+		etag = next
+	}
+}
+
+// listFeed returns the posts GET path answers with.
+func listFeed(tb testing.TB, ts *testutil.TestServer, path string) []models.Post {
+	tb.Helper()
+	status, body := ts.Do(tb, ts.NewRequest(tb, http.MethodGet, path, nil))
+	if status != http.StatusOK {
+		tb.Fatalf("GET %s: status %d", path, status)
+	}
+	var posts []models.Post
+	if err := json.Unmarshal(body, &posts); err != nil {
+		tb.Fatalf("decoding %s: %v", body, err)
+	}
+	return posts
+}
+
+func postIDs(posts []models.Post) []uint {
+	ids := make([]uint, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	return ids
+}
+
+// TestFeedCacheInvalidation checks writes show up in the next read even
+// though the cache would otherwise hold the feed for an hour.
+func TestFeedCacheInvalidation(t *testing.T) {
+	t.Setenv("FEED_CACHE_FRESH", "1h")
+	ts := testutil.NewTestServer(t)
+	hits := map[string]string{"result": "hit"}
+	first := ts.CreatePost(t, "the first post")
+
+	listFeed(t, ts, "/api/v1/posts")
+	before := counterValue(t, metrics.NameFeedCache, hits)
+	if posts := listFeed(t, ts, "/api/v1/posts"); len(posts) != 1 {
+		t.Fatalf("cached feed has %d posts, want 1", len(posts))
+	}
+	if got := counterValue(t, metrics.NameFeedCache, hits) - before; got != 1 {
+		t.Fatalf("second read: %g cache hits, want 1", got)
+	}
+
+	second := ts.CreatePost(t, "posted while the feed was cached")
+	if posts := listFeed(t, ts, "/api/v1/posts"); len(posts) != 2 || posts[0].ID != second.ID {
+		t.Errorf("feed after a new post: %v, want post %d first", postIDs(posts), second.ID)
+	}
+
+	ts.Vote(t, first.ID, 1)
+	posts := listFeed(t, ts, "/api/v1/posts")
+	if len(posts) != 2 || posts[1].Score != first.Score+1 {
+		t.Errorf("feed after a vote: %+v, want post %d at score %d", posts, first.ID, first.Score+1)
+	}
+	// The query string is part of the key: the active feed isn't the
+	// cached new one.
+	if posts := listFeed(t, ts, "/api/v1/posts?sort=active"); len(posts) != 2 || posts[0].ID != first.ID {
+		t.Errorf("active feed: %v, want the voted post %d first", postIDs(posts), first.ID)
+	}
+
+	ts.Hide(t, second.ID)
+	if posts := listFeed(t, ts, "/api/v1/posts"); len(posts) != 1 || posts[0].ID != first.ID {
+		t.Errorf("feed after a hide: %v, want only post %d", postIDs(posts), first.ID)
+	}
+}
+
+// BenchmarkFeed serves the first page of a 200-post feed with the cache
+// on and off; compare the req/s of the two.
+func BenchmarkFeed(b *testing.B) {
+	for _, fresh := range []string{"0s", "3s"} {
+		b.Run("FEED_CACHE_FRESH="+fresh, func(b *testing.B) {
+			b.Setenv("FEED_CACHE_FRESH", fresh)
+			ts := testutil.NewTestServer(b)
+			for i := 0; i < 200; i++ {
+				post := models.Post{Content: fmt.Sprintf("benchmark post %d", i), CreatedAt: time.Now(), LastActivityAt: time.Now()}
+				if err := ts.DB.Create(&post).Error; err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					resp, err := ts.Client().Do(ts.NewRequest(b, http.MethodGet, "/api/v1/posts", nil))
+					if err != nil {
+						b.Error(err)
+						return
+					}
+					io.Copy(io.Discard, resp.Body)
+					resp.Body.Close()
+				}
+			})
+			b.ReportMetric(float64(b.N)/b.Elapsed().Seconds(), "req/s")
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package http
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+const (
+	feedSize       = 50  // Posts per feed
+	feedTitleRunes = 80  // Item titles are truncated to this many characters
+	feedMaxAge     = 120 // Seconds feed readers and proxies may cache a feed
+	feedTitle      = "Whispr"
+	feedSubtitle   = "Latest anonymous posts"
+)
+
+// --- RSS 2.0 ---
+
+type rssDocument struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	SelfLink      rssLink   `xml:"atom:link"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	TTL           int       `xml:"ttl"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	Link        string  `xml:"link"`
+	GUID        rssGUID `xml:"guid"`
+	Description string  `xml:"description"`
+	PubDate     string  `xml:"pubDate"`
+}
+
+type rssGUID struct {
+	Value       string `xml:",chardata"`
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+}
+
+// --- Atom 1.0 ---
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Author  atomAuthor  `xml:"author"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	Title   string    `xml:"title"`
+	ID      string    `xml:"id"`
+	Link    atomLink  `xml:"link"`
+	Updated string    `xml:"updated"`
+	Content atomValue `xml:"content"`
+}
+
+type atomValue struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// GetRSSFeed renders the latest visible posts as RSS 2.0.
+func (e *Env) GetRSSFeed(c *gin.Context) {
+	posts, ok := e.feedPosts(c)
+	if !ok {
+		return
+	}
+	base := e.publicBaseURL(c)
+
+	channel := rssChannel{
+		Title:       feedTitle,
+		Link:        base + "/",
+		Description: feedSubtitle,
+		SelfLink:    rssLink{Href: base + "/feed.rss", Rel: "self", Type: "application/rss+xml"},
+		TTL:         feedMaxAge / 60,
+	}
+	if len(posts) > 0 {
+		channel.LastBuildDate = posts[0].CreatedAt.UTC().Format(time.RFC1123Z)
+	}
+	for _, post := range posts {
+		link := postPermalink(base, post.ID)
+		channel.Items = append(channel.Items, rssItem{
+			Title:       feedItemTitle(post.Content),
+			Link:        link,
+			GUID:        rssGUID{Value: link, IsPermaLink: true},
+			Description: post.Content,
+			PubDate:     post.CreatedAt.UTC().Format(time.RFC1123Z),
+		})
+	}
+
+	writeFeed(c, "application/rss+xml; charset=utf-8", rssDocument{
+		Version: "2.0",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: channel,
+	})
+}
+
+// GetAtomFeed renders the latest visible posts as Atom 1.0.
+func (e *Env) GetAtomFeed(c *gin.Context) {
+	posts, ok := e.feedPosts(c)
+	if !ok {
+		return
+	}
+	base := e.publicBaseURL(c)
+
+	feed := atomFeed{
+		Title:   feedTitle,
+		ID:      base + "/feed.atom",
+		Updated: time.Unix(0, 0).UTC().Format(time.RFC3339),
+		Author:  atomAuthor{Name: feedTitle},
+		Links: []atomLink{
+			{Href: base + "/feed.atom", Rel: "self"},
+			{Href: base + "/"},
+		},
+	}
+	if len(posts) > 0 {
+		feed.Updated = posts[0].CreatedAt.UTC().Format(time.RFC3339)
+	}
+	for _, post := range posts {
+		link := postPermalink(base, post.ID)
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   feedItemTitle(post.Content),
+			ID:      link,
+			Link:    atomLink{Href: link},
+			Updated: post.UpdatedAt.UTC().Format(time.RFC3339),
+			Content: atomValue{Type: "text", Value: post.Content},
+		})
+	}
+
+	writeFeed(c, "application/atom+xml; charset=utf-8", feed)
+}
+
+// feedPosts loads the newest publicly visible posts that aren't
+// archived. Feeds are cached by readers and proxies, so they never
+// include a shadow-banned viewer's own posts.
+func (e *Env) feedPosts(c *gin.Context) ([]models.Post, bool) {
+	var posts []models.Post
+	err := e.DB.WithContext(c.Request.Context()).Where("shadow_banned = ? AND archived_at IS NULL", false).
+		Order("created_at desc").Limit(feedSize).Find(&posts).Error
+	if err != nil {
+		requestLogger(c).Error("fetching feed posts", "err", err)
+		respondError(c, ErrInternal("post.list_failed"))
+		return nil, false
+	}
+	return posts, true
+}
+
+func writeFeed(c *gin.Context, contentType string, doc any) {
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		requestLogger(c).Error("rendering feed", "err", err)
+		respondError(c, ErrInternal("feed.render_failed"))
+		return
+	}
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(feedMaxAge))
+	c.Data(http.StatusOK, contentType, append([]byte(xml.Header), body...))
+}
+
+// feedItemTitle collapses whitespace and truncates content to a title.
+func feedItemTitle(content string) string {
+	title := strings.Join(strings.FieldsFunc(content, unicode.IsSpace), " ")
+	if utf8.RuneCountInString(title) <= feedTitleRunes {
+		return title
+	}
+	runes := []rune(title)
+	return strings.TrimSpace(string(runes[:feedTitleRunes-1])) + "…"
+}
+
+// postPermalink links to the single-post API route.
+func postPermalink(base string, id uint) string {
+	return base + apiV1Prefix + "/posts/" + strconv.FormatUint(uint64(id), 10)
+}
+
+// publicBaseURL returns PUBLIC_URL, or the scheme and host the request
+// arrived on, without a trailing slash.
+func (e *Env) publicBaseURL(c *gin.Context) string {
+	if e.Config.PublicURL != "" {
+		return e.Config.PublicURL
+	}
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
@@ -0,0 +1,78 @@
+package http_test
+
+import (
+	"bytes"
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// seedFeedPosts stores posts with fixed IDs and times, so the feeds
+// render the same every run.
+func seedFeedPosts(t *testing.T, ts *testutil.TestServer) {
+	t.Helper()
+	at := time.Date(2024, time.May, 12, 9, 30, 0, 0, time.UTC)
+	for i, content := range []string{
+		"Library open late <b>all week</b> & the café too — \"finally\"",
+		"Lost a blue water bottle in room 204,\n\n   please message if found",
+		strings.Repeat("Does anyone have the notes from yesterday's thermodynamics lecture? ", 3),
+		"नमस्ते! परीक्षा कहिले हो?",
+	} {
+		created := at.Add(time.Duration(i) * time.Hour)
+		seedPost(t, ts, models.Post{ID: uint(i + 1), Content: content, CreatedAt: created, UpdatedAt: created.Add(time.Minute)})
+	}
+	seedPost(t, ts, models.Post{ID: 5, Content: "shadow-banned, never in a feed", CreatedAt: at, ShadowBanned: true})
+}
+
+func TestFeeds(t *testing.T) {
+	t.Setenv("PUBLIC_URL", "https://whispr.example/")
+	ts := testutil.NewTestServer(t)
+	seedFeedPosts(t, ts)
+
+	for _, tc := range []struct {
+		path, contentType, golden string
+	}{
+		{"/feed.rss", "application/rss+xml; charset=utf-8", "feed.rss"},
+		{"/feed.atom", "application/atom+xml; charset=utf-8", "feed.atom"},
+	} {
+		resp, err := ts.Client().Do(ts.NewRequest(t, http.MethodGet, tc.path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body bytes.Buffer
+		body.ReadFrom(resp.Body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: status %d", tc.path, resp.StatusCode)
+		}
+		if got := resp.Header.Get("Content-Type"); got != tc.contentType {
+			t.Errorf("GET %s: Content-Type %q, want %q", tc.path, got, tc.contentType)
+		}
+		if got := resp.Header.Get("Cache-Control"); got != "public, max-age=120" {
+			t.Errorf("GET %s: Cache-Control %q", tc.path, got)
+		}
+
+		golden := filepath.Join("testdata", tc.golden)
+		if *update {
+			if err := os.WriteFile(golden, body.Bytes(), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		want, err := os.ReadFile(golden)
+		if err != nil {
+			t.Fatalf("%v (run with -update to create it)", err)
+		}
+		if !bytes.Equal(body.Bytes(), want) {
+			t.Errorf("GET %s differs from %s:\n%s", tc.path, golden, body.Bytes())
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sujalbistaa/whispr/internal/audit"
+	"github.com/sujalbistaa/whispr/internal/flags"
+)
+
+// FlagsMiddleware puts the flag set in the request context, where
+// handlers check it with flags.Enabled.
+func FlagsMiddleware(set *flags.Set) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Request = c.Request.WithContext(flags.WithSet(c.Request.Context(), set))
+		c.Next()
+	}
+}
+
+// RequireFlag answers 404 while f is off, so a feature being rolled out
+// looks absent rather than broken. It must run after FlagsMiddleware.
+func RequireFlag(f flags.Flag) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !flags.Enabled(c.Request.Context(), f) {
+			respondError(c, ErrNotFound("request.not_found"))
+			return
+		}
+		c.Next()
+	}
+}
+
+// --- Admin ---
+
+// FlagInput switches a flag.
+type FlagInput struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// GetFlags lists every flag with its current value and default.
+func (e *Env) GetFlags(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"flags": e.Flags.States()})
+}
+
+// SetFlag switches a flag on this server until restart. The change is
+// audited; it isn't shared with other replicas.
+func (e *Env) SetFlag(c *gin.Context) {
+	name := flags.Flag(c.Param("name"))
+	var input FlagInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, ErrValidation(err))
+		return
+	}
+
+	changed, err := e.Flags.Set(name, *input.Enabled)
+	if errors.Is(err, flags.ErrUnknown) {
+		respondError(c, ErrNotFound("flag.not_found", "name", name))
+		return
+	}
+	if changed {
+		requestLogger(c).Warn("feature flag changed", "flag", name, "enabled", *input.Enabled, "actor", adminActor(c))
+		if err := audit.Record(e.DB, adminActor(c), audit.ActionFlagSet, audit.TargetFlag, 0, map[string]any{"flag": name, "enabled": *input.Enabled}); err != nil {
+			requestLogger(c).Error("recording flag change", "err", err)
+		}
+	}
+
+	def, _ := flags.Lookup(name)
+	c.JSON(http.StatusOK, flags.State{Name: name, Enabled: *input.Enabled, Default: def.Default, Description: def.Description})
+}
@@ -0,0 +1,66 @@
+package http
+
+import (
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// hashedAsset matches fingerprinted filenames such as app.3f9a1c2b.js,
+// which can be cached forever because their name changes with content.
+var hashedAsset = regexp.MustCompile(`\.[0-9a-fA-F]{8,}\.[A-Za-z0-9]+$`)
+
+// frontendFS returns dir (FRONTEND_DIR) when set, so the UI can be edited
+// live during development, or else the embedded copy.
+func frontendFS(embedded fs.FS, dir string) fs.FS {
+	if dir != "" {
+		return os.DirFS(dir)
+	}
+	return embedded
+}
+
+// serverPath reports whether p belongs to the server rather than the UI:
+// the API, /ws, and /metrics and /debug, which are absent from the main
+// listener when they have one of their own.
+func serverPath(p string) bool {
+	for _, prefix := range []string{"/api", "/debug"} {
+		if p == prefix || strings.HasPrefix(p, prefix+"/") {
+			return true
+		}
+	}
+	return p == "/ws" || p == "/metrics"
+}
+
+// FrontendHandler serves files from fsys. Paths that don't match a file
+// get index.html so client-side routes load the app, except for server
+// paths, where a JSON 404 is more useful to API clients.
+func FrontendHandler(fsys fs.FS) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reqPath := c.Request.URL.Path
+		if serverPath(reqPath) {
+			respondError(c, ErrNotFound("request.not_found"))
+			return
+		}
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			respondError(c, ErrNotFound("request.not_found"))
+			return
+		}
+
+		name := strings.TrimPrefix(path.Clean(reqPath), "/")
+		if info, err := fs.Stat(fsys, name); name == "" || err != nil || info.IsDir() {
+			name = "index.html"
+		}
+
+		if hashedAsset.MatchString(name) {
+			c.Header("Cache-Control", "public, max-age=31536000, immutable")
+		} else {
+			c.Header("Cache-Control", "no-cache")
+		}
+		http.ServeFileFS(c.Writer, c.Request, fsys, name)
+	}
+}
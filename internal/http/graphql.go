@@ -0,0 +1,330 @@
+package http
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/graph-gophers/graphql-go"
+	"gorm.io/gorm"
+
+	"github.com/sujalbistaa/whispr/internal/db"
+	"github.com/sujalbistaa/whispr/internal/logging"
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+//go:embed schema.graphql
+var graphqlSchemaSource string
+
+const (
+	// graphqlMaxDepth allows Query > posts > votes > up and nothing deeper.
+	graphqlMaxDepth = 4
+	// graphqlMaxFirst caps the page size of a single list field.
+	graphqlMaxFirst = 50
+	// graphqlMaxPosts caps the posts one request may load across all of its
+	// fields, so aliases can't multiply the page size limit.
+	graphqlMaxPosts = 200
+	// graphqlMaxQueryLength bounds the query text; the schema is small, so
+	// legitimate queries are far shorter.
+	graphqlMaxQueryLength = 8 << 10
+)
+
+var graphqlSchema = graphql.MustParseSchema(graphqlSchemaSource, &graphqlResolver{},
+	graphql.UseStringDescriptions(),
+	graphql.MaxDepth(graphqlMaxDepth),
+	graphql.MaxQueryLength(graphqlMaxQueryLength),
+)
+
+// isGraphQLPath reports whether path is the GraphQL endpoint. It only reads,
+// so its POSTs are exempt from write-only restrictions.
+func isGraphQLPath(path string) bool {
+	return path == apiV1Prefix+"/graphql" || path == "/api/graphql"
+}
+
+// GraphQLInput is a GraphQL request body.
+type GraphQLInput struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// GraphQL executes a read-only query, given as a JSON body on POST or as
+// query parameters on GET. Query errors are reported in the response body
+// with a 200, as GraphQL clients expect.
+func (e *Env) GraphQL(c *gin.Context) {
+	var input GraphQLInput
+	if c.Request.Method == http.MethodGet {
+		input.Query = c.Query("query")
+		input.OperationName = c.Query("operationName")
+		if raw := c.Query("variables"); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &input.Variables); err != nil {
+				respondError(c, ErrBadRequest("graphql.invalid_variables"))
+				return
+			}
+		}
+	} else if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, ErrValidation(err))
+		return
+	}
+	if strings.TrimSpace(input.Query) == "" {
+		respondError(c, ErrBadRequest("graphql.query_required"))
+		return
+	}
+
+	req := &graphqlRequest{
+		db:     e.DB.Scopes(e.visiblePosts(c)),
+		votes:  e.DB,
+		board:  e.boardBySlug,
+		base:   e.publicBaseURL(c),
+		budget: graphqlMaxPosts,
+		loaded: map[uint]voteBreakdown{},
+	}
+	ctx := context.WithValue(c.Request.Context(), graphqlRequestKey{}, req)
+	c.JSON(http.StatusOK, graphqlSchema.Exec(ctx, input.Query, input.OperationName, input.Variables))
+}
+
+// --- Request state ---
+
+type graphqlRequestKey struct{}
+
+// graphqlRequest is the state shared by the resolvers of one request: the
+// caller's visibility scope, the post budget and the vote loader.
+type graphqlRequest struct {
+	db    *gorm.DB // Posts, scoped to what the caller may see
+	votes *gorm.DB
+	base  string
+	board func(ctx context.Context, slug string) (models.Board, error)
+
+	mu      sync.Mutex
+	budget  int
+	pending []uint // Posts returned so far whose votes aren't loaded
+	loaded  map[uint]voteBreakdown
+}
+
+func requestFrom(ctx context.Context) *graphqlRequest {
+	return ctx.Value(graphqlRequestKey{}).(*graphqlRequest)
+}
+
+// errGraphQLInternal hides database details from clients; the cause is
+// logged.
+var errGraphQLInternal = errors.New("internal error")
+
+// charge takes n posts from the request's budget.
+func (r *graphqlRequest) charge(n int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if n > r.budget {
+		return fmt.Errorf("query too complex: at most %d posts may be requested in total", graphqlMaxPosts)
+	}
+	r.budget -= n
+	return nil
+}
+
+// inBoard limits query to the board named slug, if one is.
+func (r *graphqlRequest) inBoard(ctx context.Context, query *gorm.DB, slug *string) (*gorm.DB, error) {
+	if slug == nil {
+		return query, nil
+	}
+	board, err := r.board(ctx, *slug)
+	if errors.Is(err, models.ErrBoardNotFound) {
+		return nil, fmt.Errorf("no board %q", *slug)
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("looking up board", "err", err)
+		return nil, errGraphQLInternal
+	}
+	return query.Where("board_id = ?", board.ID), nil
+}
+
+// resolvers wraps posts and queues their IDs for the next vote batch.
+func (r *graphqlRequest) resolvers(posts []models.Post) []*postResolver {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]*postResolver, len(posts))
+	for i, post := range posts {
+		if _, ok := r.loaded[post.ID]; !ok {
+			r.pending = append(r.pending, post.ID)
+		}
+		out[i] = &postResolver{req: r, post: post}
+	}
+	return out
+}
+
+// voteBreakdown returns the vote counts for id. The first call loads every
+// pending post in one query, so a page of posts costs one round trip
+// rather than one per post.
+func (r *graphqlRequest) voteBreakdown(ctx context.Context, id uint) (voteBreakdown, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if counts, ok := r.loaded[id]; ok {
+		return counts, nil
+	}
+
+	ids := append(r.pending, id)
+	r.pending = nil
+	var rows []struct {
+		PostID   uint
+		Up, Down int32
+	}
+	err := r.votes.WithContext(ctx).Model(&models.Vote{}).
+		Select("post_id, SUM(CASE WHEN value > 0 THEN 1 ELSE 0 END) AS up, SUM(CASE WHEN value < 0 THEN 1 ELSE 0 END) AS down").
+		Where("post_id IN ?", ids).
+		Group("post_id").
+		Scan(&rows).Error
+	if err != nil {
+		logging.FromContext(ctx).Error("loading vote breakdowns", "err", err)
+		return voteBreakdown{}, errGraphQLInternal
+	}
+	for _, postID := range ids {
+		r.loaded[postID] = voteBreakdown{}
+	}
+	for _, row := range rows {
+		r.loaded[row.PostID] = voteBreakdown{up: row.Up, down: row.Down}
+	}
+	return r.loaded[id], nil
+}
+
+// --- Resolvers ---
+
+type graphqlResolver struct{}
+
+func pageSize(first int32) (int, error) {
+	if first < 1 || first > graphqlMaxFirst {
+		return 0, fmt.Errorf("first must be between 1 and %d", graphqlMaxFirst)
+	}
+	return int(first), nil
+}
+
+func (*graphqlResolver) Posts(ctx context.Context, args struct {
+	First  int32
+	Offset int32
+	Search *string
+	Board  *string
+}) ([]*postResolver, error) {
+	limit, err := pageSize(args.First)
+	if err != nil {
+		return nil, err
+	}
+	if args.Offset < 0 {
+		return nil, errors.New("offset must not be negative")
+	}
+	req := requestFrom(ctx)
+	if err := req.charge(limit); err != nil {
+		return nil, err
+	}
+
+	query := req.db.WithContext(ctx).Where("archived_at IS NULL").Order("created_at desc, id desc").Offset(int(args.Offset)).Limit(limit)
+	if args.Search != nil {
+		if search := strings.TrimSpace(*args.Search); search != "" {
+			query = query.Scopes(db.ContainsFold("content", search))
+		}
+	}
+	query, err = req.inBoard(ctx, query, args.Board)
+	if err != nil {
+		return nil, err
+	}
+	var posts []models.Post
+	if err := query.Find(&posts).Error; err != nil {
+		logging.FromContext(ctx).Error("fetching posts", "err", err)
+		return nil, errGraphQLInternal
+	}
+	return req.resolvers(posts), nil
+}
+
+func (*graphqlResolver) Trending(ctx context.Context, args struct {
+	First int32
+	Board *string
+}) ([]*postResolver, error) {
+	limit, err := pageSize(args.First)
+	if err != nil {
+		return nil, err
+	}
+	req := requestFrom(ctx)
+	if err := req.charge(limit); err != nil {
+		return nil, err
+	}
+
+	query, err := req.inBoard(ctx, req.db.WithContext(ctx).Where("archived_at IS NULL").Order("score desc, created_at desc").Limit(limit), args.Board)
+	if err != nil {
+		return nil, err
+	}
+	var posts []models.Post
+	if err := query.Find(&posts).Error; err != nil {
+		logging.FromContext(ctx).Error("fetching trending posts", "err", err)
+		return nil, errGraphQLInternal
+	}
+	return req.resolvers(posts), nil
+}
+
+func (*graphqlResolver) Post(ctx context.Context, args struct{ ID graphql.ID }) (*postResolver, error) {
+	id, err := strconv.ParseUint(string(args.ID), 10, 32)
+	if err != nil {
+		return nil, nil
+	}
+	req := requestFrom(ctx)
+	if err := req.charge(1); err != nil {
+		return nil, err
+	}
+
+	var posts []models.Post
+	if err := req.db.WithContext(ctx).Where("id = ?", id).Limit(1).Find(&posts).Error; err != nil {
+		logging.FromContext(ctx).Error("fetching post", "err", err)
+		return nil, errGraphQLInternal
+	}
+	if len(posts) == 0 {
+		return nil, nil
+	}
+	return req.resolvers(posts)[0], nil
+}
+
+type postResolver struct {
+	req  *graphqlRequest
+	post models.Post
+}
+
+func (p *postResolver) ID() graphql.ID {
+	return graphql.ID(strconv.FormatUint(uint64(p.post.ID), 10))
+}
+
+func (p *postResolver) BoardID() int32  { return int32(p.post.BoardID) }
+func (p *postResolver) Content() string { return p.post.Content }
+func (p *postResolver) Lang() string    { return p.post.Lang }
+func (p *postResolver) Score() int32    { return int32(p.post.Score) }
+
+func (p *postResolver) ContentWarning() *string {
+	if p.post.ContentWarning == "" {
+		return nil
+	}
+	return &p.post.ContentWarning
+}
+func (p *postResolver) URL() string { return postPermalink(p.req.base, p.post.ID) }
+
+func (p *postResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: p.post.CreatedAt}
+}
+
+func (p *postResolver) LastActivityAt() graphql.Time {
+	return graphql.Time{Time: p.post.LastActivityAt}
+}
+
+func (p *postResolver) Votes(ctx context.Context) (*voteBreakdown, error) {
+	counts, err := p.req.voteBreakdown(ctx, p.post.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &counts, nil
+}
+
+type voteBreakdown struct {
+	up, down int32
+}
+
+func (v *voteBreakdown) Up() int32   { return v.up }
+func (v *voteBreakdown) Down() int32 { return v.down }
@@ -1,127 +1,579 @@
 package http
 
 import (
-	"encoding/json"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"slices"
 	"strconv"
-	"sync"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"golang.org/x/time/rate"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/sync/singleflight"
 	"gorm.io/gorm"
 
+	"github.com/sujalbistaa/whispr/internal/auth"
+	"github.com/sujalbistaa/whispr/internal/bans"
+	"github.com/sujalbistaa/whispr/internal/challenge"
+	"github.com/sujalbistaa/whispr/internal/config"
+	"github.com/sujalbistaa/whispr/internal/contentpolicy"
+	"github.com/sujalbistaa/whispr/internal/contentwarning"
+	"github.com/sujalbistaa/whispr/internal/db"
+	"github.com/sujalbistaa/whispr/internal/eventlog"
+	"github.com/sujalbistaa/whispr/internal/flags"
+	"github.com/sujalbistaa/whispr/internal/lang"
+	"github.com/sujalbistaa/whispr/internal/metrics"
 	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/push"
+	"github.com/sujalbistaa/whispr/internal/store"
+	"github.com/sujalbistaa/whispr/internal/supervisor"
+	"github.com/sujalbistaa/whispr/internal/webhook"
 	"github.com/sujalbistaa/whispr/internal/ws"
 )
 
 // --- Configuration Constants ---
-const (
-	maxPostLength  = 1000
-	rateLimitRPS   = 1.0 / 3.0 // 1 request every 3 seconds
-	rateLimitBurst = 1
-)
+const maxPostLength = 1000
 
 // --- Structs for request binding ---
 type CreatePostInput struct {
-	Content string `json:"content" binding:"required,min=1,max=1000"`
+	Content        string `json:"content" binding:"required,min=1,max=1000"`
+	ContentWarning string `json:"contentWarning"` // A contentwarning.Warning; applied by keyword when empty
 }
 type VoteInput struct {
-	Value int `json:"value" binding:"required,oneof=-1 1"` // Must be 1 or -1
+	Value  int    `json:"value" binding:"required,oneof=-1 1"`     // Must be 1 or -1
+	VoteID string `json:"voteId" binding:"omitempty,uuid_rfc4122"` // Optional; a retry with the same one isn't counted again
 }
 
+// readinessTimeout bounds the database ping in /readyz.
+const readinessTimeout = 2 * time.Second
+
 // --- WebSocket Payloads ---
 
 // WsMessage defines the JSON structure our frontend *expects*.
 type WsMessage struct {
-	Type string      `json:"type"`
-	Data interface{} `json:"data"`
+	Type  string      `json:"type"`
+	Data  interface{} `json:"data"`
+	Board string      `json:"board,omitempty"` // Slug of the board it concerns; empty for every board
+}
+
+// --- Handlers ---
+type Env struct {
+	DB          *gorm.DB
+	Posts       store.PostStore
+	Votes       store.VoteStore
+	Bookmarks   store.BookmarkStore
+	Hub         *ws.Hub     // Serves /ws and counts clients
+	Broadcaster Broadcaster // Where handlers send WebSocket messages
+	Bans        *bans.List
+	Tokens      *auth.TokenStore
+
+	Config      config.Config
+	Global      *GlobalLimiter
+	Maintenance *Maintenance
+	Flags       *flags.Set
+	Content     *contentpolicy.Policy    // nil when CONTENT_POLICY is off
+	Warnings    *contentwarning.Detector // nil when CONTENT_WARNING_AUTO is off
+	Challenge   challenge.Verifier       // nil when posts need no challenge
+	Webhooks    *webhook.Dispatcher      // nil when no webhooks are configured
+	Push        *push.Dispatcher         // nil when no VAPID keys are configured
+	Events      *eventlog.Log            // nil when EVENT_LOG_PATH is unset
+	DBHealth    *db.Health               // Run by the caller; nil when disabled
+	Supervisor  *supervisor.Supervisor   // Restarts background goroutines; one given up on fails readiness
+
+	// voterKey is the HMAC key for Vote.VoterHash.
+	voterKey []byte
+	// creatorKey is the HMAC key for Post.CreatorHash; nil when
+	// CREATOR_HASH is off.
+	creatorKey []byte
+
+	// shuttingDown flips readiness to failing during graceful shutdown.
+	shuttingDown atomic.Bool
+	// schemaCurrent caches that migrations have caught up; the schema
+	// can't fall behind a running binary, so it's only checked until then.
+	schemaCurrent atomic.Bool
+
+	// feeds caches the feed queries; feedReads shares a query between
+	// concurrent requests for the same feed.
+	feeds     FeedCache
+	feedReads singleflight.Group
+
+	publicStats publicStatsCache
+	wordStats   wordStatsCache
+	dashboard   dashboardCache
+	boards      boardCache
+	undo        undoTokens
+
+	// limiters, redis and the broadcast relay are stopped by Close.
+	limiters      []*IPRateLimiter
+	redisLimiters []*RedisRateLimiter
+	redis         *redis.Client
+	stopRelay     context.CancelFunc // nil unless relaying a primary's broadcasts
+}
+
+// SetShuttingDown makes /readyz fail so load balancers stop routing here.
+func (e *Env) SetShuttingDown() {
+	e.shuttingDown.Store(true)
+}
+
+// Close stops background workers started by SetupRoutes.
+func (e *Env) Close() {
+	for _, limiter := range e.limiters {
+		limiter.Stop()
+	}
+	if e.stopRelay != nil {
+		e.stopRelay()
+	}
+	if e.redis != nil {
+		e.redis.Close()
+	}
+	e.Webhooks.Close()
+	e.Push.Close()
+	e.Events.Close()
+}
+
+// viewer describes the caller to the store (see store.Viewer). The ban is
+// looked up up front: inside a transaction on a single-connection pool,
+// loading the ban list would wait on the transaction's own connection.
+func (e *Env) viewer(c *gin.Context) store.Viewer {
+	banID, shadowBanned := e.viewerShadowBan(c)
+	return store.Viewer{ShadowBanID: banID, ShadowBanned: shadowBanned}
+}
+
+// visiblePosts scopes a post query that doesn't go through the store to
+// what the caller may see.
+func (e *Env) visiblePosts(c *gin.Context) func(*gorm.DB) *gorm.DB {
+	return e.viewer(c).Scope
+}
+
+// voterHash identifies the caller for one-vote-per-post without storing
+// their IP: an HMAC of it, keyed per deployment.
+func (e *Env) voterHash(c *gin.Context) string {
+	mac := hmac.New(sha256.New, e.voterKey)
+	mac.Write([]byte(clientIP(c)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// creatorHash identifies the caller's IP to moderators, keyed apart from
+// voterHash so it can't be matched against votes or the identities keyed
+// by them. It is nil when CREATOR_HASH is off.
+func (e *Env) creatorHash(c *gin.Context) *string {
+	if e.creatorKey == nil {
+		return nil
+	}
+	mac := hmac.New(sha256.New, e.creatorKey)
+	mac.Write([]byte(clientIP(c)))
+	hash := hex.EncodeToString(mac.Sum(nil))
+	return &hash
+}
+
+// viewerShadowBan returns the shadow ban covering the caller, if any.
+func (e *Env) viewerShadowBan(c *gin.Context) (uint, bool) {
+	if id, ok := c.Get(shadowBanKey); ok {
+		return id.(uint), true
+	}
+	match, banned, err := e.Bans.Lookup(clientIP(c))
+	if err != nil {
+		requestLogger(c).Error("checking ban list", "err", err)
+		return 0, false
+	}
+	if !banned || !match.Shadow {
+		return 0, false
+	}
+	return match.ID, true
+}
+
+// Health reports process liveness and the effective runtime configuration.
+func (e *Env) Health(c *gin.Context) {
+	var limiterErrors uint64
+	for _, limiter := range e.redisLimiters {
+		limiterErrors += limiter.Errors()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":            "ok",
+		"maintenance":       e.Maintenance.State().Mode,
+		"rateLimits":        e.Config.RateLimit,
+		"rateLimiterErrors": limiterErrors,
+		"inFlightPosts":     e.Global.InFlight(),
+		"globalRejections":  e.Global.Rejected(),
+	})
+}
+
+// Ready reports whether this instance should receive traffic: the
+// database answers a ping and has every migration this binary expects, the
+// Hub is running, and we aren't shutting down. Failing dependencies are
+// named in the 503 body.
+func (e *Env) Ready(c *gin.Context) {
+	checks := gin.H{"database": "ok", "schema": "ok", "hub": "ok", "workers": "ok", "shutdown": "ok"}
+	ready := true
+	status := "ok"
+
+	sqlDB, err := e.DB.DB()
+	if err == nil && !e.DBHealth.Down() {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+		err = sqlDB.PingContext(ctx)
+		cancel()
+	}
+	if e.DBHealth.Down() {
+		// Degraded, not unready: reads are still served from the feed
+		// cache, and every replica shares the same database.
+		checks["database"] = "down since " + e.DBHealth.Since().UTC().Format(time.RFC3339) + "; read-only"
+		checks["schema"] = "unknown"
+		status = "degraded"
+	} else if err != nil {
+		requestLogger(c).Warn("readiness: database ping failed", "err", err)
+		checks["database"] = "unreachable"
+		checks["schema"] = "unknown"
+		ready = false
+	} else if !e.schemaCurrent.Load() {
+		version, err := db.Version(e.DB.WithContext(c.Request.Context()))
+		switch {
+		case err != nil:
+			requestLogger(c).Warn("readiness: reading schema version failed", "err", err)
+			checks["schema"] = "unknown"
+			ready = false
+		case version < db.LatestVersion():
+			checks["schema"] = fmt.Sprintf("behind: version %d, want %d", version, db.LatestVersion())
+			ready = false
+		default:
+			e.schemaCurrent.Store(true)
+		}
+	}
+	if !e.Hub.Running() {
+		checks["hub"] = "not running"
+		ready = false
+	}
+	if failed := e.Supervisor.Failed(); len(failed) > 0 {
+		checks["workers"] = "stopped after repeated panics: " + strings.Join(failed, ", ")
+		ready = false
+	}
+	if e.shuttingDown.Load() {
+		checks["shutdown"] = "shutting down"
+		ready = false
+	}
+
+	if !ready {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unavailable", "checks": checks})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"status": status, "checks": checks})
+}
+
+func (e *Env) GetPosts(c *gin.Context) {
+	if raw, ok := c.GetQuery("ids"); ok {
+		e.getPostsByID(c, raw)
+		return
+	}
+	board, feed, ok := e.newFeed(c)
+	if !ok {
+		return
+	}
+	e.serveFeed(c, "posts:"+board.Slug, feed)
 }
 
-// --- Rate Limiter ---
-type IPRateLimiter struct {
-	visitors map[string]*rate.Limiter
-	mu       sync.RWMutex
-	rps      rate.Limit
-	burst    int
+// feedSorts maps ?sort= to the feed it selects; new is the default.
+var feedSorts = map[string]store.Feed{
+	"new":    store.FeedNew,
+	"active": store.FeedActive,
 }
 
-func NewIPRateLimiter(r rate.Limit, b int) *IPRateLimiter {
-	return &IPRateLimiter{
-		visitors: make(map[string]*rate.Limiter),
-		mu:       sync.RWMutex{},
-		rps:      r,
-		burst:    b,
+// newFeed returns the feed of the request's board in the order ?sort=
+// asks for, newest first by default, limited to ?lang= if given. When it
+// reports false the error has been sent.
+func (e *Env) newFeed(c *gin.Context) (models.Board, store.Feed, bool) {
+	board, ok := e.requestBoard(c)
+	if !ok {
+		return board, store.Feed{}, false
+	}
+	feed, ok := feedSorts[c.DefaultQuery("sort", "new")]
+	if !ok {
+		respondError(c, ErrBadRequest("query.invalid_sort", "supported", "new, active"))
+		return board, feed, false
 	}
+	feed = feed.InBoard(board.ID)
+	if raw := c.Query("lang"); raw != "" {
+		langs, ok := parseLanguages(raw)
+		if !ok {
+			respondError(c, ErrBadRequest("query.invalid_lang", "supported", strings.Join(lang.Codes, ", ")))
+			return board, feed, false
+		}
+		feed = feed.InLanguages(langs)
+	}
+	return board, feed, true
 }
-func (rl *IPRateLimiter) GetLimiter(ip string) *rate.Limiter {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-	limiter, exists := rl.visitors[ip]
-	if !exists {
-		limiter = rate.NewLimiter(rl.rps, rl.burst)
-		rl.visitors[ip] = limiter
+
+// HeadPosts answers HEAD on the feed GetPosts serves with its ETag and its
+// length in X-Total-Count, counted without loading the posts.
+func (e *Env) HeadPosts(c *gin.Context) {
+	_, feed, ok := e.newFeed(c)
+	if !ok {
+		return
 	}
-	return limiter
+	ctx := c.Request.Context()
+	viewer := e.viewer(c)
+	count, err := e.Posts.Count(ctx, viewer, feed)
+	if err != nil {
+		requestLogger(c).Error("counting feed", "err", err)
+		respondError(c, ErrInternal("post.list_failed"))
+		return
+	}
+	c.Header("X-Total-Count", strconv.FormatInt(count, 10))
+	if etag := e.feedETag(ctx, c, viewer, feed); etag != "" {
+		c.Header("ETag", etag)
+		c.Header("Cache-Control", "no-cache")
+		if etagMatches(c.GetHeader("If-None-Match"), etag) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+	c.Status(http.StatusOK)
 }
-func RateLimitMiddleware(limiter *IPRateLimiter) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		ip := c.ClientIP()
-		if !limiter.GetLimiter(ip).Allow() {
-			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests. Please wait."})
+
+// postCountMaxAge is how long a post count may be reused. Clients poll it
+// to offer "new posts", so a few seconds behind is fine.
+const postCountMaxAge = 5 * time.Second
+
+// PostCount is the response to GET /posts/count.
+type PostCount struct {
+	Count int64 `json:"count"`
+}
+
+// GetPostCount counts the posts in the feed GetPosts serves that were
+// created after ?since=, or all of them, so clients can tell whether
+// refetching the feed is worth it.
+func (e *Env) GetPostCount(c *gin.Context) {
+	_, feed, ok := e.newFeed(c)
+	if !ok {
+		return
+	}
+	if raw := c.Query("since"); raw != "" {
+		since, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			respondError(c, ErrBadRequest("query.invalid_timestamp", "param", "since"))
 			return
 		}
-		c.Next()
+		feed = feed.Since(since)
 	}
+	count, err := e.Posts.Count(c.Request.Context(), e.viewer(c), feed)
+	if err != nil {
+		requestLogger(c).Error("counting feed", "err", err)
+		respondError(c, ErrInternal("post.list_failed"))
+		return
+	}
+	// private: a shadow-banned client's count includes their own posts.
+	c.Header("Cache-Control", "private, max-age="+strconv.Itoa(int(postCountMaxAge.Seconds())))
+	c.JSON(http.StatusOK, PostCount{Count: count})
 }
 
-// --- Handlers ---
-type Env struct {
-	DB  *gorm.DB
-	Hub *ws.Hub
+// parseLanguages parses a comma-separated list of language codes from
+// lang.Codes.
+func parseLanguages(raw string) ([]string, bool) {
+	var langs []string
+	for _, field := range strings.Split(raw, ",") {
+		code := strings.ToLower(strings.TrimSpace(field))
+		if !slices.Contains(lang.Codes, code) {
+			return nil, false
+		}
+		langs = append(langs, code)
+	}
+	return langs, true
 }
 
-func (e *Env) GetPosts(c *gin.Context) {
-	var posts []models.Post
-	if err := e.DB.Order("created_at desc").Where("hidden = ?", false).Find(&posts).Error; err != nil {
-		log.Printf("Error fetching posts: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
+// maxPostIDs bounds GET /posts?ids=.
+const maxPostIDs = 50
+
+// PostsByID is the response to GET /posts?ids=.
+type PostsByID struct {
+	Posts   []models.Post `json:"posts"`   // In the requested order
+	Missing []uint        `json:"missing"` // Requested IDs that don't exist or aren't visible
+}
+
+// getPostsByID resolves a comma-separated list of post IDs in one query,
+// for clients that need several specific posts at once.
+func (e *Env) getPostsByID(c *gin.Context, raw string) {
+	var ids []uint
+	seen := make(map[uint]bool)
+	for _, field := range strings.Split(raw, ",") {
+		id, err := strconv.ParseUint(strings.TrimSpace(field), 10, 32)
+		if err != nil || id == 0 {
+			respondError(c, ErrBadRequest("query.invalid_ids", "max", maxPostIDs))
+			return
+		}
+		if !seen[uint(id)] {
+			seen[uint(id)] = true
+			ids = append(ids, uint(id))
+		}
+	}
+	if len(ids) > maxPostIDs {
+		respondError(c, ErrBadRequest("query.invalid_ids", "max", maxPostIDs))
 		return
 	}
-	c.JSON(http.StatusOK, posts)
+
+	found, err := e.Posts.GetVisibleByIDs(c.Request.Context(), e.viewer(c), ids)
+	if err != nil {
+		requestLogger(c).Error("fetching posts by id", "err", err)
+		respondError(c, ErrInternal("post.list_failed"))
+		return
+	}
+	byID := make(map[uint]models.Post, len(found))
+	for _, post := range found {
+		byID[post.ID] = post
+	}
+	result := PostsByID{Posts: make([]models.Post, 0, len(found)), Missing: []uint{}}
+	for _, id := range ids {
+		if post, ok := byID[id]; ok {
+			result.Posts = append(result.Posts, post)
+		} else {
+			result.Missing = append(result.Missing, id)
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// trendingSorts maps ?sort= to the trending feed it selects; score is the
+// default.
+var trendingSorts = map[string]store.Feed{
+	"score":  store.FeedTrending,
+	"rising": store.FeedRising,
 }
 
+// GetTrendingPosts lists the highest-scoring posts of the request's board,
+// or with ?sort=rising those with the most votes in the last hour. Either
+// way each post carries votesLastHour.
 func (e *Env) GetTrendingPosts(c *gin.Context) {
-	var posts []models.Post
-	if err := e.DB.Order("score desc, created_at desc").Where("hidden = ?", false).Limit(20).Find(&posts).Error; err != nil {
-		log.Printf("Error fetching trending posts: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch posts"})
+	board, ok := e.requestBoard(c)
+	if !ok {
 		return
 	}
-	c.JSON(http.StatusOK, posts)
+	feed, ok := trendingSorts[c.DefaultQuery("sort", "score")]
+	if !ok {
+		respondError(c, ErrBadRequest("query.invalid_sort", "supported", "score, rising"))
+		return
+	}
+	e.serveFeed(c, "trending:"+board.Slug, feed.InBoard(board.ID))
+}
+
+// GetPost returns a single visible post; it is the permalink target.
+func (e *Env) GetPost(c *gin.Context) {
+	postID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, ErrBadRequest("post.invalid_id"))
+		return
+	}
+	post, err := e.Posts.GetVisible(c.Request.Context(), e.viewer(c), uint(postID))
+	if err != nil {
+		respondStoreError(c, err, "fetching post", "post.fetch_failed")
+		return
+	}
+	c.JSON(http.StatusOK, post)
+}
+
+// etagMatches applies the weak comparison used for If-None-Match.
+func etagMatches(header, etag string) bool {
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
 }
 
 func (e *Env) CreatePost(c *gin.Context) {
 	var input CreatePostInput
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+	if err := e.bindJSON(c, &input); err != nil {
+		respondError(c, ErrValidation(err))
 		return
 	}
+	if e.Content != nil {
+		content, ok := e.applyContentPolicy(c, input.Content)
+		if !ok {
+			return
+		}
+		input.Content = content
+	}
+	warning, ok := contentwarning.Parse(input.ContentWarning)
+	if !ok {
+		respondError(c, ErrBadRequest("post.invalid_content_warning", "allowed", contentwarning.Names()))
+		return
+	}
+	if warning == "" {
+		warning = e.Warnings.Detect(input.Content)
+	}
+	board, ok := e.requestBoard(c)
+	if !ok {
+		return
+	}
+	voter := e.voterHash(c)
+	remaining, ok := e.checkPostQuota(c, voter)
+	if !ok {
+		return
+	}
+	// The author upvotes their own post. The vote is recorded like any
+	// other so the score always equals the sum of the post's votes.
 	post := models.Post{
-		Content: input.Content,
-		Score:   1,
+		BoardID:        board.ID,
+		Content:        input.Content,
+		Lang:           lang.Detect(input.Content),
+		ContentWarning: string(warning),
+		Score:          1,
+		AuthorHash:     &voter,
+		CreatorHash:    e.creatorHash(c),
+		Votes:          []models.Vote{{VoterHash: &voter, Value: 1}},
+	}
+	banID, shadowBanned := e.viewerShadowBan(c)
+	if shadowBanned {
+		post.ShadowBanned = true
+		post.ShadowBanID = &banID
+	}
+	reason, held := e.fingerprintPost(c, &post)
+	var err error
+	if held {
+		err = e.Posts.Hold(c.Request.Context(), &post, reason)
+	} else {
+		err = e.Posts.Create(c.Request.Context(), &post)
+	}
+	if err != nil {
+		requestLogger(c).Error("creating post", "err", err)
+		respondError(c, ErrInternal("post.create_failed"))
+		return
+	}
+
+	metrics.PostsCreated.Inc()
+	if remaining > 0 {
+		c.Header("X-Post-Quota-Remaining", strconv.Itoa(remaining-1))
 	}
-	if err := e.DB.Create(&post).Error; err != nil {
-		log.Printf("Error creating post: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create post"})
+
+	// Near-duplicates wait hidden for a moderator, like a hidden post.
+	if held {
+		metrics.PostsHeld.Inc()
+		e.notify(c, webhook.EventPostAutoHidden, post)
+		c.JSON(http.StatusAccepted, post)
 		return
 	}
 
+	// Shadow-banned posts are never announced; only their author sees them.
+	if shadowBanned {
+		c.JSON(http.StatusCreated, post)
+		return
+	}
+	e.invalidateFeeds()
+
 	// --- UPDATE ---
 	// Send a message that matches the new frontend
-	msg := WsMessage{Type: "new_post", Data: post}
+	msg := WsMessage{Type: "new_post", Data: post, Board: board.Slug}
 	e.broadcastMessage(msg)
+	e.notify(c, webhook.EventNewPost, post)
 
 	c.JSON(http.StatusCreated, post)
 }
@@ -130,50 +582,38 @@ func (e *Env) VoteOnPost(c *gin.Context) {
 	var input VoteInput
 	postID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		respondError(c, ErrBadRequest("post.invalid_id"))
 		return
 	}
-	if err := c.ShouldBindJSON(&input); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input: " + err.Error()})
+	if err := e.bindJSON(c, &input); err != nil {
+		respondError(c, ErrValidation(err))
 		return
 	}
 
-	var post models.Post
-	var newScore int
-
-	err = e.DB.Transaction(func(tx *gorm.DB) error {
-		if err := tx.Set("gorm:query_option", "FOR UPDATE").Where("hidden = ?", false).First(&post, postID).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return errors.New("post not found")
-			}
-			return err
-		}
-		vote := models.Vote{PostID: uint(postID), Value: input.Value}
-		if err := tx.Create(&vote).Error; err != nil {
-			return errors.New("failed to record vote")
-		}
-		newScore = post.Score + input.Value
-		if err := tx.Model(&post).Update("score", newScore).Error; err != nil {
-			return errors.New("failed to update post score")
-		}
-		return nil
-	})
-
+	voteID := strings.ToLower(input.VoteID)
+	post, replayed, err := e.Votes.Vote(c.Request.Context(), e.viewer(c), uint(postID), e.voterHash(c), input.Value, voteID)
 	if err != nil {
-		log.Printf("Error in vote transaction: %v", err)
-		if err.Error() == "post not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process vote"})
-		}
+		respondStoreError(c, err, "in vote transaction", "post.vote_failed")
 		return
 	}
+	// A retry was already announced by the original.
+	if replayed {
+		c.JSON(http.StatusOK, gin.H{"id": post.ID, "score": post.Score})
+		return
+	}
+
+	e.invalidateFeeds()
+	e.announceTrending(c, post)
 
 	// --- UPDATE ---
 	// Send a message that matches the new frontend
-	payload := gin.H{"id": post.ID, "score": newScore}
-	msg := WsMessage{Type: "vote", Data: payload}
-	e.broadcastMessage(msg)
+	metrics.VotesCreated.Inc()
+
+	payload := gin.H{"id": post.ID, "score": post.Score}
+	if !post.ShadowBanned {
+		msg := WsMessage{Type: "vote", Data: payload, Board: e.boardSlugOf(c.Request.Context(), post.BoardID)}
+		e.broadcastMessage(msg)
+	}
 
 	c.JSON(http.StatusOK, payload)
 }
@@ -181,50 +621,57 @@ func (e *Env) VoteOnPost(c *gin.Context) {
 func (e *Env) DeletePost(c *gin.Context) {
 	postID, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		respondError(c, ErrBadRequest("post.invalid_id"))
 		return
 	}
 
-	var post models.Post
-
-	err = e.DB.Transaction(func(tx *gorm.DB) error {
-		if err := tx.First(&post, postID).Error; err != nil {
-			if errors.Is(err, gorm.ErrRecordNotFound) {
-				return errors.New("post not found")
-			}
-			return err
-		}
-		if err := tx.Model(&post).Update("hidden", true).Error; err != nil {
-			return errors.New("failed to hide post")
-		}
-		return nil
-	})
-
+	post, alreadyHidden, err := e.Posts.Hide(c.Request.Context(), uint(postID), adminActor(c))
 	if err != nil {
-		log.Printf("Error in delete transaction: %v", err)
-		if err.Error() == "post not found" {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete post"})
-		}
+		respondStoreError(c, err, "in delete transaction", "post.delete_failed")
+		return
+	}
+	// Nothing changed, so there is nothing to announce.
+	if alreadyHidden {
+		c.JSON(http.StatusOK, gin.H{"message": "Post already hidden", "alreadyHidden": true})
 		return
 	}
 
+	e.invalidateFeeds()
+
 	// --- UPDATE ---
 	// Send a message that matches the new frontend
+	metrics.PostsHidden.Inc()
+
 	payload := gin.H{"id": post.ID}
-	msg := WsMessage{Type: "delete", Data: payload}
+	msg := WsMessage{Type: "delete", Data: payload, Board: e.boardSlugOf(c.Request.Context(), post.BoardID)}
 	e.broadcastMessage(msg)
+	e.notify(c, webhook.EventPostHidden, post)
 
-	c.JSON(http.StatusOK, gin.H{"message": "Post hidden successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "Post hidden successfully", "alreadyHidden": false, "undoToken": e.undo.issue(post.ID, time.Now())})
 }
 
-// broadcastMessage helper now uses the WsMessage struct
-func (e *Env) broadcastMessage(msg WsMessage) {
-	jsonMsg, err := json.Marshal(msg)
+// GetAnnouncement returns the active announcement, or 204 if there is none.
+func (e *Env) GetAnnouncement(c *gin.Context) {
+	var announcement models.Announcement
+	err := e.DB.WithContext(c.Request.Context()).Where("expires_at > ?", time.Now()).Order("created_at desc").First(&announcement).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.Status(http.StatusNoContent)
+		return
+	}
 	if err != nil {
-		log.Printf("Error marshalling WS message: %v", err)
+		requestLogger(c).Error("fetching announcement", "err", err)
+		respondError(c, ErrInternal("announcement.fetch_failed"))
 		return
 	}
-	e.Hub.Broadcast <- jsonMsg
-}
\ No newline at end of file
+	c.JSON(http.StatusOK, announcement)
+}
+
+// broadcastMessage sends msg to WebSocket clients and appends it to the
+// event log. Live updates are best effort, so a failure is logged and the
+// request carries on.
+func (e *Env) broadcastMessage(msg WsMessage) {
+	e.Events.Append(msg.Type, msg.Board, msg.Data)
+	if err := e.Broadcaster.Broadcast(msg); err != nil {
+		slog.Warn("broadcasting WS message", "type", msg.Type, "err", err)
+	}
+}
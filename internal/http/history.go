@@ -0,0 +1,41 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+// ScoreHistory is the response to GET /posts/:id/history.
+type ScoreHistory struct {
+	PostID uint                   `json:"postId"`
+	Score  int                    `json:"score"`  // Current score, newer than the last point
+	Points []models.ScoreSnapshot `json:"points"` // Oldest first
+}
+
+// GetPostHistory returns the score snapshots of a visible post for a
+// sparkline. Posts get a point in each interval a vote changed them, so
+// a score holds until the next point.
+func (e *Env) GetPostHistory(c *gin.Context) {
+	postID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, ErrBadRequest("post.invalid_id"))
+		return
+	}
+	post, err := e.Posts.GetVisible(c.Request.Context(), e.viewer(c), uint(postID))
+	if err != nil {
+		respondStoreError(c, err, "fetching post", "post.fetch_failed")
+		return
+	}
+
+	result := ScoreHistory{PostID: post.ID, Score: post.Score, Points: []models.ScoreSnapshot{}}
+	if err := e.DB.WithContext(c.Request.Context()).Where("post_id = ?", post.ID).Order("taken_at").Find(&result.Points).Error; err != nil {
+		requestLogger(c).Error("fetching score history", "post", post.ID, "err", err)
+		respondError(c, ErrInternal("post.history_failed"))
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimitMiddleware rejects bodies larger than limit with 413. A declared
+// Content-Length over the limit is refused up front; otherwise the body is
+// wrapped so reading past the limit fails and ErrValidation reports 413.
+// Routes that accept larger uploads should use their own group and limit.
+func BodyLimitMiddleware(limit int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.ContentLength > limit {
+			respondError(c, ErrPayloadTooLarge(limit))
+			return
+		}
+		if c.Request.Body != nil {
+			c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+		}
+		c.Next()
+	}
+}
+
+// RequestTimeoutMiddleware puts a deadline of timeout on the request's
+// context, so the queries of a request that runs too long are canceled
+// rather than holding a database connection. Zero disables it.
+func RequestTimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+		parent := c.Request.Context()
+		ctx, cancel := context.WithTimeout(parent, timeout)
+		defer cancel()
+		c.Set(untimedContextKey, parent)
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// untimedContext is a request context with its deadline lifted: it is
+// canceled with the connection, but looks values up in the request's
+// current context, which later middleware may have added to.
+type untimedContext struct {
+	context.Context
+	values context.Context
+}
+
+func (u untimedContext) Value(key any) any {
+	return u.values.Value(key)
+}
+
+// clearDeadlines lifts the server's read and write timeouts, and the
+// request timeout, for a long-lived response on this connection only.
+// Call it before taking the request's context. The request is also
+// exempted from slow-request logging.
+func clearDeadlines(c *gin.Context) {
+	c.Set(longLivedKey, true)
+	rc := http.NewResponseController(c.Writer)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+	if parent, ok := c.Get(untimedContextKey); ok {
+		c.Request = c.Request.WithContext(untimedContext{Context: parent.(context.Context), values: c.Request.Context()})
+	}
+}
@@ -0,0 +1,134 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sujalbistaa/whispr/internal/audit"
+)
+
+// MaintenanceMode controls which requests are served while the service is
+// being worked on.
+type MaintenanceMode string
+
+const (
+	// MaintenanceOff serves everything normally.
+	MaintenanceOff MaintenanceMode = "off"
+	// MaintenanceReadOnly rejects writes but keeps reads working.
+	MaintenanceReadOnly MaintenanceMode = "readonly"
+	// MaintenanceFull rejects all API, WebSocket and feed traffic.
+	MaintenanceFull MaintenanceMode = "full"
+)
+
+// MaintenanceState is the current mode and when it last changed.
+type MaintenanceState struct {
+	Mode  MaintenanceMode `json:"mode"`
+	Since time.Time       `json:"since"`
+}
+
+// Maintenance holds the process-wide maintenance state. It is read on
+// every request and flipped by admins, so access is guarded by a lock.
+type Maintenance struct {
+	mu    sync.RWMutex
+	state MaintenanceState
+}
+
+// NewMaintenance starts in mode (MAINTENANCE_MODE).
+func NewMaintenance(mode MaintenanceMode) *Maintenance {
+	return &Maintenance{state: MaintenanceState{Mode: mode, Since: time.Now()}}
+}
+
+// State returns a snapshot of the current state.
+func (m *Maintenance) State() MaintenanceState {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+// Set switches to mode and reports whether anything changed.
+func (m *Maintenance) Set(mode MaintenanceMode) (MaintenanceState, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.state.Mode == mode {
+		return m.state, false
+	}
+	m.state = MaintenanceState{Mode: mode, Since: time.Now()}
+	return m.state, true
+}
+
+// MaintenanceMiddleware enforces the maintenance mode with 503s. Health
+// checks, metrics and admin routes are never blocked, so operators can
+// still see and undo the state, and neither is the frontend, so users get
+// a page that can explain the outage instead of a bare error.
+func MaintenanceMiddleware(m *Maintenance) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state := m.State()
+		if state.Mode == MaintenanceOff || maintenanceExempt(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+		if state.Mode == MaintenanceReadOnly {
+			if isGraphQLPath(c.Request.URL.Path) {
+				c.Next()
+				return
+			}
+			switch c.Request.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				c.Next()
+				return
+			}
+		}
+		respondError(c, ErrMaintenance(state.Mode))
+	}
+}
+
+func maintenanceExempt(path string) bool {
+	switch path {
+	case "/healthz", "/readyz", "/metrics":
+		return true
+	}
+	if strings.HasPrefix(path, apiV1Prefix+"/admin/") || strings.HasPrefix(path, "/api/admin/") {
+		return true
+	}
+	isAPI := path == "/api" || strings.HasPrefix(path, "/api/") ||
+		path == "/ws" || strings.HasPrefix(path, "/feed.")
+	return !isAPI
+}
+
+// --- Admin ---
+
+// MaintenanceInput switches the maintenance mode.
+type MaintenanceInput struct {
+	Mode MaintenanceMode `json:"mode" binding:"required,oneof=off readonly full"`
+}
+
+// GetMaintenance reports the current maintenance state.
+func (e *Env) GetMaintenance(c *gin.Context) {
+	c.JSON(http.StatusOK, e.Maintenance.State())
+}
+
+// SetMaintenance switches the maintenance mode and tells connected clients.
+func (e *Env) SetMaintenance(c *gin.Context) {
+	var input MaintenanceInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, ErrValidation(err))
+		return
+	}
+
+	state, changed := e.Maintenance.Set(input.Mode)
+	if changed {
+		requestLogger(c).Warn("maintenance mode changed", "mode", state.Mode, "actor", adminActor(c))
+		// Maintenance is often switched on because the database is in
+		// trouble, so a failed audit write must not block the switch.
+		if err := audit.Record(e.DB, adminActor(c), audit.ActionMaintenance, audit.TargetMaintenance, 0, map[string]any{"mode": state.Mode}); err != nil {
+			requestLogger(c).Error("recording maintenance change", "err", err)
+		}
+		e.broadcastMessage(WsMessage{Type: "maintenance", Data: state})
+	}
+
+	c.JSON(http.StatusOK, state)
+}
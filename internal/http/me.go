@@ -0,0 +1,40 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/sujalbistaa/whispr/internal/karma"
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+// Me is the response to GET /me: the caller's own karma and posting
+// streak. Nothing in it identifies the caller or their posts.
+type Me struct {
+	Karma      int `json:"karma"`
+	Streak     int `json:"streak"` // Consecutive UTC days posted, up to today or yesterday
+	BestStreak int `json:"bestStreak"`
+}
+
+// GetMe returns the caller's karma and streak, all zero for a client that
+// has never posted.
+func (e *Env) GetMe(c *gin.Context) {
+	var identity models.Identity
+	err := e.DB.WithContext(c.Request.Context()).Where("client_hash = ?", e.voterHash(c)).First(&identity).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		requestLogger(c).Error("fetching identity", "err", err)
+		respondError(c, ErrInternal("me.fetch_failed"))
+		return
+	}
+	// Like bookmarks, this is private to the client.
+	c.Header("Cache-Control", "private, no-store")
+	c.JSON(http.StatusOK, Me{
+		Karma:      identity.Karma,
+		Streak:     karma.CurrentStreak(identity, time.Now()),
+		BestStreak: identity.BestStreak,
+	})
+}
@@ -0,0 +1,168 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sujalbistaa/whispr/internal/karma"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// getMe returns the GET /api/v1/me response for ip and its raw body.
+func getMe(t *testing.T, ts *testutil.TestServer, ip string) (map[string]int, string) {
+	t.Helper()
+	resp, err := ts.Client().Do(ts.NewRequestFrom(t, ip, http.MethodGet, "/api/v1/me", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET /me: status %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Cache-Control"); got != "private, no-store" {
+		t.Errorf("GET /me Cache-Control %q, want private, no-store", got)
+	}
+	var raw json.RawMessage
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		t.Fatal(err)
+	}
+	var me map[string]int
+	if err := json.Unmarshal(raw, &me); err != nil {
+		t.Fatalf("GET /me: %v: %s", err, raw)
+	}
+	return me, string(raw)
+}
+
+func TestMe(t *testing.T) {
+	t.Setenv("POST_RATE_RPS", "100")
+	t.Setenv("POST_RATE_BURST", "100")
+	ts := testutil.NewTestServer(t)
+	author := ts.ClientIP()
+	var posts []models.Post
+	for _, content := range []string{"first of two from the same client", "and something else entirely"} {
+		status, body := ts.Do(t, ts.NewRequestFrom(t, author, http.MethodPost, "/api/v1/posts", map[string]string{"content": content}))
+		var post models.Post
+		if status != http.StatusCreated || json.Unmarshal(body, &post) != nil {
+			t.Fatalf("creating a post: %d %s", status, body)
+		}
+		posts = append(posts, post)
+	}
+	ts.Vote(t, posts[0].ID, 1)
+
+	settler := karma.New(ts.DB, karma.Config{BatchSize: 100})
+	if _, _, err := settler.Settle(context.Background(), time.Now().Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if me, raw := getMe(t, ts, author); len(me) != 3 || me["karma"] != 1 || me["streak"] != 1 || me["bestStreak"] != 1 {
+		t.Errorf("author's /me = %s, want karma 1 for the upvoted post and a streak of 1", raw)
+	}
+	if me, raw := getMe(t, ts, ts.ClientIP()); me["karma"] != 0 || me["streak"] != 0 || me["bestStreak"] != 0 {
+		t.Errorf("/me of a client that never posted = %s, want zeros", raw)
+	}
+
+	// Two days without a post break the streak but not the best one.
+	if err := ts.DB.Model(&models.Identity{}).Where("1 = 1").Update("last_post_on", time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, -2)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if me, raw := getMe(t, ts, author); me["streak"] != 0 || me["bestStreak"] != 1 || me["karma"] != 1 {
+		t.Errorf("/me after two idle days = %s, want streak 0, bestStreak 1", raw)
+	}
+}
+
+// identityKey matches JSON keys that would tie a post to its author.
+var identityKey = regexp.MustCompile(`(?i)identity|author|client|creator|hash|karma|streak`)
+
+// jsonKeys calls found for every object key in the JSON document data.
+func jsonKeys(data []byte, found func(string)) {
+	var walk func(any)
+	walk = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			for key, value := range v {
+				found(key)
+				walk(value)
+			}
+		case []any:
+			for _, value := range v {
+				walk(value)
+			}
+		}
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	for {
+		var v any
+		if decoder.Decode(&v) != nil {
+			return
+		}
+		walk(v)
+	}
+}
+
+// TestIdentityNotInPosts checks that nothing a post is served with, over
+// REST or WebSocket, carries its author's identity or client hash.
+func TestIdentityNotInPosts(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	ws := ts.DialWS(t)
+	author := ts.ClientIP()
+	status, created := ts.Do(t, ts.NewRequestFrom(t, author, http.MethodPost, "/api/v1/posts", map[string]string{"content": "whose post is this?"}))
+	var post models.Post
+	if status != http.StatusCreated || json.Unmarshal(created, &post) != nil {
+		t.Fatalf("creating a post: %d %s", status, created)
+	}
+	ts.Vote(t, post.ID, 1)
+
+	// Give the identity an id no post, score or count will share.
+	const identityID = 987654321
+	var identity models.Identity
+	if err := ts.DB.First(&identity).Error; err != nil {
+		t.Fatalf("no identity after posting: %v", err)
+	}
+	if err := ts.DB.Model(&identity).Update("id", identityID).Error; err != nil {
+		t.Fatal(err)
+	}
+	var stored models.Post
+	if err := ts.DB.First(&stored, post.ID).Error; err != nil || stored.AuthorHash == nil {
+		t.Fatalf("post %d has no author hash (%v)", post.ID, err)
+	}
+	secrets := []string{fmt.Sprint(identityID), identity.ClientHash, *stored.AuthorHash}
+
+	bodies := map[string][]byte{
+		"POST /posts":      created,
+		"new_post message": ws.Expect(t, "new_post", 0).Data,
+	}
+	for _, path := range []string{
+		"/api/v1/posts",
+		fmt.Sprintf("/api/v1/posts/%d", post.ID),
+		fmt.Sprintf("/api/v1/posts/%d/history", post.ID),
+		"/api/v1/trending",
+		"/api/v1/boards/general/posts",
+		"/api/v1/posts/stream",
+		"/api/v1/me",
+	} {
+		status, body := ts.Do(t, ts.NewRequestFrom(t, author, http.MethodGet, path, nil))
+		if status != http.StatusOK {
+			t.Errorf("GET %s: status %d: %s", path, status, body)
+		}
+		bodies["GET "+path] = body
+	}
+
+	for name, body := range bodies {
+		for _, secret := range secrets {
+			if strings.Contains(string(body), secret) {
+				t.Errorf("%s contains %q: %s", name, secret, body)
+			}
+		}
+		jsonKeys(body, func(key string) {
+			if identityKey.MatchString(key) && name != "GET /api/v1/me" {
+				t.Errorf("%s has key %q: %s", name, key, body)
+			}
+		})
+	}
+}
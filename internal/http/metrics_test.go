@@ -0,0 +1,62 @@
+package http_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sujalbistaa/whispr/internal/metrics"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	noLabels := map[string]string{}
+	created := map[string]string{"method": http.MethodPost, "route": "/api/v1/posts", "status": "201"}
+	before := map[string]float64{
+		metrics.NamePostsCreated: counterValue(t, metrics.NamePostsCreated, noLabels),
+		metrics.NameVotesCreated: counterValue(t, metrics.NameVotesCreated, noLabels),
+		metrics.NamePostsHidden:  counterValue(t, metrics.NamePostsHidden, noLabels),
+		metrics.NameHTTPRequests: counterValue(t, metrics.NameHTTPRequests, created),
+	}
+
+	post := ts.CreatePost(t, "counted in the metrics")
+	ts.Vote(t, post.ID, 1)
+	ts.Hide(t, post.ID)
+
+	for name, want := range map[string]float64{
+		metrics.NamePostsCreated: 1,
+		metrics.NameVotesCreated: 1,
+		metrics.NamePostsHidden:  1,
+	} {
+		if got := counterValue(t, name, noLabels) - before[name]; got != want {
+			t.Errorf("%s went up by %g, want %g", name, got, want)
+		}
+	}
+	if got := counterValue(t, metrics.NameHTTPRequests, created) - before[metrics.NameHTTPRequests]; got != 1 {
+		t.Errorf("%s%v went up by %g, want 1", metrics.NameHTTPRequests, created, got)
+	}
+
+	if status, _ := ts.Do(t, ts.NewRequest(t, http.MethodGet, "/metrics", nil)); status != http.StatusUnauthorized {
+		t.Errorf("GET /metrics without a token: status %d, want 401", status)
+	}
+	status, body := ts.Do(t, ts.AdminRequest(t, http.MethodGet, "/metrics", nil))
+	if status != http.StatusOK {
+		t.Fatalf("GET /metrics: status %d", status)
+	}
+	for _, name := range []string{
+		metrics.NameHTTPRequests,
+		metrics.NameHTTPDuration + "_bucket",
+		metrics.NameWSConnections,
+		metrics.NameInFlightPosts,
+		metrics.NameDBDown,
+		metrics.NamePostsCreated,
+		metrics.NameVotesCreated,
+		metrics.NamePostsHidden,
+		"go_sql_open_connections",
+	} {
+		if !strings.Contains(string(body), "\n"+name) {
+			t.Errorf("/metrics has no %s", name)
+		}
+	}
+}
@@ -1,54 +1,321 @@
 package http
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
+	"runtime/debug"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/sujalbistaa/whispr/internal/audit"
+	"github.com/sujalbistaa/whispr/internal/auth"
+	"github.com/sujalbistaa/whispr/internal/bans"
+	"github.com/sujalbistaa/whispr/internal/config"
+	"github.com/sujalbistaa/whispr/internal/logging"
+	"github.com/sujalbistaa/whispr/internal/reporting"
 )
 
-// AdminAuthMiddleware checks for a secret X-Admin-Token header.
-func AdminAuthMiddleware() gin.HandlerFunc {
-	// Get the secret token from the environment
-	requiredToken := os.Getenv("X_ADMIN_TOKEN")
+// gin.Context keys set by middleware.
+const (
+	adminActorKey = "adminActor"  // Fingerprint of the caller's admin token
+	adminRoleKey  = "adminRole"   // auth.Role resolved from the caller's admin token
+	shadowBanKey  = "shadowBanID" // ID of the shadow ban covering the caller
+	apiKeyKey     = "apiKey"      // models.APIKey the caller presented
+
+	rateLimitBypassKey = "rateLimitBypass" // Set when the caller skips rate limiting
+	requestIDKey       = "requestID"       // Correlation ID for this request
+	longLivedKey       = "longLived"       // Set by clearDeadlines; the response is a stream
+	untimedContextKey  = "untimedContext"  // Request context before RequestTimeoutMiddleware's deadline
+)
+
+// requestIDHeader carries the correlation ID in both directions.
+const requestIDHeader = "X-Request-ID"
+
+// traceIDHeader returns the request's trace ID when tracing is enabled.
+const traceIDHeader = "X-Trace-ID"
 
-	if requiredToken == "" {
-		panic("CRITICAL: X_ADMIN_TOKEN environment variable not set.")
+// maxRequestIDLen bounds client-supplied IDs so they can't bloat the logs.
+const maxRequestIDLen = 128
+
+// AdminAuthMiddleware checks the X-Admin-Token header against the token
+// store and stashes the caller's role in the context. Use RequireRole on
+// individual routes to restrict them further.
+//
+// When no tokens are configured the admin routes respond with 503 instead
+// of taking the whole server down.
+func AdminAuthMiddleware(tokens *auth.TokenStore) gin.HandlerFunc {
+	if tokens.Len() == 0 {
+		slog.Warn("no admin tokens configured (set X_ADMIN_TOKEN, X_ADMIN_TOKEN_FILE, X_ADMIN_TOKENS or X_ADMIN_TOKENS_FILE); admin functionality is disabled")
 	}
 
 	return func(c *gin.Context) {
+		if tokens.Len() == 0 {
+			respondError(c, ErrAdminDisabled())
+			return
+		}
+
 		// Get the token from the request header
 		suppliedToken := c.GetHeader("X-Admin-Token")
 
 		if suppliedToken == "" {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized: Admin token required"})
+			respondError(c, ErrUnauthorized("admin.token_required"))
+			return
+		}
+
+		role, ok := tokens.Resolve(suppliedToken)
+		if !ok {
+			respondError(c, ErrForbidden("admin.token_invalid"))
 			return
 		}
+		c.Set(adminActorKey, audit.Fingerprint(suppliedToken))
+		c.Set(adminRoleKey, role)
+		c.Next()
+	}
+}
 
-		if suppliedToken != requiredToken {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Forbidden: Invalid admin token"})
+// RequireRole rejects callers whose admin role is below the required one.
+// It must run after AdminAuthMiddleware.
+func RequireRole(required auth.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, _ := c.Get(adminRoleKey)
+		if r, ok := role.(auth.Role); !ok || !r.Allows(required) {
+			respondError(c, ErrForbidden("admin.role_required", "role", required))
 			return
 		}
 		c.Next()
 	}
 }
 
-// SecurityHeadersMiddleware adds basic, sensible security headers.
-func SecurityHeadersMiddleware() gin.HandlerFunc {
+// adminActor returns the fingerprint of the admin token used for this request.
+func adminActor(c *gin.Context) string {
+	return c.GetString(adminActorKey)
+}
+
+// clientIP returns the address every per-client feature (rate limiting,
+// bans) keys on. Forwarding headers are only honored from proxies listed
+// in TRUSTED_PROXIES; see ConfigureTrustedProxies.
+func clientIP(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// ConfigureTrustedProxies applies TRUSTED_PROXIES, a list of IPs or CIDRs,
+// to the router. When empty no proxy is trusted and the TCP peer address is
+// used as the client IP.
+func ConfigureTrustedProxies(router *gin.Engine, proxies []string) error {
+	if err := router.SetTrustedProxies(proxies); err != nil {
+		return fmt.Errorf("TRUSTED_PROXIES: %w", err)
+	}
+	return nil
+}
+
+// BanMiddleware rejects requests from banned IPs with 403.
+// Shadow-banned clients are let through with the ban stashed in the context
+// so handlers can quietly quarantine their content.
+// Lookup errors are logged and the request is allowed through.
+func BanMiddleware(list *bans.List) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		match, banned, err := list.Lookup(clientIP(c))
+		if err != nil {
+			requestLogger(c).Error("checking ban list", "err", err)
+		}
+		if banned && !match.Shadow {
+			respondError(c, ErrBanned())
+			return
+		}
+		if banned {
+			c.Set(shadowBanKey, match.ID)
+		}
+		c.Next()
+	}
+}
+
+// RequestIDMiddleware reuses a well-formed X-Request-ID from the client or
+// generates a new one. The ID is echoed in the response header and attached
+// to the request's logger, derived from base (slog.Default() if nil), so
+// every log line for the request carries it.
+func RequestIDMiddleware(base *slog.Logger) gin.HandlerFunc {
+	if base == nil {
+		base = slog.Default()
+	}
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Set(requestIDKey, id)
+		c.Header(requestIDHeader, id)
+
+		logger := base.With("request_id", id)
+		c.Request = c.Request.WithContext(logging.WithLogger(c.Request.Context(), logger))
+		c.Next()
+	}
+}
+
+// TraceMiddleware ties the request's span, started by otelgin, to the rest
+// of the request: the span gets the request ID as an attribute, and the
+// trace ID goes out in X-Trace-ID and onto the request's logger. It must
+// run after RequestIDMiddleware and is only installed with tracing on.
+func TraceMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		span := trace.SpanFromContext(c.Request.Context())
+		if sc := span.SpanContext(); sc.IsValid() {
+			span.SetAttributes(attribute.String("whispr.request_id", c.GetString(requestIDKey)))
+			traceID := sc.TraceID().String()
+			c.Header(traceIDHeader, traceID)
+			logger := requestLogger(c).With("trace_id", traceID)
+			c.Request = c.Request.WithContext(logging.WithLogger(c.Request.Context(), logger))
+		}
+		c.Next()
+	}
+}
+
+// RecoveryMiddleware replaces gin.Recovery: a panic in a handler goes to
+// reporter with the request ID and route, and the client gets the usual
+// 500 envelope. A write to a connection the client already closed isn't
+// worth a report; the request is just aborted. It must run after
+// RequestIDMiddleware.
+func RecoveryMiddleware(reporter reporting.ErrorReporter) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Header("X-Frame-Options", "DENY")
-		c.Header("X-Content-Type-Options", "nosniff")
+		// Middleware further in may have wrapped the writer (compression);
+		// its deferred cleanup has run by the time we recover, so answer on
+		// the writer we were given.
+		writer := c.Writer
+		defer func() {
+			v := recover()
+			if v == nil {
+				return
+			}
+			if err, ok := v.(error); ok && brokenConnection(err) {
+				requestLogger(c).Warn("client connection lost", "err", err)
+				c.Abort()
+				return
+			}
+			reporter.ReportPanic(c.Request.Context(), reporting.Panic{
+				Value:     v,
+				Stack:     debug.Stack(),
+				Source:    "http",
+				RequestID: c.GetString(requestIDKey),
+				Method:    c.Request.Method,
+				Route:     c.FullPath(),
+			})
+			c.Writer = writer
+			if writer.Written() {
+				c.Abort()
+				return
+			}
+			respondError(c, ErrInternal("request.failed"))
+		}()
+		c.Next()
+	}
+}
 
-		// --- THIS IS THE FINAL CSP FIX ---
-		// We MUST add 'unsafe-eval' for Alpine.js to work.
-		csp := "default-src 'self';"
-		csp += " script-src 'self' 'unsafe-inline' 'unsafe-eval' cdn.jsdelivr.net cdn.tailwindcss.com;" // <-- 'unsafe-eval' ADDED
-		csp += " style-src 'self' 'unsafe-inline' cdn.tailwindcss.com;" // Allow tailwindcss CDN
-		csp += " connect-src 'self' ws: wss:;" // Allow fetch, XHR, and WebSockets
-		
-		c.Header("Content-Security-Policy", csp)
-		// --- END FIX ---
+// brokenConnection reports whether err came from writing to a client that
+// has gone away.
+func brokenConnection(err error) bool {
+	if errors.Is(err, http.ErrAbortHandler) {
+		return true
+	}
+	var se *os.SyscallError
+	if !errors.As(err, &se) {
+		return false
+	}
+	msg := strings.ToLower(se.Error())
+	return strings.Contains(msg, "broken pipe") || strings.Contains(msg, "connection reset by peer")
+}
 
+// RequestLoggerMiddleware logs one line per request, replacing gin.Logger.
+// It must run after RequestIDMiddleware to pick up the request ID. A
+// request that takes longer than slow (0 disables) also logs a "slow
+// request" warning with its route; streams and WebSockets are expected to
+// last and are left out.
+func RequestLoggerMiddleware(slow time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
 		c.Next()
+		latency := time.Since(start)
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		} else if status >= http.StatusBadRequest {
+			level = slog.LevelWarn
+		}
+		requestLogger(c).Log(c.Request.Context(), level, "request",
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"status", status,
+			"latency_ms", float64(latency.Microseconds())/1000,
+			"ip", clientIP(c),
+			"bytes", c.Writer.Size(),
+		)
+		if slow > 0 && latency > slow && !c.GetBool(longLivedKey) {
+			requestLogger(c).Warn("slow request",
+				"method", c.Request.Method,
+				"route", c.FullPath(),
+				"status", status,
+				"latency_ms", float64(latency.Microseconds())/1000,
+				"threshold_ms", slow.Milliseconds(),
+			)
+		}
 	}
-}
\ No newline at end of file
+}
+
+// requestLogger returns the logger for this request, tagged with its ID.
+func requestLogger(c *gin.Context) *slog.Logger {
+	return logging.FromContext(c.Request.Context())
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// DeprecationMiddleware marks responses from a deprecated route group with
+// the Deprecation and Sunset headers and a link to the v1 equivalent.
+func DeprecationMiddleware(sunset time.Time) gin.HandlerFunc {
+	sunsetHeader := sunset.UTC().Format(http.TimeFormat)
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Sunset", sunsetHeader)
+		if successor, ok := strings.CutPrefix(c.Request.URL.Path, "/api/"); ok {
+			c.Header("Link", "<"+apiV1Prefix+"/"+successor+">; rel=\"successor-version\"")
+		}
+		c.Next()
+	}
+}
+
+// SecurityHeadersMiddleware sends the security headers policy
+// configures on every response. tls says whether PORT serves HTTPS;
+// HSTS is only sent when it does.
+func SecurityHeadersMiddleware(policy config.SecurityHeaders, tls bool) gin.HandlerFunc {
+	headers := securityHeaders(policy, tls)
+	return func(c *gin.Context) {
+		for _, h := range headers {
+			c.Header(h.name, h.value)
+		}
+		c.Next()
+	}
+}
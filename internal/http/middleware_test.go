@@ -0,0 +1,156 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sujalbistaa/whispr/internal/auth"
+	"github.com/sujalbistaa/whispr/internal/bans"
+	routes "github.com/sujalbistaa/whispr/internal/http"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// adminEngine serves GET /admin behind the admin middleware, requiring
+// role.
+func adminEngine(t *testing.T, sources auth.Sources, role auth.Role) *gin.Engine {
+	t.Helper()
+	tokens, err := auth.NewTokenStore(sources)
+	if err != nil {
+		t.Fatalf("loading tokens: %v", err)
+	}
+	engine := gin.New()
+	engine.GET("/admin", routes.AdminAuthMiddleware(tokens), routes.RequireRole(role), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return engine
+}
+
+func TestAdminAuthMiddleware(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("from-a-file\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		name    string
+		sources auth.Sources
+		role    auth.Role
+		token   string
+		want    int
+	}{
+		{"unset", auth.Sources{}, auth.RoleModerator, "anything", http.StatusServiceUnavailable},
+		{"missing", auth.Sources{Token: "secret"}, auth.RoleModerator, "", http.StatusUnauthorized},
+		{"wrong", auth.Sources{Token: "secret"}, auth.RoleModerator, "secreT", http.StatusForbidden},
+		{"prefix of the right one", auth.Sources{Token: "secret"}, auth.RoleModerator, "secre", http.StatusForbidden},
+		{"correct", auth.Sources{Token: "secret"}, auth.RoleModerator, "secret", http.StatusOK},
+		{"from X_ADMIN_TOKEN_FILE", auth.Sources{TokenFile: tokenFile}, auth.RoleAdmin, "from-a-file", http.StatusOK},
+		{"moderator on an admin route", auth.Sources{Tokens: "moderator:mod"}, auth.RoleAdmin, "mod", http.StatusForbidden},
+		{"moderator on a moderator route", auth.Sources{Tokens: "moderator:mod"}, auth.RoleModerator, "mod", http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+			if tc.token != "" {
+				req.Header.Set("X-Admin-Token", tc.token)
+			}
+			rec := httptest.NewRecorder()
+			adminEngine(t, tc.sources, tc.role).ServeHTTP(rec, req)
+			if rec.Code != tc.want {
+				t.Errorf("status %d, want %d: %s", rec.Code, tc.want, rec.Body)
+			}
+		})
+	}
+}
+
+// TestAdminRoutesWithoutToken starts a whole server with no admin token:
+// it must come up, serve the public routes and refuse the admin ones.
+func TestAdminRoutesWithoutToken(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	t.Setenv("X_ADMIN_TOKEN", "")
+	bare := testutil.NewTestServer(t)
+
+	for _, tc := range []struct {
+		ts    *testutil.TestServer
+		token string
+		want  int
+	}{
+		{bare, testutil.AdminToken, http.StatusServiceUnavailable},
+		{ts, "", http.StatusUnauthorized},
+		{ts, "not-the-token", http.StatusForbidden},
+		{ts, testutil.AdminToken, http.StatusOK},
+	} {
+		req := tc.ts.NewRequest(t, http.MethodGet, "/api/v1/admin/stats", nil)
+		if tc.token != "" {
+			req.Header.Set("X-Admin-Token", tc.token)
+		}
+		if status, body := tc.ts.Do(t, req); status != tc.want {
+			t.Errorf("token %q: status %d, want %d: %s", tc.token, status, tc.want, body)
+		}
+	}
+	if status, _ := bare.Do(t, bare.NewRequest(t, http.MethodGet, "/api/v1/posts", nil)); status != http.StatusOK {
+		t.Errorf("public route without an admin token: status %d, want 200", status)
+	}
+}
+
+func TestTrustedProxies(t *testing.T) {
+	database := testutil.NewDB(t)
+	if err := database.Create(&models.BannedIP{CIDR: "203.0.113.5/32"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	list := bans.NewList(database)
+
+	for _, tc := range []struct {
+		name    string
+		proxies []string
+		peer    string
+		want    int
+	}{
+		// The banned address is only believed from a trusted proxy.
+		{"trusted proxy", []string{"10.0.0.0/8"}, "10.1.2.3:4567", http.StatusForbidden},
+		{"untrusted peer", []string{"10.0.0.0/8"}, "192.0.2.9:4567", http.StatusOK},
+		{"no proxies", nil, "10.1.2.3:4567", http.StatusOK},
+	} {
+		engine := gin.New()
+		if err := routes.ConfigureTrustedProxies(engine, tc.proxies); err != nil {
+			t.Fatal(err)
+		}
+		engine.GET("/", routes.BanMiddleware(list), func(c *gin.Context) {
+			c.String(http.StatusOK, c.ClientIP())
+		})
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = tc.peer
+		req.Header.Set("X-Forwarded-For", "203.0.113.5")
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("%s: status %d, want %d (client IP %q)", tc.name, rec.Code, tc.want, rec.Body)
+		}
+	}
+
+	if err := routes.ConfigureTrustedProxies(gin.New(), []string{"not-a-cidr"}); err == nil {
+		t.Error("ConfigureTrustedProxies accepted an invalid CIDR")
+	}
+}
+
+// TestForwardedForThroughRoutes checks that rate limits and bans key on
+// the forwarded address, not the proxy's, through the real routes.
+func TestForwardedForThroughRoutes(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	createBan(t, ts, "203.0.113.5", false)
+
+	ban := ts.NewRequestFrom(t, "203.0.113.5", http.MethodPost, "/api/v1/posts", map[string]string{"content": "from a banned address"})
+	if status, _ := ts.Do(t, ban); status != http.StatusForbidden {
+		t.Errorf("banned client: status %d, want 403", status)
+	}
+	// Every test request comes from loopback, so were the proxy used,
+	// the second client would share the first one's bucket.
+	for i, content := range []string{"one client posts", "another one posts too"} {
+		if status, body := ts.Do(t, ts.NewRequest(t, http.MethodPost, "/api/v1/posts", map[string]string{"content": content})); status != http.StatusCreated {
+			t.Errorf("client %d: status %d, want 201: %s", i+1, status, body)
+		}
+	}
+}
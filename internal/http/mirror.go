@@ -0,0 +1,21 @@
+package http
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadOnlyMiddleware answers anything but GET, HEAD and OPTIONS with 405
+// on a read-only mirror, pointing writers at primary when it is known.
+func ReadOnlyMiddleware(primary string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+		c.Header("Allow", "GET, HEAD, OPTIONS")
+		respondError(c, ErrReadOnly(primary))
+	}
+}
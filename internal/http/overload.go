@@ -0,0 +1,81 @@
+package http
+
+import (
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+
+	"github.com/sujalbistaa/whispr/internal/metrics"
+)
+
+// GlobalLimiter protects the backend from request floods that per-IP
+// limits can't see, such as a spam wave spread over many addresses.
+// It caps both concurrent in-flight POSTs and their combined rate.
+type GlobalLimiter struct {
+	sem     chan struct{} // nil when concurrency is unlimited
+	limiter *rate.Limiter // nil when the global rate is unlimited
+
+	inFlight atomic.Int64
+	rejected atomic.Uint64
+}
+
+// NewGlobalLimiter creates a limiter allowing maxInFlight concurrent POSTs
+// and rps POSTs per second with the given burst. Zero disables either cap.
+func NewGlobalLimiter(maxInFlight int, rps float64, burst int) *GlobalLimiter {
+	g := &GlobalLimiter{}
+	if maxInFlight > 0 {
+		g.sem = make(chan struct{}, maxInFlight)
+	}
+	if rps > 0 {
+		g.limiter = rate.NewLimiter(rate.Limit(rps), max(burst, 1))
+	}
+	return g
+}
+
+// InFlight returns the number of POSTs currently being handled.
+func (g *GlobalLimiter) InFlight() int64 {
+	return g.inFlight.Load()
+}
+
+// Rejected returns how many POSTs were turned away.
+func (g *GlobalLimiter) Rejected() uint64 {
+	return g.rejected.Load()
+}
+
+// Middleware rejects POSTs over either cap with 503 and Retry-After.
+// Other methods, and GraphQL queries (which only read), pass through untouched.
+func (g *GlobalLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodPost || isGraphQLPath(c.Request.URL.Path) {
+			c.Next()
+			return
+		}
+
+		if g.limiter != nil && !g.limiter.Allow() {
+			g.reject(c)
+			return
+		}
+		if g.sem != nil {
+			select {
+			case g.sem <- struct{}{}:
+				defer func() { <-g.sem }()
+			default:
+				g.reject(c)
+				return
+			}
+		}
+
+		g.inFlight.Add(1)
+		defer g.inFlight.Add(-1)
+		c.Next()
+	}
+}
+
+func (g *GlobalLimiter) reject(c *gin.Context) {
+	g.rejected.Add(1)
+	metrics.RateLimited("global")
+	c.Header("Retry-After", "1")
+	respondError(c, ErrOverloaded(1))
+}
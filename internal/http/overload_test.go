@@ -0,0 +1,101 @@
+package http_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	routes "github.com/sujalbistaa/whispr/internal/http"
+)
+
+// TestGlobalLimiterBoundsConcurrency holds POSTs in a slow handler and
+// checks no more than the cap ever run at once.
+func TestGlobalLimiterBoundsConcurrency(t *testing.T) {
+	const maxInFlight, clients = 3, 10
+	g := routes.NewGlobalLimiter(maxInFlight, 0, 0)
+	release := make(chan struct{})
+	var running, peak atomic.Int32
+	engine := gin.New()
+	engine.POST("/slow", g.Middleware(), func(c *gin.Context) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		<-release
+		running.Add(-1)
+		c.Status(http.StatusOK)
+	})
+
+	codes := make(chan *httptest.ResponseRecorder, clients)
+	var wg sync.WaitGroup
+	for i := 0; i < clients; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			engine.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/slow", nil))
+			codes <- rec
+		}()
+	}
+	// The rejected ones come back while the others are still held.
+	rejected := 0
+	for deadline := time.After(5 * time.Second); rejected < clients-maxInFlight; {
+		select {
+		case rec := <-codes:
+			if rec.Code != http.StatusServiceUnavailable || rec.Header().Get("Retry-After") == "" {
+				t.Fatalf("request over the cap: status %d, Retry-After %q; want 503 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+			}
+			rejected++
+		case <-deadline:
+			t.Fatalf("only %d requests rejected while %d were held", rejected, maxInFlight)
+		}
+	}
+	if got := g.InFlight(); got != maxInFlight {
+		t.Errorf("InFlight = %d while held, want %d", got, maxInFlight)
+	}
+	close(release)
+	wg.Wait()
+	close(codes)
+	for rec := range codes {
+		if rec.Code != http.StatusOK {
+			t.Errorf("held request: status %d, want 200", rec.Code)
+		}
+	}
+	if got := peak.Load(); got != maxInFlight {
+		t.Errorf("%d handlers ran at once, want at most and at least %d", got, maxInFlight)
+	}
+	if g.InFlight() != 0 || g.Rejected() != clients-maxInFlight {
+		t.Errorf("after: InFlight %d, Rejected %d; want 0 and %d", g.InFlight(), g.Rejected(), clients-maxInFlight)
+	}
+}
+
+func TestGlobalLimiterRate(t *testing.T) {
+	g := routes.NewGlobalLimiter(0, 0.001, 2)
+	engine := gin.New()
+	engine.Use(g.Middleware())
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	engine.POST("/", ok)
+	engine.GET("/", ok)
+	serve := func(method string) int {
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, httptest.NewRequest(method, "/", nil))
+		return rec.Code
+	}
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusServiceUnavailable} {
+		if got := serve(http.MethodPost); got != want {
+			t.Errorf("POST %d: status %d, want %d", i+1, got, want)
+		}
+	}
+	if got := serve(http.MethodGet); got != http.StatusOK {
+		t.Errorf("GET over the POST rate: status %d, want 200", got)
+	}
+}
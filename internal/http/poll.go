@@ -0,0 +1,78 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPollTimeout = 25 * time.Second
+	maxPollTimeout     = 60 * time.Second
+)
+
+// PollMessage is a live update as GET /poll returns it: a WebSocket
+// message and its sequence number.
+type PollMessage struct {
+	Seq     uint64          `json:"seq"`
+	Message json.RawMessage `json:"message"`
+}
+
+// Poll is the long-polling fallback for clients that can't keep a
+// WebSocket open. It returns the messages for ?board= (as for /ws) sent
+// after ?since_seq=, waiting up to ?timeout= seconds for one when there
+// are none yet, and an empty array when none arrive. Clients pass the
+// last seq they received in the next poll. A client that goes away while
+// waiting is let go at once.
+func (e *Env) Poll(c *gin.Context) {
+	var since uint64
+	if raw := c.Query("since_seq"); raw != "" {
+		n, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			respondError(c, ErrBadRequest("query.invalid_since_seq"))
+			return
+		}
+		since = n
+	}
+	timeout := defaultPollTimeout
+	if raw := c.Query("timeout"); raw != "" {
+		seconds, err := strconv.Atoi(raw)
+		if err != nil || seconds < 0 || time.Duration(seconds)*time.Second > maxPollTimeout {
+			respondError(c, ErrBadRequest("query.invalid_timeout", "max", int(maxPollTimeout.Seconds())))
+			return
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+	rooms, ok := e.requestRooms(c)
+	if !ok {
+		return
+	}
+
+	// The wait can outlast the server's write timeout.
+	clearDeadlines(c)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	messages := []PollMessage{}
+wait:
+	for {
+		events, recorded := e.Hub.Since(since, rooms)
+		if len(events) > 0 {
+			for _, event := range events {
+				messages = append(messages, PollMessage{Seq: event.Seq, Message: event.Data})
+			}
+			break
+		}
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-timer.C:
+			break wait
+		case <-recorded:
+		}
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(http.StatusOK, messages)
+}
@@ -0,0 +1,97 @@
+package http_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	routes "github.com/sujalbistaa/whispr/internal/http"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// poll sends GET /api/v1/poll with query and returns the messages and
+// how long the server held the request.
+func poll(t *testing.T, ts *testutil.TestServer, query string) ([]routes.PollMessage, time.Duration) {
+	t.Helper()
+	start := time.Now()
+	status, body := ts.Do(t, ts.NewRequest(t, http.MethodGet, "/api/v1/poll?"+query, nil))
+	if status != http.StatusOK {
+		t.Errorf("poll ?%s: status %d: %s", query, status, body)
+		return nil, time.Since(start)
+	}
+	var msgs []routes.PollMessage
+	if err := json.Unmarshal(body, &msgs); err != nil {
+		t.Errorf("poll ?%s: %v: %s", query, err, body)
+	}
+	return msgs, time.Since(start)
+}
+
+func TestPollWakesAllWaiters(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+
+	const pollers = 20
+	var wg sync.WaitGroup
+	results := make([][]routes.PollMessage, pollers)
+	held := make([]time.Duration, pollers)
+	for i := range pollers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], held[i] = poll(t, ts, "since_seq=0&timeout=30")
+		}()
+	}
+	// Give every poller time to park on an empty buffer.
+	time.Sleep(300 * time.Millisecond)
+	post := ts.CreatePost(t, "wake up, everyone")
+
+	done := make(chan struct{})
+	go func() { wg.Wait(); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("parked pollers still waiting 5s after a broadcast")
+	}
+	for i, msgs := range results {
+		if len(msgs) != 1 || msgs[0].Seq != 1 {
+			t.Errorf("poller %d got %+v, want the one new_post at seq 1", i, msgs)
+			continue
+		}
+		var msg struct {
+			Type string `json:"type"`
+			Data struct {
+				ID uint `json:"id"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal(msgs[0].Message, &msg); err != nil || msg.Type != "new_post" || msg.Data.ID != post.ID {
+			t.Errorf("poller %d got %s, want new_post for post %d", i, msgs[0].Message, post.ID)
+		}
+		if held[i] < 200*time.Millisecond {
+			t.Errorf("poller %d returned after %s, before the broadcast", i, held[i])
+		}
+	}
+}
+
+func TestPollQueuedAndTimeout(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	for _, content := range []string{"first in the queue", "second thing to say today", "a third, quite different"} {
+		ts.CreatePost(t, content)
+	}
+
+	msgs, held := poll(t, ts, "since_seq=1&timeout=30")
+	if len(msgs) != 2 || msgs[0].Seq != 2 || msgs[1].Seq != 3 {
+		t.Errorf("since_seq=1 got %+v, want seqs 2 and 3", msgs)
+	}
+	if held > time.Second {
+		t.Errorf("queued messages took %s, want them at once", held)
+	}
+
+	msgs, held = poll(t, ts, "since_seq=3&timeout=1")
+	if msgs == nil || len(msgs) != 0 {
+		t.Errorf("poll with nothing new got %+v, want an empty array", msgs)
+	}
+	if held < time.Second {
+		t.Errorf("poll with nothing new returned after %s, want the 1s timeout", held)
+	}
+}
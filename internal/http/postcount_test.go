@@ -0,0 +1,161 @@
+package http_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	routes "github.com/sujalbistaa/whispr/internal/http"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// headCount sends the HEAD request req and returns its X-Total-Count,
+// or -1 unless it succeeded, and the response.
+func headCount(t *testing.T, ts *testutil.TestServer, req *http.Request) (int, *http.Response) {
+	t.Helper()
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return -1, resp
+	}
+	count, err := strconv.Atoi(resp.Header.Get("X-Total-Count"))
+	if err != nil {
+		t.Fatalf("HEAD %s: X-Total-Count %q", req.URL.Path, resp.Header.Get("X-Total-Count"))
+	}
+	return count, resp
+}
+
+// postCount returns the count GET path answers with.
+func postCount(t *testing.T, ts *testutil.TestServer, path string) int {
+	t.Helper()
+	status, body := ts.Do(t, ts.NewRequest(t, http.MethodGet, path, nil))
+	var count routes.PostCount
+	if status != http.StatusOK || json.Unmarshal(body, &count) != nil {
+		t.Fatalf("GET %s: %d %s", path, status, body)
+	}
+	return int(count.Count)
+}
+
+func TestHeadPosts(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	for _, content := range []string{"the first of a few", "k cha sathi, ramro din", "one that gets hidden"} {
+		ts.CreatePost(t, content)
+	}
+	ts.Hide(t, listFeed(t, ts, "/api/v1/posts")[0].ID)
+	seedPost(t, ts, models.Post{Content: "only its author sees this", ShadowBanned: true, CreatedAt: time.Now()})
+
+	for _, path := range []string{"/api/v1/posts", "/api/v1/posts?lang=ne", "/api/v1/boards/general/posts", "/api/v1/posts?sort=active"} {
+		feed := listFeed(t, ts, path)
+		count, resp := headCount(t, ts, ts.NewRequest(t, http.MethodHead, path, nil))
+		if count != len(feed) {
+			t.Errorf("HEAD %s: X-Total-Count %d, GET returns %d posts", path, count, len(feed))
+		}
+		if resp.ContentLength > 0 {
+			t.Errorf("HEAD %s sent a %d-byte body", path, resp.ContentLength)
+		}
+		u, _ := url.Parse(path)
+		countPath := u.Path + "/count?" + u.RawQuery
+		if n := postCount(t, ts, countPath); n != len(feed) {
+			t.Errorf("GET %s = %d, GET %s returns %d posts", countPath, n, path, len(feed))
+		}
+
+		get, err := ts.Client().Do(ts.NewRequest(t, http.MethodGet, path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		get.Body.Close()
+		etag := resp.Header.Get("ETag")
+		if etag == "" || etag != get.Header.Get("ETag") {
+			t.Errorf("HEAD %s: ETag %q, GET's is %q", path, etag, get.Header.Get("ETag"))
+		}
+		req := ts.NewRequest(t, http.MethodHead, path, nil)
+		req.Header.Set("If-None-Match", etag)
+		if _, resp := headCount(t, ts, req); resp.StatusCode != http.StatusNotModified {
+			t.Errorf("HEAD %s If-None-Match its ETag: status %d, want 304", path, resp.StatusCode)
+		}
+	}
+	if n := postCount(t, ts, "/api/v1/posts/count"); n != 2 {
+		t.Errorf("count %d, want 2: hidden and shadow-banned posts left out", n)
+	}
+}
+
+// TestPostCountConcurrentInserts counts the feed while posts are being
+// added: each GET of the feed must hold at least the count taken before
+// it and at most the one taken after.
+func TestPostCountConcurrentInserts(t *testing.T) {
+	t.Setenv("FEED_CACHE_FRESH", "0s")
+	ts := testutil.NewTestServer(t)
+
+	const inserts = 200
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	var done atomic.Bool
+	errs := make(chan error, 1)
+	go func() {
+		defer done.Store(true)
+		for i := range inserts {
+			at := base.Add(time.Duration(i) * time.Second)
+			post := models.Post{Content: fmt.Sprintf("inserted while counting, %d", i), CreatedAt: at, LastActivityAt: at}
+			if err := ts.DB.Create(&post).Error; err != nil {
+				errs <- err
+				return
+			}
+			// Leave the readers time to interleave.
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	rounds := 0
+	for !done.Load() {
+		before, _ := headCount(t, ts, ts.NewRequest(t, http.MethodHead, "/api/v1/posts", nil))
+		feed := len(listFeed(t, ts, "/api/v1/posts"))
+		after := postCount(t, ts, "/api/v1/posts/count")
+		if before > feed || feed > after {
+			t.Fatalf("round %d: HEAD counted %d, then the feed had %d, then /count said %d", rounds, before, feed, after)
+		}
+		rounds++
+	}
+	select {
+	case err := <-errs:
+		t.Fatal(err)
+	default:
+	}
+	t.Logf("%d rounds while inserting", rounds)
+
+	feed := listFeed(t, ts, "/api/v1/posts")
+	if count, _ := headCount(t, ts, ts.NewRequest(t, http.MethodHead, "/api/v1/posts", nil)); count != inserts || len(feed) != inserts {
+		t.Errorf("after the inserts: X-Total-Count %d, feed %d, want %d", count, len(feed), inserts)
+	}
+
+	// Only posts created after since count; none is created exactly then.
+	since := base.Add(149*time.Second + 500*time.Millisecond)
+	want := 0
+	for _, post := range feed {
+		if post.CreatedAt.After(since) {
+			want++
+		}
+	}
+	path := "/api/v1/posts/count?since=" + url.QueryEscape(since.Format(time.RFC3339Nano))
+	if n := postCount(t, ts, path); n != want || want != 50 {
+		t.Errorf("GET %s = %d, want %d (50) posts in the feed after it", path, n, want)
+	}
+	resp, err := ts.Client().Do(ts.NewRequest(t, http.MethodGet, path, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get("Cache-Control"); got != "private, max-age=5" {
+		t.Errorf("count Cache-Control %q, want private, max-age=5", got)
+	}
+	if status, code := errorCode(t, ts, ts.NewRequest(t, http.MethodGet, "/api/v1/posts/count?since=an+hour+ago", nil)); status != http.StatusBadRequest || code != routes.CodeBadRequest {
+		t.Errorf("bad since: %d %s, want 400 %s", status, code, routes.CodeBadRequest)
+	}
+}
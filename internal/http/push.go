@@ -0,0 +1,119 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/push"
+)
+
+// pushExcerpt is how many characters of a post a notification shows.
+const pushExcerpt = 120
+
+// PushSubscriptionInput is what PushSubscription.toJSON() returns in the
+// browser, posted as is.
+type PushSubscriptionInput struct {
+	Endpoint string `json:"endpoint" binding:"required,url,max=512"`
+	Keys     struct {
+		P256dh string `json:"p256dh" binding:"required,max=128"`
+		Auth   string `json:"auth" binding:"required,max=64"`
+	} `json:"keys" binding:"required"`
+}
+
+// UnsubscribeInput names the subscription to delete.
+type UnsubscribeInput struct {
+	Endpoint string `json:"endpoint" binding:"required,max=512"`
+}
+
+// GetPushKey returns the VAPID public key browsers subscribe with and the
+// score that makes a post trending, or 204 when push is off.
+func (e *Env) GetPushKey(c *gin.Context) {
+	if e.Push == nil {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"publicKey": e.Config.Push.PublicKey, "threshold": e.Config.Push.Threshold})
+}
+
+// SubscribePush stores a browser's push subscription. Subscribing again
+// from the same browser updates its keys.
+func (e *Env) SubscribePush(c *gin.Context) {
+	if e.Push == nil {
+		respondError(c, ErrNotFound("push.disabled"))
+		return
+	}
+	var input PushSubscriptionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, ErrValidation(err))
+		return
+	}
+	if !e.Config.Push.AllowsEndpoint(input.Endpoint) {
+		respondError(c, ErrBadRequest("push.invalid_endpoint"))
+		return
+	}
+	if !push.ValidKeys(input.Keys.P256dh, input.Keys.Auth) {
+		respondError(c, ErrBadRequest("push.invalid_keys"))
+		return
+	}
+
+	sub := models.PushSubscription{Endpoint: input.Endpoint, P256dh: input.Keys.P256dh, Auth: input.Keys.Auth}
+	if err := e.Push.Subscribe(c.Request.Context(), &sub); err != nil {
+		requestLogger(c).Error("saving push subscription", "err", err)
+		respondError(c, ErrInternal("push.subscribe_failed"))
+		return
+	}
+	c.Status(http.StatusCreated)
+}
+
+// UnsubscribePush deletes a browser's push subscription. Deleting one
+// that doesn't exist succeeds too.
+func (e *Env) UnsubscribePush(c *gin.Context) {
+	if e.Push == nil {
+		respondError(c, ErrNotFound("push.disabled"))
+		return
+	}
+	var input UnsubscribeInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, ErrValidation(err))
+		return
+	}
+	if err := e.Push.Unsubscribe(c.Request.Context(), input.Endpoint); err != nil {
+		requestLogger(c).Error("deleting push subscription", "err", err)
+		respondError(c, ErrInternal("push.unsubscribe_failed"))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// announceTrending notifies push subscribers the first time post reaches
+// Push.Threshold. It runs after the vote commits, so the notification
+// never waits on the vote or holds its row lock; MarkNotified makes sure
+// only one vote announces each post. Shadow-banned posts are never
+// announced, and failures are logged without failing the vote.
+func (e *Env) announceTrending(c *gin.Context, post models.Post) {
+	if e.Push == nil || post.NotifiedAt != nil || post.ShadowBanned || post.Score < e.Config.Push.Threshold {
+		return
+	}
+	first, err := e.Posts.MarkNotified(c.Request.Context(), post.ID, e.Config.Push.Threshold)
+	if err != nil {
+		requestLogger(c).Error("marking post notified", "post", post.ID, "err", err)
+		return
+	}
+	if !first {
+		return
+	}
+	body := post.Content
+	if runes := []rune(body); len(runes) > pushExcerpt {
+		body = string(runes[:pushExcerpt-1]) + "…"
+	}
+	requestLogger(c).Info("announcing trending post", "post", post.ID, "score", post.Score)
+	e.Push.Notify(push.Notification{
+		PostID: post.ID,
+		Title:  "Trending on Whispr: +" + strconv.Itoa(post.Score),
+		Body:   body,
+		URL:    e.publicBaseURL(c) + "/#trending",
+	})
+}
@@ -0,0 +1,42 @@
+package http
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// postQuotaWindow is the rolling window of the daily post quota.
+const postQuotaWindow = 24 * time.Hour
+
+// checkPostQuota enforces RateLimit.PostQuota for author, answering 429
+// with the time a post frees up once it's used. It returns how many posts
+// are left including this one, or -1 when the caller isn't subject to the
+// quota. Concurrent posts can overshoot it by a few; the per-IP rate limit
+// keeps that small.
+func (e *Env) checkPostQuota(c *gin.Context, author string) (int, bool) {
+	quota := e.Config.RateLimit.PostQuota
+	_, isAdmin := c.Get(adminRoleKey)
+	if quota == 0 || c.GetBool(rateLimitBypassKey) || isAdmin {
+		return -1, true
+	}
+	recent, err := e.Posts.RecentByAuthor(c.Request.Context(), author, time.Now().Add(-postQuotaWindow), quota)
+	if err != nil {
+		// The quota is a courtesy limit; the rate limit still applies.
+		requestLogger(c).Error("checking post quota", "err", err)
+		return -1, true
+	}
+	c.Header("X-Post-Quota-Limit", strconv.Itoa(quota))
+	if len(recent) < quota {
+		return quota - len(recent), true
+	}
+	// The oldest of the last quota posts leaves the window first.
+	resetAt := recent[quota-1].Add(postQuotaWindow)
+	retryAfter := int(math.Ceil(time.Until(resetAt).Seconds()))
+	c.Header("X-Post-Quota-Remaining", "0")
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	respondError(c, ErrQuotaExceeded(quota, resetAt, retryAfter))
+	return 0, false
+}
@@ -0,0 +1,108 @@
+package http_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	routes "github.com/sujalbistaa/whispr/internal/http"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// postAs sends req and returns the response with its decoded body.
+func postAs(t *testing.T, ts *testutil.TestServer, req *http.Request) (*http.Response, []byte) {
+	t.Helper()
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var body json.RawMessage
+	json.NewDecoder(resp.Body).Decode(&body)
+	return resp, body
+}
+
+// TestPostQuotaRollover uses up a quota of three, then moves the oldest
+// post to either side of the 24-hour boundary.
+func TestPostQuotaRollover(t *testing.T) {
+	t.Setenv("POST_DAILY_QUOTA", "3")
+	t.Setenv("POST_RATE_RPS", "100")
+	t.Setenv("POST_RATE_BURST", "100")
+	ts := testutil.NewTestServer(t)
+	ip := ts.ClientIP()
+	contents := []string{
+		"the library closes early on fridays now",
+		"anyone selling a secondhand bike near campus?",
+		"cafeteria coffee got noticeably worse this week",
+		"lost a blue umbrella outside lecture hall b",
+		"who keeps leaving the lab window open overnight",
+		"midterm grades are finally up on the portal",
+	}
+	newPost := func(i int) *http.Request {
+		return ts.NewRequestFrom(t, ip, http.MethodPost, "/api/v1/posts", map[string]string{"content": contents[i]})
+	}
+
+	var first models.Post
+	for i := 0; i < 3; i++ {
+		resp, body := postAs(t, ts, newPost(i))
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("post %d: status %d: %s", i+1, resp.StatusCode, body)
+		}
+		if i == 0 {
+			json.Unmarshal(body, &first)
+		}
+		if got, want := resp.Header.Get("X-Post-Quota-Remaining"), strconv.Itoa(2-i); got != want || resp.Header.Get("X-Post-Quota-Limit") != "3" {
+			t.Errorf("post %d: quota headers %q of %q, want %s of 3", i+1, got, resp.Header.Get("X-Post-Quota-Limit"), want)
+		}
+	}
+
+	// age backdates the first post by the given amount and tries the
+	// next post.
+	next := 3
+	age := func(by time.Duration) (int, *apiError, *http.Response) {
+		t.Helper()
+		if err := ts.DB.Model(&models.Post{}).Where("id = ?", first.ID).Update("created_at", time.Now().Add(-by)).Error; err != nil {
+			t.Fatal(err)
+		}
+		resp, body := postAs(t, ts, newPost(next))
+		next++
+		var apiErr apiError
+		json.Unmarshal(body, &apiErr)
+		return resp.StatusCode, &apiErr, resp
+	}
+
+	status, apiErr, resp := age(24*time.Hour - time.Minute)
+	if status != http.StatusTooManyRequests || apiErr.Error.Code != routes.CodeQuotaExceeded {
+		t.Fatalf("a minute before the oldest post rolls off: %d %s, want 429 %s", status, apiErr.Error.Code, routes.CodeQuotaExceeded)
+	}
+	var details struct {
+		ResetAt    time.Time `json:"resetAt"`
+		RetryAfter int       `json:"retryAfter"`
+	}
+	json.Unmarshal(apiErr.Error.Details, &details)
+	if until := time.Until(details.ResetAt); until <= 0 || until > time.Minute {
+		t.Errorf("resetAt %s is %s away, want within the minute", details.ResetAt, until)
+	}
+	if details.RetryAfter < 1 || details.RetryAfter > 60 || resp.Header.Get("Retry-After") != strconv.Itoa(details.RetryAfter) {
+		t.Errorf("retryAfter %d, Retry-After %q; want the same, at most 60", details.RetryAfter, resp.Header.Get("Retry-After"))
+	}
+	if got := resp.Header.Get("X-Post-Quota-Remaining"); got != "0" {
+		t.Errorf("X-Post-Quota-Remaining = %q on a 429, want 0", got)
+	}
+
+	if status, apiErr, resp := age(24*time.Hour + time.Second); status != http.StatusCreated {
+		t.Fatalf("a second after the oldest post rolled off: %d %s, want 201", status, apiErr.Error.Code)
+	} else if got := resp.Header.Get("X-Post-Quota-Remaining"); got != "0" {
+		t.Errorf("X-Post-Quota-Remaining = %q after rollover, want 0", got)
+	}
+
+	// Admins aren't held to it.
+	admin := newPost(next)
+	admin.Header.Set("X-Admin-Token", testutil.AdminToken)
+	if resp, body := postAs(t, ts, admin); resp.StatusCode != http.StatusCreated {
+		t.Errorf("admin over the quota: status %d: %s", resp.StatusCode, body)
+	}
+}
@@ -0,0 +1,212 @@
+package http
+
+import (
+	"context"
+	"math"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/time/rate"
+
+	"github.com/sujalbistaa/whispr/internal/auth"
+	"github.com/sujalbistaa/whispr/internal/metrics"
+	"github.com/sujalbistaa/whispr/internal/supervisor"
+)
+
+// --- Rate Limiter ---
+
+// RateLimiter decides whether a request identified by key may proceed.
+// An error means the limiter could not decide; see RateLimitMiddleware.
+type RateLimiter interface {
+	Allow(ctx context.Context, key string) (Decision, error)
+}
+
+// Decision is the outcome of a rate limit check.
+type Decision struct {
+	Allowed    bool
+	Limit      int           // Bucket size
+	Remaining  int           // Whole tokens left after this request
+	RetryAfter time.Duration // Wait before the next token, when rejected
+}
+
+// visitor is a single client's token bucket and when it was last used.
+type visitor struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// IPRateLimiter keeps a token bucket per client IP.
+type IPRateLimiter struct {
+	visitors map[string]*visitor
+	mu       sync.Mutex
+	rps      rate.Limit
+	burst    int
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+func NewIPRateLimiter(r rate.Limit, b int) *IPRateLimiter {
+	return &IPRateLimiter{
+		visitors: make(map[string]*visitor),
+		rps:      r,
+		burst:    b,
+		stop:     make(chan struct{}),
+	}
+}
+
+// GetLimiter returns the bucket for ip, creating it if needed,
+// and marks the visitor as seen.
+func (rl *IPRateLimiter) GetLimiter(ip string) *rate.Limiter {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	v, exists := rl.visitors[ip]
+	if !exists {
+		v = &visitor{limiter: rate.NewLimiter(rl.rps, rl.burst)}
+		rl.visitors[ip] = v
+	}
+	v.lastSeen = time.Now()
+	return v.limiter
+}
+
+// Allow takes a token from ip's bucket. It never fails.
+// A reservation is used so the wait is known; it is canceled when the
+// request is rejected so rejected requests don't consume future tokens.
+func (rl *IPRateLimiter) Allow(_ context.Context, ip string) (Decision, error) {
+	limiter := rl.GetLimiter(ip)
+	now := time.Now()
+	decision := Decision{Limit: rl.burst}
+
+	reservation := limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		decision.RetryAfter = time.Minute
+		return decision, nil
+	}
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		decision.RetryAfter = delay
+		return decision, nil
+	}
+
+	decision.Allowed = true
+	decision.Remaining = max(0, int(limiter.TokensAt(now)))
+	return decision, nil
+}
+
+// Cleanup removes visitors idle for longer than ttl.
+// It never touches the token buckets of visitors it keeps.
+func (rl *IPRateLimiter) Cleanup(ttl time.Duration) int {
+	cutoff := time.Now().Add(-ttl)
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	removed := 0
+	for ip, v := range rl.visitors {
+		if v.lastSeen.Before(cutoff) {
+			delete(rl.visitors, ip)
+			removed++
+		}
+	}
+	return removed
+}
+
+// StartCleanup runs Cleanup every interval until Stop is called, under
+// sup as name.
+func (rl *IPRateLimiter) StartCleanup(sup *supervisor.Supervisor, name string, interval, ttl time.Duration) {
+	go sup.Run(context.Background(), name, func(context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				rl.Cleanup(ttl)
+			case <-rl.stop:
+				return
+			}
+		}
+	})
+}
+
+// Stop ends the cleanup loop. It is safe to call more than once.
+func (rl *IPRateLimiter) Stop() {
+	rl.stopOnce.Do(func() { close(rl.stop) })
+}
+
+// RateLimitBypassMiddleware marks requests that skip rate limiting:
+// clients inside the allowlist and callers presenting a valid admin token.
+// It never rejects a request.
+func RateLimitBypassMiddleware(allowlist []netip.Prefix, tokens *auth.TokenStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.GetHeader("X-Admin-Token"); token != "" {
+			if _, ok := tokens.Resolve(token); ok {
+				c.Set(rateLimitBypassKey, true)
+				c.Next()
+				return
+			}
+		}
+		if addr, err := netip.ParseAddr(clientIP(c)); err == nil {
+			addr = addr.Unmap()
+			for _, prefix := range allowlist {
+				if prefix.Contains(addr) {
+					c.Set(rateLimitBypassKey, true)
+					break
+				}
+			}
+		}
+		c.Next()
+	}
+}
+
+// RateLimitMiddleware rejects clients that exceed the limiter with 429.
+// Requests flagged by RateLimitBypassMiddleware or authenticated by
+// AdminAuthMiddleware pass through with X-RateLimit-Bypass set, and
+// requests with an API key were already limited in the key's bucket by
+// APIKeyMiddleware.
+// If the limiter errors, the request is allowed when failOpen is set and
+// rejected with 503 otherwise.
+func RateLimitMiddleware(limiter RateLimiter, failOpen bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		_, isAdmin := c.Get(adminRoleKey)
+		if c.GetBool(rateLimitBypassKey) || isAdmin {
+			c.Header("X-RateLimit-Bypass", "true")
+			c.Next()
+			return
+		}
+		if _, ok := c.Get(apiKeyKey); ok {
+			c.Next()
+			return
+		}
+		if allowRequest(c, limiter, clientIP(c), failOpen) {
+			c.Next()
+		}
+	}
+}
+
+// allowRequest takes a token from key's bucket and sets the rate limit
+// headers. It reports whether the request may go on; if not, it has
+// already been answered.
+func allowRequest(c *gin.Context, limiter RateLimiter, key string, failOpen bool) bool {
+	decision, err := limiter.Allow(c.Request.Context(), key)
+	if err != nil {
+		requestLogger(c).Error("rate limiter error", "fail_open", failOpen, "err", err)
+		metrics.RateLimitError(c.FullPath(), failOpen)
+		if !failOpen {
+			respondError(c, ErrUnavailable("request.rate_limiter_unavailable"))
+			return false
+		}
+		return true
+	}
+
+	c.Header("X-RateLimit-Limit", strconv.Itoa(decision.Limit))
+	c.Header("X-RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	if !decision.Allowed {
+		retryAfter := int(math.Ceil(decision.RetryAfter.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		metrics.RateLimited(c.FullPath())
+		respondError(c, ErrRateLimited(retryAfter))
+		return false
+	}
+	return true
+}
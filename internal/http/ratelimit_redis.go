@@ -0,0 +1,91 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/sujalbistaa/whispr/internal/config"
+)
+
+// tokenBucketScript refills and takes one token from a bucket stored as a
+// Redis hash, atomically. KEYS[1] is the bucket; ARGV is rate (tokens per
+// second), burst, the current time in milliseconds and the key TTL in ms.
+// It returns {allowed, whole tokens remaining, ms until the next token}.
+// Tokens are stored in fixed point: a near-empty bucket would otherwise
+// be written as e.g. 1e-05, which some Lua implementations can't read
+// back, refilling the bucket.
+var tokenBucketScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+local allowed = 0
+local retry = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	retry = math.ceil((1 - tokens) / rate * 1000)
+end
+redis.call('HSET', KEYS[1], 'tokens', string.format('%.6f', tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], ARGV[4])
+return {allowed, math.floor(tokens), retry}
+`)
+
+// RedisRateLimiter is a token bucket per key shared by every replica.
+type RedisRateLimiter struct {
+	client *redis.Client
+	prefix string
+	rps    float64
+	burst  int
+	ttl    time.Duration
+
+	errors atomic.Uint64
+}
+
+// NewRedisRateLimiter creates a limiter whose buckets live under prefix.
+// Buckets expire once they would have fully refilled.
+func NewRedisRateLimiter(client *redis.Client, prefix string, limit config.RouteLimit) *RedisRateLimiter {
+	refill := time.Duration(math.Ceil(float64(limit.Burst)/limit.RPS*1000)) * time.Millisecond
+	return &RedisRateLimiter{
+		client: client,
+		prefix: prefix,
+		rps:    limit.RPS,
+		burst:  limit.Burst,
+		ttl:    refill + time.Second,
+	}
+}
+
+// Allow takes a token from key's bucket.
+func (rl *RedisRateLimiter) Allow(ctx context.Context, key string) (Decision, error) {
+	now := time.Now().UnixMilli()
+	res, err := tokenBucketScript.Run(ctx, rl.client, []string{rl.prefix + key},
+		strconv.FormatFloat(rl.rps, 'f', -1, 64), rl.burst, now, rl.ttl.Milliseconds()).Int64Slice()
+	if err != nil || len(res) != 3 {
+		rl.errors.Add(1)
+		if err == nil {
+			err = fmt.Errorf("unexpected rate limit script result %v", res)
+		}
+		return Decision{}, err
+	}
+	return Decision{
+		Allowed:    res[0] == 1,
+		Limit:      rl.burst,
+		Remaining:  int(res[1]),
+		RetryAfter: time.Duration(res[2]) * time.Millisecond,
+	}, nil
+}
+
+// Errors returns how many times Redis could not be reached.
+func (rl *RedisRateLimiter) Errors() uint64 {
+	return rl.errors.Load()
+}
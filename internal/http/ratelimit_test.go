@@ -0,0 +1,333 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"golang.org/x/time/rate"
+
+	"github.com/sujalbistaa/whispr/internal/config"
+	routes "github.com/sujalbistaa/whispr/internal/http"
+	"github.com/sujalbistaa/whispr/internal/metrics"
+	"github.com/sujalbistaa/whispr/internal/reporting"
+	"github.com/sujalbistaa/whispr/internal/supervisor"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// newLimiter builds a RateLimiter refilling rps tokens a second into
+// buckets of burst.
+type newLimiter func(t *testing.T, rps float64, burst int) routes.RateLimiter
+
+func TestIPRateLimiter(t *testing.T) {
+	testRateLimiter(t, func(t *testing.T, rps float64, burst int) routes.RateLimiter {
+		return routes.NewIPRateLimiter(rate.Limit(rps), burst)
+	})
+}
+
+func TestRedisRateLimiter(t *testing.T) {
+	testRateLimiter(t, func(t *testing.T, rps float64, burst int) routes.RateLimiter {
+		return routes.NewRedisRateLimiter(newRedis(t), "test:", config.RouteLimit{RPS: rps, Burst: burst})
+	})
+}
+
+func newRedis(t *testing.T) *redis.Client {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// testRateLimiter is the behavior every RateLimiter shares.
+func testRateLimiter(t *testing.T, newLimiter newLimiter) {
+	ctx := context.Background()
+	allow := func(t *testing.T, l routes.RateLimiter, key string) routes.Decision {
+		t.Helper()
+		d, err := l.Allow(ctx, key)
+		if err != nil {
+			t.Fatalf("Allow(%q): %v", key, err)
+		}
+		return d
+	}
+
+	t.Run("burst then reject", func(t *testing.T) {
+		l := newLimiter(t, 1, 3)
+		for i := 0; i < 3; i++ {
+			d := allow(t, l, "a")
+			if !d.Allowed || d.Limit != 3 || d.Remaining != 2-i {
+				t.Errorf("request %d = %+v, want allowed with %d remaining of 3", i+1, d, 2-i)
+			}
+		}
+		d := allow(t, l, "a")
+		if d.Allowed || d.Remaining != 0 {
+			t.Errorf("request over the burst = %+v, want rejected", d)
+		}
+		if d.RetryAfter <= 0 || d.RetryAfter > time.Second {
+			t.Errorf("RetryAfter = %s, want within a second", d.RetryAfter)
+		}
+	})
+
+	t.Run("keys are independent", func(t *testing.T) {
+		l := newLimiter(t, 1, 1)
+		if !allow(t, l, "a").Allowed {
+			t.Fatal("first request for a rejected")
+		}
+		if allow(t, l, "a").Allowed {
+			t.Error("second request for a allowed")
+		}
+		if !allow(t, l, "b").Allowed {
+			t.Error("first request for b rejected after a ran out")
+		}
+	})
+
+	t.Run("refills", func(t *testing.T) {
+		l := newLimiter(t, 20, 1)
+		allow(t, l, "a")
+		d := allow(t, l, "a")
+		if d.Allowed {
+			t.Fatal("second request allowed before refilling")
+		}
+		time.Sleep(d.RetryAfter + 20*time.Millisecond)
+		if !allow(t, l, "a").Allowed {
+			t.Error("rejected after waiting RetryAfter")
+		}
+	})
+
+	t.Run("rejections don't borrow tokens", func(t *testing.T) {
+		l := newLimiter(t, 10, 1)
+		allow(t, l, "a")
+		for i := 0; i < 5; i++ {
+			allow(t, l, "a")
+		}
+		time.Sleep(150 * time.Millisecond)
+		if !allow(t, l, "a").Allowed {
+			t.Error("rejected requests pushed back the next token")
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		l := newLimiter(t, 0.01, 10)
+		var allowed atomic.Int32
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if d, err := l.Allow(ctx, "a"); err == nil && d.Allowed {
+					allowed.Add(1)
+				}
+			}()
+		}
+		wg.Wait()
+		if n := allowed.Load(); n != 10 {
+			t.Errorf("%d of 50 concurrent requests allowed, want the burst of 10", n)
+		}
+	})
+}
+
+func TestRedisRateLimiterUnreachable(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+	t.Cleanup(func() { client.Close() })
+	l := routes.NewRedisRateLimiter(client, "test:", config.RouteLimit{RPS: 1, Burst: 1})
+	server.Close()
+
+	if _, err := l.Allow(context.Background(), "a"); err == nil {
+		t.Fatal("Allow succeeded with Redis down")
+	}
+	if l.Errors() != 1 {
+		t.Errorf("Errors() = %d, want 1", l.Errors())
+	}
+}
+
+// brokenLimiter can never decide.
+type brokenLimiter struct{}
+
+func (brokenLimiter) Allow(context.Context, string) (routes.Decision, error) {
+	return routes.Decision{}, errors.New("unreachable")
+}
+
+func TestRateLimitMiddlewareFailure(t *testing.T) {
+	for _, tc := range []struct {
+		failOpen bool
+		fallback string
+		want     int
+	}{
+		{true, "open", http.StatusOK},
+		{false, "closed", http.StatusServiceUnavailable},
+	} {
+		engine := gin.New()
+		engine.GET("/broken", routes.RateLimitMiddleware(brokenLimiter{}, tc.failOpen), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+		labels := map[string]string{"limiter": "/broken", "fallback": tc.fallback}
+		before := counterValue(t, metrics.NameRateLimitErrors, labels)
+		rec := httptest.NewRecorder()
+		engine.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/broken", nil))
+		if rec.Code != tc.want {
+			t.Errorf("failOpen=%v: status %d, want %d", tc.failOpen, rec.Code, tc.want)
+		}
+		if got := counterValue(t, metrics.NameRateLimitErrors, labels) - before; got != 1 {
+			t.Errorf("failOpen=%v: %s%v went up by %g, want 1", tc.failOpen, metrics.NameRateLimitErrors, labels, got)
+		}
+	}
+}
+
+// counterValue returns the value of the counter name with exactly labels,
+// or 0 if it hasn't been counted yet.
+func counterValue(t *testing.T, name string, labels map[string]string) float64 {
+	t.Helper()
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metric:
+		for _, m := range family.GetMetric() {
+			if len(m.GetLabel()) != len(labels) {
+				continue
+			}
+			for _, label := range m.GetLabel() {
+				if labels[label.GetName()] != label.GetValue() {
+					continue metric
+				}
+			}
+			return m.GetCounter().GetValue()
+		}
+	}
+	return 0
+}
+
+func TestIPRateLimiterCleanupKeepsTokens(t *testing.T) {
+	l := routes.NewIPRateLimiter(rate.Limit(0.001), 2)
+	ctx := context.Background()
+	if d, _ := l.Allow(ctx, "a"); !d.Allowed || d.Remaining != 1 {
+		t.Fatalf("first request = %+v, want allowed with 1 remaining", d)
+	}
+	for i := 0; i < 10; i++ {
+		if removed := l.Cleanup(time.Hour); removed != 0 {
+			t.Fatalf("Cleanup removed %d active visitors", removed)
+		}
+	}
+	if d, _ := l.Allow(ctx, "a"); !d.Allowed || d.Remaining != 0 {
+		t.Errorf("request after cleanups = %+v, want the last token still there", d)
+	}
+	if d, _ := l.Allow(ctx, "a"); d.Allowed {
+		t.Error("a third request was allowed from a bucket of 2")
+	}
+}
+
+func TestIPRateLimiterCleanupForgetsIdle(t *testing.T) {
+	l := routes.NewIPRateLimiter(rate.Limit(0.001), 1)
+	ctx := context.Background()
+	l.Allow(ctx, "idle")
+	time.Sleep(20 * time.Millisecond)
+	l.Allow(ctx, "active")
+
+	if removed := l.Cleanup(10 * time.Millisecond); removed != 1 {
+		t.Errorf("Cleanup removed %d visitors, want just the idle one", removed)
+	}
+	if d, _ := l.Allow(ctx, "idle"); !d.Allowed {
+		t.Error("a forgotten visitor didn't start with a full bucket")
+	}
+	if d, _ := l.Allow(ctx, "active"); d.Allowed {
+		t.Error("the active visitor's bucket was reset")
+	}
+}
+
+func TestIPRateLimiterStop(t *testing.T) {
+	l := routes.NewIPRateLimiter(rate.Limit(0.001), 1)
+	sup := supervisor.New(supervisor.DefaultConfig, &reporting.Recorder{})
+	l.StartCleanup(sup, "test-cleanup", time.Millisecond, time.Millisecond)
+	l.Allow(context.Background(), "a")
+	time.Sleep(20 * time.Millisecond)
+	if d, _ := l.Allow(context.Background(), "a"); !d.Allowed {
+		t.Error("the cleanup loop never forgot the idle visitor")
+	}
+	l.Stop()
+	l.Stop() // Safe twice
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	t.Setenv("POST_RATE_RPS", "0.1")
+	t.Setenv("POST_RATE_BURST", "2")
+	ts := testutil.NewTestServer(t)
+	ip := ts.ClientIP()
+	post := func(content string) (*http.Response, []byte) {
+		t.Helper()
+		resp, err := ts.Client().Do(ts.NewRequestFrom(t, ip, http.MethodPost, "/api/v1/posts", map[string]string{"content": content}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp, body
+	}
+
+	// Under the limit, then at it
+	for _, tc := range []struct{ content, remaining string }{
+		{"the first of my posts", "1"},
+		{"another thought entirely", "0"},
+	} {
+		resp, body := post(tc.content)
+		if resp.StatusCode != http.StatusCreated {
+			t.Fatalf("status %d, want 201: %s", resp.StatusCode, body)
+		}
+		if got := resp.Header.Get("X-RateLimit-Limit"); got != "2" {
+			t.Errorf("X-RateLimit-Limit = %q, want 2", got)
+		}
+		if got := resp.Header.Get("X-RateLimit-Remaining"); got != tc.remaining {
+			t.Errorf("X-RateLimit-Remaining = %q, want %s", got, tc.remaining)
+		}
+		if got := resp.Header.Get("Retry-After"); got != "" {
+			t.Errorf("Retry-After = %q on an allowed request", got)
+		}
+	}
+
+	// Over it: a token comes back every 10 seconds.
+	resp, body := post("one post too many")
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("status %d, want 429: %s", resp.StatusCode, body)
+	}
+	retryAfter, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || retryAfter < 9 || retryAfter > 10 {
+		t.Errorf("Retry-After = %q, want 9 or 10 seconds", resp.Header.Get("Retry-After"))
+	}
+	if got := resp.Header.Get("X-RateLimit-Remaining"); got != "0" {
+		t.Errorf("X-RateLimit-Remaining = %q, want 0", got)
+	}
+	var apiErr struct {
+		Error struct {
+			Code    string `json:"code"`
+			Details struct {
+				RetryAfter int `json:"retryAfter"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &apiErr); err != nil {
+		t.Fatal(err)
+	}
+	if apiErr.Error.Code != routes.CodeRateLimited || apiErr.Error.Details.RetryAfter != retryAfter {
+		t.Errorf("error %+v, want %s with retryAfter %d", apiErr.Error, routes.CodeRateLimited, retryAfter)
+	}
+
+	// Another client has a bucket of its own.
+	if status, _ := ts.Do(t, ts.NewRequest(t, http.MethodPost, "/api/v1/posts", map[string]string{"content": "from someone else"})); status != http.StatusCreated {
+		t.Errorf("another client: status %d, want 201", status)
+	}
+}
@@ -0,0 +1,40 @@
+package http_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sujalbistaa/whispr/internal/db"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// TestReadySchemaBehind rolls the recorded schema version back and checks
+// readiness fails until the migrations catch up again.
+func TestReadySchemaBehind(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	ready := func() (int, map[string]string) {
+		status, body := ts.Do(t, ts.NewRequest(t, http.MethodGet, "/readyz", nil))
+		var resp struct {
+			Checks map[string]string `json:"checks"`
+		}
+		json.Unmarshal(body, &resp)
+		return status, resp.Checks
+	}
+
+	if err := ts.DB.Exec("DELETE FROM schema_migrations WHERE version = ?", db.LatestVersion()).Error; err != nil {
+		t.Fatal(err)
+	}
+	status, checks := ready()
+	if status != http.StatusServiceUnavailable || !strings.HasPrefix(checks["schema"], "behind") {
+		t.Errorf("schema behind: status %d, schema %q; want 503 behind", status, checks["schema"])
+	}
+
+	if err := db.Migrate(ts.DB); err != nil {
+		t.Fatal(err)
+	}
+	if status, checks := ready(); status != http.StatusOK || checks["schema"] != "ok" {
+		t.Errorf("after migrating: status %d, schema %q; want 200 ok", status, checks["schema"])
+	}
+}
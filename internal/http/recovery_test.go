@@ -0,0 +1,74 @@
+package http_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	routes "github.com/sujalbistaa/whispr/internal/http"
+	"github.com/sujalbistaa/whispr/internal/reporting"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+	"github.com/sujalbistaa/whispr/internal/ws"
+)
+
+func TestRecoveryMiddleware(t *testing.T) {
+	reporter := &reporting.Recorder{}
+	engine := gin.New()
+	engine.Use(routes.RequestIDMiddleware(nil), routes.RecoveryMiddleware(reporter))
+	engine.POST("/posts/:id/boom", func(c *gin.Context) { panic("handler blew up") })
+	engine.GET("/gone", func(c *gin.Context) { panic(http.ErrAbortHandler) })
+
+	rec := httptest.NewRecorder()
+	engine.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/posts/7/boom", nil))
+	var apiErr apiError
+	if err := json.Unmarshal(rec.Body.Bytes(), &apiErr); err != nil || rec.Code != http.StatusInternalServerError || apiErr.Error.Code != routes.CodeInternal {
+		t.Errorf("status %d, body %s; want the 500 envelope", rec.Code, rec.Body)
+	}
+	panics := reporter.Panics()
+	if len(panics) != 1 {
+		t.Fatalf("%d panics reported, want 1", len(panics))
+	}
+	p := panics[0]
+	if p.Value != "handler blew up" || p.Source != "http" || p.Method != http.MethodPost || p.Route != "/posts/:id/boom" {
+		t.Errorf("reported %+v", p)
+	}
+	if id := rec.Header().Get("X-Request-ID"); id == "" || p.RequestID != id {
+		t.Errorf("reported request ID %q, response has %q", p.RequestID, id)
+	}
+	if !strings.Contains(string(p.Stack), "recovery_test.go") {
+		t.Errorf("stack doesn't reach the handler:\n%s", p.Stack)
+	}
+
+	// A client that went away isn't worth a report.
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/gone", nil))
+	if n := len(reporter.Panics()); n != 1 {
+		t.Errorf("%d panics reported after an aborted request, want still 1", n)
+	}
+}
+
+// TestHubPanicReported registers a client whose send channel is already
+// closed, so the hub panics delivering the next broadcast.
+func TestHubPanicReported(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	broken := &ws.Client{Hub: ts.Hub, Send: make(chan []byte, 1)}
+	close(broken.Send)
+	ts.Hub.Register <- broken
+
+	ts.CreatePost(t, "too much for the hub")
+	deadline := time.Now().Add(2 * time.Second)
+	for len(ts.Panics.Panics()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	panics := ts.Panics.Panics()
+	if len(panics) == 0 || panics[0].Source != "ws.hub" || len(panics[0].Stack) == 0 {
+		t.Fatalf("reported %+v, want a ws.hub panic with its stack", panics)
+	}
+	if status, _ := ts.Do(t, ts.NewRequest(t, http.MethodGet, "/healthz", nil)); status != http.StatusOK {
+		t.Errorf("server unhealthy after the hub panicked: status %d", status)
+	}
+}
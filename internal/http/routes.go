@@ -1,81 +1,491 @@
 package http
 
 import (
-	"os"
+	"context"
+	"crypto/rand"
+	"log"
+	"log/slog"
+	"net/http"
 	"time"
 
-	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"golang.org/x/time/rate"
 	"gorm.io/gorm"
 
+	"github.com/sujalbistaa/whispr/internal/auth"
+	"github.com/sujalbistaa/whispr/internal/bans"
+	"github.com/sujalbistaa/whispr/internal/challenge"
+	"github.com/sujalbistaa/whispr/internal/config"
+	"github.com/sujalbistaa/whispr/internal/contentpolicy"
+	"github.com/sujalbistaa/whispr/internal/contentwarning"
+	"github.com/sujalbistaa/whispr/internal/db"
+	"github.com/sujalbistaa/whispr/internal/eventlog"
+	"github.com/sujalbistaa/whispr/internal/flags"
+	"github.com/sujalbistaa/whispr/internal/metrics"
+	"github.com/sujalbistaa/whispr/internal/push"
+	"github.com/sujalbistaa/whispr/internal/reporting"
+	"github.com/sujalbistaa/whispr/internal/store"
+	"github.com/sujalbistaa/whispr/internal/supervisor"
+	"github.com/sujalbistaa/whispr/internal/webhook"
 	"github.com/sujalbistaa/whispr/internal/ws"
+	"github.com/sujalbistaa/whispr/public"
 )
 
+// registerMetrics exposes gauges that are read from live state on scrape.
+func registerMetrics(env *Env) {
+	if sqlDB, err := env.DB.DB(); err == nil {
+		if err := metrics.RegisterDB(sqlDB); err != nil {
+			slog.Error("registering DB metrics", "err", err)
+		}
+	}
+	if err := metrics.RegisterGauge(metrics.NameWSConnections, "Connected WebSocket clients.", func() float64 {
+		return float64(env.Hub.ClientCount())
+	}); err != nil {
+		slog.Error("registering WS metrics", "err", err)
+	}
+	if err := metrics.RegisterGauge(metrics.NameDBDown, "1 while the database is unreachable and the server is read-only.", func() float64 {
+		if env.DBHealth.Down() {
+			return 1
+		}
+		return 0
+	}); err != nil {
+		slog.Error("registering DB health metrics", "err", err)
+	}
+	if err := metrics.RegisterGauge(metrics.NameInFlightPosts, "POST requests currently being handled.", func() float64 {
+		return float64(env.Global.InFlight())
+	}); err != nil {
+		slog.Error("registering in-flight metrics", "err", err)
+	}
+}
+
+// apiV1Prefix is where version 1 of the REST API is mounted.
+const apiV1Prefix = "/api/v1"
+
+// apiLegacySunset is when the unversioned /api aliases go away.
+var apiLegacySunset = time.Date(2027, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+// apiRoutes holds the handlers shared by every API version, so a future
+// version can mount them selectively alongside its own.
+type apiRoutes struct {
+	env                             *Env
+	separateAdmin                   bool // The admin routes have a listener of their own
+	adminAuth, moderator, adminOnly gin.HandlerFunc
+	createPost, vote, stats, stream []gin.HandlerFunc
+	words, challenge                []gin.HandlerFunc
+	subscribe, unsubscribe          []gin.HandlerFunc
+	cspReport                       []gin.HandlerFunc
+}
+
+// registerV1 mounts the v1 REST surface on api, including the admin
+// routes unless they have a listener of their own. A read-only mirror
+// gets only the reads, and the CSP report collector, which writes
+// nothing.
+func (r apiRoutes) registerV1(api *gin.RouterGroup) {
+	r.registerV1Reads(api)
+	api.POST("/csp-report", r.cspReport...)
+	if !r.env.Config.Mirror.ReadOnly {
+		r.registerV1Writes(api)
+	}
+	if !r.separateAdmin {
+		r.registerV1Admin(api)
+	}
+}
+
+// registerV1Admin mounts the v1 admin routes under api.
+func (r apiRoutes) registerV1Admin(api *gin.RouterGroup) {
+	admin := api.Group("/admin", r.adminAuth)
+	r.registerV1AdminReads(admin)
+	if !r.env.Config.Mirror.ReadOnly {
+		r.registerV1AdminWrites(admin)
+	}
+}
+
+func (r apiRoutes) registerV1Reads(api *gin.RouterGroup) {
+	env := r.env
+
+	api.GET("/posts", env.GetPosts)
+	api.HEAD("/posts", env.HeadPosts)
+	api.GET("/posts/count", env.GetPostCount)
+	api.GET("/trending", env.GetTrendingPosts)
+	api.GET("/posts/stream", r.stream...)
+	api.GET("/poll", env.Poll)
+	api.GET("/posts/:id", env.GetPost)
+	api.GET("/posts/:id/history", env.GetPostHistory)
+	api.GET("/announcement", env.GetAnnouncement)
+	api.GET("/boards", env.GetBoards)
+	api.GET("/boards/:slug/posts", env.GetPosts)
+	api.HEAD("/boards/:slug/posts", env.HeadPosts)
+	api.GET("/boards/:slug/posts/count", env.GetPostCount)
+	api.GET("/boards/:slug/trending", env.GetTrendingPosts)
+	api.GET("/stats", r.stats...)
+	api.GET("/stats/words", r.words...)
+	api.GET("/challenge", r.challenge...)
+	api.GET("/graphql", env.GraphQL)
+	api.GET("/openapi.json", env.GetOpenAPISpec)
+	api.GET("/docs", env.GetAPIDocs)
+	api.GET("/bookmarks", env.GetBookmarks)
+	api.GET("/archive/posts", env.GetArchivedPosts)
+	api.GET("/me", env.GetMe)
+	api.GET("/push/key", env.GetPushKey)
+}
+
+func (r apiRoutes) registerV1AdminReads(admin *gin.RouterGroup) {
+	env := r.env
+
+	admin.GET("/stats", r.moderator, env.GetAdminStats)
+	admin.GET("/dashboard", r.moderator, env.GetDashboard)
+	admin.GET("/activity", r.moderator, env.GetActivity)
+	admin.GET("/audit", r.moderator, env.GetAuditLog)
+	admin.GET("/posts", r.moderator, env.GetAdminPosts)
+	admin.GET("/shadow-posts", r.moderator, env.GetShadowBannedPosts)
+	admin.GET("/queue", r.moderator, env.GetModerationQueue)
+	admin.GET("/bans", r.adminOnly, env.GetBans)
+	admin.GET("/export", r.adminOnly, env.ExportPosts)
+	admin.GET("/strikes", r.moderator, env.GetStrikes)
+	admin.GET("/api-keys", r.adminOnly, env.GetAPIKeys)
+	admin.GET("/maintenance", r.moderator, env.GetMaintenance)
+	admin.GET("/flags", r.moderator, env.GetFlags)
+	admin.GET("/webhooks/deliveries", r.adminOnly, env.GetWebhookDeliveries)
+	admin.GET("/runtime", r.adminOnly, env.GetRuntime)
+}
+
+func (r apiRoutes) registerV1Writes(api *gin.RouterGroup) {
+	env := r.env
+
+	api.POST("/graphql", env.GraphQL)
+	api.POST("/posts", r.createPost...)
+	api.POST("/boards/:slug/posts", r.createPost...)
+	api.POST("/posts/:id/vote", r.vote...)
+	api.PUT("/posts/:id/bookmark", env.BookmarkPost)
+	api.DELETE("/posts/:id/bookmark", env.UnbookmarkPost)
+	api.POST("/push/subscribe", r.subscribe...)
+	api.DELETE("/push/subscribe", r.unsubscribe...)
+	// The original path of DELETE /admin/posts/:id, kept for existing
+	// clients unless the admin routes have a listener of their own.
+	if !r.separateAdmin {
+		api.DELETE("/posts/:id", r.adminAuth, r.moderator, env.DeletePost)
+	}
+}
+
+func (r apiRoutes) registerV1AdminWrites(admin *gin.RouterGroup) {
+	env := r.env
+
+	admin.POST("/queue/:id/approve", r.moderator, env.ApproveHeldPost)
+	admin.POST("/queue/:id/reject", r.moderator, env.RejectHeldPost)
+	admin.POST("/posts/hide-by-keyword", r.moderator, env.HideByKeyword)
+	admin.PATCH("/posts/:id/content-warning", r.moderator, env.SetContentWarning)
+	admin.DELETE("/posts/:id", r.moderator, env.DeletePost)
+	admin.POST("/posts/:id/undo", r.moderator, env.UndoDeletePost)
+	admin.POST("/posts/:id/unarchive", r.adminOnly, env.UnarchivePost)
+	admin.POST("/bans", r.adminOnly, env.CreateBan)
+	admin.DELETE("/bans/:id", r.adminOnly, env.DeleteBan)
+	admin.POST("/announce", r.adminOnly, env.CreateAnnouncement)
+	admin.POST("/boards", r.adminOnly, env.CreateBoard)
+	admin.POST("/tokens/reload", r.adminOnly, env.ReloadTokens)
+	admin.DELETE("/strikes/:id", r.adminOnly, env.ClearStrikes)
+	admin.POST("/api-keys", r.adminOnly, env.CreateAPIKey)
+	admin.DELETE("/api-keys/:id", r.adminOnly, env.RevokeAPIKey)
+	admin.POST("/maintenance", r.adminOnly, env.SetMaintenance)
+	admin.PUT("/flags/:name", r.adminOnly, env.SetFlag)
+}
+
+// Deps are the shared services SetupRoutes hands to the handlers.
+type Deps struct {
+	DB          *gorm.DB
+	Hub         *ws.Hub
+	Broadcaster Broadcaster // HubBroadcaster{Hub} if nil
+	Tokens      *auth.TokenStore
+	Logger      *slog.Logger            // Base of every request logger; slog.Default() if nil
+	Reporter    reporting.ErrorReporter // Receives recovered panics; logged to Logger if nil
+	Supervisor  *supervisor.Supervisor  // Restarts background goroutines; one with supervisor.DefaultConfig if nil
+	AdminRouter *gin.Engine             // Gets the admin API, /metrics and /debug instead of router if set
+}
+
 // SetupRoutes configures all application routes and middleware.
-func SetupRoutes(router *gin.Engine, db *gorm.DB, hub *ws.Hub) {
+// The returned Env must be closed on shutdown to stop background workers.
+func SetupRoutes(router *gin.Engine, cfg config.Config, deps Deps) *Env {
+	database, hub, tokens := deps.DB, deps.Hub, deps.Tokens
+	reporter := deps.Reporter
+	if reporter == nil {
+		reporter = reporting.LogReporter{Logger: deps.Logger}
+	}
+	sup := deps.Supervisor
+	if sup == nil {
+		sup = supervisor.New(supervisor.DefaultConfig, reporter)
+	}
+	postStore := store.New(database, cfg.Strikes)
 
 	// --- Dependencies ---
-	env := &Env{DB: db, Hub: hub}
+	env := &Env{
+		DB:          database,
+		Hub:         hub,
+		Broadcaster: deps.Broadcaster,
+		Posts:       postStore,
+		Votes:       postStore,
+		Bookmarks:   postStore,
+		Bans:        bans.NewList(database),
+		Tokens:      tokens,
+		Config:      cfg,
+		Maintenance: NewMaintenance(MaintenanceMode(cfg.MaintenanceMode)),
+		Flags:       flags.New(cfg.Features),
+		Webhooks:    webhook.New(cfg.Webhooks, reporter),
+		Push:        push.New(cfg.Push, database, reporter),
+		Supervisor:  sup,
+		feeds:       newMemoryFeedCache(),
+	}
+	if cfg.Content.Enabled() {
+		env.Content = contentpolicy.New(cfg.Content.Kinds, cfg.Content.AllowDomains)
+	}
+	if cfg.Warnings.Auto {
+		env.Warnings = contentwarning.New(cfg.Warnings.Keywords)
+	}
+	if env.Broadcaster == nil {
+		env.Broadcaster = HubBroadcaster{Hub: hub}
+	}
+	events, err := eventlog.New(cfg.EventLog, sup)
+	if err != nil {
+		log.Fatalf("Opening event log: %v", err)
+	}
+	env.Events = events
+	if cfg.DBHealth.Enabled() {
+		env.DBHealth = db.NewHealth(database, cfg.DBHealth)
+	}
+	env.voterKey = []byte(cfg.VoterHashSecret)
+	if len(env.voterKey) == 0 {
+		env.voterKey = make([]byte, 32)
+		rand.Read(env.voterKey)
+		slog.Warn("VOTER_HASH_SECRET is not set; the one-vote-per-client limit resets on restart and isn't shared between replicas")
+	}
+	if cfg.Creator.Enabled {
+		env.creatorKey = []byte(cfg.Creator.Salt)
+	}
+	if cfg.Webhooks.Enabled() && cfg.Webhooks.Secret == "" {
+		slog.Warn("webhooks are configured without WEBHOOK_SECRET; deliveries won't be signed")
+	}
+	adminAuth := AdminAuthMiddleware(tokens)
+	moderator := RequireRole(auth.RoleModerator)
+	adminOnly := RequireRole(auth.RoleAdmin)
 
 	// --- Middleware ---
 
-	// Apply global middleware
-	router.Use(gin.Logger())
-	router.Use(gin.Recovery())
-	router.Use(SecurityHeadersMiddleware()) // Security headers
-	
+	// The admin listener, if any, runs the same middleware as the main one.
+	engines := []*gin.Engine{router}
+	adminRouter := router
+	if deps.AdminRouter != nil {
+		adminRouter = deps.AdminRouter
+		engines = append(engines, adminRouter)
+	}
+
+	// Only trust forwarding headers from configured proxies
+	for _, engine := range engines {
+		if err := ConfigureTrustedProxies(engine, cfg.TrustedProxies); err != nil {
+			log.Fatalf("Invalid trusted proxy configuration: %v", err)
+		}
+	}
+	if len(cfg.TrustedProxies) == 0 {
+		slog.Info("no trusted proxies configured; using direct connection addresses as client IPs")
+	}
+
+	// Global middleware
+	middleware := []gin.HandlerFunc{metrics.Middleware()}
+	if cfg.Tracing.Enabled() {
+		// Probes would swamp the traces without telling us anything
+		middleware = append(middleware, otelgin.Middleware(cfg.Tracing.ServiceName, otelgin.WithFilter(func(r *http.Request) bool {
+			return r.URL.Path != "/healthz" && r.URL.Path != "/readyz"
+		})))
+	}
+	middleware = append(middleware, RequestIDMiddleware(deps.Logger))
+	if cfg.Tracing.Enabled() {
+		middleware = append(middleware, TraceMiddleware())
+	}
+	middleware = append(middleware,
+		RequestLoggerMiddleware(cfg.SlowRequest),
+		RecoveryMiddleware(reporter),
+		FlagsMiddleware(env.Flags),
+		SecurityHeadersMiddleware(cfg.Headers, cfg.TLS.Enabled()),
+		CompressionMiddleware(cfg.CompressionMinSize),
+	)
+
 	// CORS Middleware
-	corsOrigin := os.Getenv("CORS_ORIGIN")
-	if corsOrigin == "" {
-		corsOrigin = "*" // Default to allow all for local dev
-	}
-	router.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{corsOrigin},
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Admin-Token"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-	}))
+	if cfg.CORS.AnyOrigin {
+		slog.Warn("CORS allows any origin with credentials; set CORS_ORIGIN to explicit origins in production")
+	}
+	middleware = append(middleware, CORSMiddleware(cfg.CORS))
+
+	// After CORS so browsers can read the 503
+	if mode := env.Maintenance.State().Mode; mode != MaintenanceOff {
+		slog.Warn("starting in maintenance mode", "mode", mode)
+	}
+	middleware = append(middleware,
+		MaintenanceMiddleware(env.Maintenance),
+		DegradedMiddleware(env.DBHealth),
+		RequestTimeoutMiddleware(cfg.RequestTimeout),
+	)
+	for _, engine := range engines {
+		engine.Use(middleware...)
+	}
 
 	// --- Rate Limiter Setup ---
-	limiter := NewIPRateLimiter(rate.Limit(rateLimitRPS), rateLimitBurst)
-	go func() {
-		for {
-			time.Sleep(10 * time.Minute)
-			limiter.mu.Lock()
-			for ip, v := range limiter.visitors {
-				if !v.Allow() {
-					// If the limiter is still full, keep it.
-				} else {
-					// If allowed, it means it's old, so remove it.
-					delete(limiter.visitors, ip)
-				}
-			}
-			limiter.mu.Unlock()
+	rateLimits := cfg.RateLimit
+
+	if rateLimits.RedisURL != "" {
+		opts, err := redis.ParseURL(rateLimits.RedisURL)
+		if err != nil {
+			log.Fatalf("Invalid REDIS_URL: %v", err)
+		}
+		env.redis = redis.NewClient(opts)
+	}
+
+	// The primary publishes its broadcasts for mirrors to relay.
+	if channel := cfg.Mirror.Channel; channel != "" {
+		if cfg.Mirror.ReadOnly {
+			ctx, cancel := context.WithCancel(context.Background())
+			env.stopRelay = cancel
+			go sup.Run(ctx, "broadcast-relay", func(ctx context.Context) {
+				relayBroadcasts(ctx, env.redis, channel, hub)
+			})
+		} else {
+			env.Broadcaster = RedisBroadcaster{Local: env.Broadcaster, Client: env.redis, Channel: channel}
+		}
+	}
+
+	newLimiter := func(name string, limit config.RouteLimit) RateLimiter {
+		if env.redis != nil {
+			limiter := NewRedisRateLimiter(env.redis, "whispr:ratelimit:"+name+":", limit)
+			env.redisLimiters = append(env.redisLimiters, limiter)
+			return limiter
 		}
-	}()
+		limiter := NewIPRateLimiter(rate.Limit(limit.RPS), limit.Burst)
+		limiter.StartCleanup(sup, "ratelimit."+name, rateLimits.IdleTTL, rateLimits.IdleTTL)
+		env.limiters = append(env.limiters, limiter)
+		return limiter
+	}
 
+	postLimit, _ := rateLimits.For(config.RouteCreatePost)
+	postLimiter := newLimiter("posts", postLimit)
+
+	bypass := RateLimitBypassMiddleware(rateLimits.Allowlist, tokens)
+	voteHandlers := []gin.HandlerFunc{BanMiddleware(env.Bans), bypass}
+	if voteLimit, ok := rateLimits.For(config.RouteVote); ok {
+		voteHandlers = append(voteHandlers, RateLimitMiddleware(newLimiter("votes", voteLimit), rateLimits.FailOpen))
+	}
+	voteHandlers = append(voteHandlers, env.VoteOnPost)
+
+	// Both stats endpoints share a bucket.
+	statsLimit, _ := rateLimits.For(config.RouteStats)
+	statsLimiter := RateLimitMiddleware(newLimiter("stats", statsLimit), rateLimits.FailOpen)
+	statsHandlers := []gin.HandlerFunc{bypass, statsLimiter, env.GetPublicStats}
+	wordsHandlers := []gin.HandlerFunc{bypass, statsLimiter, env.GetWordStats}
+	streamLimit, _ := rateLimits.For(config.RouteStream)
+	streamHandlers := []gin.HandlerFunc{bypass, RateLimitMiddleware(newLimiter("stream", streamLimit), rateLimits.FailOpen), env.StreamPosts}
+
+	// Strike cooldowns replace the per-IP limit for the clients under one.
+	// The challenge is checked after the rate limit, so a flood is turned
+	// away before it costs a challenge lookup or a call to the CAPTCHA
+	// provider.
+	postHandlers := []gin.HandlerFunc{BanMiddleware(env.Bans), bypass, env.StrikeCooldown, RateLimitMiddleware(postLimiter, rateLimits.FailOpen)}
+	if cfg.Challenge.Enabled() {
+		var store challenge.Store = challenge.NewMemoryStore(cfg.Challenge.MaxPending)
+		if env.redis != nil {
+			store = challenge.NewRedisStore(env.redis, "whispr:challenge:")
+		}
+		env.Challenge = challenge.New(cfg.Challenge, store)
+		postHandlers = append(postHandlers, ChallengeMiddleware(env.Challenge))
+	}
+	postHandlers = append(postHandlers, env.CreatePost)
+	challengeLimit, _ := rateLimits.For(config.RouteChallenge)
+	challengeHandlers := []gin.HandlerFunc{bypass, RateLimitMiddleware(newLimiter("challenge", challengeLimit), rateLimits.FailOpen), env.GetChallenge}
+	// Subscribing and unsubscribing share a bucket.
+	pushLimit, _ := rateLimits.For(config.RoutePush)
+	pushLimiter := RateLimitMiddleware(newLimiter("push", pushLimit), rateLimits.FailOpen)
+	subscribeHandlers := []gin.HandlerFunc{BanMiddleware(env.Bans), bypass, pushLimiter, env.SubscribePush}
+	unsubscribeHandlers := []gin.HandlerFunc{bypass, pushLimiter, env.UnsubscribePush}
+	cspLimit, _ := rateLimits.For(config.RouteCSPReport)
+	cspReportHandlers := []gin.HandlerFunc{bypass, RateLimitMiddleware(newLimiter("csp", cspLimit), rateLimits.FailOpen), env.ReportCSP}
+
+	// API keys are limited per key on every route, in place of the per-IP
+	// limits above.
+	apiKeys := APIKeyMiddleware(database, newLimiter("apikeys", rateLimits.APIKey), rateLimits.FailOpen)
+
+	env.Global = NewGlobalLimiter(rateLimits.GlobalMaxInFlight, rateLimits.GlobalRPS, rateLimits.GlobalBurst)
+
+	// --- Metrics ---
+	registerMetrics(env)
+
+	// --- Health ---
+
+	router.GET("/healthz", env.Health)
+	router.GET("/readyz", env.Ready)
+
+	// Served here only when METRICS_ADDR doesn't give metrics their own
+	// listener; on ADMIN_ADDR's if set.
+	if cfg.MetricsAddr == "" {
+		adminRouter.GET("/metrics", adminAuth, moderator, gin.WrapH(metrics.Handler()))
+	}
+
+	// --- Profiling ---
+
+	registerPprof(adminRouter.Group("/debug/pprof", adminAuth, adminOnly))
 
 	// --- API Routes ---
 
-	api := router.Group("/api")
-	{
-		api.GET("/posts", env.GetPosts)
-		api.GET("/trending", env.GetTrendingPosts)
-		api.POST("/posts", RateLimitMiddleware(limiter), env.CreatePost)
-		api.POST("/posts/:id/vote", env.VoteOnPost)
-		api.DELETE("/posts/:id", AdminAuthMiddleware(), env.DeletePost)
+	// Handlers are registered once per version. /api is the deprecated,
+	// unversioned alias of v1 and will be removed after apiLegacySunset.
+	routes := apiRoutes{
+		env:           env,
+		separateAdmin: adminRouter != router,
+		adminAuth:     adminAuth,
+		moderator:     moderator,
+		adminOnly:     adminOnly,
+		createPost:    postHandlers,
+		vote:          voteHandlers,
+		stats:         statsHandlers,
+		words:         wordsHandlers,
+		stream:        streamHandlers,
+		challenge:     challengeHandlers,
+		subscribe:     subscribeHandlers,
+		unsubscribe:   unsubscribeHandlers,
+		cspReport:     cspReportHandlers,
+	}
+	bodyLimit := BodyLimitMiddleware(cfg.MaxBodyBytes)
+	routes.registerV1(router.Group(apiV1Prefix, bodyLimit, apiKeys, env.Global.Middleware()))
+	routes.registerV1(router.Group("/api", DeprecationMiddleware(apiLegacySunset), bodyLimit, apiKeys, env.Global.Middleware()))
+	if routes.separateAdmin {
+		slog.Info("serving the admin API on its own listener", "addr", cfg.AdminAddr)
+		routes.registerV1Admin(adminRouter.Group(apiV1Prefix, bodyLimit, apiKeys, env.Global.Middleware()))
+		routes.registerV1Admin(adminRouter.Group("/api", DeprecationMiddleware(apiLegacySunset), bodyLimit, apiKeys, env.Global.Middleware()))
+		notFound := []gin.HandlerFunc{func(c *gin.Context) {
+			respondError(c, ErrNotFound("request.not_found"))
+		}}
+		if cfg.Mirror.ReadOnly {
+			notFound = append([]gin.HandlerFunc{ReadOnlyMiddleware(cfg.Mirror.PrimaryURL)}, notFound...)
+		}
+		adminRouter.NoRoute(notFound...)
 	}
 
 	// --- WebSocket Route ---
 
-	router.GET("/ws", func(c *gin.Context) {
-		ws.ServeWs(hub, c.Writer, c.Request)
-	})
+	router.GET("/ws", env.ServeWS)
+
+	// --- Feeds ---
+
+	router.GET("/feed.rss", env.GetRSSFeed)
+	router.GET("/feed.atom", env.GetAtomFeed)
 
 	// --- Serve Frontend ---
-	// This MUST come AFTER your API routes.
-	// We serve a single file at the root. This does not conflict with /api.
-	router.StaticFile("/", "./public/index.html") // <-- THIS IS THE FIX
-}
\ No newline at end of file
+	// Unmatched paths fall through to the frontend, which serves index.html
+	// for client-side routes.
+	frontend := FrontendHandler(frontendFS(public.Files, cfg.FrontendDir))
+	router.GET("/", frontend)
+	if cfg.Mirror.ReadOnly {
+		// Writes aren't registered, so they all land here.
+		slog.Info("serving as a read-only mirror", "primary", cfg.Mirror.PrimaryURL)
+		router.NoRoute(ReadOnlyMiddleware(cfg.Mirror.PrimaryURL), frontend)
+	} else {
+		router.NoRoute(frontend)
+	}
+
+	return env
+}
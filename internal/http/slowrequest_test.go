@@ -0,0 +1,99 @@
+package http_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	routes "github.com/sujalbistaa/whispr/internal/http"
+	"github.com/sujalbistaa/whispr/internal/server"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// logLines collects JSON log lines written through its logger.
+type logLines struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (l *logLines) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.Write(p)
+}
+
+func (l *logLines) logger() *slog.Logger {
+	return slog.New(slog.NewJSONHandler(l, nil))
+}
+
+// withMsg returns the lines logged with msg.
+func (l *logLines) withMsg(t *testing.T, msg string) []map[string]any {
+	t.Helper()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var lines []map[string]any
+	for _, raw := range bytes.Split(bytes.TrimSpace(l.buf.Bytes()), []byte("\n")) {
+		var line map[string]any
+		if err := json.Unmarshal(raw, &line); err != nil {
+			t.Fatalf("log line %s: %v", raw, err)
+		}
+		if line["msg"] == msg {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func TestSlowRequestLog(t *testing.T) {
+	logs := &logLines{}
+	engine := gin.New()
+	engine.Use(routes.RequestIDMiddleware(logs.logger()), routes.RequestLoggerMiddleware(50*time.Millisecond))
+	engine.GET("/slow/:id", func(c *gin.Context) {
+		time.Sleep(100 * time.Millisecond)
+		c.Status(http.StatusAccepted)
+	})
+	engine.GET("/fast", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	for _, path := range []string{"/fast", "/slow/42"} {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	slow := logs.withMsg(t, "slow request")
+	if len(slow) != 1 {
+		t.Fatalf("%d slow request lines, want 1 for /slow/42: %v", len(slow), slow)
+	}
+	line := slow[0]
+	if line["level"] != "WARN" || line["route"] != "/slow/:id" || line["status"] != float64(http.StatusAccepted) || line["threshold_ms"] != float64(50) || line["request_id"] == nil {
+		t.Errorf("slow request line %v", line)
+	}
+	if latency, _ := line["latency_ms"].(float64); latency < 100 {
+		t.Errorf("latency_ms = %v, want at least 100", line["latency_ms"])
+	}
+	if requests := logs.withMsg(t, "request"); len(requests) != 2 {
+		t.Errorf("%d request lines, want one per request", len(requests))
+	}
+}
+
+// TestSlowRequestLogSkipsStreams sets the threshold so low every request
+// is slow, and checks the NDJSON stream is still left out.
+func TestSlowRequestLogSkipsStreams(t *testing.T) {
+	t.Setenv("SLOW_REQUEST_THRESHOLD", "1ns")
+	logs := &logLines{}
+	ts := testutil.NewTestServer(t, server.WithLogger(logs.logger()))
+	ts.Do(t, ts.NewRequest(t, http.MethodGet, "/api/v1/posts/12345", nil))
+	ts.Do(t, ts.NewRequest(t, http.MethodGet, "/api/v1/posts/stream", nil))
+
+	var routesLogged []any
+	for _, line := range logs.withMsg(t, "slow request") {
+		routesLogged = append(routesLogged, line["route"])
+	}
+	if len(routesLogged) != 1 || routesLogged[0] != "/api/v1/posts/:id" {
+		t.Errorf("slow requests logged for %v, want only /api/v1/posts/:id", routesLogged)
+	}
+}
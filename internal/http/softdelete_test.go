@@ -0,0 +1,48 @@
+package http_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// TestSoftDelete hides a post and checks it is gone from every public
+// read while its row, votes included, stays for moderators.
+func TestSoftDelete(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	post := ts.CreatePost(t, "a post about to be hidden")
+	kept := ts.CreatePost(t, "a post that stays up")
+	ts.Vote(t, post.ID, 1)
+
+	if ts.Hide(t, post.ID) {
+		t.Fatal("a visible post was reported as already hidden")
+	}
+
+	feed := feedIDs(t, ts, ts.ClientIP())
+	if feed[post.ID] || !feed[kept.ID] {
+		t.Errorf("feed after hiding = %v, want only %d", feed, kept.ID)
+	}
+	path := fmt.Sprintf("/api/v1/posts/%d", post.ID)
+	if status, _ := ts.Do(t, ts.NewRequest(t, http.MethodGet, path, nil)); status != http.StatusNotFound {
+		t.Errorf("GET %s: status %d, want 404", path, status)
+	}
+	if status := ts.VoteFrom(t, ts.ClientIP(), post.ID, 1); status != http.StatusNotFound {
+		t.Errorf("voting on a hidden post: status %d, want 404", status)
+	}
+
+	var stored models.Post
+	if err := ts.DB.Unscoped().First(&stored, post.ID).Error; err != nil {
+		t.Fatalf("hidden post was deleted: %v", err)
+	}
+	if !stored.HiddenAt.Valid || stored.HiddenBy == "" {
+		t.Errorf("hidden post has hiddenAt %v and hiddenBy %q", stored.HiddenAt, stored.HiddenBy)
+	}
+	var votes int64
+	ts.DB.Model(&models.Vote{}).Where("post_id = ?", post.ID).Count(&votes)
+	if votes != 2 { // The author's and the one cast above
+		t.Errorf("%d votes kept on the hidden post, want 2", votes)
+	}
+}
@@ -0,0 +1,45 @@
+package http
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/simhash"
+)
+
+// fingerprintPost sets post's fingerprint and reports whether it nearly
+// duplicates a post from the last Spam.Window, from any client, along with
+// what to record about the match. Clients exempt from the posting quota
+// are never held. Lookup errors are logged and let the post through.
+func (e *Env) fingerprintPost(c *gin.Context, post *models.Post) (map[string]any, bool) {
+	fp, ok := simhash.Sum(post.Content)
+	if !ok {
+		return nil, false
+	}
+	signed := int64(fp)
+	post.Fingerprint = &signed
+	bands := simhash.Split(fp)
+	for i, field := range []**int32{&post.Band0, &post.Band1, &post.Band2, &post.Band3, &post.Band4, &post.Band5} {
+		band := int32(bands[i])
+		*field = &band
+	}
+
+	_, isAdmin := c.Get(adminRoleKey)
+	if !e.Config.Spam.Enabled() || c.GetBool(rateLimitBypassKey) || isAdmin {
+		return nil, false
+	}
+	since := time.Now().Add(-e.Config.Spam.Window)
+	id, distance, err := e.Posts.FindSimilar(c.Request.Context(), fp, since, e.Config.Spam.MaxDistance)
+	if errors.Is(err, models.ErrPostNotFound) {
+		return nil, false
+	}
+	if err != nil {
+		requestLogger(c).Error("looking up similar posts", "err", err)
+		return nil, false
+	}
+	requestLogger(c).Info("holding near-duplicate post", "similarTo", id, "distance", distance)
+	return map[string]any{"similarTo": id, "distance": distance}, true
+}
@@ -0,0 +1,200 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/words"
+)
+
+// publicStatsTTL is how long GET /stats reuses its counts, so the public
+// endpoint costs the database at most three aggregates per board per
+// interval.
+const publicStatsTTL = 30 * time.Second
+
+const (
+	// wordStatsTTL is how long GET /stats/words reuses a count: tokenizing
+	// thousands of posts is too slow to do per request.
+	wordStatsTTL = 5 * time.Minute
+	// wordStatsMaxPosts bounds how many of the newest posts in the window
+	// are read, and so how long a refresh takes.
+	wordStatsMaxPosts = 2000
+	wordStatsTop      = 50
+	defaultWordWindow = 24 * time.Hour
+	maxWordWindow     = 7 * 24 * time.Hour
+)
+
+// PublicStats is the response to GET /stats. Counts cover what everyone can
+// see: hidden and shadow-banned posts, and votes on them, are left out.
+// With ?board= they cover that board only.
+type PublicStats struct {
+	Board      string    `json:"board,omitempty"` // Slug of the board counted; empty for every board
+	Posts      int64     `json:"posts"`
+	PostsToday int64     `json:"postsToday"` // Created in the last 24 hours
+	Votes      int64     `json:"votes"`
+	Online     int       `json:"online"` // Connected WebSocket clients, on the board if there is one, counted live
+	AsOf       time.Time `json:"asOf"`   // When the other counts were taken
+}
+
+// WordStats is the response to GET /stats/words: the most used terms in
+// visible posts from the last Window.
+type WordStats struct {
+	Window    string       `json:"window"`
+	Posts     int          `json:"posts"`     // Posts read, at most wordStatsMaxPosts
+	Truncated bool         `json:"truncated"` // The window held more posts than were read; the oldest were skipped
+	Terms     []words.Term `json:"terms"`
+	AsOf      time.Time    `json:"asOf"`
+}
+
+// wordStatsCache holds the last count by window. Windows are whole hours
+// up to maxWordWindow, so there are at most 168 of them. The lock only
+// guards the map; counts are taken outside it, one per window at a time.
+type wordStatsCache struct {
+	mu     sync.Mutex
+	stats  map[time.Duration]WordStats
+	counts singleflight.Group
+}
+
+// get returns the cached count for window if it is fresh.
+func (w *wordStatsCache) get(window time.Duration) (WordStats, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	stats, ok := w.stats[window]
+	return stats, ok && time.Since(stats.AsOf) <= wordStatsTTL
+}
+
+func (w *wordStatsCache) put(window time.Duration, stats WordStats) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stats == nil {
+		w.stats = make(map[time.Duration]WordStats)
+	}
+	w.stats[window] = stats
+}
+
+// publicStatsCache holds the last counts by board ID, 0 for every board.
+// The lock is held while they are refreshed, so concurrent requests wait
+// for one refresh instead of each running their own.
+type publicStatsCache struct {
+	mu    sync.Mutex
+	stats map[uint]PublicStats
+}
+
+// GetPublicStats returns site-wide or board-wide counts for the landing
+// page.
+func (e *Env) GetPublicStats(c *gin.Context) {
+	board, ok := e.optionalBoard(c)
+	if !ok {
+		return
+	}
+	e.publicStats.mu.Lock()
+	if e.publicStats.stats == nil {
+		e.publicStats.stats = make(map[uint]PublicStats)
+	}
+	stats := e.publicStats.stats[board.ID]
+	if time.Since(stats.AsOf) > publicStatsTTL {
+		fresh, err := e.countPublicStats(c.Request.Context(), board)
+		if err != nil {
+			e.publicStats.mu.Unlock()
+			requestLogger(c).Error("counting public stats", "err", err)
+			respondError(c, ErrInternal("stats.fetch_failed"))
+			return
+		}
+		e.publicStats.stats[board.ID], stats = fresh, fresh
+	}
+	e.publicStats.mu.Unlock()
+
+	stats.Online = e.Hub.ClientCount()
+	if board.ID != 0 {
+		stats.Online = e.Hub.RoomCount(board.Slug)
+	}
+	c.Header("Cache-Control", "public, max-age=30")
+	c.JSON(http.StatusOK, stats)
+}
+
+func (e *Env) countPublicStats(ctx context.Context, board models.Board) (PublicStats, error) {
+	stats := PublicStats{Board: board.Slug, AsOf: time.Now()}
+	visible := e.DB.WithContext(ctx).Model(&models.Post{}).Where("shadow_banned = ?", false)
+	votes := e.DB.WithContext(ctx).Model(&models.Vote{}).
+		Joins("JOIN posts ON posts.id = votes.post_id AND posts.hidden_at IS NULL AND posts.shadow_banned = ?", false)
+	if board.ID != 0 {
+		visible = visible.Where("board_id = ?", board.ID)
+		votes = votes.Where("posts.board_id = ?", board.ID)
+	}
+	if err := visible.Session(&gorm.Session{}).Count(&stats.Posts).Error; err != nil {
+		return stats, err
+	}
+	if err := visible.Session(&gorm.Session{}).Where("created_at >= ?", stats.AsOf.Add(-24*time.Hour)).Count(&stats.PostsToday).Error; err != nil {
+		return stats, err
+	}
+	err := votes.Count(&stats.Votes).Error
+	return stats, err
+}
+
+// GetWordStats returns the terms most used in visible posts over the
+// ?window= duration, 24h by default, for a word cloud. Windows are
+// rounded down to whole hours.
+func (e *Env) GetWordStats(c *gin.Context) {
+	window := defaultWordWindow
+	if raw := c.Query("window"); raw != "" {
+		d, err := time.ParseDuration(raw)
+		if err != nil || d < time.Hour || d > maxWordWindow {
+			respondError(c, ErrBadRequest("query.invalid_window", "max", strconv.Itoa(int(maxWordWindow.Hours()))+"h"))
+			return
+		}
+		window = d.Truncate(time.Hour)
+	}
+
+	stats, ok := e.wordStats.get(window)
+	if !ok {
+		// The count is shared with concurrent requests for the window, so
+		// it isn't canceled with this one.
+		ctx := context.WithoutCancel(c.Request.Context())
+		fresh, err, _ := e.wordStats.counts.Do(window.String(), func() (any, error) {
+			stats, err := e.countWords(ctx, window)
+			if err == nil {
+				e.wordStats.put(window, stats)
+			}
+			return stats, err
+		})
+		if err != nil {
+			requestLogger(c).Error("counting words", "err", err)
+			respondError(c, ErrInternal("stats.fetch_failed"))
+			return
+		}
+		stats = fresh.(WordStats)
+	}
+
+	c.Header("Cache-Control", "public, max-age="+strconv.Itoa(int(wordStatsTTL.Seconds())))
+	c.JSON(http.StatusOK, stats)
+}
+
+func (e *Env) countWords(ctx context.Context, window time.Duration) (WordStats, error) {
+	stats := WordStats{Window: strconv.Itoa(int(window.Hours())) + "h", AsOf: time.Now()}
+	var contents []string
+	// One more than the cap tells whether the window held more.
+	err := e.DB.WithContext(ctx).Model(&models.Post{}).
+		Where("shadow_banned = ? AND created_at >= ?", false, stats.AsOf.Add(-window)).
+		Order("created_at desc").Limit(wordStatsMaxPosts+1).Pluck("content", &contents).Error
+	if err != nil {
+		return stats, err
+	}
+	if len(contents) > wordStatsMaxPosts {
+		contents, stats.Truncated = contents[:wordStatsMaxPosts], true
+	}
+	var counter words.Counter
+	for _, content := range contents {
+		counter.Add(content)
+	}
+	stats.Posts = len(contents)
+	stats.Terms = counter.Top(wordStatsTop)
+	return stats, nil
+}
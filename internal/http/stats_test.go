@@ -0,0 +1,90 @@
+package http_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	routes "github.com/sujalbistaa/whispr/internal/http"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+	"github.com/sujalbistaa/whispr/internal/words"
+)
+
+func getWordStats(t *testing.T, ts *testutil.TestServer, window string) routes.WordStats {
+	t.Helper()
+	status, body := ts.Do(t, ts.NewRequest(t, http.MethodGet, "/api/v1/stats/words?window="+window, nil))
+	var stats routes.WordStats
+	if status != http.StatusOK || json.Unmarshal(body, &stats) != nil {
+		t.Errorf("GET /stats/words?window=%s: %d %s", window, status, body)
+	}
+	return stats
+}
+
+func TestWordStats(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	ts.CreatePost(t, "canteen momo is back, canteen queue is long")
+	ts.CreatePost(t, "क्यान्टिनमा मम फेरि आयो")
+	stats := getWordStats(t, ts, "24h")
+	if stats.Posts != 2 || len(stats.Terms) == 0 || stats.Terms[0] != (words.Term{Term: "canteen", Count: 2}) {
+		t.Errorf("word stats %+v, want canteen counted twice in 2 posts", stats)
+	}
+}
+
+// TestWordStatsCountOutsideLock holds up one window's count in the
+// database and checks other windows are still served from the cache
+// meanwhile, and that requests for the slow window share its count.
+func TestWordStatsCountOutsideLock(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	ts.CreatePost(t, "a post worth counting")
+	getWordStats(t, ts, "1h")
+
+	var counts atomic.Int32
+	release := make(chan struct{})
+	err := ts.DB.Callback().Query().Before("gorm:query").Register("test:hold", func(tx *gorm.DB) {
+		if tx.Statement.Table == "posts" {
+			counts.Add(1)
+			<-release
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ts.DB.Callback().Query().Remove("test:hold") })
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			getWordStats(t, ts, "24h")
+		}()
+	}
+	for deadline := time.Now().Add(2 * time.Second); counts.Load() == 0; time.Sleep(5 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			close(release)
+			t.Fatal("the 24h count never reached the database")
+		}
+	}
+
+	served := make(chan routes.WordStats)
+	go func() { served <- getWordStats(t, ts, "1h") }()
+	select {
+	case stats := <-served:
+		if stats.Posts != 1 {
+			t.Errorf("cached 1h stats %+v, want 1 post", stats)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("a cached window waited for another window's count")
+	}
+
+	close(release)
+	wg.Wait()
+	if n := counts.Load(); n != 1 {
+		t.Errorf("five concurrent requests ran %d counts, want 1", n)
+	}
+}
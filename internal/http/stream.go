@@ -0,0 +1,62 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+// streamBatchSize is how many posts are read, written and flushed at a
+// time by StreamPosts.
+const streamBatchSize = 500
+
+// StreamPosts writes every visible post, oldest first, as newline-delimited
+// JSON. since (RFC3339) skips posts created before it. Posts are read in
+// batches keyed on id, so memory use stays flat however many there are,
+// and no connection is held between batches. The stream stops when the
+// client goes away.
+func (e *Env) StreamPosts(c *gin.Context) {
+	// A full stream can take longer than the server's write timeout.
+	clearDeadlines(c)
+
+	ctx := c.Request.Context()
+	query := e.DB.WithContext(ctx).Scopes(e.visiblePosts(c))
+	if raw := c.Query("since"); raw != "" {
+		since, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			respondError(c, ErrBadRequest("query.invalid_timestamp", "param", "since"))
+			return
+		}
+		query = query.Where("created_at >= ?", since)
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	encoder := json.NewEncoder(c.Writer)
+
+	count := 0
+	var posts []models.Post
+	err := query.FindInBatches(&posts, streamBatchSize, func(tx *gorm.DB, batch int) error {
+		for _, post := range posts {
+			if err := encoder.Encode(post); err != nil {
+				return err
+			}
+		}
+		count += len(posts)
+		c.Writer.Flush()
+		return ctx.Err()
+	}).Error
+	if ctx.Err() != nil {
+		requestLogger(c).Info("post stream canceled", "posts", count, "err", ctx.Err())
+		return
+	}
+	if err != nil {
+		// The status is already sent; the client sees a truncated stream.
+		requestLogger(c).Error("streaming posts", "posts", count, "err", err)
+	}
+}
@@ -0,0 +1,120 @@
+package http_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// seedStreamPosts stores n posts of about 1 KB each, created a minute
+// apart up to now, with every tenth one hidden, in one statement.
+func seedStreamPosts(t *testing.T, ts *testutil.TestServer, n int) {
+	t.Helper()
+	if ts.DB.Dialector.Name() != "sqlite" {
+		t.Skip("seedStreamPosts needs SQLite")
+	}
+	err := ts.DB.Exec(`INSERT INTO posts (content, created_at, updated_at, last_activity_at, hidden_at)
+		WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < ?)
+		SELECT 'post ' || i || ' ' || ?, datetime('now', (i - ?) || ' minutes'), datetime('now'), datetime('now'),
+			CASE WHEN i % 10 = 0 THEN datetime('now') END
+		FROM n`, n, strings.Repeat("x", 1000), n).Error
+	if err != nil {
+		t.Fatalf("seeding posts: %v", err)
+	}
+}
+
+// streamPosts reads GET path from ip as NDJSON, checking the posts come
+// oldest first, and returns how many there were.
+func streamPosts(t *testing.T, ts *testutil.TestServer, ip, path string) int {
+	t.Helper()
+	resp, err := ts.Client().Do(ts.NewRequestFrom(t, ip, http.MethodGet, path, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/x-ndjson" {
+		t.Fatalf("GET %s: status %d, Content-Type %q", path, resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(nil, 64<<10)
+	count := 0
+	var last uint
+	for scanner.Scan() {
+		var post models.Post
+		if err := json.Unmarshal(scanner.Bytes(), &post); err != nil {
+			t.Fatalf("line %d: %v", count+1, err)
+		}
+		if post.ID <= last {
+			t.Fatalf("line %d: post %d after %d", count+1, post.ID, last)
+		}
+		last = post.ID
+		count++
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+// TestStreamPosts streams a 10k-post database of about 10 MB and checks
+// the server's heap never grows by more than a fraction of that.
+func TestStreamPosts(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	const seeded = 10_000
+	seedStreamPosts(t, ts, seeded)
+	ip := ts.ClientIP()
+
+	// Collect garbage early, so the heap is close to what's live.
+	defer debug.SetGCPercent(debug.SetGCPercent(10))
+	runtime.GC()
+	var base runtime.MemStats
+	runtime.ReadMemStats(&base)
+	var peak atomic.Uint64
+	done := make(chan struct{})
+	sampled := make(chan struct{})
+	go func() {
+		defer close(sampled)
+		var m runtime.MemStats
+		for {
+			runtime.ReadMemStats(&m)
+			if m.HeapAlloc > peak.Load() {
+				peak.Store(m.HeapAlloc)
+			}
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+	}()
+	count := streamPosts(t, ts, ip, "/api/v1/posts/stream")
+	close(done)
+	<-sampled
+
+	if want := seeded - seeded/10; count != want {
+		t.Errorf("streamed %d posts, want the %d visible ones", count, want)
+	}
+	// Holding every post at once would take well over 10 MB.
+	if growth := int64(peak.Load()) - int64(base.HeapAlloc); growth > 4<<20 {
+		t.Errorf("heap grew by %.1f MB while streaming", float64(growth)/(1<<20))
+	}
+
+	since := time.Now().Add(-100 * time.Minute).UTC().Format(time.RFC3339)
+	if count := streamPosts(t, ts, ip, "/api/v1/posts/stream?since="+since); count < 85 || count > 95 {
+		t.Errorf("since=%s streamed %d posts, want the 90 or so visible ones in the last 100 minutes", since, count)
+	}
+
+	// Two a minute at most.
+	if status, _ := ts.Do(t, ts.NewRequestFrom(t, ip, http.MethodGet, "/api/v1/posts/stream", nil)); status != http.StatusTooManyRequests {
+		t.Errorf("third stream in a minute: status %d, want 429", status)
+	}
+}
@@ -0,0 +1,155 @@
+package http
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/sujalbistaa/whispr/internal/audit"
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+// StruckClient is an anonymous client's strikes and cooldown as shown to
+// moderators. ID is the identity's, also the targetId of its
+// identity.* audit entries.
+type StruckClient struct {
+	ID            uint       `json:"id"`
+	Strikes       int        `json:"strikes"`
+	StrikesSince  *time.Time `json:"strikesSince"`
+	Cooldown      int        `json:"cooldown"` // Seconds between posts while CooldownUntil is ahead
+	CooldownUntil *time.Time `json:"cooldownUntil"`
+}
+
+func struckClient(identity models.Identity) StruckClient {
+	return StruckClient{
+		ID:            identity.ID,
+		Strikes:       identity.Strikes,
+		StrikesSince:  identity.StrikesSince,
+		Cooldown:      identity.CooldownSeconds,
+		CooldownUntil: identity.CooldownUntil,
+	}
+}
+
+// StrikeCooldown turns away posts from clients in a strike cooldown that
+// posted less than the cooldown ago, with 429 and Retry-After. It runs
+// before the per-IP limiter, so a refused post doesn't spend a token.
+// Clients that skip rate limiting skip it too, and like the daily quota
+// it lets posts through when the database can't be asked.
+func (e *Env) StrikeCooldown(c *gin.Context) {
+	_, isAdmin := c.Get(adminRoleKey)
+	if !e.Config.Strikes.Enabled() || c.GetBool(rateLimitBypassKey) || isAdmin {
+		c.Next()
+		return
+	}
+	ctx := c.Request.Context()
+	now := time.Now()
+	author := e.voterHash(c)
+
+	var identity models.Identity
+	err := e.DB.WithContext(ctx).Where("client_hash = ? AND cooldown_until > ?", author, now).Take(&identity).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.Next()
+		return
+	}
+	if err != nil {
+		requestLogger(c).Error("checking strike cooldown", "err", err)
+		c.Next()
+		return
+	}
+	cooldown := time.Duration(identity.CooldownSeconds) * time.Second
+	recent, err := e.Posts.RecentByAuthor(ctx, author, now.Add(-cooldown), 1)
+	if err != nil {
+		requestLogger(c).Error("checking strike cooldown", "err", err)
+		c.Next()
+		return
+	}
+	if len(recent) == 0 {
+		c.Next()
+		return
+	}
+	next := recent[0].Add(cooldown)
+	if next.After(*identity.CooldownUntil) {
+		next = *identity.CooldownUntil
+	}
+	retryAfter := int(math.Ceil(next.Sub(now).Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	respondError(c, ErrCooldown(cooldown, next, retryAfter))
+}
+
+// GetStrikes lists clients with strikes in the current window or a
+// cooldown in force, those in a cooldown first. With ?post= it returns
+// just the author of that post, whatever their strikes.
+func (e *Env) GetStrikes(c *gin.Context) {
+	now := time.Now()
+	query := e.DB.WithContext(c.Request.Context()).Model(&models.Identity{})
+	if raw := c.Query("post"); raw != "" {
+		postID, err := strconv.ParseUint(raw, 10, 32)
+		if err != nil {
+			respondError(c, ErrBadRequest("post.invalid_id"))
+			return
+		}
+		query = query.Joins("JOIN posts ON posts.author_hash = identities.client_hash").Where("posts.id = ?", postID)
+	} else {
+		query = query.Where("(strikes > 0 AND strikes_since > ?) OR cooldown_until > ?", now.Add(-e.Config.Strikes.Window), now).
+			Order("cooldown_until IS NULL, cooldown_until desc, strikes desc").Limit(maxAuditLimit)
+	}
+
+	var identities []models.Identity
+	if err := query.Find(&identities).Error; err != nil {
+		requestLogger(c).Error("fetching strikes", "err", err)
+		respondError(c, ErrInternal("strike.fetch_failed"))
+		return
+	}
+	clients := make([]StruckClient, len(identities))
+	for i, identity := range identities {
+		clients[i] = struckClient(identity)
+	}
+	c.Header("Cache-Control", "private, no-store")
+	c.JSON(http.StatusOK, clients)
+}
+
+// ClearStrikes forgives a client's strikes and lifts their cooldown.
+func (e *Env) ClearStrikes(c *gin.Context) {
+	identityID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, ErrBadRequest("strike.invalid_id"))
+		return
+	}
+
+	var identity models.Identity
+	err = e.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&identity, identityID).Error; err != nil {
+			return err
+		}
+		before := struckClient(identity)
+		err := tx.Model(&identity).Updates(map[string]any{
+			"strikes":          0,
+			"strikes_since":    nil,
+			"cooldown_seconds": 0,
+			"cooldown_until":   nil,
+		}).Error
+		if err != nil {
+			return err
+		}
+		return audit.Record(tx, adminActor(c), audit.ActionStrikesClear, audit.TargetIdentity, identity.ID, map[string]any{
+			"strikes":       before.Strikes,
+			"cooldown":      before.Cooldown,
+			"cooldownUntil": before.CooldownUntil,
+		})
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			respondError(c, ErrNotFound("strike.not_found"))
+			return
+		}
+		requestLogger(c).Error("clearing strikes", "err", err)
+		respondError(c, ErrInternal("strike.clear_failed"))
+		return
+	}
+	c.JSON(http.StatusOK, struckClient(identity))
+}
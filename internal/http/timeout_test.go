@@ -0,0 +1,91 @@
+package http_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	routes "github.com/sujalbistaa/whispr/internal/http"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// slowQueries makes every query on posts, once enabled, first run a
+// recursive query that never ends, so it only returns when its context
+// is canceled. It runs as an Exec because the SQLite driver interrupts a
+// statement for its context only while executing it, not while stepping
+// through rows afterwards.
+func slowQueries(t *testing.T, database *gorm.DB) *atomic.Bool {
+	t.Helper()
+	var enabled atomic.Bool
+	err := database.Callback().Query().Before("gorm:query").Register("test:slow", func(tx *gorm.DB) {
+		if !enabled.Load() || tx.Statement.Table != "posts" {
+			return
+		}
+		_, err := tx.Statement.ConnPool.ExecContext(tx.Statement.Context,
+			"WITH RECURSIVE r(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM r) SELECT count(*) FROM r")
+		tx.AddError(err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { database.Callback().Query().Remove("test:slow") })
+	return &enabled
+}
+
+// waitIdle fails the test unless every database connection is back in
+// the pool within wait.
+func waitIdle(t *testing.T, database *gorm.DB, wait time.Duration) {
+	t.Helper()
+	sqlDB, err := database.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(wait); sqlDB.Stats().InUse > 0; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d connections still in use after %s", sqlDB.Stats().InUse, wait)
+		}
+	}
+}
+
+func TestSlowQueryTimesOut(t *testing.T) {
+	t.Setenv("REQUEST_TIMEOUT", "200ms")
+	ts := testutil.NewTestServer(t)
+	post := ts.CreatePost(t, "fetched too slowly")
+	slowQueries(t, ts.DB).Store(true)
+
+	start := time.Now()
+	status, code := errorCode(t, ts, ts.NewRequest(t, http.MethodGet, fmt.Sprintf("/api/v1/posts/%d", post.ID), nil))
+	if took := time.Since(start); took > 2*time.Second {
+		t.Errorf("request took %s with a 200ms timeout", took)
+	}
+	if status != http.StatusServiceUnavailable || code != routes.CodeTimeout {
+		t.Errorf("slow query: %d %s, want 503 %s", status, code, routes.CodeTimeout)
+	}
+	waitIdle(t, ts.DB, time.Second)
+}
+
+// TestSlowQueryClientGone checks a query is canceled when its client
+// hangs up, long before the request timeout.
+func TestSlowQueryClientGone(t *testing.T) {
+	t.Setenv("REQUEST_TIMEOUT", "1m")
+	ts := testutil.NewTestServer(t)
+	post := ts.CreatePost(t, "abandoned halfway")
+	slowQueries(t, ts.DB).Store(true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	req := ts.NewRequest(t, http.MethodGet, fmt.Sprintf("/api/v1/posts/%d", post.ID), nil).WithContext(ctx)
+	if resp, err := ts.Client().Do(req); !errors.Is(err, context.DeadlineExceeded) {
+		if err == nil {
+			resp.Body.Close()
+		}
+		t.Fatalf("request finished (%v) while its query was still running", err)
+	}
+	waitIdle(t, ts.DB, 2*time.Second)
+}
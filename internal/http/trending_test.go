@@ -0,0 +1,111 @@
+package http_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// velocities maps each post of a trending response to its votesLastHour.
+func velocities(t *testing.T, posts []models.Post) map[uint]int64 {
+	t.Helper()
+	counts := map[uint]int64{}
+	for _, post := range posts {
+		if post.VotesLastHour == nil {
+			t.Fatalf("post %d has no votesLastHour", post.ID)
+		}
+		counts[post.ID] = *post.VotesLastHour
+	}
+	return counts
+}
+
+// TestTrendingRising seeds votes either side of the hour window and checks
+// sort=rising orders by the votes inside it.
+func TestTrendingRising(t *testing.T) {
+	t.Setenv("FEED_CACHE_FRESH", "0s")
+	ts := testutil.NewTestServer(t)
+	now := time.Now().UTC()
+
+	// Older votes lift the score but not the velocity.
+	veteran := seedPost(t, ts, models.Post{Content: "veteran", Score: 6, CreatedAt: now.Add(-5 * time.Hour)})
+	seedVotes(t, ts, veteran.ID, 5, now.Add(-2*time.Hour))
+	seedVotes(t, ts, veteran.ID, 1, now.Add(-10*time.Minute))
+	// early and late tie on velocity, so the newer one goes first.
+	early := seedPost(t, ts, models.Post{Content: "early", Score: 3, CreatedAt: now.Add(-4 * time.Hour)})
+	seedVotes(t, ts, early.ID, 3, now.Add(-59*time.Minute))
+	late := seedPost(t, ts, models.Post{Content: "late", Score: 3, CreatedAt: now.Add(-3 * time.Hour)})
+	seedVotes(t, ts, late.ID, 3, now.Add(-30*time.Minute))
+	// stale had all its votes just before the window opened.
+	stale := seedPost(t, ts, models.Post{Content: "stale", Score: 10, CreatedAt: now.Add(-2 * time.Hour)})
+	seedVotes(t, ts, stale.ID, 10, now.Add(-61*time.Minute))
+	hidden := seedPost(t, ts, models.Post{Content: "hidden", Score: 8, CreatedAt: now.Add(-time.Hour)})
+	seedVotes(t, ts, hidden.ID, 8, now.Add(-5*time.Minute))
+	ts.Hide(t, hidden.ID)
+	banned := seedPost(t, ts, models.Post{Content: "banned", Score: 8, ShadowBanned: true, CreatedAt: now.Add(-time.Hour)})
+	seedVotes(t, ts, banned.ID, 8, now.Add(-5*time.Minute))
+
+	rising := listFeed(t, ts, "/api/v1/trending?sort=rising")
+	if got, want := postIDs(rising), []uint{late.ID, early.ID, veteran.ID}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("rising order = %v, want %v", got, want)
+	}
+	want := map[uint]int64{late.ID: 3, early.ID: 3, veteran.ID: 1}
+	if got := velocities(t, rising); !reflect.DeepEqual(got, want) {
+		t.Fatalf("rising votesLastHour = %v, want %v", got, want)
+	}
+
+	trending := listFeed(t, ts, "/api/v1/trending")
+	if got, want := postIDs(trending), []uint{stale.ID, veteran.ID, late.ID, early.ID}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("score order = %v, want %v", got, want)
+	}
+	want[stale.ID] = 0
+	if got := velocities(t, trending); !reflect.DeepEqual(got, want) {
+		t.Fatalf("score votesLastHour = %v, want %v", got, want)
+	}
+
+	// A burst of fresh votes moves the veteran to the top.
+	seedVotes(t, ts, veteran.ID, 3, now)
+	rising = listFeed(t, ts, "/api/v1/trending?sort=rising")
+	if got, want := postIDs(rising), []uint{veteran.ID, late.ID, early.ID}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("rising order after burst = %v, want %v", got, want)
+	}
+	if got := *rising[0].VotesLastHour; got != 4 {
+		t.Fatalf("veteran votesLastHour = %d, want 4", got)
+	}
+
+	// The other feeds don't carry the field.
+	for _, post := range listFeed(t, ts, "/api/v1/posts") {
+		if post.VotesLastHour != nil {
+			t.Fatalf("post %d in /api/v1/posts has votesLastHour", post.ID)
+		}
+	}
+}
+
+// TestTrendingRisingLimit checks rising lists at most 20 posts, keeping the
+// fastest ones.
+func TestTrendingRisingLimit(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	now := time.Now().UTC()
+	var want []uint
+	for i := 25; i > 0; i-- {
+		post := seedPost(t, ts, models.Post{Content: "rising", CreatedAt: now.Add(-time.Duration(i) * time.Minute)})
+		seedVotes(t, ts, post.ID, i, now.Add(-time.Minute))
+		if len(want) < 20 {
+			want = append(want, post.ID)
+		}
+	}
+	if got := postIDs(listFeed(t, ts, "/api/v1/trending?sort=rising")); !reflect.DeepEqual(got, want) {
+		t.Fatalf("rising = %v, want %v", got, want)
+	}
+}
+
+func TestTrendingInvalidSort(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	status, code := errorCode(t, ts, ts.NewRequest(t, http.MethodGet, "/api/v1/trending?sort=new", nil))
+	if status != http.StatusBadRequest || code != "bad_request" {
+		t.Fatalf("sort=new: %d %q, want 400 bad_request", status, code)
+	}
+}
@@ -0,0 +1,142 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/sujalbistaa/whispr/internal/audit"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/strikes"
+)
+
+// undoWindow is how long after hiding a post a moderator can undo it.
+const undoWindow = 60 * time.Second
+
+// UndoHideInput is the body of POST /admin/posts/:id/undo.
+type UndoHideInput struct {
+	UndoToken string `json:"undoToken" binding:"required"`
+}
+
+// undoTokens holds the tokens DeletePost hands out, each good for one
+// undo of one post within undoWindow. They live in memory, so the undo
+// must reach the instance that hid the post; elsewhere it is refused like
+// an expired token. The zero value is ready to use.
+type undoTokens struct {
+	mu      sync.Mutex
+	pending map[string]pendingUndo
+}
+
+type pendingUndo struct {
+	postID  uint
+	expires time.Time
+}
+
+// issue returns a new token undoing the hide of post id, and drops
+// expired ones. Hides are rare enough to sweep on every issue.
+func (u *undoTokens) issue(id uint, now time.Time) string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	token := hex.EncodeToString(buf)
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.pending == nil {
+		u.pending = map[string]pendingUndo{}
+	}
+	for k, p := range u.pending {
+		if !now.Before(p.expires) {
+			delete(u.pending, k)
+		}
+	}
+	u.pending[token] = pendingUndo{postID: id, expires: now.Add(undoWindow)}
+	return token
+}
+
+// redeem reports whether token undoes the hide of post id and hasn't
+// expired, using it up if so. A token for another post is left alone.
+func (u *undoTokens) redeem(token string, id uint, now time.Time) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	p, ok := u.pending[token]
+	if !ok || p.postID != id {
+		return false
+	}
+	delete(u.pending, token)
+	return now.Before(p.expires)
+}
+
+// errUndoStale means the post is no longer hidden, so there is nothing
+// left to undo.
+var errUndoStale = errors.New("post is not hidden")
+
+// UndoDeletePost unhides a post hidden moments ago by DeletePost, given
+// the undoToken from its response, and announces it again as a new post.
+// The undo is audited with a link to the hide it reverses, and takes back
+// the author's strike. A token that is unknown, expired, used or for
+// another post gets 410.
+func (e *Env) UndoDeletePost(c *gin.Context) {
+	postID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		respondError(c, ErrBadRequest("post.invalid_id"))
+		return
+	}
+	var input UndoHideInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		respondError(c, ErrValidation(err))
+		return
+	}
+	if !e.undo.redeem(input.UndoToken, uint(postID), time.Now()) {
+		respondError(c, ErrGone("post.undo_expired"))
+		return
+	}
+
+	var post models.Post
+	err = e.DB.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+		var hide models.AuditLog
+		if err := tx.Where("action = ? AND target_type = ? AND target_id = ?", audit.ActionHidePost, audit.TargetPost, postID).
+			Order("id desc").First(&hide).Error; err != nil {
+			return err
+		}
+		res := tx.Unscoped().Model(&models.Post{}).Where("id = ? AND hidden_at IS NOT NULL", postID).
+			Updates(map[string]any{"hidden_at": nil, "hidden_by": ""})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errUndoStale
+		}
+		if err := tx.First(&post, postID).Error; err != nil {
+			return err
+		}
+		if err := audit.Record(tx, adminActor(c), audit.ActionUndoHide, audit.TargetPost, post.ID, map[string]any{"undoes": hide.ID}); err != nil {
+			return err
+		}
+		if post.AuthorHash == nil {
+			return nil
+		}
+		return strikes.Forgive(tx, adminActor(c), *post.AuthorHash, post.ID, time.Now())
+	})
+	if errors.Is(err, errUndoStale) {
+		respondError(c, ErrGone("post.undo_expired"))
+		return
+	}
+	if err != nil {
+		requestLogger(c).Error("undoing hide", "err", err)
+		respondError(c, ErrInternal("post.undo_failed"))
+		return
+	}
+
+	e.invalidateFeeds()
+	if !post.ShadowBanned {
+		e.broadcastMessage(WsMessage{Type: "new_post", Data: post, Board: e.boardSlugOf(c.Request.Context(), post.BoardID)})
+	}
+	c.JSON(http.StatusOK, post)
+}
@@ -0,0 +1,65 @@
+package http_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+func TestLegacyAPIPaths(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+
+	resp, err := ts.Client().Do(ts.NewRequest(t, http.MethodPost, "/api/posts", map[string]string{"content": "posted through the old path"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var post models.Post
+	json.NewDecoder(resp.Body).Decode(&post)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("POST /api/posts: status %d, want 201", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Deprecation"); got != "true" {
+		t.Errorf("Deprecation = %q, want true", got)
+	}
+	if got := resp.Header.Get("Sunset"); got != "Thu, 01 Apr 2027 00:00:00 GMT" {
+		t.Errorf("Sunset = %q", got)
+	}
+	if got, want := resp.Header.Get("Link"), `</api/v1/posts>; rel="successor-version"`; got != want {
+		t.Errorf("Link = %q, want %q", got, want)
+	}
+
+	for _, path := range []string{"/api/v1/posts/%d", "/api/posts/%d"} {
+		path = fmt.Sprintf(path, post.ID)
+		resp, err := ts.Client().Do(ts.NewRequest(t, http.MethodGet, path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got models.Post
+		json.NewDecoder(resp.Body).Decode(&got)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK || got.ID != post.ID || got.Content != post.Content {
+			t.Errorf("GET %s: status %d, post %+v; want post %d", path, resp.StatusCode, got, post.ID)
+		}
+		deprecated := resp.Header.Get("Deprecation") != ""
+		if want := !strings.HasPrefix(path, "/api/v1/"); deprecated != want {
+			t.Errorf("GET %s: Deprecation header present = %v, want %v", path, deprecated, want)
+		}
+	}
+}
+
+func TestLegacyAPISharesRateLimits(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	ip := ts.ClientIP()
+	if status, body := ts.Do(t, ts.NewRequestFrom(t, ip, http.MethodPost, "/api/v1/posts", map[string]string{"content": "the first post"})); status != http.StatusCreated {
+		t.Fatalf("POST /api/v1/posts: status %d: %s", status, body)
+	}
+	if status, _ := ts.Do(t, ts.NewRequestFrom(t, ip, http.MethodPost, "/api/posts", map[string]string{"content": "straight after, on the old path"})); status != http.StatusTooManyRequests {
+		t.Errorf("POST /api/posts right after /api/v1/posts: status %d, want 429", status)
+	}
+}
@@ -0,0 +1,125 @@
+package http_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	routes "github.com/sujalbistaa/whispr/internal/http"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// TestVoteRace votes on one post from many clients at once, through the
+// routes, and checks no vote is lost on the way to the score, the vote
+// rows or the broadcasts.
+func TestVoteRace(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	post := ts.CreatePost(t, "a post everyone votes on at once")
+	ws := ts.DialWS(t)
+
+	const voters = 50
+	var wg sync.WaitGroup
+	for i := 0; i < voters; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ts.Vote(t, post.ID, 1)
+		}()
+	}
+	wg.Wait()
+
+	var stored models.Post
+	if err := ts.DB.First(&stored, post.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if want := post.Score + voters; stored.Score != want {
+		t.Errorf("score = %d, want %d", stored.Score, want)
+	}
+	var rows int64
+	ts.DB.Model(&models.Vote{}).Where("post_id = ?", post.ID).Count(&rows)
+	if want := int64(voters + 1); rows != want { // The author's own upvote too
+		t.Errorf("%d vote rows, want %d", rows, want)
+	}
+
+	// Each vote is announced with the score it produced, so no two
+	// broadcasts carry the same one. The hub drops messages rather than
+	// block when its queue is full, so a burst may not all arrive.
+	seen := map[int]bool{}
+	for {
+		msg, ok := ws.Poll(t, "vote", 200*time.Millisecond)
+		if !ok {
+			break
+		}
+		var vote struct {
+			ID    uint `json:"id"`
+			Score int  `json:"score"`
+		}
+		json.Unmarshal(msg.Data, &vote)
+		if vote.ID != post.ID || vote.Score <= post.Score || vote.Score > stored.Score || seen[vote.Score] {
+			t.Errorf("unexpected vote broadcast %+v", vote)
+		}
+		seen[vote.Score] = true
+	}
+	if len(seen) == 0 {
+		t.Error("no vote was broadcast")
+	}
+}
+
+func TestVoteTwiceFromOneClient(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	post := ts.CreatePost(t, "vote on me only once")
+	ip := ts.ClientIP()
+
+	if status := ts.VoteFrom(t, ip, post.ID, 1); status != http.StatusOK {
+		t.Fatalf("first vote: status %d, want 200", status)
+	}
+	if status := ts.VoteFrom(t, ip, post.ID, -1); status != http.StatusConflict {
+		t.Errorf("second vote: status %d, want 409", status)
+	}
+	if score := ts.Vote(t, post.ID, 1); score != post.Score+2 {
+		t.Errorf("score after another client voted = %d, want %d", score, post.Score+2)
+	}
+}
+
+// TestConcurrentVotesFromOneClient sends the same client's vote many times
+// at once. Only the unique index on (post_id, voter_hash) can tell them
+// apart, and every loser has to come back as a 409, not a 500.
+func TestConcurrentVotesFromOneClient(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	post := ts.CreatePost(t, "double-clicked")
+	ip := ts.ClientIP()
+
+	const attempts = 10
+	statuses := make(chan int, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			statuses <- ts.VoteFrom(t, ip, post.ID, 1)
+		}()
+	}
+	wg.Wait()
+	close(statuses)
+	counts := map[int]int{}
+	for status := range statuses {
+		counts[status]++
+	}
+	if counts[http.StatusOK] != 1 || counts[http.StatusConflict] != attempts-1 {
+		t.Errorf("statuses %v, want one 200 and %d 409s", counts, attempts-1)
+	}
+
+	status, code := errorCode(t, ts, ts.NewRequestFrom(t, ip, http.MethodPost, fmt.Sprintf("/api/v1/posts/%d/vote", post.ID), map[string]int{"value": 1}))
+	if status != http.StatusConflict || code != routes.CodeConflict {
+		t.Errorf("repeat vote: %d %s, want 409 %s", status, code, routes.CodeConflict)
+	}
+	var stored models.Post
+	ts.DB.First(&stored, post.ID)
+	if stored.Score != post.Score+1 {
+		t.Errorf("score = %d, want %d", stored.Score, post.Score+1)
+	}
+}
@@ -0,0 +1,28 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/webhook"
+)
+
+// notify queues a webhook event for post. It never blocks the request.
+func (e *Env) notify(c *gin.Context, event webhook.Event, post models.Post) {
+	e.Webhooks.Send(event, webhook.Post{
+		ID:        post.ID,
+		Content:   post.Content,
+		URL:       postPermalink(e.publicBaseURL(c), post.ID),
+		CreatedAt: post.CreatedAt,
+	})
+}
+
+// GetWebhookDeliveries lists recent webhook delivery attempts, newest
+// first, with queue and drop counters. ?failed=true shows only failures.
+func (e *Env) GetWebhookDeliveries(c *gin.Context) {
+	failedOnly, _ := strconv.ParseBool(c.Query("failed"))
+	c.JSON(http.StatusOK, e.Webhooks.Stats(failedOnly))
+}
@@ -0,0 +1,137 @@
+// Package i18n translates user-facing API messages. Each locale is an
+// embedded JSON catalog mapping stable message keys to text with {name}
+// placeholders; English is complete and every other locale falls back to
+// it key by key.
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Fallback is the locale used when nothing in Accept-Language matches and
+// for keys a locale doesn't translate.
+const Fallback = "en"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+// Default is the catalog built from the embedded locales.
+var Default = mustLoad(localeFiles)
+
+// Catalog holds the messages of every locale.
+type Catalog struct {
+	messages map[string]map[string]string // locale -> key -> text
+	locales  []string
+
+	missing sync.Map // "locale key" pairs already logged
+}
+
+// Load reads locales/<locale>.json from fsys. The fallback locale must be
+// present, and other locales may only use keys it defines.
+func Load(fsys fs.FS) (*Catalog, error) {
+	files, err := fs.Glob(fsys, "locales/*.json")
+	if err != nil {
+		return nil, err
+	}
+	c := &Catalog{messages: map[string]map[string]string{}}
+	for _, file := range files {
+		raw, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(raw, &messages); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		locale := strings.TrimSuffix(path.Base(file), ".json")
+		c.messages[locale] = messages
+		c.locales = append(c.locales, locale)
+	}
+	fallback, ok := c.messages[Fallback]
+	if !ok {
+		return nil, fmt.Errorf("missing %s catalog", Fallback)
+	}
+	for locale, messages := range c.messages {
+		for key := range messages {
+			if _, ok := fallback[key]; !ok {
+				return nil, fmt.Errorf("%s: key %q is not in the %s catalog", locale, key, Fallback)
+			}
+		}
+	}
+	slices.Sort(c.locales)
+	return c, nil
+}
+
+func mustLoad(fsys fs.FS) *Catalog {
+	c, err := Load(fsys)
+	if err != nil {
+		panic("i18n: " + err.Error())
+	}
+	return c
+}
+
+// Locales lists the available locales.
+func (c *Catalog) Locales() []string {
+	return c.locales
+}
+
+// Negotiate picks the best available locale for an Accept-Language header,
+// matching on the primary language ("ne-NP" selects "ne") and honouring
+// q-values. It returns Fallback when nothing matches.
+func (c *Catalog) Negotiate(acceptLanguage string) string {
+	best, bestQ := Fallback, 0.0
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.TrimSpace(name) == "q" {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		base, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if _, ok := c.messages[base]; ok && q > bestQ {
+			best, bestQ = base, q
+		}
+	}
+	return best
+}
+
+// Message renders key in locale. args are alternating placeholder names
+// and values, as in slog: Message("ne", "query.invalid_limit", "max", 200).
+// A key the locale lacks falls back to English and is logged once.
+func (c *Catalog) Message(locale, key string, args ...any) string {
+	text, ok := c.messages[locale][key]
+	if !ok {
+		if _, logged := c.missing.LoadOrStore(locale+" "+key, true); !logged {
+			slog.Warn("missing translation", "locale", locale, "key", key)
+		}
+		if text, ok = c.messages[Fallback][key]; !ok {
+			text = key
+		}
+	}
+	if len(args) == 0 {
+		return text
+	}
+	pairs := make([]string, 0, len(args))
+	for i := 0; i+1 < len(args); i += 2 {
+		pairs = append(pairs, "{"+fmt.Sprint(args[i])+"}", fmt.Sprint(args[i+1]))
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// Has reports whether key is defined.
+func (c *Catalog) Has(key string) bool {
+	_, ok := c.messages[Fallback][key]
+	return ok
+}
@@ -0,0 +1,192 @@
+// Package karma keeps the posting streak and karma of each anonymous
+// client in an Identity keyed by the client's hash. Streaks advance in the
+// transaction that creates a post, through RecordPost. Karma is settled
+// later by a Worker: once a post is SettleAfter old, its author earns a
+// point if it is still up with net upvotes.
+//
+// Posts are settled by a conditional update of karma_at, so every
+// instance can run the worker: a batch another instance settled first is
+// rolled back and skipped.
+package karma
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/sujalbistaa/whispr/internal/db"
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+// Config configures a Worker.
+type Config struct {
+	Interval    time.Duration // Time between runs; 0 disables the worker
+	SettleAfter time.Duration // How old a post must be before it is settled
+	BatchSize   int           // Posts settled per transaction
+}
+
+// Enabled reports whether the worker should run.
+func (c Config) Enabled() bool {
+	return c.Interval > 0
+}
+
+// day is the UTC day t falls in, as its midnight.
+func day(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// RecordPost advances the streak of client, who posted at the given
+// time, creating its identity on its first post. Call it in the
+// transaction that creates the post.
+func RecordPost(tx *gorm.DB, client string, at time.Time) error {
+	today := day(at)
+	identity := models.Identity{ClientHash: client, LastPostOn: today}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&identity).Error; err != nil {
+		return err
+	}
+	identity = models.Identity{}
+	if err := tx.Scopes(db.LockForUpdate).Where("client_hash = ?", client).First(&identity).Error; err != nil {
+		return err
+	}
+	switch {
+	case identity.Streak > 0 && identity.LastPostOn.Equal(today):
+		return nil
+	case identity.Streak > 0 && identity.LastPostOn.Equal(today.AddDate(0, 0, -1)):
+		identity.Streak++
+	default:
+		identity.Streak = 1
+	}
+	identity.BestStreak = max(identity.BestStreak, identity.Streak)
+	return tx.Model(&identity).Updates(map[string]any{
+		"streak":       identity.Streak,
+		"best_streak":  identity.BestStreak,
+		"last_post_on": today,
+	}).Error
+}
+
+// CurrentStreak is identity's streak as of now: a streak whose last day
+// was before yesterday has been broken, though nothing has reset it yet.
+func CurrentStreak(identity models.Identity, now time.Time) int {
+	if identity.LastPostOn.Before(day(now).AddDate(0, 0, -1)) {
+		return 0
+	}
+	return identity.Streak
+}
+
+// Worker settles karma on an interval.
+type Worker struct {
+	db  *gorm.DB
+	cfg Config
+}
+
+// New returns a worker for cfg; call Run to start it.
+func New(db *gorm.DB, cfg Config) *Worker {
+	return &Worker{db: db, cfg: cfg}
+}
+
+// Run settles immediately and then every Interval until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	slog.Info("karma worker started", "interval", w.cfg.Interval, "settleAfter", w.cfg.SettleAfter)
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		w.runAndLog(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			slog.Info("karma worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) runAndLog(ctx context.Context, now time.Time) {
+	start := time.Now()
+	settled, credited, err := w.Settle(ctx, now)
+	if err != nil && ctx.Err() == nil {
+		slog.Error("settling karma failed", "settled", settled, "credited", credited, "err", err)
+		return
+	}
+	slog.Info("karma settled", "settled", settled, "credited", credited, "duration", time.Since(start))
+}
+
+// errTaken rolls back a batch another instance settled first.
+var errTaken = errors.New("batch settled by another instance")
+
+// Settle settles every post created more than SettleAfter before now that
+// hasn't been, and returns how many it settled and how many of those
+// earned their author karma. A post earns it if it is visible, not
+// shadow-banned, and scores above the 1 its author's own vote gives it.
+// Posts are read in batches keyed on id, hidden ones included, so they
+// are settled without credit and not read again.
+func (w *Worker) Settle(ctx context.Context, now time.Time) (settled, credited int64, err error) {
+	cutoff := now.Add(-w.cfg.SettleAfter)
+	database := w.db.WithContext(ctx)
+
+	var last uint
+	for {
+		var posts []models.Post
+		if err := database.Unscoped().Select("id", "author_hash", "score", "shadow_banned", "hidden_at").
+			Where("id > ? AND karma_at IS NULL AND created_at < ?", last, cutoff).
+			Order("id").Limit(w.cfg.BatchSize).Find(&posts).Error; err != nil {
+			return settled, credited, err
+		}
+		if len(posts) == 0 {
+			return settled, credited, nil
+		}
+
+		ids := make([]uint, len(posts))
+		earned := map[string]int{}
+		var batchCredited int64
+		for i, post := range posts {
+			ids[i] = post.ID
+			if post.AuthorHash != nil && !post.HiddenAt.Valid && !post.ShadowBanned && post.Score > 1 {
+				earned[*post.AuthorHash]++
+				batchCredited++
+			}
+		}
+		err := database.Transaction(func(tx *gorm.DB) error {
+			res := tx.Unscoped().Model(&models.Post{}).Where("id IN ? AND karma_at IS NULL", ids).
+				UpdateColumn("karma_at", now)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected != int64(len(ids)) {
+				return errTaken
+			}
+			for author, points := range earned {
+				if err := credit(tx, author, points, now); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		switch {
+		case errors.Is(err, errTaken):
+		case err != nil:
+			return settled, credited, err
+		default:
+			settled += int64(len(ids))
+			credited += batchCredited
+		}
+		if len(posts) < w.cfg.BatchSize {
+			return settled, credited, nil
+		}
+		last = ids[len(ids)-1]
+	}
+}
+
+// credit adds points to author's karma. Authors of posts made before
+// identities existed get one with no streak.
+func credit(tx *gorm.DB, author string, points int, now time.Time) error {
+	identity := models.Identity{ClientHash: author, LastPostOn: day(now)}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&identity).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.Identity{}).Where("client_hash = ?", author).
+		UpdateColumn("karma", gorm.Expr("karma + ?", points)).Error
+}
@@ -0,0 +1,100 @@
+// Package lang guesses the language of a post: English or Nepali, the
+// latter in Devanagari or romanized ("k cha sathi"). Nepali in Devanagari
+// is told by its script. Latin text is scored against short lists of
+// words common in one language and rare in the other. Text with too few
+// of them, or as many of each, is undetermined.
+package lang
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Codes returned by Detect: ISO 639-1, or the BCP 47 code for
+// undetermined.
+const (
+	English      = "en"
+	Nepali       = "ne"
+	Undetermined = "und"
+)
+
+// Codes lists the languages Detect can return besides Undetermined.
+var Codes = []string{English, Nepali}
+
+const (
+	// minWords is how many marker words Latin text needs to be tagged.
+	minWords = 2
+	// The winning language needs this many times the other's markers.
+	minRatio = 2
+	// minLetters is how many Devanagari letters make a post Nepali.
+	minLetters = 4
+)
+
+// Function words and other very common words, lowercased. Words shared by
+// both, or common in romanized Nepali slang too ("ho", "k"), are left out
+// of English.
+var markers = map[string]string{}
+
+func init() {
+	for _, w := range strings.Fields(`
+		the and is are was were i you he she it we they my your me him her
+		to of in on for with that this these those have has had not but be
+		been so just what when how why do does did at from about like can
+		will would there their an or if all out up get know think people
+		really because feel dont im its no yes am than then them who some
+		time never always want love could should being our us any anyone
+		everyone something nothing only even still after before got going
+		much very said`) {
+		markers[w] = English
+	}
+	for _, w := range strings.Fields(`
+		cha chha xa chu chhu xu chau chhau xau chan chhan xan chaina
+		chhaina xaina hoina thiyo thyo thiye thiena bhayo vayo bhaye vaye
+		bhanera vanera bhanchu vanchu garnu garne gareko garyo garchu garxu
+		garchha garcha garxa gardai huncha hunchha hunxa hunna hola holaa
+		raixa rahecha rahexa parcha parchha parxa sakchu sakdina lagyo
+		lagcha lagxa malai timilai tapailai usle maile timile mero timro
+		tero hamro usko uniharu hami timi tapai tapaai ma yo tyo ke kei
+		kina kasto kasari kati kaha kahile aja aaja bholi hijo ahile sathi
+		sathiharu keta keti dai didi bhai baini ramro naramro dherai ekdam
+		sabai kura ra pani ani tara kinaki ko ki lai le sanga bata samma
+		jasto jastai ni hai ta nai khai khana khayo gayo aayo jancha janxa
+		aauchu herda bela ghar kaam paisa maya`) {
+		markers[w] = Nepali
+	}
+}
+
+// Detect returns the language of text: English, Nepali or Undetermined.
+func Detect(text string) string {
+	var devanagari, letters int
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			letters++
+			if unicode.Is(unicode.Devanagari, r) {
+				devanagari++
+			}
+		}
+	}
+	if devanagari >= minLetters && devanagari*2 >= letters {
+		return Nepali
+	}
+
+	var en, ne int
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '\''
+	}) {
+		switch markers[strings.ReplaceAll(word, "'", "")] {
+		case English:
+			en++
+		case Nepali:
+			ne++
+		}
+	}
+	switch {
+	case en >= minWords && en >= minRatio*ne:
+		return English
+	case ne >= minWords && ne >= minRatio*en:
+		return Nepali
+	}
+	return Undetermined
+}
@@ -0,0 +1,53 @@
+package lang_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sujalbistaa/whispr/internal/lang"
+)
+
+func TestDetect(t *testing.T) {
+	for _, tc := range []struct {
+		text, want string
+	}{
+		{"I really think the library should stay open later", lang.English},
+		{"Does anyone know when the results are out?", lang.English},
+		{"k cha sathi, aaja ta ekdam ramro din thiyo", lang.Nepali},
+		{"malai ta yo kura pani thaha thiena hai", lang.Nepali},
+		{"आज त धेरै जाडो छ", lang.Nepali},
+		{"exam मा के आयो?", lang.Nepali},
+		{"lol", lang.Undetermined},
+		{"", lang.Undetermined},
+		{"ok", lang.Undetermined},
+		{"the ma", lang.Undetermined},
+		{"🔥🔥🔥", lang.Undetermined},
+	} {
+		if got := lang.Detect(tc.text); got != tc.want {
+			t.Errorf("Detect(%q) = %q, want %q", tc.text, got, tc.want)
+		}
+	}
+}
+
+// BenchmarkDetect runs Detect on posts of the longest length CreatePost
+// accepts, where it has the most to scan.
+func BenchmarkDetect(b *testing.B) {
+	for _, bc := range []struct {
+		name, text string
+	}{
+		{"short", "k cha sathi?"},
+		{"english", "I really think the library should stay open later because "},
+		{"romanized", "malai ta yo kura pani thaha thiena hai, aaja ekdam ramro "},
+		{"devanagari", "आज त धेरै जाडो छ, कलेज जान मन लागेन। "},
+	} {
+		text := bc.text
+		if bc.name != "short" {
+			text = strings.Repeat(text, 1000/len([]rune(text)))
+		}
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				lang.Detect(text)
+			}
+		})
+	}
+}
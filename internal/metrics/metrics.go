@@ -0,0 +1,253 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metric names are part of the public contract with dashboards and alerts.
+// Rename only with a deprecation period.
+// Connection pool stats are exported by the standard go_sql_* collector
+// with db_name="whispr".
+const (
+	NameHTTPRequests      = "whispr_http_requests_total"
+	NameHTTPDuration      = "whispr_http_request_duration_seconds"
+	NameRateLimitRejected = "whispr_rate_limit_rejections_total"
+	NameRateLimitErrors   = "whispr_rate_limit_errors_total"
+	NameWSConnections     = "whispr_ws_connections"
+	NameWSDropped         = "whispr_ws_broadcasts_dropped_total"
+	NamePostsCreated      = "whispr_posts_created_total"
+	NameVotesCreated      = "whispr_votes_created_total"
+	NamePostsHidden       = "whispr_posts_hidden_total"
+	NamePostsHeld         = "whispr_posts_held_total"
+	NamePostsArchived     = "whispr_posts_archived_total"
+	NameInFlightPosts     = "whispr_inflight_posts"
+	NameDBDown            = "whispr_db_down"
+	NameWebhookDeliveries = "whispr_webhook_deliveries_total"
+	NameWebhookDropped    = "whispr_webhook_dropped_total"
+	NamePushDeliveries    = "whispr_push_deliveries_total"
+	NamePushDropped       = "whispr_push_dropped_total"
+	NameEventLogDropped   = "whispr_event_log_dropped_total"
+	NameRetentionPurged   = "whispr_retention_purged_total"
+	NameFeedCache         = "whispr_feed_cache_requests_total"
+	NameWorkerRestarts    = "whispr_worker_restarts_total"
+
+	dbName = "whispr"
+)
+
+// Registry holds every whispr metric. It is separate from the default
+// registry so tests and embedders get a clean slate.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: NameHTTPRequests,
+		Help: "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    NameHTTPDuration,
+		Help:    "HTTP request latency by method and route template.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	rateLimitRejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: NameRateLimitRejected,
+		Help: "Requests rejected by a rate limiter, by limiter.",
+	}, []string{"limiter"})
+
+	rateLimitErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: NameRateLimitErrors,
+		Help: "Requests a rate limiter couldn't decide on, e.g. with Redis unreachable, by limiter and fallback: open or closed.",
+	}, []string{"limiter", "fallback"})
+
+	// PostsCreated counts posts stored by CreatePost.
+	PostsCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: NamePostsCreated,
+		Help: "Posts created.",
+	})
+
+	// VotesCreated counts votes recorded by VoteOnPost.
+	VotesCreated = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: NameVotesCreated,
+		Help: "Votes recorded.",
+	})
+
+	// PostsHidden counts posts hidden by moderation.
+	PostsHidden = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: NamePostsHidden,
+		Help: "Posts hidden by moderators.",
+	})
+
+	// PostsHeld counts new posts held for moderation as near-duplicates.
+	PostsHeld = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: NamePostsHeld,
+		Help: "New posts held for moderation as near-duplicates of recent ones.",
+	})
+
+	webhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: NameWebhookDeliveries,
+		Help: "Webhook delivery attempts by result: ok, retry or dead_letter.",
+	}, []string{"result"})
+
+	// WebhookDropped counts webhook deliveries dropped because the queue was full.
+	WebhookDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: NameWebhookDropped,
+		Help: "Webhook deliveries dropped because the queue was full.",
+	})
+
+	pushDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: NamePushDeliveries,
+		Help: "Web Push delivery attempts by result: ok, retry, failed or gone.",
+	}, []string{"result"})
+
+	// PushDropped counts trending notifications dropped because the queue
+	// was full.
+	PushDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: NamePushDropped,
+		Help: "Web Push notifications dropped because the queue was full.",
+	})
+
+	// PostsArchived counts posts the archive worker took out of the feeds.
+	PostsArchived = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: NamePostsArchived,
+		Help: "Posts archived because the feeds were over MAX_VISIBLE_POSTS.",
+	})
+
+	// EventLogDropped counts broadcast messages left out of the event log.
+	EventLogDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: NameEventLogDropped,
+		Help: "Event log records dropped because the queue was full or writing failed.",
+	})
+
+	// WSBroadcastDropped counts WebSocket messages dropped because the hub's
+	// queue was full.
+	WSBroadcastDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: NameWSDropped,
+		Help: "WebSocket broadcasts dropped because the hub's queue was full.",
+	})
+
+	retentionPurged = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: NameRetentionPurged,
+		Help: "Rows permanently deleted by the retention worker, by kind: posts, votes, orphaned_votes or bans; vote_ids counts votes whose retry ID was cleared.",
+	}, []string{"kind"})
+
+	feedCache = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: NameFeedCache,
+		Help: "Feed requests by cache result: hit, miss or stale.",
+	}, []string{"result"})
+
+	workerRestarts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: NameWorkerRestarts,
+		Help: "Background goroutines restarted after a panic, by worker.",
+	}, []string{"worker"})
+)
+
+func init() {
+	Registry.MustRegister(
+		httpRequests,
+		httpDuration,
+		rateLimitRejected,
+		rateLimitErrors,
+		PostsCreated,
+		VotesCreated,
+		PostsHidden,
+		PostsHeld,
+		PostsArchived,
+		webhookDeliveries,
+		WebhookDropped,
+		pushDeliveries,
+		PushDropped,
+		EventLogDropped,
+		WSBroadcastDropped,
+		retentionPurged,
+		feedCache,
+		workerRestarts,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// RateLimited records a request rejected by the named limiter.
+func RateLimited(limiter string) {
+	rateLimitRejected.WithLabelValues(limiter).Inc()
+	recentRateLimited.add(time.Now())
+}
+
+// RateLimitError records a request the named limiter couldn't decide on,
+// let through when failOpen and rejected otherwise.
+func RateLimitError(limiter string, failOpen bool) {
+	fallback := "closed"
+	if failOpen {
+		fallback = "open"
+	}
+	rateLimitErrors.WithLabelValues(limiter, fallback).Inc()
+}
+
+// WebhookDelivered records the result of one webhook delivery attempt.
+func WebhookDelivered(result string) {
+	webhookDeliveries.WithLabelValues(result).Inc()
+}
+
+// PushDelivered records the result of one Web Push delivery attempt.
+func PushDelivered(result string) {
+	pushDeliveries.WithLabelValues(result).Inc()
+}
+
+// RetentionPurged records n rows of kind deleted by the retention worker.
+func RetentionPurged(kind string, n int64) {
+	retentionPurged.WithLabelValues(kind).Add(float64(n))
+}
+
+// FeedCache records a feed request answered with result.
+func FeedCache(result string) {
+	feedCache.WithLabelValues(result).Inc()
+}
+
+// WorkerRestarted records a supervised worker restarted after a panic.
+func WorkerRestarted(worker string) {
+	workerRestarts.WithLabelValues(worker).Inc()
+}
+
+// RegisterDB exposes connection pool stats from sqlDB.Stats().
+func RegisterDB(sqlDB *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(sqlDB, dbName))
+}
+
+// RegisterGauge exposes a value computed on each scrape, such as the
+// current WebSocket connection count.
+func RegisterGauge(name, help string, fn func() float64) error {
+	return Registry.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: name,
+		Help: help,
+	}, fn))
+}
+
+// Middleware records request counts and latency per route template.
+// Unmatched paths share a single "unmatched" label to bound cardinality.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		method := c.Request.Method
+		httpRequests.WithLabelValues(method, route, strconv.Itoa(c.Writer.Status())).Inc()
+		httpDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+	}
+}
+
+// Handler serves the registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
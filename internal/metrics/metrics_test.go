@@ -0,0 +1,81 @@
+package metrics_test
+
+import (
+	"testing"
+
+	"github.com/sujalbistaa/whispr/internal/metrics"
+)
+
+// TestNames pins every metric name: dashboards and alerts query them, so
+// a rename must be deliberate, not a side effect of a refactor.
+func TestNames(t *testing.T) {
+	for got, want := range map[string]string{
+		metrics.NameHTTPRequests:      "whispr_http_requests_total",
+		metrics.NameHTTPDuration:      "whispr_http_request_duration_seconds",
+		metrics.NameRateLimitRejected: "whispr_rate_limit_rejections_total",
+		metrics.NameRateLimitErrors:   "whispr_rate_limit_errors_total",
+		metrics.NameWSConnections:     "whispr_ws_connections",
+		metrics.NameWSDropped:         "whispr_ws_broadcasts_dropped_total",
+		metrics.NamePostsCreated:      "whispr_posts_created_total",
+		metrics.NameVotesCreated:      "whispr_votes_created_total",
+		metrics.NamePostsHidden:       "whispr_posts_hidden_total",
+		metrics.NamePostsHeld:         "whispr_posts_held_total",
+		metrics.NamePostsArchived:     "whispr_posts_archived_total",
+		metrics.NameInFlightPosts:     "whispr_inflight_posts",
+		metrics.NameDBDown:            "whispr_db_down",
+		metrics.NameWebhookDeliveries: "whispr_webhook_deliveries_total",
+		metrics.NameWebhookDropped:    "whispr_webhook_dropped_total",
+		metrics.NamePushDeliveries:    "whispr_push_deliveries_total",
+		metrics.NamePushDropped:       "whispr_push_dropped_total",
+		metrics.NameEventLogDropped:   "whispr_event_log_dropped_total",
+		metrics.NameRetentionPurged:   "whispr_retention_purged_total",
+		metrics.NameFeedCache:         "whispr_feed_cache_requests_total",
+		metrics.NameWorkerRestarts:    "whispr_worker_restarts_total",
+	} {
+		if got != want {
+			t.Errorf("metric renamed to %q, was %q", got, want)
+		}
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	// Labeled metrics only show up once counted.
+	metrics.RateLimited("test")
+	metrics.RateLimitError("test", true)
+	metrics.WebhookDelivered("ok")
+	metrics.PushDelivered("ok")
+	metrics.RetentionPurged("posts", 0)
+	metrics.FeedCache("hit")
+	metrics.WorkerRestarted("test")
+
+	families, err := metrics.Registry.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	registered := map[string]bool{}
+	for _, family := range families {
+		registered[family.GetName()] = true
+	}
+	for _, name := range []string{
+		metrics.NameRateLimitRejected,
+		metrics.NameRateLimitErrors,
+		metrics.NamePostsCreated,
+		metrics.NameVotesCreated,
+		metrics.NamePostsHidden,
+		metrics.NamePostsHeld,
+		metrics.NamePostsArchived,
+		metrics.NameWebhookDeliveries,
+		metrics.NameWebhookDropped,
+		metrics.NamePushDeliveries,
+		metrics.NamePushDropped,
+		metrics.NameEventLogDropped,
+		metrics.NameWSDropped,
+		metrics.NameRetentionPurged,
+		metrics.NameFeedCache,
+		metrics.NameWorkerRestarts,
+	} {
+		if !registered[name] {
+			t.Errorf("%s isn't registered", name)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// recentCounter counts events per minute over the last hour, for
+// dashboards that want "how many lately" without querying Prometheus.
+// Counts are per process.
+type recentCounter struct {
+	mu      sync.Mutex
+	minutes [60]int64 // Unix minute each bucket counts
+	counts  [60]uint64
+}
+
+func (r *recentCounter) add(now time.Time) {
+	minute := now.Unix() / 60
+	i := minute % int64(len(r.counts))
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.minutes[i] != minute {
+		r.minutes[i], r.counts[i] = minute, 0
+	}
+	r.counts[i]++
+}
+
+// lastHour sums the buckets of the 60 minutes up to now.
+func (r *recentCounter) lastHour(now time.Time) uint64 {
+	minute := now.Unix() / 60
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var total uint64
+	for i, m := range r.minutes {
+		if minute-m < int64(len(r.counts)) {
+			total += r.counts[i]
+		}
+	}
+	return total
+}
+
+var recentRateLimited recentCounter
+
+// RateLimitedLastHour returns how many requests this process's rate
+// limiters rejected in the last hour.
+func RateLimitedLastHour() uint64 {
+	return recentRateLimited.lastHour(time.Now())
+}
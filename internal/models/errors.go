@@ -0,0 +1,22 @@
+package models
+
+import "errors"
+
+// Sentinel errors for outcomes callers branch on. Return them, wrapped or
+// not, from transactions and match them with errors.Is; the HTTP layer
+// maps each to a status in one place.
+var (
+	// ErrPostNotFound means the post doesn't exist or the caller can't
+	// see it.
+	ErrPostNotFound = errors.New("post not found")
+
+	// ErrVoteConflict means the client has already voted on the post.
+	ErrVoteConflict = errors.New("already voted on post")
+
+	// ErrVoteIDReused means the vote's client-generated ID belongs to
+	// another client's vote, or to a vote on another post.
+	ErrVoteIDReused = errors.New("vote ID already used")
+
+	// ErrBoardNotFound means no board has the requested slug.
+	ErrBoardNotFound = errors.New("board not found")
+)
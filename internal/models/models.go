@@ -8,20 +8,156 @@ import (
 
 // Post represents a single anonymous confession.
 type Post struct {
-	ID        uint           `gorm:"primarykey" json:"id"`
-	Content   string         `gorm:"not null" json:"content"`
-	Score     int            `gorm:"not null;default:0" json:"score"`
-	Hidden    bool           `gorm:"not null;default:false" json:"-"` // Hidden from API responses
-	CreatedAt time.Time      `json:"createdAt"`
-	UpdatedAt time.Time      `json:"updatedAt"`
-	Votes     []Vote         `gorm:"foreignKey:PostID" json:"-"` // Has-many relationship
+	ID             uint           `gorm:"primarykey" json:"id"`
+	Content        string         `gorm:"not null" json:"content"`
+	BoardID        uint           `gorm:"not null;default:1;index:idx_posts_board_feed,priority:1;index:idx_posts_board_trending,priority:1;index:idx_posts_board_active,priority:1" json:"boardId"`
+	Lang           string         `gorm:"size:8;not null;default:und" json:"lang"`                     // ISO 639-1 code from lang.Detect, or "und"
+	ContentWarning string         `gorm:"size:32;not null;default:''" json:"contentWarning,omitempty"` // contentwarning.Warning, empty for none
+	Score          int            `gorm:"not null;default:0;index:idx_posts_trending,priority:2,sort:desc;index:idx_posts_board_trending,priority:3,sort:desc" json:"score"`
+	ShadowBanned   bool           `gorm:"not null;default:false;index" json:"-"` // Visible only to its shadow-banned author
+	ShadowBanID    *uint          `gorm:"index" json:"-"`                        // Ban that caused ShadowBanned
+	CreatedAt      time.Time      `gorm:"index:idx_posts_created;index:idx_posts_feed,priority:2,sort:desc;index:idx_posts_trending,priority:3,sort:desc;index:idx_posts_author_created,priority:2;index:idx_posts_fingerprint_band0,priority:2;index:idx_posts_fingerprint_band1,priority:2;index:idx_posts_fingerprint_band2,priority:2;index:idx_posts_fingerprint_band3,priority:2;index:idx_posts_fingerprint_band4,priority:2;index:idx_posts_fingerprint_band5,priority:2;index:idx_posts_board_feed,priority:3,sort:desc;index:idx_posts_board_trending,priority:4,sort:desc" json:"createdAt"`
+	UpdatedAt      time.Time      `json:"updatedAt"`
+	LastActivityAt time.Time      `gorm:"index:idx_posts_board_active,priority:3,sort:desc" json:"lastActivityAt"`                                                                                                                              // CreatedAt, or when it was last voted on
+	HiddenAt       gorm.DeletedAt `gorm:"index:idx_posts_feed,priority:1;index:idx_posts_trending,priority:1;index:idx_posts_board_feed,priority:2;index:idx_posts_board_trending,priority:2;index:idx_posts_board_active,priority:2" json:"-"` // Soft delete: queries skip hidden posts unless Unscoped
+	HiddenBy       string         `gorm:"size:64" json:"-"`                                                                                                                                                                                     // Fingerprint of the hiding moderator's token
+	AuthorHash     *string        `gorm:"size:64;index:idx_posts_author_created,priority:1" json:"-"`                                                                                                                                           // Keyed hash of the client that posted it, like Vote.VoterHash
+	NotifiedAt     *time.Time     `json:"-"`                                                                                                                                                                                                    // When push subscribers were told it's trending; nil if never
+	KarmaAt        *time.Time     `gorm:"index" json:"-"`                                                                                                                                                                                       // When the karma job settled it; nil until then
+	ArchivedAt     *time.Time     `gorm:"index" json:"archivedAt,omitempty"`                                                                                                                                                                    // When the archive job took it out of the feeds; nil while it's in them
+	CreatorHash    *string        `gorm:"size:64;index" json:"-"`                                                                                                                                                                               // Salted hash of the creating IP, for moderators only; nil unless CREATOR_HASH is on
+	Votes          []Vote         `gorm:"foreignKey:PostID" json:"-"`                                                                                                                                                                           // Has-many relationship
+	VotesLastHour  *int64         `gorm:"-" json:"votesLastHour,omitempty"`                                                                                                                                                                     // Set only on trending feeds; see store.VelocityWindow
+
+	// SimHash of Content for finding near-duplicates, nil for posts too
+	// short to fingerprint. The bands are its six parts, each
+	// indexed with CreatedAt; see package simhash.
+	Fingerprint *int64 `json:"-"`
+	Band0       *int32 `gorm:"column:fingerprint_band0;index:idx_posts_fingerprint_band0,priority:1" json:"-"`
+	Band1       *int32 `gorm:"column:fingerprint_band1;index:idx_posts_fingerprint_band1,priority:1" json:"-"`
+	Band2       *int32 `gorm:"column:fingerprint_band2;index:idx_posts_fingerprint_band2,priority:1" json:"-"`
+	Band3       *int32 `gorm:"column:fingerprint_band3;index:idx_posts_fingerprint_band3,priority:1" json:"-"`
+	Band4       *int32 `gorm:"column:fingerprint_band4;index:idx_posts_fingerprint_band4,priority:1" json:"-"`
+	Band5       *int32 `gorm:"column:fingerprint_band5;index:idx_posts_fingerprint_band5,priority:1" json:"-"`
+}
+
+// The default board is created by the boards migration and holds every
+// post made through the routes that don't name a board.
+const (
+	DefaultBoardID   = 1
+	DefaultBoardSlug = "general"
+)
+
+// Board is a community with a feed of its own, such as one campus.
+type Board struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Slug      string    `gorm:"size:64;not null;uniqueIndex" json:"slug"` // Lowercase letters, digits and hyphens; used in URLs
+	Name      string    `gorm:"size:100;not null" json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
 }
 
 // Vote represents a +1 or -1 vote on a Post.
 type Vote struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
-	PostID    uint           `gorm:"not null;index" json:"postId"`
-	Value     int            `gorm:"not null" json:"value"` // Should be +1 or -1
-	CreatedAt time.Time      `json:"createdAt"`
+	PostID    uint           `gorm:"not null;index;index:idx_votes_post_created,priority:1;uniqueIndex:idx_votes_post_voter,priority:1" json:"postId"`
+	VoterHash *string        `gorm:"size:64;uniqueIndex:idx_votes_post_voter,priority:2" json:"-"` // Keyed hash of the client; one vote per post each
+	Value     int            `gorm:"not null" json:"value"`                                        // Should be +1 or -1
+	VoteID    *string        `gorm:"size:36;uniqueIndex:idx_votes_vote_id" json:"-"`               // Client-generated UUID that makes retries safe; cleared after VoteIDTTL
+	CreatedAt time.Time      `gorm:"index:idx_votes_created;index:idx_votes_post_created,priority:2" json:"createdAt"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
-}
\ No newline at end of file
+}
+
+// VoteIDTTL is how long a Vote's VoteID is kept to recognize a retry.
+const VoteIDTTL = 24 * time.Hour
+
+// AuditLog records a single moderation action taken through the admin API.
+type AuditLog struct {
+	ID                    uint           `gorm:"primarykey" json:"id"`
+	Action                string         `gorm:"not null;index" json:"action"`
+	TargetType            string         `gorm:"not null;index:idx_audit_target" json:"targetType"`
+	TargetID              uint           `gorm:"index:idx_audit_target" json:"targetId"`
+	ActorTokenFingerprint string         `gorm:"not null" json:"actorTokenFingerprint"` // Hash of the admin token, never the token itself
+	Metadata              map[string]any `gorm:"type:text;serializer:json" json:"metadata,omitempty"`
+	CreatedAt             time.Time      `gorm:"index" json:"createdAt"`
+}
+
+// BannedIP blocks a single address or a CIDR range from posting and voting.
+type BannedIP struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	CIDR      string     `gorm:"size:64;not null;uniqueIndex" json:"cidr"` // Single IPs are stored as /32 or /128; sized so MySQL can index it
+	Reason    string     `json:"reason"`
+	Shadow    bool       `gorm:"not null;default:false" json:"shadow"` // Shadow bans accept posts but hide them from everyone else
+	ExpiresAt *time.Time `gorm:"index" json:"expiresAt"`               // Nil means the ban never expires
+	CreatedAt time.Time  `json:"createdAt"`
+}
+
+// PushSubscription is a browser that asked for Web Push notifications of
+// trending posts. Endpoint is the push service URL the browser handed out;
+// P256dh and Auth are its keys for encrypting messages to it.
+type PushSubscription struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Endpoint  string    `gorm:"size:512;not null;uniqueIndex" json:"-"` // Sized so MySQL can index it
+	P256dh    string    `gorm:"size:128;not null" json:"-"`
+	Auth      string    `gorm:"size:64;not null" json:"-"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Bookmark is a post a client saved for later. Clients are identified
+// like voters, by a keyed hash of their IP, and only see their own.
+type Bookmark struct {
+	ID         uint      `gorm:"primarykey" json:"-"`
+	PostID     uint      `gorm:"not null;index;uniqueIndex:idx_bookmarks_client_post,priority:2" json:"postId"`
+	ClientHash string    `gorm:"size:64;not null;uniqueIndex:idx_bookmarks_client_post,priority:1" json:"-"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// ScoreSnapshot is a post's score at TakenAt, the start of a snapshot
+// interval. There is at most one per post and interval.
+type ScoreSnapshot struct {
+	ID      uint      `gorm:"primarykey" json:"-"`
+	PostID  uint      `gorm:"not null;uniqueIndex:idx_score_snapshots_post_taken,priority:1" json:"-"`
+	Score   int       `gorm:"not null" json:"score"`
+	TakenAt time.Time `gorm:"not null;index;uniqueIndex:idx_score_snapshots_post_taken,priority:2" json:"takenAt"`
+}
+
+// Announcement is a moderator banner pushed to every client until it expires.
+// Only the most recently created unexpired announcement is active.
+type Announcement struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Message   string    `gorm:"not null" json:"message"`
+	Level     string    `gorm:"not null;default:info" json:"level"` // info, warning or critical
+	ExpiresAt time.Time `gorm:"not null;index" json:"expiresAt"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Identity is the running tally of an anonymous client, keyed like
+// Vote.VoterHash. It is never joined to posts in responses: only the
+// client itself can read its own through GET /api/me.
+type Identity struct {
+	ID         uint      `gorm:"primarykey" json:"-"`
+	ClientHash string    `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	Karma      int       `gorm:"not null;default:0" json:"karma"`      // One point per post that settled with net upvotes
+	Streak     int       `gorm:"not null;default:0" json:"streak"`     // Consecutive UTC days posted, ending LastPostOn
+	BestStreak int       `gorm:"not null;default:0" json:"bestStreak"` // Longest Streak so far
+	LastPostOn time.Time `gorm:"not null" json:"-"`                    // UTC midnight of the last day posted
+	// Moderation strikes: posts of this client hidden by moderators, and
+	// the posting cooldown they escalated to; see package strikes.
+	Strikes         int        `gorm:"not null;default:0" json:"-"` // Strikes since StrikesSince
+	StrikesSince    *time.Time `json:"-"`                           // First strike of the current window; nil before any
+	CooldownSeconds int        `gorm:"not null;default:0" json:"-"` // Minimum time between posts until CooldownUntil
+	CooldownUntil   *time.Time `gorm:"index" json:"-"`
+	CreatedAt       time.Time  `json:"-"`
+	UpdatedAt       time.Time  `json:"-"`
+}
+
+// APIKey lets a bot or integration call the public API under a rate limit
+// of its own instead of its IP's. Only a hash of the key is stored.
+type APIKey struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	Label     string     `gorm:"size:100;not null" json:"label"`
+	Scope     string     `gorm:"size:16;not null" json:"scope"`  // auth.Scope; only "read" so far
+	Prefix    string     `gorm:"size:16;not null" json:"prefix"` // Start of the key, to tell keys apart
+	KeyHash   string     `gorm:"size:64;not null;uniqueIndex" json:"-"`
+	RevokedAt *time.Time `json:"revokedAt"` // Nil while the key works
+	CreatedAt time.Time  `json:"createdAt"`
+}
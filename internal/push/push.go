@@ -0,0 +1,343 @@
+// Package push sends Web Push notifications to subscribed browsers when a
+// post starts trending. Notifications are queued without blocking the
+// caller; background workers encrypt one message per subscription, send
+// it to the browser's push service with retries, and delete
+// subscriptions the push service reports gone.
+package push
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	webpush "github.com/SherClockHolmes/webpush-go"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/sujalbistaa/whispr/internal/metrics"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/reporting"
+)
+
+// DefaultHosts are the push services of Chrome, Firefox, Safari and Edge.
+// Subscriptions must point at one of them, so the server can't be made
+// to send requests anywhere else.
+var DefaultHosts = []string{"fcm.googleapis.com", "push.services.mozilla.com", "push.apple.com", "notify.windows.com"}
+
+// Config configures a Dispatcher.
+type Config struct {
+	PublicKey    string        // VAPID public key, URL-safe base64, handed to browsers
+	PrivateKey   string        // VAPID private key, URL-safe base64
+	Subject      string        // mailto: or https: contact for push service operators
+	Threshold    int           // Score at which a post is announced
+	AllowedHosts []string      // Push services subscriptions may use, with their subdomains
+	QueueSize    int           // Pending notifications before new ones are dropped
+	MaxAttempts  int           // Tries per subscription before giving up
+	Timeout      time.Duration // Per-request timeout
+	TTL          time.Duration // How long a push service holds a message for an offline browser
+}
+
+// Enabled reports whether VAPID keys are configured.
+func (c Config) Enabled() bool {
+	return c.PublicKey != "" && c.PrivateKey != ""
+}
+
+// AllowsEndpoint reports whether raw is an https URL on an allowed push
+// service.
+func (c Config) AllowsEndpoint(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" || u.User != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range c.AllowedHosts {
+		if host == allowed || strings.HasSuffix(host, "."+allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidKeys reports whether p256dh and auth, as a browser encodes them,
+// are a P-256 public key and a 16-byte secret.
+func ValidKeys(p256dh, auth string) bool {
+	key, err := decodeKey(p256dh)
+	if err != nil || len(key) != 65 || key[0] != 4 {
+		return false
+	}
+	secret, err := decodeKey(auth)
+	return err == nil && len(secret) == 16
+}
+
+// decodeKey decodes base64 with or without padding, URL-safe or not, as
+// different browsers and libraries produce.
+func decodeKey(s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+	if b, err := base64.RawURLEncoding.DecodeString(s); err == nil {
+		return b, nil
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+// Notification is the JSON message the service worker receives.
+type Notification struct {
+	PostID uint   `json:"postId"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+	URL    string `json:"url"` // Opened when the notification is clicked
+}
+
+const (
+	workers    = 4
+	batchSize  = 500
+	maxBackoff = time.Minute
+)
+
+type job struct {
+	sub  models.PushSubscription
+	body []byte
+}
+
+// Dispatcher queues notifications and sends them in the background.
+// A nil *Dispatcher is valid and drops everything, so callers don't need
+// to check whether push is configured.
+type Dispatcher struct {
+	cfg      Config
+	db       *gorm.DB
+	client   *http.Client
+	queue    chan Notification
+	jobs     chan job
+	reporter reporting.ErrorReporter
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	dropped atomic.Uint64
+}
+
+// New starts a dispatcher for cfg sending to the subscriptions in
+// database, or returns nil when no VAPID keys are configured. A panic
+// while sending goes to reporter and drops only that message. Call Close
+// on shutdown.
+func New(cfg Config, database *gorm.DB, reporter reporting.ErrorReporter) *Dispatcher {
+	if !cfg.Enabled() {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		cfg:      cfg,
+		db:       database,
+		client:   &http.Client{Timeout: cfg.Timeout},
+		queue:    make(chan Notification, cfg.QueueSize),
+		jobs:     make(chan job),
+		reporter: reporter,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	d.wg.Add(1)
+	go d.fanOut()
+	for i := 0; i < workers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+	return d
+}
+
+// Notify queues n for every subscription. It never blocks: when the queue
+// is full the notification is dropped and counted.
+func (d *Dispatcher) Notify(n Notification) {
+	if d == nil {
+		return
+	}
+	select {
+	case d.queue <- n:
+	default:
+		d.dropped.Add(1)
+		metrics.PushDropped.Inc()
+		slog.Warn("push queue full, dropping notification", "post", n.PostID)
+	}
+}
+
+// Subscribe stores sub, replacing the keys of an existing subscription to
+// the same endpoint.
+func (d *Dispatcher) Subscribe(ctx context.Context, sub *models.PushSubscription) error {
+	return d.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "endpoint"}},
+		DoUpdates: clause.AssignmentColumns([]string{"p256dh", "auth"}),
+	}).Create(sub).Error
+}
+
+// Unsubscribe deletes the subscription to endpoint, if there is one.
+func (d *Dispatcher) Unsubscribe(ctx context.Context, endpoint string) error {
+	return d.db.WithContext(ctx).Where("endpoint = ?", endpoint).Delete(&models.PushSubscription{}).Error
+}
+
+// Close stops the workers. Notifications still queued or being sent are
+// abandoned and logged.
+func (d *Dispatcher) Close() {
+	if d == nil {
+		return
+	}
+	d.cancel()
+	d.wg.Wait()
+	if n := len(d.queue); n > 0 {
+		slog.Warn("abandoning queued push notifications on shutdown", "count", n)
+	}
+}
+
+// fanOut turns each notification into a job per subscription, reading
+// subscriptions in batches so a large audience isn't held in memory.
+func (d *Dispatcher) fanOut() {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case n := <-d.queue:
+			d.fanOutRecovering(n)
+		}
+	}
+}
+
+func (d *Dispatcher) fanOutRecovering(n Notification) {
+	defer reporting.Recover(d.ctx, d.reporter, "push")
+	body, err := json.Marshal(n)
+	if err != nil {
+		slog.Error("encoding push notification", "post", n.PostID, "err", err)
+		return
+	}
+	var last uint
+	for {
+		var subs []models.PushSubscription
+		if err := d.db.WithContext(d.ctx).Where("id > ?", last).Order("id").Limit(batchSize).Find(&subs).Error; err != nil {
+			if d.ctx.Err() == nil {
+				slog.Error("loading push subscriptions", "post", n.PostID, "err", err)
+			}
+			return
+		}
+		for _, sub := range subs {
+			select {
+			case <-d.ctx.Done():
+				return
+			case d.jobs <- job{sub: sub, body: body}:
+			}
+		}
+		if len(subs) < batchSize {
+			return
+		}
+		last = subs[len(subs)-1].ID
+	}
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case j := <-d.jobs:
+			d.sendRecovering(j)
+		}
+	}
+}
+
+func (d *Dispatcher) sendRecovering(j job) {
+	defer reporting.Recover(d.ctx, d.reporter, "push")
+	d.send(j)
+}
+
+// errGone means the push service no longer knows the subscription.
+var errGone = errors.New("subscription gone")
+
+// send tries j until it succeeds, fails for good, MaxAttempts is reached
+// or the dispatcher closes, backing off exponentially between tries.
+// Subscriptions the push service reports gone are deleted.
+func (d *Dispatcher) send(j job) {
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		wait, err := d.post(j)
+		switch {
+		case err == nil:
+			metrics.PushDelivered("ok")
+			return
+		case errors.Is(err, errGone):
+			metrics.PushDelivered("gone")
+			if err := d.db.WithContext(d.ctx).Delete(&models.PushSubscription{}, j.sub.ID).Error; err != nil && d.ctx.Err() == nil {
+				slog.Error("deleting expired push subscription", "subscription", j.sub.ID, "err", err)
+			}
+			return
+		case wait < 0 || attempt >= d.cfg.MaxAttempts:
+			metrics.PushDelivered("failed")
+			slog.Warn("push delivery failed", "subscription", j.sub.ID, "host", host(j.sub.Endpoint), "attempts", attempt, "err", err)
+			return
+		}
+		metrics.PushDelivered("retry")
+
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-time.After(max(backoff, wait)):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// post sends one message. On failure it returns how long the push service
+// asked to wait before retrying, or -1 when retrying won't help.
+func (d *Dispatcher) post(j job) (time.Duration, error) {
+	resp, err := webpush.SendNotificationWithContext(d.ctx, j.body, &webpush.Subscription{
+		Endpoint: j.sub.Endpoint,
+		Keys:     webpush.Keys{P256dh: j.sub.P256dh, Auth: j.sub.Auth},
+	}, &webpush.Options{
+		HTTPClient: d.client,
+		// The library adds the mailto: back.
+		Subscriber:      strings.TrimPrefix(d.cfg.Subject, "mailto:"),
+		TTL:             int(d.cfg.TTL.Seconds()),
+		Urgency:         webpush.UrgencyNormal,
+		VAPIDPublicKey:  d.cfg.PublicKey,
+		VAPIDPrivateKey: d.cfg.PrivateKey,
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode <= 299:
+		return 0, nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return 0, errGone
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		wait := time.Duration(0)
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			wait = min(time.Duration(seconds)*time.Second, maxBackoff)
+		}
+		return wait, fmt.Errorf("unexpected status %s", resp.Status)
+	default:
+		// A malformed message, a rejected VAPID key or an oversized
+		// payload fails the same way next time.
+		return -1, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+}
+
+// host returns the push service of an endpoint for logs. The rest of the
+// URL identifies the browser.
+func host(endpoint string) string {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "invalid URL"
+	}
+	return u.Host
+}
@@ -0,0 +1,78 @@
+// Package reporting sends recovered panics somewhere a person will see
+// them. The HTTP recovery middleware, the WebSocket hub and the background
+// workers all hand their panics to an ErrorReporter rather than crashing
+// the process or printing a stack nobody reads.
+package reporting
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+)
+
+// Panic is one recovered panic.
+type Panic struct {
+	Value  any
+	Stack  []byte // From debug.Stack() in the recovering goroutine
+	Source string // Where it happened: "http", "ws.hub", "retention", ...
+
+	// Set for panics in a request.
+	RequestID string
+	Method    string
+	Route     string // Registered route, e.g. /api/v1/posts/:id
+}
+
+// ErrorReporter receives recovered panics. ReportPanic is called from the
+// goroutine that panicked, while its stack is still intact, and must be
+// safe for concurrent use.
+type ErrorReporter interface {
+	ReportPanic(ctx context.Context, p Panic)
+}
+
+// Recover reports a panic in the calling goroutine to r instead of letting
+// it crash the process. Defer it directly:
+//
+//	defer reporting.Recover(ctx, r, "retention")
+func Recover(ctx context.Context, r ErrorReporter, source string) {
+	if v := recover(); v != nil {
+		r.ReportPanic(ctx, Panic{Value: v, Stack: debug.Stack(), Source: source})
+	}
+}
+
+// LogReporter logs panics with their stack. It is the default.
+type LogReporter struct {
+	Logger *slog.Logger // slog.Default() if nil
+}
+
+func (r LogReporter) ReportPanic(ctx context.Context, p Panic) {
+	logger := r.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	attrs := []any{"source", p.Source, "panic", fmt.Sprint(p.Value)}
+	if p.RequestID != "" {
+		attrs = append(attrs, "request_id", p.RequestID, "method", p.Method, "route", p.Route)
+	}
+	logger.ErrorContext(ctx, "panic recovered", append(attrs, "stack", string(p.Stack))...)
+}
+
+// Recorder keeps every panic reported to it, for tests.
+type Recorder struct {
+	mu     sync.Mutex
+	panics []Panic
+}
+
+func (r *Recorder) ReportPanic(_ context.Context, p Panic) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.panics = append(r.panics, p)
+}
+
+// Panics returns the panics reported so far, oldest first.
+func (r *Recorder) Panics() []Panic {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Panic(nil), r.panics...)
+}
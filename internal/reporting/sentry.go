@@ -0,0 +1,63 @@
+package reporting
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// SentryConfig configures reporting to Sentry.
+type SentryConfig struct {
+	DSN         string
+	Environment string // Optional, e.g. "production"
+}
+
+// Enabled reports whether panics should go to Sentry.
+func (c SentryConfig) Enabled() bool {
+	return c.DSN != ""
+}
+
+// Sentry logs panics like LogReporter and also sends them to Sentry,
+// tagged with their source and, for requests, the request ID and route.
+type Sentry struct {
+	hub *sentry.Hub
+	log LogReporter
+}
+
+// NewSentry returns a reporter for the project in cfg.DSN. Log lines go to
+// logger (slog.Default() if nil).
+func NewSentry(cfg SentryConfig, logger *slog.Logger) (*Sentry, error) {
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn:              cfg.DSN,
+		Environment:      cfg.Environment,
+		AttachStacktrace: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("creating Sentry client: %w", err)
+	}
+	return &Sentry{hub: sentry.NewHub(client, sentry.NewScope()), log: LogReporter{Logger: logger}}, nil
+}
+
+func (s *Sentry) ReportPanic(ctx context.Context, p Panic) {
+	s.log.ReportPanic(ctx, p)
+	// Still in the panicking goroutine, so the SDK's own stack trace
+	// includes the frames that panicked.
+	hub := s.hub.Clone()
+	hub.ConfigureScope(func(scope *sentry.Scope) {
+		scope.SetLevel(sentry.LevelFatal)
+		scope.SetTag("source", p.Source)
+		if p.RequestID != "" {
+			scope.SetTag("request_id", p.RequestID)
+			scope.SetTag("route", p.Method+" "+p.Route)
+		}
+	})
+	hub.RecoverWithContext(ctx, p.Value)
+}
+
+// Flush waits for queued events to be sent, until ctx is done. It reports
+// whether everything went out.
+func (s *Sentry) Flush(ctx context.Context) bool {
+	return s.hub.FlushWithContext(ctx)
+}
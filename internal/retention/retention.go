@@ -0,0 +1,234 @@
+// Package retention permanently removes data whispr no longer needs:
+// posts hidden long ago with their votes, votes left without a post,
+// long-expired bans, and the IDs of votes too old to be retried.
+package retention
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sujalbistaa/whispr/internal/metrics"
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+// Config configures a Worker.
+type Config struct {
+	Interval  time.Duration // Time between sweeps; 0 disables the worker
+	MaxAge    time.Duration // How long hidden posts and expired bans are kept
+	BatchSize int           // Rows deleted per transaction
+	DryRun    bool          // Only log what a sweep would delete
+}
+
+// Enabled reports whether the worker should run.
+func (c Config) Enabled() bool {
+	return c.Interval > 0
+}
+
+// Result counts the rows a sweep deleted, or would delete in a dry run.
+type Result struct {
+	Posts         int64 // Hidden posts
+	Votes         int64 // Votes on those posts
+	OrphanedVotes int64 // Votes whose post no longer exists
+	Bans          int64 // Expired bans
+	VoteIDs       int64 // Vote IDs older than models.VoteIDTTL, cleared
+}
+
+// Worker runs sweeps on an interval.
+type Worker struct {
+	db  *gorm.DB
+	cfg Config
+}
+
+// New returns a worker for cfg; call Run to start it.
+func New(db *gorm.DB, cfg Config) *Worker {
+	return &Worker{db: db, cfg: cfg}
+}
+
+// Run sweeps immediately and then every Interval until ctx is done. A
+// sweep in progress stops between batches; the batch in flight is rolled
+// back.
+func (w *Worker) Run(ctx context.Context) {
+	slog.Info("retention worker started", "interval", w.cfg.Interval, "maxAge", w.cfg.MaxAge, "dryRun", w.cfg.DryRun)
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		w.sweepAndLog(ctx)
+		select {
+		case <-ctx.Done():
+			slog.Info("retention worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (w *Worker) sweepAndLog(ctx context.Context) {
+	start := time.Now()
+	result, err := w.Sweep(ctx)
+	attrs := []any{
+		"posts", result.Posts, "votes", result.Votes,
+		"orphanedVotes", result.OrphanedVotes, "bans", result.Bans, "voteIds", result.VoteIDs,
+		"dryRun", w.cfg.DryRun, "duration", time.Since(start),
+	}
+	if err != nil && ctx.Err() == nil {
+		slog.Error("retention sweep failed", append(attrs, "err", err)...)
+		return
+	}
+	slog.Info("retention sweep complete", attrs...)
+}
+
+// Sweep deletes everything past retention in batches and returns what it
+// removed, including batches committed before an error.
+func (w *Worker) Sweep(ctx context.Context) (Result, error) {
+	var result Result
+	db := w.db.WithContext(ctx)
+	cutoff := time.Now().Add(-w.cfg.MaxAge)
+
+	if w.cfg.DryRun {
+		return w.count(db, cutoff)
+	}
+
+	// Hidden posts go with their votes, bookmarks and score history.
+	for {
+		var ids []uint
+		if err := db.Unscoped().Model(&models.Post{}).Where("hidden_at < ?", cutoff).
+			Order("id").Limit(w.cfg.BatchSize).Pluck("id", &ids).Error; err != nil {
+			return result, err
+		}
+		if len(ids) == 0 {
+			break
+		}
+		var votes, posts int64
+		err := db.Transaction(func(tx *gorm.DB) error {
+			res := tx.Unscoped().Where("post_id IN ?", ids).Delete(&models.Vote{})
+			if res.Error != nil {
+				return res.Error
+			}
+			votes = res.RowsAffected
+			if err := tx.Where("post_id IN ?", ids).Delete(&models.Bookmark{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("post_id IN ?", ids).Delete(&models.ScoreSnapshot{}).Error; err != nil {
+				return err
+			}
+			res = tx.Unscoped().Where("id IN ?", ids).Delete(&models.Post{})
+			posts = res.RowsAffected
+			return res.Error
+		})
+		if err != nil {
+			return result, err
+		}
+		result.Posts += posts
+		result.Votes += votes
+		metrics.RetentionPurged("posts", posts)
+		metrics.RetentionPurged("votes", votes)
+		if len(ids) < w.cfg.BatchSize {
+			break
+		}
+	}
+
+	n, err := w.deleteBatches(db, &models.Vote{}, orphanedVotes)
+	result.OrphanedVotes = n
+	metrics.RetentionPurged("orphaned_votes", n)
+	if err != nil {
+		return result, err
+	}
+
+	n, err = w.deleteBatches(db, &models.BannedIP{}, expiredBans(cutoff))
+	result.Bans = n
+	metrics.RetentionPurged("bans", n)
+	if err != nil {
+		return result, err
+	}
+
+	n, err = w.clearVoteIDs(db, time.Now().Add(-models.VoteIDTTL))
+	result.VoteIDs = n
+	metrics.RetentionPurged("vote_ids", n)
+	return result, err
+}
+
+// count reports what Sweep would delete.
+func (w *Worker) count(db *gorm.DB, cutoff time.Time) (Result, error) {
+	var result Result
+	hidden := db.Unscoped().Model(&models.Post{}).Select("id").Where("hidden_at < ?", cutoff)
+	if err := db.Unscoped().Model(&models.Post{}).Where("hidden_at < ?", cutoff).Count(&result.Posts).Error; err != nil {
+		return result, err
+	}
+	if err := db.Unscoped().Model(&models.Vote{}).Where("post_id IN (?)", hidden).Count(&result.Votes).Error; err != nil {
+		return result, err
+	}
+	if err := db.Unscoped().Model(&models.Vote{}).Scopes(orphanedVotes).Count(&result.OrphanedVotes).Error; err != nil {
+		return result, err
+	}
+	if err := db.Model(&models.BannedIP{}).Scopes(expiredBans(cutoff)).Count(&result.Bans).Error; err != nil {
+		return result, err
+	}
+	err := db.Unscoped().Model(&models.Vote{}).Scopes(expiredVoteIDs(time.Now().Add(-models.VoteIDTTL))).Count(&result.VoteIDs).Error
+	return result, err
+}
+
+// deleteBatches deletes the rows of model matching scope, a batch per
+// transaction. IDs are selected first because MySQL can't delete from a
+// table it's also selecting from with a LIMIT.
+func (w *Worker) deleteBatches(db *gorm.DB, model any, scope func(*gorm.DB) *gorm.DB) (int64, error) {
+	var total int64
+	for {
+		var ids []uint
+		if err := db.Unscoped().Model(model).Scopes(scope).Order("id").Limit(w.cfg.BatchSize).Pluck("id", &ids).Error; err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+		res := db.Unscoped().Where("id IN ?", ids).Delete(model)
+		if res.Error != nil {
+			return total, res.Error
+		}
+		total += res.RowsAffected
+		if len(ids) < w.cfg.BatchSize {
+			return total, nil
+		}
+	}
+}
+
+// clearVoteIDs removes the IDs of votes cast before cutoff, a batch per
+// statement, so retries of them are no longer recognized. The votes stay.
+func (w *Worker) clearVoteIDs(db *gorm.DB, cutoff time.Time) (int64, error) {
+	var total int64
+	for {
+		var ids []uint
+		if err := db.Unscoped().Model(&models.Vote{}).Scopes(expiredVoteIDs(cutoff)).Order("id").Limit(w.cfg.BatchSize).Pluck("id", &ids).Error; err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+		res := db.Unscoped().Model(&models.Vote{}).Where("id IN ?", ids).UpdateColumn("vote_id", nil)
+		if res.Error != nil {
+			return total, res.Error
+		}
+		total += res.RowsAffected
+		if len(ids) < w.cfg.BatchSize {
+			return total, nil
+		}
+	}
+}
+
+func orphanedVotes(tx *gorm.DB) *gorm.DB {
+	return tx.Where("NOT EXISTS (SELECT 1 FROM posts WHERE posts.id = votes.post_id)")
+}
+
+func expiredVoteIDs(cutoff time.Time) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("vote_id IS NOT NULL AND created_at < ?", cutoff)
+	}
+}
+
+func expiredBans(cutoff time.Time) func(*gorm.DB) *gorm.DB {
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where("expires_at < ?", cutoff)
+	}
+}
@@ -0,0 +1,173 @@
+package seed
+
+import (
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sujalbistaa/whispr/internal/lang"
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+// Options controls how much demo data Run generates.
+type Options struct {
+	Posts       int           // Number of posts to create
+	MaxVotes    int           // Upper bound on votes per post
+	Spread      time.Duration // Posts are backdated across this window
+	BatchSize   int           // Rows per INSERT
+	RandomSeed  uint64        // Non-zero for reproducible output
+	HiddenRatio float64       // Fraction of posts created already hidden
+	Wipe        bool          // Delete all posts and votes first
+}
+
+// DefaultOptions returns settings suitable for local development.
+func DefaultOptions() Options {
+	return Options{
+		Posts:       500,
+		MaxVotes:    40,
+		Spread:      30 * 24 * time.Hour,
+		BatchSize:   200,
+		HiddenRatio: 0.02,
+	}
+}
+
+// Result reports what Run inserted.
+type Result struct {
+	Posts int
+	Votes int
+}
+
+// Run inserts fake posts and votes. Unless opts.Wipe is set it only adds
+// rows; existing data is left alone, so it is safe (if noisy) to run
+// twice. It takes any *gorm.DB, so tests can use it to build fixtures.
+func Run(db *gorm.DB, opts Options) (Result, error) {
+	seed := opts.RandomSeed
+	if seed == 0 {
+		seed = uint64(time.Now().UnixNano())
+	}
+	rng := rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = 200
+	}
+
+	var result Result
+	now := time.Now()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if opts.Wipe {
+			if err := wipe(tx); err != nil {
+				return err
+			}
+		}
+		posts := make([]models.Post, 0, opts.Posts)
+		for i := 0; i < opts.Posts; i++ {
+			created := now.Add(-time.Duration(rng.Int64N(int64(opts.Spread) + 1)))
+			content := fakeContent(rng)
+			post := models.Post{
+				Content:        content,
+				Lang:           lang.Detect(content),
+				Score:          1,
+				Votes:          []models.Vote{{Value: 1, CreatedAt: created}}, // The author's upvote
+				CreatedAt:      created,
+				UpdatedAt:      created,
+				LastActivityAt: created,
+			}
+			if rng.Float64() < opts.HiddenRatio {
+				post.HiddenAt = gorm.DeletedAt{Time: created, Valid: true}
+			}
+			// Skew towards a few popular posts, like a real feed.
+			n := int(float64(opts.MaxVotes) * rng.Float64() * rng.Float64())
+			for j := 0; j < n; j++ {
+				value := 1
+				if rng.Float64() < 0.3 {
+					value = -1
+				}
+				voted := created.Add(time.Duration(rng.Int64N(int64(now.Sub(created)) + 1)))
+				post.Votes = append(post.Votes, models.Vote{Value: value, CreatedAt: voted})
+				post.Score += value
+				if voted.After(post.LastActivityAt) {
+					post.LastActivityAt = voted
+				}
+			}
+			posts = append(posts, post)
+		}
+		// Votes are inserted separately so their batches stay BatchSize
+		// rows too.
+		if err := tx.Omit("Votes").CreateInBatches(&posts, opts.BatchSize).Error; err != nil {
+			return err
+		}
+		result.Posts = len(posts)
+
+		var votes []models.Vote
+		for _, post := range posts {
+			for _, vote := range post.Votes {
+				vote.PostID = post.ID
+				votes = append(votes, vote)
+			}
+		}
+		if err := tx.CreateInBatches(&votes, opts.BatchSize).Error; err != nil {
+			return err
+		}
+		result.Votes = len(votes)
+		return nil
+	})
+	return result, err
+}
+
+// wipe deletes every vote and post, hidden ones included.
+func wipe(tx *gorm.DB) error {
+	all := tx.Unscoped().Session(&gorm.Session{AllowGlobalUpdate: true})
+	if err := all.Delete(&models.Vote{}).Error; err != nil {
+		return err
+	}
+	return all.Delete(&models.Post{}).Error
+}
+
+var (
+	openers = []string{
+		"Confession:", "Unpopular opinion:", "PSA:", "Does anyone else think", "Honestly,",
+		"To whoever", "Not gonna lie,", "Hot take:", "Can we talk about how", "Shoutout to",
+	}
+	subjects = []string{
+		"the library wifi", "the 8am stats lecture", "the dining hall pasta", "my roommate",
+		"the guy who plays guitar in the quad", "finals week", "the new parking rules",
+		"the campus shuttle", "the group project", "the vending machine on the third floor",
+		"the professor who never posts slides", "the gym at 6am", "the dorm fire alarm",
+	}
+	middles = []string{
+		"is somehow worse than last semester", "deserves an award", "has been living in my head rent free",
+		"needs to be studied by scientists", "is the only thing keeping me going",
+		"made me question all my life choices", "is actually underrated", "ruined my entire week",
+	}
+	// asides pad some posts out so lengths vary like real ones.
+	asides = []string{
+		"I've been thinking about this since Tuesday.",
+		"Nobody at home believes me when I tell them.",
+		"My friends keep saying I'm overreacting but I stand by it.",
+		"It's not even that deep, but it's the principle.",
+		"I brought it up in my seminar and the whole room went quiet.",
+		"Someone please tell me I'm not the only one.",
+		"Every single day this semester, without fail.",
+		"I would write a strongly worded email if I thought anyone read them.",
+	}
+	endings = []string{
+		"", "", " lol", " 😭", " Not even sorry.", " Change my mind.", " Who's with me?",
+		" Send help.", " Anyway, good luck on midterms.", " That's it, that's the post.",
+	}
+)
+
+// fakeContent assembles a campus-board style post. Most are one line; a
+// few ramble on.
+func fakeContent(rng *rand.Rand) string {
+	opener := openers[rng.IntN(len(openers))]
+	punct := "."
+	if strings.HasPrefix(opener, "Does") || strings.HasPrefix(opener, "Can") {
+		punct = "?"
+	}
+	content := opener + " " + subjects[rng.IntN(len(subjects))] + " " + middles[rng.IntN(len(middles))] + punct
+	for n := int(4 * rng.Float64() * rng.Float64()); n > 0; n-- {
+		content += " " + asides[rng.IntN(len(asides))]
+	}
+	return content + endings[rng.IntN(len(endings))]
+}
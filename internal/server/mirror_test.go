@@ -0,0 +1,91 @@
+package server_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// routeParam matches the :name and *name segments of a gin route.
+var routeParam = regexp.MustCompile(`[:*][^/]+`)
+
+// routes lists the routes a test server registers.
+func routes(t *testing.T, ts *testutil.TestServer) []gin.RouteInfo {
+	t.Helper()
+	engine, ok := ts.App.Handler().(*gin.Engine)
+	if !ok {
+		t.Fatalf("Handler is a %T, not a gin engine", ts.App.Handler())
+	}
+	return engine.Routes()
+}
+
+// TestReadOnlyMirror boots a mirror and checks it registers no write
+// routes, answering every write the primary serves with 405 read_only.
+func TestReadOnlyMirror(t *testing.T) {
+	primary := testutil.NewTestServer(t)
+	t.Setenv("READ_ONLY", "true")
+	t.Setenv("PRIMARY_URL", "https://whispr.example")
+	mirror := testutil.NewTestServer(t)
+
+	for _, route := range routes(t, mirror) {
+		switch {
+		case route.Method == http.MethodGet || route.Method == http.MethodHead:
+		// Browsers report CSP violations to whichever host served the page;
+		// collecting them writes nothing.
+		case route.Method == http.MethodPost && strings.HasSuffix(route.Path, "/csp-report"):
+		// pprof takes symbol lookups by POST too.
+		case route.Path == "/debug/pprof/symbol":
+		default:
+			t.Errorf("mirror registers %s %s", route.Method, route.Path)
+		}
+	}
+
+	writes := 0
+	for _, route := range routes(t, primary) {
+		switch route.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			continue
+		}
+		if strings.HasSuffix(route.Path, "/csp-report") || route.Path == "/debug/pprof/symbol" {
+			continue
+		}
+		writes++
+		path := routeParam.ReplaceAllString(route.Path, "1")
+		req := mirror.AdminRequest(t, route.Method, path, map[string]any{})
+		resp, err := mirror.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var body struct {
+			Error struct {
+				Code    string `json:"code"`
+				Details struct {
+					Primary string `json:"primary"`
+				} `json:"details"`
+			} `json:"error"`
+		}
+		json.NewDecoder(resp.Body).Decode(&body)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusMethodNotAllowed || body.Error.Code != "read_only" || body.Error.Details.Primary != "https://whispr.example" {
+			t.Errorf("mirror %s %s: %d %+v, want 405 read_only pointing at the primary", route.Method, path, resp.StatusCode, body.Error)
+		}
+		if got := resp.Header.Get("Allow"); got != "GET, HEAD, OPTIONS" {
+			t.Errorf("mirror %s %s: Allow %q", route.Method, path, got)
+		}
+	}
+	if writes < 20 {
+		t.Errorf("the primary has only %d write routes; is it a mirror too?", writes)
+	}
+
+	// Reads and realtime still work.
+	if status, body := mirror.Do(t, mirror.NewRequest(t, http.MethodGet, "/api/v1/posts", nil)); status != http.StatusOK {
+		t.Errorf("mirror GET /api/v1/posts: %d %s", status, body)
+	}
+	mirror.DialWS(t)
+}
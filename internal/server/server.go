@@ -0,0 +1,483 @@
+// Package server assembles a complete whispr server: database, WebSocket
+// hub, routes and background workers. cmd/server is a thin wrapper around
+// it; other programs can embed it, and tests can run a full server against
+// an in-memory database.
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/acme/autocert"
+	"gorm.io/gorm"
+	gormtracing "gorm.io/plugin/opentelemetry/tracing"
+
+	"github.com/sujalbistaa/whispr/internal/archive"
+	"github.com/sujalbistaa/whispr/internal/auth"
+	"github.com/sujalbistaa/whispr/internal/config"
+	"github.com/sujalbistaa/whispr/internal/db"
+	"github.com/sujalbistaa/whispr/internal/history"
+	routes "github.com/sujalbistaa/whispr/internal/http"
+	"github.com/sujalbistaa/whispr/internal/karma"
+	"github.com/sujalbistaa/whispr/internal/metrics"
+	"github.com/sujalbistaa/whispr/internal/reporting"
+	"github.com/sujalbistaa/whispr/internal/retention"
+	"github.com/sujalbistaa/whispr/internal/supervisor"
+	"github.com/sujalbistaa/whispr/internal/tracing"
+	"github.com/sujalbistaa/whispr/internal/ws"
+)
+
+// shutdownTimeout bounds the graceful shutdown Run starts when its
+// context is canceled.
+const shutdownTimeout = 5 * time.Second
+
+// Server is a configured whispr server. Create one with New, then either
+// call Run, or serve Handler yourself and call Shutdown when done.
+type Server struct {
+	cfg      config.Config
+	db       *gorm.DB
+	ownDB    bool // Opened by New, so closed by Shutdown
+	hub      *ws.Hub
+	bcast    routes.Broadcaster // nil means straight to hub
+	logger   *slog.Logger
+	reporter reporting.ErrorReporter
+	sup      *supervisor.Supervisor
+	tokens   *auth.TokenStore
+	env      *routes.Env
+
+	srv         *http.Server
+	adminSrv    *http.Server       // nil without ADMIN_ADDR
+	abort       context.CancelFunc // Cancels in-flight requests, and their queries
+	metricsSrv  *http.Server       // nil without METRICS_ADDR
+	redirectSrv *http.Server       // nil without TLS or TLS_REDIRECT_ADDR
+
+	stopTracing func(context.Context) error // nil with tracing off
+
+	stopWorkers context.CancelFunc
+	workers     sync.WaitGroup
+
+	shutdownOnce sync.Once
+	shutdownErr  error
+	done         chan struct{} // Closed once Shutdown has finished
+}
+
+// Option customizes a Server built by New.
+type Option func(*Server)
+
+// WithDB uses database instead of connecting to cfg.DatabaseURL. The
+// caller owns it: Shutdown leaves it open.
+func WithDB(database *gorm.DB) Option {
+	return func(s *Server) { s.db = database }
+}
+
+// WithHub serves /ws from hub instead of a new ws.Hub. The caller runs it.
+func WithHub(hub *ws.Hub) Option {
+	return func(s *Server) { s.hub = hub }
+}
+
+// WithBroadcaster sends the handlers' WebSocket messages through b instead
+// of straight to the hub, e.g. to fan them out across replicas. b is
+// responsible for reaching this server's own clients too, typically via a
+// hub passed with WithHub.
+func WithBroadcaster(b routes.Broadcaster) Option {
+	return func(s *Server) { s.bcast = b }
+}
+
+// WithLogger sends the server's log lines, including one per request, to
+// logger instead of slog.Default().
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Server) { s.logger = logger }
+}
+
+// WithReporter sends recovered panics, from handlers, the hub New creates
+// and the background workers, to r instead of the log (or Sentry, with
+// SENTRY_DSN set).
+func WithReporter(r reporting.ErrorReporter) Option {
+	return func(s *Server) { s.reporter = r }
+}
+
+// New builds a server from cfg. It connects to the database (unless WithDB
+// is given), runs migrations when cfg.MigrateOnStart is set and the server
+// isn't a read-only mirror, loads the admin tokens and registers the
+// routes. Nothing listens until Run.
+func New(cfg config.Config, opts ...Option) (*Server, error) {
+	s := &Server{cfg: cfg, done: make(chan struct{})}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.logger == nil {
+		s.logger = slog.Default()
+	}
+	if s.reporter == nil {
+		if cfg.Sentry.Enabled() {
+			sentry, err := reporting.NewSentry(cfg.Sentry, s.logger)
+			if err != nil {
+				return nil, err
+			}
+			s.reporter = sentry
+		} else {
+			s.reporter = reporting.LogReporter{Logger: s.logger}
+		}
+	}
+	s.sup = supervisor.New(supervisor.DefaultConfig, s.reporter)
+
+	if s.db == nil {
+		database, err := db.Init(cfg.DatabaseURL, cfg.DB)
+		if err != nil {
+			return nil, fmt.Errorf("initializing database: %w", err)
+		}
+		s.db, s.ownDB = database, true
+	}
+	if cfg.Tracing.Enabled() {
+		stop, err := tracing.Setup(context.Background(), cfg.Tracing)
+		if err != nil {
+			s.closeDB()
+			return nil, fmt.Errorf("setting up tracing: %w", err)
+		}
+		s.stopTracing = stop
+		// Registers callbacks on the connection, so a WithDB one is traced too
+		if err := s.db.Use(gormtracing.NewPlugin(gormtracing.WithoutMetrics())); err != nil {
+			s.closeDB()
+			return nil, fmt.Errorf("tracing database queries: %w", err)
+		}
+		s.logger.Info("tracing enabled", "endpoint", cfg.Tracing.Endpoint, "service", cfg.Tracing.ServiceName)
+	}
+	// Migrations normally run as a deploy step ("server migrate"); until
+	// they do, /readyz reports the schema as behind. A mirror's replica
+	// gets them from the primary.
+	if cfg.MigrateOnStart && cfg.Mirror.ReadOnly {
+		s.logger.Warn("READ_ONLY is set; not running migrations despite MIGRATE_ON_START")
+	} else if cfg.MigrateOnStart {
+		s.logger.Info("running database migrations")
+		if err := db.Migrate(s.db); err != nil {
+			s.closeDB()
+			return nil, fmt.Errorf("running migrations: %w", err)
+		}
+		s.logger.Info("migrations complete", "version", db.LatestVersion())
+	} else if version, err := db.Version(s.db); err != nil {
+		s.logger.Error("reading schema version", "err", err)
+	} else if version < db.LatestVersion() {
+		s.logger.Warn("database schema is behind; run \"server migrate\" or set MIGRATE_ON_START=true",
+			"version", version, "want", db.LatestVersion())
+	}
+
+	if s.hub == nil {
+		hub := ws.NewHub()
+		hub.Reporter = s.reporter
+		hub.Supervisor = s.sup
+		go hub.Run()
+		s.hub = hub
+	}
+
+	tokens, err := auth.NewTokenStore(cfg.Admin)
+	if err != nil {
+		s.closeDB()
+		return nil, fmt.Errorf("loading admin tokens: %w", err)
+	}
+	s.tokens = tokens
+
+	// Logging and recovery are added in SetupRoutes
+	router := gin.New()
+	var adminRouter *gin.Engine
+	if cfg.AdminAddr != "" {
+		adminRouter = gin.New()
+	}
+	s.env = routes.SetupRoutes(router, cfg, routes.Deps{
+		DB:          s.db,
+		Hub:         s.hub,
+		Broadcaster: s.bcast,
+		Tokens:      tokens,
+		Logger:      s.logger,
+		Reporter:    s.reporter,
+		Supervisor:  s.sup,
+		AdminRouter: adminRouter,
+	})
+
+	// Requests derive their contexts from base, so a shutdown that runs
+	// out of time can cancel their queries.
+	base, abort := context.WithCancel(context.Background())
+	s.abort = abort
+	s.srv = &http.Server{
+		Addr:        ":" + cfg.Port,
+		Handler:     router,
+		BaseContext: func(net.Listener) context.Context { return base },
+	}
+	cfg.Server.Apply(s.srv)
+	s.setupTLS()
+
+	// The admin API, /metrics and /debug on a listener of their own, e.g.
+	// on a private interface. It shares the main one's base context so a
+	// late shutdown cancels both.
+	if adminRouter != nil {
+		s.adminSrv = &http.Server{
+			Addr:        cfg.AdminAddr,
+			Handler:     adminRouter,
+			BaseContext: s.srv.BaseContext,
+		}
+		cfg.Server.Apply(s.adminSrv)
+	}
+
+	// Optionally serve /metrics on its own listener, e.g. a private port
+	if addr := cfg.MetricsAddr; addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		s.metricsSrv = &http.Server{Addr: addr, Handler: mux}
+	}
+	return s, nil
+}
+
+// setupTLS prepares HTTPS on PORT when configured: a static certificate
+// is loaded by Run, autocert fetches them on demand. The plain-HTTP
+// listener redirects to HTTPS and answers autocert's HTTP-01 challenges.
+func (s *Server) setupTLS() {
+	tlsCfg := s.cfg.TLS
+	if !tlsCfg.Enabled() {
+		return
+	}
+	var redirect http.Handler = http.HandlerFunc(s.redirectToHTTPS)
+	if tlsCfg.Autocert() {
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(tlsCfg.AutocertDomains...),
+			Cache:      autocert.DirCache(tlsCfg.AutocertCacheDir),
+			Email:      tlsCfg.AutocertEmail,
+		}
+		s.srv.TLSConfig = manager.TLSConfig()
+		redirect = manager.HTTPHandler(redirect)
+	}
+	if tlsCfg.RedirectAddr != "" {
+		s.redirectSrv = &http.Server{
+			Addr:              tlsCfg.RedirectAddr,
+			Handler:           redirect,
+			ReadHeaderTimeout: s.cfg.Server.ReadHeaderTimeout,
+			IdleTimeout:       s.cfg.Server.IdleTimeout,
+		}
+	}
+}
+
+// redirectToHTTPS sends a plain-HTTP request to the same URL on PORT.
+func (s *Server) redirectToHTTPS(w http.ResponseWriter, r *http.Request) {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	if s.cfg.Port != "443" {
+		host = net.JoinHostPort(host, s.cfg.Port)
+	}
+	http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+}
+
+// Handler returns the server's routes, for serving on a listener of your
+// own or from httptest.
+func (s *Server) Handler() http.Handler {
+	return s.srv.Handler
+}
+
+// AdminHandler returns the routes served on ADMIN_ADDR, or nil when it
+// isn't set and Handler serves them.
+func (s *Server) AdminHandler() http.Handler {
+	if s.adminSrv == nil {
+		return nil
+	}
+	return s.adminSrv.Handler
+}
+
+// DB returns the server's database connection.
+func (s *Server) DB() *gorm.DB {
+	return s.db
+}
+
+// DBHealth returns the database health monitor, or nil when
+// DB_HEALTH_INTERVAL is 0. Run runs it; tests can call Check themselves.
+func (s *Server) DBHealth() *db.Health {
+	return s.env.DBHealth
+}
+
+// ReloadTokens re-reads the admin tokens, as on SIGHUP.
+func (s *Server) ReloadTokens() error {
+	if err := s.tokens.Reload(); err != nil {
+		return err
+	}
+	s.logger.Info("admin tokens reloaded", "count", s.tokens.Len())
+	return nil
+}
+
+// Run starts the background workers and listens on PORT, over HTTPS when
+// TLS is configured, plus ADMIN_ADDR, METRICS_ADDR and the TLS redirect
+// listener if set. It blocks until ctx is canceled, then shuts down gracefully, or
+// until Shutdown is called elsewhere. A listener that fails to start
+// shuts the server down and its error is returned.
+func (s *Server) Run(ctx context.Context) error {
+	s.startWorkers()
+
+	errs := make(chan error, 4)
+	if s.redirectSrv != nil {
+		go func() {
+			s.logger.Info("HTTP redirect listening", "addr", s.redirectSrv.Addr)
+			if err := s.redirectSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errs <- fmt.Errorf("redirect listen: %w", err)
+			}
+		}()
+	}
+	if s.adminSrv != nil {
+		go func() {
+			s.logger.Info("admin listening", "addr", s.adminSrv.Addr)
+			if err := s.adminSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errs <- fmt.Errorf("admin listen: %w", err)
+			}
+		}()
+	}
+	if s.metricsSrv != nil {
+		go func() {
+			s.logger.Info("metrics listening", "addr", s.metricsSrv.Addr)
+			if err := s.metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errs <- fmt.Errorf("metrics listen: %w", err)
+			}
+		}()
+	}
+	go func() {
+		var err error
+		if s.cfg.TLS.Enabled() {
+			s.logger.Info("server listening", "port", s.cfg.Port, "tls", true)
+			// Empty paths with autocert: TLSConfig supplies the certificates
+			err = s.srv.ListenAndServeTLS(s.cfg.TLS.CertFile, s.cfg.TLS.KeyFile)
+		} else {
+			s.logger.Info("server listening", "port", s.cfg.Port)
+			err = s.srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errs <- fmt.Errorf("listen: %w", err)
+		}
+	}()
+
+	select {
+	case err := <-errs:
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		s.Shutdown(shutdownCtx)
+		return err
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return s.Shutdown(shutdownCtx)
+}
+
+// startWorkers runs the background jobs until Shutdown, each restarted
+// after a panic. A read-only mirror skips those that write to the
+// database.
+func (s *Server) startWorkers() {
+	workerCtx, stop := context.WithCancel(context.Background())
+	s.stopWorkers = stop
+	start := func(name string, run func(context.Context)) {
+		s.workers.Add(1)
+		go func() {
+			defer s.workers.Done()
+			s.sup.Run(workerCtx, name, run)
+		}()
+	}
+	writes := !s.cfg.Mirror.ReadOnly
+	if writes && s.cfg.Retention.Enabled() {
+		start("retention", retention.New(s.db, s.cfg.Retention).Run)
+	}
+	if writes && s.cfg.History.Enabled() {
+		start("history", history.New(s.db, s.cfg.History).Run)
+	}
+	if writes && s.cfg.Karma.Enabled() {
+		start("karma", karma.New(s.db, s.cfg.Karma).Run)
+	}
+	if writes && s.cfg.Archive.Enabled() {
+		start("archive", archive.New(s.db, s.cfg.Archive).Run)
+	}
+	if s.env.DBHealth != nil {
+		start("db-health", s.env.DBHealth.Run)
+	}
+}
+
+// Shutdown stops the server gracefully: readiness fails first, then after
+// SHUTDOWN_DRAIN_DELAY the listeners stop and in-flight requests finish
+// within ctx. Background workers are stopped, a database opened by New is
+// closed and pending traces and panic reports are flushed. Later calls wait for the first and return its result.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.shutdownOnce.Do(func() {
+		defer close(s.done)
+		s.logger.Info("shutting down server")
+
+		// Fail readiness first so load balancers stop sending new traffic,
+		// optionally waiting for them to notice before we stop accepting.
+		s.env.SetShuttingDown()
+		if delay := s.cfg.ShutdownDrainDelay; delay > 0 {
+			s.logger.Info("draining before shutdown", "delay", delay)
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+			}
+		}
+
+		// The admin listener drains alongside the main one.
+		var admin sync.WaitGroup
+		if s.adminSrv != nil {
+			admin.Add(1)
+			go func() {
+				defer admin.Done()
+				if err := s.adminSrv.Shutdown(ctx); err != nil {
+					s.logger.Error("admin server forced to shutdown", "err", err)
+				}
+			}()
+		}
+		if err := s.srv.Shutdown(ctx); err != nil {
+			s.shutdownErr = fmt.Errorf("server forced to shutdown: %w", err)
+		}
+		admin.Wait()
+		// Requests still running are out of time.
+		s.abort()
+		if s.metricsSrv != nil {
+			if err := s.metricsSrv.Shutdown(ctx); err != nil {
+				s.logger.Error("metrics server forced to shutdown", "err", err)
+			}
+		}
+		if s.redirectSrv != nil {
+			if err := s.redirectSrv.Shutdown(ctx); err != nil {
+				s.logger.Error("redirect server forced to shutdown", "err", err)
+			}
+		}
+		if s.stopWorkers != nil {
+			s.stopWorkers()
+		}
+		s.workers.Wait()
+		s.env.Close()
+		s.closeDB()
+		if f, ok := s.reporter.(interface{ Flush(context.Context) bool }); ok && !f.Flush(ctx) {
+			s.logger.Warn("not every panic report was sent before shutdown")
+		}
+		if s.stopTracing != nil {
+			if err := s.stopTracing(ctx); err != nil {
+				s.logger.Error("flushing traces", "err", err)
+			}
+		}
+
+		s.logger.Info("server exiting")
+	})
+	<-s.done
+	return s.shutdownErr
+}
+
+// closeDB closes the database if New opened it.
+func (s *Server) closeDB() {
+	if !s.ownDB {
+		return
+	}
+	if sqlDB, err := s.db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
@@ -0,0 +1,348 @@
+package server_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/sujalbistaa/whispr/internal/config"
+	"github.com/sujalbistaa/whispr/internal/server"
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// selfSigned writes a certificate for 127.0.0.1 and its key to dir and
+// returns their paths and a pool trusting the certificate.
+func selfSigned(t *testing.T, dir string) (certFile, keyFile string, pool *x509.CertPool) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "whispr test"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool = x509.NewCertPool()
+	pool.AddCert(cert)
+	return certFile, keyFile, pool
+}
+
+// freePort returns a port nothing is listening on right now.
+func freePort(t *testing.T) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	return ln.Addr().(*net.TCPAddr).Port
+}
+
+// waitListening waits for addr to accept connections.
+func waitListening(t *testing.T, addr string) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		conn, err := net.Dial("tcp", addr)
+		if err == nil {
+			conn.Close()
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s never started listening: %v", addr, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTLS(t *testing.T) {
+	database := testutil.NewDB(t)
+	certFile, keyFile, pool := selfSigned(t, t.TempDir())
+	port, redirectPort := freePort(t), freePort(t)
+	t.Setenv("PORT", strconv.Itoa(port))
+	t.Setenv("TLS_CERT_FILE", certFile)
+	t.Setenv("TLS_KEY_FILE", keyFile)
+	t.Setenv("TLS_REDIRECT_ADDR", "127.0.0.1:"+strconv.Itoa(redirectPort))
+	t.Setenv("X_ADMIN_TOKEN", testutil.AdminToken)
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, err := server.New(cfg, server.WithDB(database))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stopped := make(chan error, 1)
+	go func() { stopped <- app.Run(ctx) }()
+	addr := "127.0.0.1:" + strconv.Itoa(port)
+	redirectAddr := "127.0.0.1:" + strconv.Itoa(redirectPort)
+	waitListening(t, addr)
+	waitListening(t, redirectAddr)
+
+	tlsConfig := &tls.Config{RootCAs: pool}
+	hc := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	base := "https://" + addr
+
+	// Plain HTTP on the redirect listener is sent to HTTPS on PORT.
+	resp, err := hc.Get("http://" + redirectAddr + "/api/v1/posts?page=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if want := base + "/api/v1/posts?page=1"; resp.StatusCode != http.StatusMovedPermanently || resp.Header.Get("Location") != want {
+		t.Errorf("redirect: %d to %q, want 301 to %q", resp.StatusCode, resp.Header.Get("Location"), want)
+	}
+
+	// The WebSocket feed works over wss.
+	dialer := websocket.Dialer{TLSClientConfig: tlsConfig, HandshakeTimeout: 5 * time.Second}
+	conn, _, err := dialer.Dial("wss://"+addr+"/ws", nil)
+	if err != nil {
+		t.Fatalf("dialing wss: %v", err)
+	}
+	defer conn.Close()
+	time.Sleep(50 * time.Millisecond) // Let the hub register it
+
+	resp, err = hc.Post(base+"/api/v1/posts", "application/json", strings.NewReader(`{"content":"posted over HTTPS"}`))
+	if err != nil {
+		t.Fatalf("POST over HTTPS: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("POST over HTTPS: status %d, want 201", resp.StatusCode)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg struct {
+			Type string `json:"type"`
+		}
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("reading wss: %v", err)
+		}
+		if json.Unmarshal(data, &msg) == nil && msg.Type == "new_post" {
+			break
+		}
+	}
+
+	// Plain HTTP on PORT is refused.
+	if resp, err := http.Get("http://" + addr + "/api/v1/posts"); err == nil {
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("plain HTTP on PORT: status %d", resp.StatusCode)
+		}
+	}
+
+	// Shutting down closes both listeners.
+	cancel()
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("Run didn't return after canceling")
+	}
+	for _, a := range []string{addr, redirectAddr} {
+		if conn, err := net.Dial("tcp", a); err == nil {
+			conn.Close()
+			t.Errorf("%s still listening after shutdown", a)
+		}
+	}
+}
+
+// startServer runs a server on PORT from the environment until the test
+// ends and returns its address once it is listening.
+func startServer(t *testing.T) string {
+	t.Helper()
+	database := testutil.NewDB(t)
+	port := freePort(t)
+	t.Setenv("PORT", strconv.Itoa(port))
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	app, err := server.New(cfg, server.WithDB(database))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() { stopped <- app.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-stopped; err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	})
+	addr := "127.0.0.1:" + strconv.Itoa(port)
+	waitListening(t, addr)
+	return addr
+}
+
+func TestRequestLimits(t *testing.T) {
+	t.Setenv("HTTP_MAX_HEADER_BYTES", "1024")
+	t.Setenv("MAX_BODY_BYTES", "256")
+	t.Setenv("HTTP_WRITE_TIMEOUT", "300ms")
+	base := "http://" + startServer(t)
+
+	// Oversized headers never reach the router.
+	req, _ := http.NewRequest(http.MethodGet, base+"/api/v1/posts", nil)
+	req.Header.Set("X-Padding", strings.Repeat("x", 8<<10))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestHeaderFieldsTooLarge {
+		t.Errorf("oversized headers: status %d, want 431", resp.StatusCode)
+	}
+
+	// Oversized bodies get the error envelope.
+	body := `{"content":"` + strings.Repeat("a", 512) + `"}`
+	resp, err = http.Post(base+"/api/v1/posts", "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var apiErr struct {
+		Error struct {
+			Code string `json:"code"`
+		} `json:"error"`
+	}
+	json.NewDecoder(resp.Body).Decode(&apiErr)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestEntityTooLarge || apiErr.Error.Code != "payload_too_large" {
+		t.Errorf("oversized body: status %d, code %q; want 413 payload_too_large", resp.StatusCode, apiErr.Error.Code)
+	}
+
+	// A WebSocket outlives the write timeout.
+	conn, _, err := websocket.DefaultDialer.Dial("ws://"+strings.TrimPrefix(base, "http://")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("dialing /ws: %v", err)
+	}
+	defer conn.Close()
+	time.Sleep(600 * time.Millisecond)
+	resp, err = http.Post(base+"/api/v1/posts", "application/json", strings.NewReader(`{"content":"still listening?"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("POST: status %d, want 201", resp.StatusCode)
+	}
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg struct {
+			Type string `json:"type"`
+		}
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("WebSocket closed after the write timeout: %v", err)
+		}
+		if json.Unmarshal(data, &msg) == nil && msg.Type == "new_post" {
+			break
+		}
+	}
+}
+
+// TestEndToEnd takes a post through its life on a server built by New
+// against in-memory SQLite: created, voted on and deleted, with each step
+// seen through the REST API and the WebSocket.
+func TestEndToEnd(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	ws := ts.DialWS(t)
+
+	var post struct {
+		ID      uint   `json:"id"`
+		Content string `json:"content"`
+		Score   int    `json:"score"`
+	}
+	status, body := ts.Do(t, ts.NewRequest(t, http.MethodPost, "/api/v1/posts", map[string]string{"content": "from start to finish"}))
+	if status != http.StatusCreated {
+		t.Fatalf("POST /api/v1/posts: status %d: %s", status, body)
+	}
+	json.Unmarshal(body, &post)
+	if msg := ws.Expect(t, "new_post", 0); !strings.Contains(string(msg.Data), `"from start to finish"`) {
+		t.Errorf("new_post broadcast %s doesn't carry the post", msg.Data)
+	}
+	path := "/api/v1/posts/" + strconv.FormatUint(uint64(post.ID), 10)
+	if status, _ := ts.Do(t, ts.NewRequest(t, http.MethodGet, path, nil)); status != http.StatusOK {
+		t.Fatalf("GET %s: status %d", path, status)
+	}
+
+	status, body = ts.Do(t, ts.NewRequest(t, http.MethodPost, path+"/vote", map[string]int{"value": 1}))
+	var voted struct {
+		Score int `json:"score"`
+	}
+	json.Unmarshal(body, &voted)
+	if status != http.StatusOK || voted.Score != post.Score+1 {
+		t.Fatalf("vote: status %d, score %d; want 200, %d", status, voted.Score, post.Score+1)
+	}
+	msg := ws.Expect(t, "vote", 0)
+	json.Unmarshal(msg.Data, &voted)
+	if voted.Score != post.Score+1 {
+		t.Errorf("vote broadcast %s, want score %d", msg.Data, post.Score+1)
+	}
+
+	if status, body := ts.Do(t, ts.NewRequest(t, http.MethodDelete, path, nil)); status != http.StatusUnauthorized {
+		t.Errorf("DELETE without a token: status %d: %s", status, body)
+	}
+	if status, body := ts.Do(t, ts.AdminRequest(t, http.MethodDelete, path, nil)); status != http.StatusOK {
+		t.Fatalf("DELETE %s: status %d: %s", path, status, body)
+	}
+	ws.Expect(t, "delete", 0)
+	if status, _ := ts.Do(t, ts.NewRequest(t, http.MethodGet, path, nil)); status != http.StatusNotFound {
+		t.Errorf("GET after delete: status %d, want 404", status)
+	}
+	if status, body := ts.Do(t, ts.NewRequest(t, http.MethodGet, "/api/v1/posts", nil)); status != http.StatusOK || string(body) != "[]" {
+		t.Errorf("feed after delete: status %d, %s; want an empty feed", status, body)
+	}
+}
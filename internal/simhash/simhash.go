@@ -0,0 +1,110 @@
+// Package simhash fingerprints post content so near-duplicates can be
+// found cheaply. Similar texts get fingerprints that differ in few bits,
+// so a spammer who varies a message by a word or some punctuation still
+// lands close to the original.
+//
+// To find close fingerprints without comparing against every one, each
+// fingerprint is split into Bands. Two fingerprints at most MaxDistance
+// bits apart agree on at least one whole band, so only posts sharing a
+// band with the new one need checking, and each band can be indexed.
+package simhash
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// Bands is how many parts a fingerprint is split into for lookups.
+	// More bands find more distant matches but make smaller, busier
+	// buckets.
+	Bands = 6
+	// MaxDistance is the largest distance bands are guaranteed to find.
+	MaxDistance = Bands - 1
+
+	// MinLength is the shortest normalized text that gets a fingerprint.
+	// Short posts ("lol", "same") repeat innocently all the time.
+	MinLength = 24
+
+	shingle = 3 // Runes per feature
+)
+
+// Config configures near-duplicate detection of new posts.
+type Config struct {
+	MaxDistance int           // Differing bits still counted as a duplicate, 0 to MaxDistance
+	Window      time.Duration // How far back posts are compared against; 0 disables
+}
+
+// Enabled reports whether new posts are checked.
+func (c Config) Enabled() bool {
+	return c.Window > 0
+}
+
+// Sum returns the fingerprint of text, or false when text is too short
+// to fingerprint meaningfully. Case, punctuation, symbols and spacing
+// don't affect it.
+func Sum(text string) (uint64, bool) {
+	runes := normalize(text)
+	if len(runes) < MinLength {
+		return 0, false
+	}
+	var weights [64]int
+	for i := 0; i+shingle <= len(runes); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(string(runes[i : i+shingle])))
+		sum := h.Sum64()
+		for bit := range weights {
+			if sum&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+	var fp uint64
+	for bit, w := range weights {
+		if w > 0 {
+			fp |= 1 << bit
+		}
+	}
+	return fp, true
+}
+
+// Distance returns how many bits a and b differ in.
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Split returns the bands of fp, most significant first. They are 10 or
+// 11 bits wide.
+func Split(fp uint64) [Bands]int {
+	var bands [Bands]int
+	for i := range bands {
+		start, end := i*64/Bands, (i+1)*64/Bands
+		bands[i] = int(fp >> (64 - end) & (1<<(end-start) - 1))
+	}
+	return bands
+}
+
+// normalize lowercases text and keeps only its letters and digits, with
+// a single space between words.
+func normalize(text string) []rune {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Mc, r):
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		default:
+			space = true
+		}
+	}
+	return []rune(b.String())
+}
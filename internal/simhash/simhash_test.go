@@ -0,0 +1,145 @@
+package simhash_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/sujalbistaa/whispr/internal/simhash"
+)
+
+// defaultDistance is SPAM_SIMILARITY_DISTANCE's default.
+const defaultDistance = 4
+
+func sum(t *testing.T, text string) uint64 {
+	t.Helper()
+	fp, ok := simhash.Sum(text)
+	if !ok {
+		t.Fatalf("Sum(%q) found it too short", text)
+	}
+	return fp
+}
+
+func TestSumIgnoresFormatting(t *testing.T) {
+	base := sum(t, "Free iPhone giveaway, click the link in my bio to claim yours today")
+	for _, text := range []string{
+		"FREE iphone GIVEAWAY click the link in my bio to claim yours today",
+		"free   iphone giveaway... click the link in my bio to claim yours today!!!",
+		"Free iPhone giveaway 🎁 click the link in my bio — to claim yours today",
+	} {
+		if got := sum(t, text); got != base {
+			t.Errorf("Sum(%q) differs from the original by %d bits, want 0", text, simhash.Distance(got, base))
+		}
+	}
+}
+
+func TestSumTooShort(t *testing.T) {
+	for _, text := range []string{"", "lol", "same here!!", "  ok   ok   ok   ok   ok  "} {
+		if _, ok := simhash.Sum(text); ok {
+			t.Errorf("Sum(%q) fingerprinted a text shorter than MinLength", text)
+		}
+	}
+}
+
+func TestNearDuplicates(t *testing.T) {
+	for _, pair := range [][2]string{
+		{
+			"Earn $500 a day working from home, message me on telegram for details",
+			"Earn $500 a day working from home!! message me on telegram for the details",
+		},
+		{
+			"Earn $500 a day working from home, message me on telegram for details",
+			"Earn $600 a day working from home, message me on telegram for details",
+		},
+		{
+			"Selling second hand engineering mathematics books, cheap, contact me",
+			"Selling second-hand engineering mathematics books, cheap!! contact me",
+		},
+	} {
+		if d := simhash.Distance(sum(t, pair[0]), sum(t, pair[1])); d > defaultDistance {
+			t.Errorf("%q and %q are %d bits apart, want at most %d", pair[0], pair[1], d, defaultDistance)
+		}
+	}
+}
+
+func TestDistinctTexts(t *testing.T) {
+	for _, pair := range [][2]string{
+		{
+			"The library is open late during exam week, bring your ID card",
+			"Anyone know a good place for momo near the main campus gate?",
+		},
+		{
+			// Same shape, different message
+			"The library is open late during exam week, bring your ID card",
+			"The cafeteria closes early during exam week, bring your own lunch",
+		},
+		{
+			"Does anyone have notes from yesterday's thermodynamics lecture?",
+			"Lost a blue water bottle in room 204, please message if found",
+		},
+	} {
+		if d := simhash.Distance(sum(t, pair[0]), sum(t, pair[1])); d <= defaultDistance {
+			t.Errorf("%q and %q are only %d bits apart, want more than %d", pair[0], pair[1], d, defaultDistance)
+		}
+	}
+}
+
+func TestDistance(t *testing.T) {
+	for _, tc := range []struct {
+		a, b uint64
+		want int
+	}{
+		{0, 0, 0},
+		{0, 1, 1},
+		{0b1010, 0b0101, 4},
+		{0, ^uint64(0), 64},
+	} {
+		if got := simhash.Distance(tc.a, tc.b); got != tc.want {
+			t.Errorf("Distance(%b, %b) = %d, want %d", tc.a, tc.b, got, tc.want)
+		}
+	}
+}
+
+func TestSplit(t *testing.T) {
+	bands := simhash.Split(^uint64(0))
+	total := 0
+	for i, band := range bands {
+		width := 0
+		for ; band > 0; band >>= 1 {
+			width++
+		}
+		if width != 10 && width != 11 {
+			t.Errorf("band %d is %d bits wide", i, width)
+		}
+		total += width
+	}
+	if total != 64 {
+		t.Errorf("bands cover %d bits, want 64", total)
+	}
+	if got := simhash.Split(1 << 63)[0]; got == 0 {
+		t.Error("the most significant bit isn't in the first band")
+	}
+	if got := simhash.Split(1)[simhash.Bands-1]; got != 1 {
+		t.Error("the least significant bit isn't in the last band")
+	}
+}
+
+// TestSplitFindsClose checks the guarantee lookups rely on: fingerprints
+// at most MaxDistance bits apart share a band.
+func TestSplitFindsClose(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 10000; i++ {
+		a := rng.Uint64()
+		b := a
+		for flips := rng.Intn(simhash.MaxDistance + 1); flips > 0; flips-- {
+			b ^= 1 << rng.Intn(64)
+		}
+		shared := false
+		bandsA, bandsB := simhash.Split(a), simhash.Split(b)
+		for j := range bandsA {
+			shared = shared || bandsA[j] == bandsB[j]
+		}
+		if !shared {
+			t.Fatalf("%016x and %016x are %d bits apart but share no band", a, b, simhash.Distance(a, b))
+		}
+	}
+}
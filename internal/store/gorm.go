@@ -0,0 +1,379 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/sujalbistaa/whispr/internal/audit"
+	"github.com/sujalbistaa/whispr/internal/db"
+	"github.com/sujalbistaa/whispr/internal/karma"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/simhash"
+	"github.com/sujalbistaa/whispr/internal/strikes"
+)
+
+// GormStore implements PostStore, VoteStore and BookmarkStore with GORM.
+type GormStore struct {
+	db      *gorm.DB
+	strikes strikes.Config
+}
+
+// New returns a store backed by database, which escalates strikes
+// against the authors of hidden posts as strikeCfg says.
+func New(database *gorm.DB, strikeCfg strikes.Config) *GormStore {
+	return &GormStore{db: database, strikes: strikeCfg}
+}
+
+// maxTxAttempts is how many times retrySerialization runs a transaction.
+const maxTxAttempts = 3
+
+// retrySerialization runs tx until it succeeds, fails with an error other
+// than a serialization failure, or has been tried maxTxAttempts times.
+// tx must start a fresh transaction and reset anything it fills in.
+func retrySerialization(ctx context.Context, tx func() error) error {
+	backoff := 10 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		err := tx()
+		if err == nil || attempt >= maxTxAttempts || !db.IsSerializationFailure(err) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// VelocityWindow is how far back Post.VotesLastHour counts votes.
+//
+// The counts are a windowed COUNT over votes, which
+// idx_votes_post_created (post_id, created_at) answers from the index. A
+// rolling counter kept on posts by the vote transaction would make the
+// read a plain indexed sort, but it needs something to take votes back
+// out once they leave the window, and a sweep that lags makes the counts
+// wrong in between. Votes in one hour are few next to the posts they
+// land on, so counting them on read stays cheap, and the feed cache
+// absorbs repeated reads.
+const VelocityWindow = time.Hour
+
+func (s *GormStore) List(ctx context.Context, viewer Viewer, feed Feed) ([]models.Post, error) {
+	since := time.Now().Add(-VelocityWindow)
+	query := s.db.WithContext(ctx).Scopes(viewer.Scope, feed.filter)
+	if feed.rising {
+		recent := s.db.Model(&models.Vote{}).Select("post_id, COUNT(*) AS recent").
+			Where("created_at >= ?", since).Group("post_id")
+		query = query.Joins("JOIN (?) AS velocity ON velocity.post_id = posts.id", recent).
+			Order("velocity.recent desc, posts.created_at desc")
+	} else {
+		query = query.Order(feed.order)
+	}
+	if feed.limit > 0 {
+		query = query.Limit(feed.limit)
+	}
+	if feed.offset > 0 {
+		query = query.Offset(feed.offset)
+	}
+	var posts []models.Post
+	if err := query.Find(&posts).Error; err != nil || !feed.velocity {
+		return posts, err
+	}
+	return posts, s.countVelocity(ctx, posts, since)
+}
+
+// countVelocity sets VotesLastHour on posts to their votes since since.
+func (s *GormStore) countVelocity(ctx context.Context, posts []models.Post, since time.Time) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	var rows []struct {
+		PostID uint
+		Recent int64
+	}
+	err := s.db.WithContext(ctx).Model(&models.Vote{}).Select("post_id, COUNT(*) AS recent").
+		Where("post_id IN ? AND created_at >= ?", ids, since).Group("post_id").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+	recent := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		recent[row.PostID] = row.Recent
+	}
+	for i := range posts {
+		count := recent[posts[i].ID]
+		posts[i].VotesLastHour = &count
+	}
+	return nil
+}
+
+func (s *GormStore) Count(ctx context.Context, viewer Viewer, feed Feed) (int64, error) {
+	var count int64
+	err := s.db.WithContext(ctx).Model(&models.Post{}).Scopes(viewer.Scope, feed.filter).Count(&count).Error
+	return count, err
+}
+
+func (s *GormStore) Version(ctx context.Context, viewer Viewer) (FeedVersion, error) {
+	var version FeedVersion
+	err := s.db.WithContext(ctx).Model(&models.Post{}).Scopes(viewer.Scope).
+		Select("COUNT(*) AS count, COALESCE(" + db.CastText(s.db, "MAX(updated_at)") + ", '') AS latest").
+		Scan(&version).Error
+	return version, err
+}
+
+func (s *GormStore) GetVisible(ctx context.Context, viewer Viewer, id uint) (models.Post, error) {
+	var post models.Post
+	err := s.db.WithContext(ctx).Scopes(viewer.Scope).First(&post, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return post, models.ErrPostNotFound
+	}
+	return post, err
+}
+
+func (s *GormStore) GetVisibleByIDs(ctx context.Context, viewer Viewer, ids []uint) ([]models.Post, error) {
+	var posts []models.Post
+	err := s.db.WithContext(ctx).Scopes(viewer.Scope).Where("id IN ?", ids).Find(&posts).Error
+	return posts, err
+}
+
+// stampActivity sets a new post's CreatedAt, unless given, and starts its
+// LastActivityAt there.
+func stampActivity(post *models.Post) {
+	if post.CreatedAt.IsZero() {
+		post.CreatedAt = time.Now()
+	}
+	if post.LastActivityAt.IsZero() {
+		post.LastActivityAt = post.CreatedAt
+	}
+}
+
+func (s *GormStore) Create(ctx context.Context, post *models.Post) error {
+	stampActivity(post)
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(post).Error; err != nil {
+			return err
+		}
+		if post.AuthorHash == nil {
+			return nil
+		}
+		if err := karma.RecordPost(tx, *post.AuthorHash, post.CreatedAt); err != nil {
+			return fmt.Errorf("recording streak: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *GormStore) RecentByAuthor(ctx context.Context, author string, since time.Time, limit int) ([]time.Time, error) {
+	var created []time.Time
+	err := s.db.WithContext(ctx).Unscoped().Model(&models.Post{}).
+		Where("author_hash = ? AND created_at > ?", author, since).
+		Order("created_at desc").Limit(limit).Pluck("created_at", &created).Error
+	return created, err
+}
+
+// maxSimilarCandidates bounds how many posts sharing a band with a new
+// one are compared, should a burst of spam fill a band.
+const maxSimilarCandidates = 200
+
+func (s *GormStore) FindSimilar(ctx context.Context, fingerprint uint64, since time.Time, maxDistance int) (uint, int, error) {
+	// One range scan per band index; the OR of ANDs keeps each branch on
+	// its own (band, created_at) index.
+	query := s.db.WithContext(ctx)
+	for i, band := range simhash.Split(fingerprint) {
+		query = query.Or(fmt.Sprintf("fingerprint_band%d = ? AND created_at > ?", i), band, since)
+	}
+	var candidates []models.Post
+	err := s.db.WithContext(ctx).Unscoped().Select("id", "fingerprint").Where(query).
+		Order("created_at desc").Limit(maxSimilarCandidates).Find(&candidates).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, candidate := range candidates {
+		if candidate.Fingerprint == nil {
+			continue
+		}
+		if d := simhash.Distance(fingerprint, uint64(*candidate.Fingerprint)); d <= maxDistance {
+			return candidate.ID, d, nil
+		}
+	}
+	return 0, 0, models.ErrPostNotFound
+}
+
+func (s *GormStore) Hold(ctx context.Context, post *models.Post, reason map[string]any) error {
+	post.HiddenAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	post.HiddenBy = audit.ActorSpamFilter
+	stampActivity(post)
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(post).Error; err != nil {
+			return err
+		}
+		if err := audit.Record(tx, audit.ActorSpamFilter, audit.ActionHoldPost, audit.TargetPost, post.ID, reason); err != nil {
+			return fmt.Errorf("writing audit log: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *GormStore) MarkNotified(ctx context.Context, id uint, threshold int) (bool, error) {
+	// The conditions are checked by the update itself, so it is the only
+	// read that matters and two voters can't both win.
+	res := s.db.WithContext(ctx).Model(&models.Post{}).
+		Where("id = ? AND notified_at IS NULL AND score >= ? AND shadow_banned = ?", id, threshold, false).
+		UpdateColumn("notified_at", time.Now())
+	return res.RowsAffected == 1, res.Error
+}
+
+func (s *GormStore) Hide(ctx context.Context, id uint, actor string) (models.Post, bool, error) {
+	var post models.Post
+	var alreadyHidden bool
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().First(&post, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return models.ErrPostNotFound
+			}
+			return err
+		}
+		if post.HiddenAt.Valid {
+			alreadyHidden = true
+			return nil
+		}
+		// The soft-delete scope limits the update to a visible post, so
+		// when two requests race only one of them hides it.
+		now := time.Now()
+		res := tx.Model(&post).Updates(map[string]any{"hidden_at": now, "hidden_by": actor})
+		if res.Error != nil {
+			return fmt.Errorf("hiding post: %w", res.Error)
+		}
+		if res.RowsAffected == 0 {
+			alreadyHidden = true
+			return nil
+		}
+		if err := audit.Record(tx, actor, audit.ActionHidePost, audit.TargetPost, post.ID, nil); err != nil {
+			return fmt.Errorf("writing audit log: %w", err)
+		}
+		if post.AuthorHash != nil {
+			if err := strikes.Add(tx, s.strikes, actor, *post.AuthorHash, post.ID, now); err != nil {
+				return fmt.Errorf("recording strike: %w", err)
+			}
+		}
+		return nil
+	})
+	return post, alreadyHidden, err
+}
+
+// Vote is the pattern for a read-modify-write on a post: lock the row,
+// translate constraint errors into sentinels, and run the transaction
+// again when it loses a race with another.
+func (s *GormStore) Vote(ctx context.Context, viewer Viewer, id uint, voter string, value int, voteID string) (models.Post, bool, error) {
+	var post models.Post
+	var replayed bool
+	err := retrySerialization(ctx, func() error {
+		post, replayed = models.Post{}, false
+		return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Scopes(db.LockForUpdate, viewer.Scope).First(&post, id).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return models.ErrPostNotFound
+				}
+				return err
+			}
+			// Checked under the post's lock, so a retry racing the
+			// original sees it once it commits.
+			vote := models.Vote{PostID: id, VoterHash: &voter, Value: value}
+			if voteID != "" {
+				var err error
+				if replayed, err = castWithID(tx, id, voter, voteID); err != nil || replayed {
+					return err
+				}
+				vote.VoteID = &voteID
+			}
+			if err := tx.Create(&vote).Error; err != nil {
+				if db.IsUniqueViolation(err) {
+					return models.ErrVoteConflict
+				}
+				return fmt.Errorf("recording vote: %w", err)
+			}
+			// Add to the score in SQL rather than writing back post.Score, so a
+			// concurrent vote can't be overwritten even where the row isn't
+			// locked. Our update holds the row until commit, so the read-back
+			// sees exactly our change.
+			post.LastActivityAt = time.Now()
+			if err := tx.Model(&post).Updates(map[string]any{
+				"score":            gorm.Expr("score + ?", value),
+				"last_activity_at": post.LastActivityAt,
+			}).Error; err != nil {
+				return fmt.Errorf("updating post score: %w", err)
+			}
+			if err := tx.Model(&models.Post{}).Where("id = ?", post.ID).Pluck("score", &post.Score).Error; err != nil {
+				return fmt.Errorf("reading post score: %w", err)
+			}
+			return nil
+		})
+	})
+	// Where the post isn't locked, the original can commit between the
+	// check and the insert; its insert wins and ours hits the index.
+	if errors.Is(err, models.ErrVoteConflict) && voteID != "" {
+		cast, castErr := castWithID(s.db.WithContext(ctx), id, voter, voteID)
+		if errors.Is(castErr, models.ErrVoteIDReused) {
+			return post, false, castErr
+		}
+		if cast {
+			post, err = s.GetVisible(ctx, viewer, id)
+			return post, err == nil, err
+		}
+	}
+	return post, replayed, err
+}
+
+// castWithID reports whether voter has already voted on post id with
+// voteID, or fails with models.ErrVoteIDReused if someone used it for
+// another vote. Votes cleared by retention no longer have one.
+func castWithID(tx *gorm.DB, id uint, voter, voteID string) (bool, error) {
+	var prior models.Vote
+	err := tx.Unscoped().Where("vote_id = ?", voteID).Take(&prior).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("looking up vote ID: %w", err)
+	}
+	if prior.PostID != id || prior.VoterHash == nil || *prior.VoterHash != voter {
+		return false, models.ErrVoteIDReused
+	}
+	return true, nil
+}
+
+func (s *GormStore) Bookmark(ctx context.Context, viewer Viewer, id uint, client string) error {
+	if _, err := s.GetVisible(ctx, viewer, id); err != nil {
+		return err
+	}
+	bookmark := models.Bookmark{PostID: id, ClientHash: client}
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&bookmark).Error
+}
+
+func (s *GormStore) Unbookmark(ctx context.Context, id uint, client string) error {
+	return s.db.WithContext(ctx).Where("post_id = ? AND client_hash = ?", id, client).Delete(&models.Bookmark{}).Error
+}
+
+func (s *GormStore) Bookmarks(ctx context.Context, viewer Viewer, client string, offset, limit int) ([]models.Post, int64, error) {
+	// Joining on posts drops bookmarks of posts hidden since, which are
+	// soft-deleted, without touching the bookmarks themselves.
+	query := s.db.WithContext(ctx).Model(&models.Post{}).Scopes(viewer.Scope).
+		Joins("JOIN bookmarks ON bookmarks.post_id = posts.id AND bookmarks.client_hash = ?", client)
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	posts := []models.Post{}
+	err := query.Select("posts.*").Order("bookmarks.created_at desc, bookmarks.id desc").Offset(offset).Limit(limit).Find(&posts).Error
+	return posts, total, err
+}
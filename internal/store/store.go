@@ -0,0 +1,187 @@
+// Package store reads and writes posts, votes and bookmarks. Handlers
+// depend on the PostStore, VoteStore and BookmarkStore interfaces;
+// GormStore implements them on the application database. Lookups that find nothing return
+// models.ErrPostNotFound, a repeated vote returns
+// models.ErrVoteConflict, and a vote ID used for another vote returns
+// models.ErrVoteIDReused.
+package store
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/sujalbistaa/whispr/internal/lang"
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+// Viewer is who a read is answered for. Posts that aren't shadow-banned
+// are visible to everyone; a shadow-banned client also sees their own
+// posts, so the ban isn't apparent to them. Hidden posts are never
+// visible.
+type Viewer struct {
+	ShadowBanID  uint // The ban covering the viewer when ShadowBanned
+	ShadowBanned bool
+}
+
+// Scope limits a post query to what v may see. Hidden posts are
+// soft-deleted, so every query on models.Post already leaves them out.
+func (v Viewer) Scope(db *gorm.DB) *gorm.DB {
+	if v.ShadowBanned {
+		return db.Where("shadow_banned = ? OR shadow_ban_id = ?", false, v.ShadowBanID)
+	}
+	return db.Where("shadow_banned = ?", false)
+}
+
+// Feed is a listing of posts: an order, an optional limit and offset,
+// and optionally a board, languages and a start time. Feeds list posts
+// still in the feeds, except FeedArchive, which lists archived ones.
+type Feed struct {
+	order    string
+	limit    int
+	offset   int
+	board    uint
+	langs    []string
+	since    time.Time
+	velocity bool // Fill in Post.VotesLastHour
+	rising   bool // Order by VotesLastHour rather than order
+	archived bool // List archived posts instead
+}
+
+var (
+	// FeedNew lists every visible post, newest first.
+	FeedNew = Feed{order: "created_at desc"}
+	// FeedTrending lists the 20 highest-scoring posts.
+	FeedTrending = Feed{order: "score desc, created_at desc", limit: 20, velocity: true}
+	// FeedRising lists the 20 posts with the most votes in the last
+	// VelocityWindow; posts without any aren't listed.
+	FeedRising = Feed{order: "created_at desc", limit: 20, velocity: true, rising: true}
+	// FeedActive lists every visible post, most recently active first.
+	FeedActive = Feed{order: "last_activity_at desc, created_at desc"}
+	// FeedArchive lists every visible archived post, most recently
+	// archived first.
+	FeedArchive = Feed{order: "archived_at desc, created_at desc", archived: true}
+)
+
+// InBoard returns f limited to posts on board id.
+func (f Feed) InBoard(id uint) Feed {
+	f.board = id
+	return f
+}
+
+// InLanguages returns f limited to posts in langs. Posts whose language
+// couldn't be determined are always included.
+func (f Feed) InLanguages(langs []string) Feed {
+	f.langs = append(langs[:len(langs):len(langs)], lang.Undetermined)
+	return f
+}
+
+// Since returns f limited to posts created after t.
+func (f Feed) Since(t time.Time) Feed {
+	f.since = t
+	return f
+}
+
+// Page returns f limited to limit posts after skipping offset.
+func (f Feed) Page(offset, limit int) Feed {
+	f.offset = offset
+	f.limit = limit
+	return f
+}
+
+// Decays reports whether f can change without a write, because the vote
+// counts it carries cover a window that moves with the clock.
+func (f Feed) Decays() bool {
+	return f.velocity
+}
+
+// filter limits a post query to the posts f lists, ignoring its order,
+// limit and offset.
+func (f Feed) filter(db *gorm.DB) *gorm.DB {
+	if f.archived {
+		db = db.Where("archived_at IS NOT NULL")
+	} else {
+		db = db.Where("archived_at IS NULL")
+	}
+	if f.board != 0 {
+		db = db.Where("board_id = ?", f.board)
+	}
+	if len(f.langs) > 0 {
+		db = db.Where("lang IN ?", f.langs)
+	}
+	if !f.since.IsZero() {
+		db = db.Where("created_at > ?", f.since)
+	}
+	return db
+}
+
+// FeedVersion identifies what a viewer's feed holds without loading it:
+// any post, vote or hide changes the count or the latest update.
+type FeedVersion struct {
+	Count  int64
+	Latest string
+}
+
+// PostStore reads, creates and hides posts.
+type PostStore interface {
+	// List returns the posts of feed that viewer may see.
+	List(ctx context.Context, viewer Viewer, feed Feed) ([]models.Post, error)
+	// Count returns how many posts of feed viewer may see, ignoring its
+	// limit and offset.
+	Count(ctx context.Context, viewer Viewer, feed Feed) (int64, error)
+	// Version returns the FeedVersion of viewer's feeds.
+	Version(ctx context.Context, viewer Viewer) (FeedVersion, error)
+	// GetVisible returns post id if viewer may see it, archived or not.
+	GetVisible(ctx context.Context, viewer Viewer, id uint) (models.Post, error)
+	// GetVisibleByIDs returns the posts among ids that viewer may see, in
+	// no particular order.
+	GetVisibleByIDs(ctx context.Context, viewer Viewer, ids []uint) ([]models.Post, error)
+	// Create stores post along with any votes it carries, and advances
+	// its author's posting streak in the same transaction. A post without
+	// a LastActivityAt is last active when it was created.
+	Create(ctx context.Context, post *models.Post) error
+	// RecentByAuthor returns when author's posts after since were
+	// created, newest first and at most limit of them. Hidden posts
+	// count.
+	RecentByAuthor(ctx context.Context, author string, since time.Time, limit int) ([]time.Time, error)
+	// FindSimilar returns the newest post created after since whose
+	// fingerprint is at most maxDistance bits from fingerprint, and the
+	// distance. Hidden and shadow-banned posts count. maxDistance must not
+	// exceed simhash.MaxDistance.
+	FindSimilar(ctx context.Context, fingerprint uint64, since time.Time, maxDistance int) (id uint, distance int, err error)
+	// Hold stores post hidden, on behalf of the spam filter, for a
+	// moderator to review, and records why in the audit log.
+	Hold(ctx context.Context, post *models.Post, reason map[string]any) error
+	// MarkNotified records that post id was announced as trending, if it
+	// hasn't been yet and is visible to everyone with a score of at least
+	// threshold. Only the first of concurrent calls reports true.
+	MarkNotified(ctx context.Context, id uint, threshold int) (bool, error)
+	// Hide hides post id on behalf of actor, records it in the audit log
+	// and adds a strike against its author. A post that was already
+	// hidden is returned with alreadyHidden set and left untouched.
+	Hide(ctx context.Context, id uint, actor string) (post models.Post, alreadyHidden bool, err error)
+}
+
+// VoteStore records votes.
+type VoteStore interface {
+	// Vote records voter's vote of value on post id, which viewer must be
+	// able to see, bumps its LastActivityAt, and returns the post with its
+	// new score. A non-empty voteID identifies the vote: when voter has
+	// already cast a vote with it on the post, that vote is replayed,
+	// leaving everything untouched and returning the post as it is now.
+	Vote(ctx context.Context, viewer Viewer, id uint, voter string, value int, voteID string) (post models.Post, replayed bool, err error)
+}
+
+// BookmarkStore keeps the posts each client saved.
+type BookmarkStore interface {
+	// Bookmark saves post id, which viewer must be able to see, for
+	// client. Saving it again changes nothing.
+	Bookmark(ctx context.Context, viewer Viewer, id uint, client string) error
+	// Unbookmark removes post id from client's bookmarks, if it is there.
+	Unbookmark(ctx context.Context, id uint, client string) error
+	// Bookmarks returns the posts client saved that viewer can still see,
+	// most recently saved first, skipping offset and at most limit of
+	// them, along with how many there are in all.
+	Bookmarks(ctx context.Context, viewer Viewer, client string, offset, limit int) ([]models.Post, int64, error)
+}
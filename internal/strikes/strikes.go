@@ -0,0 +1,113 @@
+// Package strikes slows down clients whose posts moderators keep hiding.
+// Each hidden post is a strike against its author's Identity. Strikes
+// count within a Window that opens at the first one; once they reach a
+// Level, the client may post only once per the Level's Cooldown for the
+// Level's For, in place of the usual few seconds.
+//
+// Strikes and cooldowns change in the transaction that hides the post,
+// and every change is written to the audit log.
+package strikes
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/sujalbistaa/whispr/internal/audit"
+	"github.com/sujalbistaa/whispr/internal/db"
+	"github.com/sujalbistaa/whispr/internal/models"
+)
+
+// Level is a step of the escalation schedule.
+type Level struct {
+	Strikes  int           // Strikes in the window that reach it
+	Cooldown time.Duration // Minimum time between posts
+	For      time.Duration // How long the cooldown lasts from the latest strike
+}
+
+// Config configures strikes.
+type Config struct {
+	Window time.Duration // How long strikes count from the first one
+	Levels []Level       // By Strikes, ascending; none disables cooldowns
+}
+
+// Enabled reports whether strikes escalate to cooldowns.
+func (c Config) Enabled() bool {
+	return len(c.Levels) > 0
+}
+
+// level returns the highest level strikes reach, or false for none.
+func (c Config) level(strikes int) (Level, bool) {
+	var reached Level
+	var ok bool
+	for _, level := range c.Levels {
+		if strikes >= level.Strikes {
+			reached, ok = level, true
+		}
+	}
+	return reached, ok
+}
+
+// Add records a strike by actor against author for hiding post id. A
+// strike at or above a level starts that level's cooldown afresh, unless
+// a stricter one is in force. Call it in the transaction that hides the
+// post.
+func Add(tx *gorm.DB, cfg Config, actor, author string, id uint, now time.Time) error {
+	identity, err := lock(tx, author, now)
+	if err != nil {
+		return err
+	}
+	if identity.StrikesSince == nil || !now.Before(identity.StrikesSince.Add(cfg.Window)) {
+		identity.Strikes, identity.StrikesSince = 0, &now
+	}
+	identity.Strikes++
+	updates := map[string]any{"strikes": identity.Strikes, "strikes_since": identity.StrikesSince}
+	if err := audit.Record(tx, actor, audit.ActionStrike, audit.TargetIdentity, identity.ID, map[string]any{"post": id, "strikes": identity.Strikes}); err != nil {
+		return err
+	}
+
+	if level, ok := cfg.level(identity.Strikes); ok {
+		until := now.Add(level.For)
+		inForce := identity.CooldownUntil != nil && identity.CooldownUntil.After(now)
+		if !inForce || level.Cooldown >= time.Duration(identity.CooldownSeconds)*time.Second {
+			updates["cooldown_seconds"] = int(level.Cooldown.Seconds())
+			updates["cooldown_until"] = until
+			if err := audit.Record(tx, actor, audit.ActionCooldown, audit.TargetIdentity, identity.ID, map[string]any{
+				"strikes":  identity.Strikes,
+				"cooldown": level.Cooldown.String(),
+				"until":    until.UTC(),
+			}); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Model(&identity).Updates(updates).Error
+}
+
+// Forgive takes back a strike by actor against author for post id, when
+// hiding it was undone. A cooldown the strike caused stays in force;
+// clearing it is up to an admin.
+func Forgive(tx *gorm.DB, actor, author string, id uint, now time.Time) error {
+	identity, err := lock(tx, author, now)
+	if err != nil || identity.Strikes == 0 {
+		return err
+	}
+	remaining := identity.Strikes - 1
+	if err := tx.Model(&identity).Update("strikes", remaining).Error; err != nil {
+		return err
+	}
+	return audit.Record(tx, actor, audit.ActionStrikeForgive, audit.TargetIdentity, identity.ID, map[string]any{"post": id, "strikes": remaining})
+}
+
+// lock returns author's identity locked for update, creating it for
+// authors who posted before identities existed.
+func lock(tx *gorm.DB, author string, now time.Time) (models.Identity, error) {
+	identity := models.Identity{ClientHash: author, LastPostOn: now.UTC().Truncate(24 * time.Hour)}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&identity).Error; err != nil {
+		return identity, err
+	}
+	identity = models.Identity{}
+	err := tx.Scopes(db.LockForUpdate).Where("client_hash = ?", author).First(&identity).Error
+	return identity, err
+}
@@ -0,0 +1,130 @@
+// Package supervisor keeps long-running goroutines alive. A panic is
+// reported to an ErrorReporter and the function restarted after a delay
+// that doubles with each panic in a row. One that keeps panicking is
+// given up on and reported as failed, which fails readiness, rather than
+// restarted forever or left silently dead.
+package supervisor
+
+import (
+	"context"
+	"log/slog"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sujalbistaa/whispr/internal/metrics"
+	"github.com/sujalbistaa/whispr/internal/reporting"
+)
+
+// Config bounds the restarts of each supervised function.
+type Config struct {
+	MaxRestarts int           // Restarts after panics in a row before giving up
+	Backoff     time.Duration // Delay before the first restart, doubled for each after it
+	MaxBackoff  time.Duration // Cap on the delay
+	// ResetAfter is how long a run must last without panicking for the
+	// next panic to count as the first again.
+	ResetAfter time.Duration
+}
+
+// DefaultConfig gives up after five panics in a row, the last restart
+// coming about 3 seconds after the first panic.
+var DefaultConfig = Config{
+	MaxRestarts: 5,
+	Backoff:     100 * time.Millisecond,
+	MaxBackoff:  30 * time.Second,
+	ResetAfter:  time.Minute,
+}
+
+// Supervisor runs functions under Config's restart policy and remembers
+// how each has fared. It is safe for concurrent use.
+type Supervisor struct {
+	cfg      Config
+	reporter reporting.ErrorReporter
+
+	mu       sync.Mutex
+	restarts map[string]int
+	failed   map[string]bool
+}
+
+// New returns a supervisor restarting under cfg and reporting panics to
+// reporter.
+func New(cfg Config, reporter reporting.ErrorReporter) *Supervisor {
+	return &Supervisor{
+		cfg:      cfg,
+		reporter: reporter,
+		restarts: make(map[string]int),
+		failed:   make(map[string]bool),
+	}
+}
+
+// Run calls fn in the calling goroutine until it returns without
+// panicking or ctx is done. Each panic is reported with name as its
+// source and fn called again after the backoff; after MaxRestarts panics
+// in a row name is marked failed and Run returns.
+func (s *Supervisor) Run(ctx context.Context, name string, fn func(context.Context)) {
+	backoff, streak := s.cfg.Backoff, 0
+	for {
+		start := time.Now()
+		if !s.call(ctx, name, fn) || ctx.Err() != nil {
+			return
+		}
+		if time.Since(start) >= s.cfg.ResetAfter {
+			backoff, streak = s.cfg.Backoff, 0
+		}
+		if streak == s.cfg.MaxRestarts {
+			s.mu.Lock()
+			s.failed[name] = true
+			s.mu.Unlock()
+			slog.Error("giving up on worker after repeated panics", "worker", name, "restarts", streak)
+			return
+		}
+		streak++
+		s.mu.Lock()
+		s.restarts[name]++
+		s.mu.Unlock()
+		metrics.WorkerRestarted(name)
+		slog.Warn("restarting worker after panic", "worker", name, "attempt", streak, "delay", backoff)
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return
+		}
+		backoff = min(2*backoff, s.cfg.MaxBackoff)
+	}
+}
+
+// call runs fn once and reports whether it panicked.
+func (s *Supervisor) call(ctx context.Context, name string, fn func(context.Context)) (panicked bool) {
+	defer func() {
+		if v := recover(); v != nil {
+			panicked = true
+			s.reporter.ReportPanic(ctx, reporting.Panic{Value: v, Stack: debug.Stack(), Source: name})
+		}
+	}()
+	fn(ctx)
+	return false
+}
+
+// Restarts returns how many times name has been restarted in all.
+func (s *Supervisor) Restarts(name string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.restarts[name]
+}
+
+// Failed returns the names given up on, sorted. A nil Supervisor has
+// given up on nothing.
+func (s *Supervisor) Failed() []string {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	names := make([]string, 0, len(s.failed))
+	for name := range s.failed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
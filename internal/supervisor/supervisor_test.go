@@ -0,0 +1,121 @@
+package supervisor_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/sujalbistaa/whispr/internal/reporting"
+	"github.com/sujalbistaa/whispr/internal/supervisor"
+)
+
+func TestRestartsAfterPanic(t *testing.T) {
+	reporter := &reporting.Recorder{}
+	s := supervisor.New(supervisor.Config{MaxRestarts: 5, Backoff: time.Millisecond, MaxBackoff: time.Millisecond, ResetAfter: time.Minute}, reporter)
+
+	calls := 0
+	s.Run(context.Background(), "worker", func(context.Context) {
+		calls++
+		if calls <= 2 {
+			panic("boom")
+		}
+	})
+
+	if calls != 3 {
+		t.Errorf("fn called %d times, want 3", calls)
+	}
+	if got := s.Restarts("worker"); got != 2 {
+		t.Errorf("Restarts = %d, want 2", got)
+	}
+	if failed := s.Failed(); len(failed) != 0 {
+		t.Errorf("Failed = %v after fn returned", failed)
+	}
+	panics := reporter.Panics()
+	if len(panics) != 2 {
+		t.Fatalf("%d panics reported, want 2", len(panics))
+	}
+	for _, p := range panics {
+		if p.Value != "boom" || p.Source != "worker" || len(p.Stack) == 0 {
+			t.Errorf("reported %+v, want boom from worker with a stack", p)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	const backoff = 20 * time.Millisecond
+	reporter := &reporting.Recorder{}
+	s := supervisor.New(supervisor.Config{MaxRestarts: 4, Backoff: backoff, MaxBackoff: 3 * backoff, ResetAfter: time.Minute}, reporter)
+
+	var calls []time.Time
+	s.Run(context.Background(), "flaky", func(context.Context) {
+		calls = append(calls, time.Now())
+		panic("always")
+	})
+
+	// The first call and one for each restart
+	if len(calls) != 5 {
+		t.Fatalf("fn called %d times, want 5", len(calls))
+	}
+	// Doubling from Backoff, capped at MaxBackoff
+	want := []time.Duration{backoff, 2 * backoff, 3 * backoff, 3 * backoff}
+	for i, delay := range want {
+		gap := calls[i+1].Sub(calls[i])
+		if gap < delay || gap > delay+5*backoff {
+			t.Errorf("restart %d came %s after the panic, want about %s", i+1, gap, delay)
+		}
+	}
+	if got := s.Restarts("flaky"); got != 4 {
+		t.Errorf("Restarts = %d, want 4", got)
+	}
+	if got := len(reporter.Panics()); got != 5 {
+		t.Errorf("%d panics reported, want 5", got)
+	}
+	if failed := s.Failed(); !slices.Equal(failed, []string{"flaky"}) {
+		t.Errorf("Failed = %v, want [flaky]", failed)
+	}
+}
+
+func TestBackoffResetsAfterLongRun(t *testing.T) {
+	s := supervisor.New(supervisor.Config{MaxRestarts: 1, Backoff: time.Millisecond, MaxBackoff: time.Millisecond, ResetAfter: 10 * time.Millisecond}, &reporting.Recorder{})
+
+	// Each run lasts past ResetAfter, so no panic is ever the second in a
+	// row and the one restart allowed is never used up.
+	calls := 0
+	s.Run(context.Background(), "steady", func(context.Context) {
+		calls++
+		if calls <= 3 {
+			time.Sleep(20 * time.Millisecond)
+			panic("now and then")
+		}
+	})
+
+	if calls != 4 {
+		t.Errorf("fn called %d times, want 4", calls)
+	}
+	if failed := s.Failed(); len(failed) != 0 {
+		t.Errorf("Failed = %v, want none", failed)
+	}
+}
+
+func TestRunStopsWithContext(t *testing.T) {
+	s := supervisor.New(supervisor.Config{MaxRestarts: 5, Backoff: time.Hour, MaxBackoff: time.Hour, ResetAfter: time.Minute}, &reporting.Recorder{})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Run(ctx, "waiting", func(context.Context) { panic("once") })
+	}()
+	// Run is now waiting out the hour-long backoff.
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run didn't return when ctx was canceled during the backoff")
+	}
+	if failed := s.Failed(); len(failed) != 0 {
+		t.Errorf("Failed = %v after canceling, want none", failed)
+	}
+}
@@ -0,0 +1,37 @@
+package testutil
+
+import (
+	"sync"
+
+	routes "github.com/sujalbistaa/whispr/internal/http"
+)
+
+// Broadcasts is a routes.Broadcaster that records the messages handlers
+// send instead of delivering them. Pass it to NewTestServer with
+// server.WithBroadcaster; clients from DialWS then receive nothing.
+type Broadcasts struct {
+	mu       sync.Mutex
+	messages []routes.WsMessage
+}
+
+// Broadcast records msg.
+func (b *Broadcasts) Broadcast(msg routes.WsMessage) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.messages = append(b.messages, msg)
+	return nil
+}
+
+// Messages returns the messages sent so far, oldest first.
+func (b *Broadcasts) Messages() []routes.WsMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]routes.WsMessage(nil), b.messages...)
+}
+
+// Reset forgets the messages sent so far.
+func (b *Broadcasts) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.messages = nil
+}
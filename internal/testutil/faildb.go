@@ -0,0 +1,138 @@
+package testutil
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/sujalbistaa/whispr/internal/db"
+)
+
+// ErrDBDown is what every statement on a FailingDB returns while it is
+// down.
+var ErrDBDown = errors.New("testutil: database is down")
+
+// FailingDB is a migrated in-memory SQLite database that can be switched
+// off, after which pings and statements fail as if the server had gone
+// away. Its data survives being switched back on. Pass DB to
+// NewTestServer with server.WithDB.
+type FailingDB struct {
+	DB   *gorm.DB
+	down atomic.Bool
+}
+
+// NewFailingDB returns a FailingDB that is up, closed when the test ends.
+func NewFailingDB(t testing.TB) *FailingDB {
+	t.Helper()
+	probe, err := sql.Open(sqlite.DriverName, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := &FailingDB{}
+	conn := sql.OpenDB(&failingConnector{dsn: "file::memory:", driver: probe.Driver(), down: &f.down})
+	probe.Close()
+	// One connection that is never recycled, or the database goes with it.
+	conn.SetMaxOpenConns(1)
+	conn.SetMaxIdleConns(1)
+	t.Cleanup(func() { conn.Close() })
+
+	f.DB, err = gorm.Open(sqlite.Dialector{Conn: conn}, &gorm.Config{Logger: logger.Discard, TranslateError: true})
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	if err := db.Migrate(f.DB); err != nil {
+		t.Fatalf("migrating database: %v", err)
+	}
+	return f
+}
+
+// SetDown switches the database off or back on.
+func (f *FailingDB) SetDown(down bool) {
+	f.down.Store(down)
+}
+
+type failingConnector struct {
+	dsn    string
+	driver driver.Driver
+	down   *atomic.Bool
+}
+
+func (c *failingConnector) Connect(context.Context) (driver.Conn, error) {
+	if c.down.Load() {
+		return nil, ErrDBDown
+	}
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &failingConn{conn: conn, down: c.down}, nil
+}
+
+func (c *failingConnector) Driver() driver.Driver { return c.driver }
+
+// failingConn passes everything through to the SQLite connection while
+// the database is up. It never reports driver.ErrBadConn, so database/sql
+// keeps the connection, and the in-memory database, open.
+type failingConn struct {
+	conn driver.Conn
+	down *atomic.Bool
+}
+
+func (c *failingConn) check() error {
+	if c.down.Load() {
+		return ErrDBDown
+	}
+	return nil
+}
+
+func (c *failingConn) Close() error { return c.conn.Close() }
+
+func (c *failingConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *failingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	return c.conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+}
+
+func (c *failingConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *failingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	return c.conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c *failingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	return c.conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c *failingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := c.check(); err != nil {
+		return nil, err
+	}
+	return c.conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+func (c *failingConn) Ping(ctx context.Context) error {
+	if err := c.check(); err != nil {
+		return err
+	}
+	return c.conn.(driver.Pinger).Ping(ctx)
+}
@@ -0,0 +1,415 @@
+// Package testutil runs a complete whispr server for tests: a fresh
+// database with every migration applied, a running WebSocket hub and the
+// real routes, served by httptest. Helpers drive it the way a client
+// would.
+//
+// The database is an in-memory SQLite one per server. Set
+// TEST_DATABASE_URL to run against another database instead, e.g.
+// Postgres in CI; its tables are emptied when each server starts, so
+// point it at a database used for nothing else, and don't run tests
+// that share it in parallel.
+//
+// NewTestServer configures the server through the environment with
+// t.Setenv, so it can't be used from parallel tests either. Tests set
+// anything else they need the same way before calling it; setting
+// X_ADMIN_TOKEN, even to "", replaces AdminToken.
+package testutil
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"gorm.io/gorm"
+
+	"github.com/sujalbistaa/whispr/internal/config"
+	"github.com/sujalbistaa/whispr/internal/db"
+	"github.com/sujalbistaa/whispr/internal/models"
+	"github.com/sujalbistaa/whispr/internal/reporting"
+	"github.com/sujalbistaa/whispr/internal/server"
+	"github.com/sujalbistaa/whispr/internal/ws"
+)
+
+// AdminToken is the admin-role token every test server accepts.
+const AdminToken = "test-admin-token"
+
+// wsTimeout bounds DialWS and is the default wait for a message.
+const wsTimeout = 2 * time.Second
+
+// TestServer is a running server plus handles on its internals.
+type TestServer struct {
+	*httptest.Server
+	App *server.Server
+	Hub *ws.Hub
+	DB  *gorm.DB
+	// Panics recovered in handlers, the hub and the workers. They are
+	// also logged when the test ends.
+	Panics *reporting.Recorder
+
+	// lastIP numbers the client addresses NewRequest hands out.
+	lastIP atomic.Uint32
+}
+
+// NewTestServer starts a server and stops it when the test ends. opts are
+// passed on to server.New after the test's own.
+func NewTestServer(t testing.TB, opts ...server.Option) *TestServer {
+	t.Helper()
+	t.Setenv("DATABASE_URL", databaseURL())
+	if _, set := os.LookupEnv("X_ADMIN_TOKEN"); !set {
+		t.Setenv("X_ADMIN_TOKEN", AdminToken)
+	}
+	t.Setenv("MIGRATE_ON_START", "true")
+	// NewRequest sets a client address per request in X-Forwarded-For.
+	t.Setenv("TRUSTED_PROXIES", "127.0.0.1")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("loading config: %v", err)
+	}
+	database := openDB(t, cfg.DatabaseURL, cfg.DB)
+
+	panics := &reporting.Recorder{}
+	hub := ws.NewHub()
+	hub.Reporter = panics
+	go hub.Run()
+	logger := slog.New(slog.NewTextHandler(testWriter{t}, &slog.HandlerOptions{Level: slog.LevelWarn}))
+	app, err := server.New(cfg, append([]server.Option{
+		server.WithDB(database),
+		server.WithHub(hub),
+		server.WithLogger(logger),
+		server.WithReporter(panics),
+	}, opts...)...)
+	if err != nil {
+		t.Fatalf("building server: %v", err)
+	}
+
+	ts := &TestServer{Server: httptest.NewServer(app.Handler()), App: app, Hub: hub, DB: app.DB(), Panics: panics}
+	t.Cleanup(func() {
+		for _, p := range panics.Panics() {
+			t.Logf("recovered panic (%s): %v\n%s", p.Source, p.Value, p.Stack)
+		}
+		ts.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := app.Shutdown(ctx); err != nil {
+			t.Errorf("shutting down: %v", err)
+		}
+	})
+	return ts
+}
+
+// NewDB returns an empty, fully migrated database for tests that need
+// one without a server, closed when the test ends. Like NewTestServer it
+// can't be used from parallel tests.
+func NewDB(t testing.TB) *gorm.DB {
+	t.Helper()
+	t.Setenv("DATABASE_URL", databaseURL())
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("loading config: %v", err)
+	}
+	return openDB(t, cfg.DatabaseURL, cfg.DB)
+}
+
+func databaseURL() string {
+	if url := os.Getenv("TEST_DATABASE_URL"); url != "" {
+		return url
+	}
+	return "sqlite://file::memory:"
+}
+
+func openDB(t testing.TB, url string, opts db.Options) *gorm.DB {
+	t.Helper()
+	database, err := db.Init(url, opts)
+	if err != nil {
+		t.Fatalf("opening database: %v", err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := database.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if err := db.Migrate(database); err != nil {
+		t.Fatalf("migrating database: %v", err)
+	}
+	if err := reset(database); err != nil {
+		t.Fatalf("emptying database: %v", err)
+	}
+	return database
+}
+
+// reset deletes every row but the default board, children first, so a
+// shared database starts each server empty. It is a no-op on a fresh one.
+func reset(database *gorm.DB) error {
+	all := database.Unscoped().Session(&gorm.Session{AllowGlobalUpdate: true})
+	for _, model := range []any{&models.Vote{}, &models.Bookmark{}, &models.ScoreSnapshot{}, &models.Post{}, &models.AuditLog{}, &models.BannedIP{}, &models.Announcement{}, &models.PushSubscription{}, &models.Identity{}, &models.APIKey{}} {
+		if err := all.Delete(model).Error; err != nil {
+			return err
+		}
+	}
+	return all.Where("id <> ?", models.DefaultBoardID).Delete(&models.Board{}).Error
+}
+
+// ClientIP returns an address no earlier request has used, so rate limits
+// and the one-vote rule start fresh for it.
+func (s *TestServer) ClientIP() string {
+	n := s.lastIP.Add(1)
+	return fmt.Sprintf("10.%d.%d.%d", n>>16&0xff, n>>8&0xff, n&0xff)
+}
+
+// NewRequest builds a request from a new client address (see ClientIP).
+// body, if not nil, is sent as JSON; a []byte is sent as it is.
+func (s *TestServer) NewRequest(t testing.TB, method, path string, body any) *http.Request {
+	t.Helper()
+	return s.NewRequestFrom(t, s.ClientIP(), method, path, body)
+}
+
+// NewRequestFrom is NewRequest from a chosen client address.
+func (s *TestServer) NewRequestFrom(t testing.TB, ip, method, path string, body any) *http.Request {
+	t.Helper()
+	var reader io.Reader
+	if raw, ok := body.([]byte); ok {
+		reader = bytes.NewReader(raw)
+	} else if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("encoding request body: %v", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequest(method, s.URL+path, reader)
+	if err != nil {
+		t.Fatalf("building request: %v", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("X-Forwarded-For", ip)
+	return req
+}
+
+// AdminRequest is NewRequest carrying AdminToken.
+func (s *TestServer) AdminRequest(t testing.TB, method, path string, body any) *http.Request {
+	t.Helper()
+	req := s.NewRequest(t, method, path, body)
+	req.Header.Set("X-Admin-Token", AdminToken)
+	return req
+}
+
+// Do sends req and returns the status and body.
+func (s *TestServer) Do(t testing.TB, req *http.Request) (int, []byte) {
+	t.Helper()
+	resp, err := s.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", req.Method, req.URL.Path, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("%s %s: reading body: %v", req.Method, req.URL.Path, err)
+	}
+	return resp.StatusCode, body
+}
+
+// CreatePost posts content from a new client and returns the stored post.
+func (s *TestServer) CreatePost(t testing.TB, content string) models.Post {
+	t.Helper()
+	var post models.Post
+	s.expect(t, s.NewRequest(t, http.MethodPost, "/api/v1/posts", map[string]string{"content": content}), http.StatusCreated, &post)
+	return post
+}
+
+// Vote votes value on post id from a new client and returns the new score.
+func (s *TestServer) Vote(t testing.TB, id uint, value int) int {
+	t.Helper()
+	var result struct {
+		Score int `json:"score"`
+	}
+	s.expect(t, s.NewRequest(t, http.MethodPost, s.postPath(id)+"/vote", map[string]int{"value": value}), http.StatusOK, &result)
+	return result.Score
+}
+
+// VoteFrom votes value on post id from ip and returns the status, so
+// tests can check duplicates and missing posts.
+func (s *TestServer) VoteFrom(t testing.TB, ip string, id uint, value int) int {
+	t.Helper()
+	status, _ := s.Do(t, s.NewRequestFrom(t, ip, http.MethodPost, s.postPath(id)+"/vote", map[string]int{"value": value}))
+	return status
+}
+
+// Hide hides post id as an admin and reports whether it was already
+// hidden.
+func (s *TestServer) Hide(t testing.TB, id uint) bool {
+	t.Helper()
+	req := s.AdminRequest(t, http.MethodDelete, "/api/v1/admin/posts/"+strconv.FormatUint(uint64(id), 10), nil)
+	var result struct {
+		AlreadyHidden bool `json:"alreadyHidden"`
+	}
+	s.expect(t, req, http.StatusOK, &result)
+	return result.AlreadyHidden
+}
+
+func (s *TestServer) postPath(id uint) string {
+	return "/api/v1/posts/" + strconv.FormatUint(uint64(id), 10)
+}
+
+// expect sends req, fails the test unless it answers with status, and
+// decodes the body into out.
+func (s *TestServer) expect(t testing.TB, req *http.Request, status int, out any) {
+	t.Helper()
+	got, body := s.Do(t, req)
+	if got != status {
+		t.Fatalf("%s %s: status %d, want %d: %s", req.Method, req.URL.Path, got, status, body)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		t.Fatalf("%s %s: decoding %s: %v", req.Method, req.URL.Path, body, err)
+	}
+}
+
+// Message is one broadcast received by a WSClient.
+type Message struct {
+	Type  string          `json:"type"`
+	Data  json.RawMessage `json:"data"`
+	Board string          `json:"board"`
+}
+
+// WSClient is a WebSocket connection that collects broadcasts.
+type WSClient struct {
+	conn     *websocket.Conn
+	messages chan Message
+}
+
+// DialWS connects to /ws and returns once the hub has registered the
+// client, so no broadcast sent afterwards is missed. It is closed when
+// the test ends.
+func (s *TestServer) DialWS(t testing.TB) *WSClient {
+	t.Helper()
+	return s.DialBoards(t)
+}
+
+// DialBoards is DialWS following the boards with the given slugs rather
+// than the default board.
+func (s *TestServer) DialBoards(t testing.TB, slugs ...string) *WSClient {
+	t.Helper()
+	path := "/ws"
+	if len(slugs) > 0 {
+		path += "?board=" + strings.Join(slugs, ",")
+	}
+	before := s.Hub.ClientCount()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(s.URL, "http")+path, nil)
+	if err != nil {
+		t.Fatalf("dialing /ws: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	for deadline := time.Now().Add(wsTimeout); s.Hub.ClientCount() <= before; {
+		if time.Now().After(deadline) {
+			t.Fatal("WebSocket client was never registered")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	client := &WSClient{conn: conn, messages: make(chan Message, 64)}
+	go client.read()
+	return client
+}
+
+func (c *WSClient) read() {
+	defer close(c.messages)
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		var msg Message
+		if json.Unmarshal(data, &msg) == nil {
+			c.messages <- msg
+		}
+	}
+}
+
+// Next returns the next message, failing the test if none arrives within
+// timeout (2s if zero).
+func (c *WSClient) Next(t testing.TB, timeout time.Duration) Message {
+	t.Helper()
+	if timeout == 0 {
+		timeout = wsTimeout
+	}
+	select {
+	case msg, ok := <-c.messages:
+		if !ok {
+			t.Fatal("WebSocket closed while waiting for a message")
+		}
+		return msg
+	case <-time.After(timeout):
+		t.Fatalf("no WebSocket message within %s", timeout)
+	}
+	return Message{}
+}
+
+// Expect skips messages until one of type typ arrives and returns it,
+// failing the test after timeout (2s if zero).
+func (c *WSClient) Expect(t testing.TB, typ string, timeout time.Duration) Message {
+	t.Helper()
+	if timeout == 0 {
+		timeout = wsTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			t.Fatalf("no %q WebSocket message within %s", typ, timeout)
+		}
+		msg := c.Next(t, remaining)
+		if msg.Type == typ {
+			return msg
+		}
+	}
+}
+
+// Poll is Expect without failing: it reports whether a message of type
+// typ arrived within wait.
+func (c *WSClient) Poll(t testing.TB, typ string, wait time.Duration) (Message, bool) {
+	t.Helper()
+	deadline := time.After(wait)
+	for {
+		select {
+		case msg, ok := <-c.messages:
+			if !ok {
+				return Message{}, false
+			}
+			if msg.Type == typ {
+				return msg, true
+			}
+		case <-deadline:
+			return Message{}, false
+		}
+	}
+}
+
+// ExpectNone fails the test if a message of type typ arrives within
+// wait, skipping messages of other types.
+func (c *WSClient) ExpectNone(t testing.TB, typ string, wait time.Duration) {
+	t.Helper()
+	if msg, ok := c.Poll(t, typ, wait); ok {
+		t.Fatalf("unexpected %q WebSocket message: %s", typ, msg.Data)
+	}
+}
+
+// testWriter sends the server's log lines to the test log.
+type testWriter struct{ t testing.TB }
+
+func (w testWriter) Write(p []byte) (int, error) {
+	w.t.Log(strings.TrimSuffix(string(p), "\n"))
+	return len(p), nil
+}
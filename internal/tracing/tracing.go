@@ -0,0 +1,51 @@
+// Package tracing exports OpenTelemetry traces over OTLP/HTTP. It is off
+// unless OTEL_EXPORTER_OTLP_ENDPOINT is set; until Setup runs, the global
+// tracer provider is OpenTelemetry's no-op one, so spans started elsewhere
+// cost next to nothing.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+)
+
+// Config configures trace export.
+type Config struct {
+	Endpoint    string // OTLP/HTTP collector, e.g. http://localhost:4318
+	ServiceName string
+}
+
+// Enabled reports whether traces should be exported.
+func (c Config) Enabled() bool {
+	return c.Endpoint != ""
+}
+
+// Setup installs a tracer provider that batches spans to cfg.Endpoint and
+// propagates W3C trace context. The exporter also honours the other
+// OTEL_EXPORTER_OTLP_* variables, e.g. for headers. Call the returned
+// function on shutdown to flush what's left.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("creating OTLP exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(cfg.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("building trace resource: %w", err)
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}
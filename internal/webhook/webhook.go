@@ -0,0 +1,403 @@
+// Package webhook delivers event notifications to external HTTP endpoints
+// such as a Discord channel. Events are queued without blocking the caller
+// and delivered by background workers with retries.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sujalbistaa/whispr/internal/metrics"
+	"github.com/sujalbistaa/whispr/internal/reporting"
+)
+
+// Event names a kind of notification. Endpoints subscribe to a subset.
+type Event string
+
+const (
+	EventNewPost        Event = "new_post"
+	EventPostHidden     Event = "post_hidden"      // A moderator hid a post
+	EventPostAutoHidden Event = "post_auto_hidden" // A new post was held for moderation as a near-duplicate
+
+	// Reserved for user reports; accepted in subscriptions but not
+	// emitted yet.
+	EventPostReported Event = "post_reported"
+)
+
+// Events lists every event name an endpoint may subscribe to.
+var Events = []Event{EventNewPost, EventPostHidden, EventPostReported, EventPostAutoHidden}
+
+// Endpoint is one receiver. An empty Events list subscribes to everything.
+type Endpoint struct {
+	URL    string
+	Events []Event
+}
+
+// Wants reports whether the endpoint subscribes to event.
+func (e Endpoint) Wants(event Event) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, want := range e.Events {
+		if want == event {
+			return true
+		}
+	}
+	return false
+}
+
+// discord reports whether the endpoint is a Discord webhook, which only
+// accepts its own message format.
+func (e Endpoint) discord() bool {
+	u, err := url.Parse(e.URL)
+	if err != nil {
+		return false
+	}
+	host := strings.TrimPrefix(u.Hostname(), "www.")
+	return (host == "discord.com" || host == "discordapp.com") && strings.HasPrefix(u.Path, "/api/webhooks/")
+}
+
+// Config configures a Dispatcher.
+type Config struct {
+	Endpoints   []Endpoint
+	Secret      string        // HMAC key for X-Whispr-Signature; empty sends no signature
+	QueueSize   int           // Pending deliveries before new events are dropped
+	MaxAttempts int           // Tries per delivery before it is dead-lettered
+	Timeout     time.Duration // Per-request timeout
+}
+
+// Enabled reports whether any endpoint is configured.
+func (c Config) Enabled() bool {
+	return len(c.Endpoints) > 0
+}
+
+// Post is the data sent with post events.
+type Post struct {
+	ID        uint      `json:"id"`
+	Content   string    `json:"content"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Payload is the JSON body sent to generic endpoints.
+type Payload struct {
+	ID        string    `json:"id"`
+	Event     Event     `json:"event"`
+	CreatedAt time.Time `json:"createdAt"`
+	Data      any       `json:"data"`
+}
+
+// Attempt records one delivery try, for the admin delivery log.
+type Attempt struct {
+	Delivery   string    `json:"delivery"`
+	Event      Event     `json:"event"`
+	URL        string    `json:"url"`
+	Attempt    int       `json:"attempt"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DeadLetter bool      `json:"deadLetter,omitempty"` // Final failure; no more retries
+	At         time.Time `json:"at"`
+	DurationMS int64     `json:"durationMs"`
+}
+
+const (
+	workers     = 2
+	historySize = 200
+	maxBackoff  = time.Minute
+)
+
+type job struct {
+	endpoint Endpoint
+	event    Event
+	id       string
+	body     []byte
+}
+
+// Dispatcher queues events and delivers them in the background.
+// A nil *Dispatcher is valid and drops everything, so callers don't need
+// to check whether webhooks are configured.
+type Dispatcher struct {
+	cfg      Config
+	client   *http.Client
+	queue    chan job
+	reporter reporting.ErrorReporter
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	dropped atomic.Uint64
+
+	mu      sync.Mutex
+	history []Attempt // Ring buffer of recent attempts, oldest first
+}
+
+// New starts a dispatcher for cfg, or returns nil when no endpoint is
+// configured. A panic while delivering goes to reporter and drops only
+// that delivery. Call Close on shutdown.
+func New(cfg Config, reporter reporting.ErrorReporter) *Dispatcher {
+	if !cfg.Enabled() {
+		return nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		cfg:      cfg,
+		client:   &http.Client{Timeout: cfg.Timeout},
+		queue:    make(chan job, cfg.QueueSize),
+		reporter: reporter,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+	for i := 0; i < workers; i++ {
+		d.wg.Add(1)
+		go d.work()
+	}
+	return d
+}
+
+// Send queues event for every subscribed endpoint. It never blocks: when
+// the queue is full the delivery is dropped and counted.
+func (d *Dispatcher) Send(event Event, data any) {
+	if d == nil {
+		return
+	}
+	payload := Payload{ID: newID(), Event: event, CreatedAt: time.Now().UTC(), Data: data}
+	var generic []byte
+	for _, endpoint := range d.cfg.Endpoints {
+		if !endpoint.Wants(event) {
+			continue
+		}
+		var body []byte
+		var err error
+		if endpoint.discord() {
+			body, err = json.Marshal(discordMessage(payload))
+		} else {
+			if generic == nil {
+				generic, err = json.Marshal(payload)
+			}
+			body = generic
+		}
+		if err != nil {
+			slog.Error("encoding webhook payload", "event", event, "err", err)
+			return
+		}
+		select {
+		case d.queue <- job{endpoint: endpoint, event: event, id: payload.ID, body: body}:
+		default:
+			d.dropped.Add(1)
+			metrics.WebhookDropped.Inc()
+			slog.Warn("webhook queue full, dropping delivery", "event", event, "url", redact(endpoint.URL))
+		}
+	}
+}
+
+// Close stops the workers. Deliveries still queued or waiting for a retry
+// are abandoned and logged.
+func (d *Dispatcher) Close() {
+	if d == nil {
+		return
+	}
+	d.cancel()
+	d.wg.Wait()
+	if n := len(d.queue); n > 0 {
+		slog.Warn("abandoning queued webhook deliveries on shutdown", "count", n)
+	}
+}
+
+// Stats summarizes the dispatcher for the admin endpoint.
+type Stats struct {
+	Endpoints int       `json:"endpoints"`
+	Queued    int       `json:"queued"`
+	Dropped   uint64    `json:"dropped"`
+	Attempts  []Attempt `json:"attempts"` // Newest first
+}
+
+// Stats returns counters and recent attempts; with failedOnly, successful
+// attempts are left out.
+func (d *Dispatcher) Stats(failedOnly bool) Stats {
+	if d == nil {
+		return Stats{Attempts: []Attempt{}}
+	}
+	d.mu.Lock()
+	attempts := make([]Attempt, 0, len(d.history))
+	for i := len(d.history) - 1; i >= 0; i-- {
+		if a := d.history[i]; !failedOnly || a.Error != "" {
+			attempts = append(attempts, a)
+		}
+	}
+	d.mu.Unlock()
+	return Stats{
+		Endpoints: len(d.cfg.Endpoints),
+		Queued:    len(d.queue),
+		Dropped:   d.dropped.Load(),
+		Attempts:  attempts,
+	}
+}
+
+func (d *Dispatcher) work() {
+	defer d.wg.Done()
+	for {
+		select {
+		case <-d.ctx.Done():
+			return
+		case j := <-d.queue:
+			d.deliverRecovering(j)
+		}
+	}
+}
+
+func (d *Dispatcher) deliverRecovering(j job) {
+	defer reporting.Recover(d.ctx, d.reporter, "webhook")
+	d.deliver(j)
+}
+
+// deliver tries j until it succeeds, MaxAttempts is reached or the
+// dispatcher closes, backing off exponentially between tries.
+func (d *Dispatcher) deliver(j job) {
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		start := time.Now()
+		status, err := d.post(j)
+		a := Attempt{
+			Delivery:   j.id,
+			Event:      j.event,
+			URL:        redact(j.endpoint.URL),
+			Attempt:    attempt,
+			StatusCode: status,
+			At:         start.UTC(),
+			DurationMS: time.Since(start).Milliseconds(),
+		}
+		if err == nil {
+			d.record(a)
+			metrics.WebhookDelivered("ok")
+			return
+		}
+		a.Error = err.Error()
+		if attempt >= d.cfg.MaxAttempts {
+			// Dead letter: keep the body in the log so it can be replayed.
+			a.DeadLetter = true
+			d.record(a)
+			metrics.WebhookDelivered("dead_letter")
+			slog.Error("webhook delivery failed permanently",
+				"delivery", j.id, "event", j.event, "url", a.URL, "attempts", attempt, "err", err, "body", string(j.body))
+			return
+		}
+		d.record(a)
+		metrics.WebhookDelivered("retry")
+
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// post sends one request. Any 2xx counts as delivered.
+func (d *Dispatcher) post(j job) (int, error) {
+	req, err := http.NewRequestWithContext(d.ctx, http.MethodPost, j.endpoint.URL, bytes.NewReader(j.body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Whispr-Webhook/1")
+	req.Header.Set("X-Whispr-Event", string(j.event))
+	req.Header.Set("X-Whispr-Delivery", j.id)
+	req.Header.Set("X-Whispr-Timestamp", timestamp)
+	if d.cfg.Secret != "" {
+		req.Header.Set("X-Whispr-Signature", "sha256="+Sign(d.cfg.Secret, timestamp, j.body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *Dispatcher) record(a Attempt) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if len(d.history) == historySize {
+		copy(d.history, d.history[1:])
+		d.history = d.history[:historySize-1]
+	}
+	d.history = append(d.history, a)
+}
+
+// Sign returns the hex HMAC-SHA256 of "timestamp.body" under secret.
+// Receivers recompute it from the X-Whispr-Timestamp header and the raw
+// body, and should reject stale timestamps to stop replays.
+func Sign(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// discordMessage renders a payload as a Discord chat message.
+func discordMessage(p Payload) map[string]any {
+	var text string
+	switch p.Event {
+	case EventNewPost:
+		text = "**New post**"
+	case EventPostHidden:
+		text = "**Post hidden by a moderator**"
+	case EventPostAutoHidden:
+		text = "**Post held for review**"
+	case EventPostReported:
+		text = "**Post reported**"
+	default:
+		text = "**" + string(p.Event) + "**"
+	}
+	if post, ok := p.Data.(Post); ok {
+		text += " " + post.URL + "\n> " + strings.ReplaceAll(post.Content, "\n", "\n> ")
+	}
+	// Discord rejects messages over 2000 characters.
+	if runes := []rune(text); len(runes) > 2000 {
+		text = string(runes[:1999]) + "…"
+	}
+	return map[string]any{
+		"content":          text,
+		"allowed_mentions": map[string]any{"parse": []string{}},
+	}
+}
+
+// redact strips the path and query, which often carry the webhook secret
+// (Discord puts its token in the path), from URLs shown in logs.
+func redact(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "invalid URL"
+	}
+	return u.Scheme + "://" + u.Host + "/…"
+}
+
+func newID() string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
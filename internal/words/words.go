@@ -0,0 +1,104 @@
+// Package words counts the terms posts use, for a word cloud of what
+// campus is talking about. Text is split on anything that isn't a letter,
+// digit or combining mark, lowercased, and stripped of stop words from an
+// embedded list covering English, romanized Nepali and Devanagari.
+package words
+
+import (
+	_ "embed"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed stopwords.txt
+var stopwordList string
+
+// stopwords holds stopwordList, skipping blank lines and # comments.
+var stopwords = map[string]bool{}
+
+func init() {
+	for _, line := range strings.Split(stopwordList, "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			stopwords[line] = true
+		}
+	}
+}
+
+// minRunes is the shortest term counted. Devanagari vowel signs are runes
+// of their own, so "को" is two.
+const minRunes = 2
+
+// Tokens returns the terms of text in order, lowercased, without stop
+// words, numbers or terms shorter than minRunes. Apostrophes inside a
+// word are dropped, so "don't" is "dont", and zero-width joiners, which
+// only change how a Devanagari conjunct is drawn, are ignored.
+func Tokens(text string) []string {
+	var tokens []string
+	var b strings.Builder
+	letters := false
+	flush := func() {
+		term := b.String()
+		b.Reset()
+		if letters && utf8.RuneCountInString(term) >= minRunes && !stopwords[term] {
+			tokens = append(tokens, term)
+		}
+		letters = false
+	}
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case r == '\'' || r == '’' || unicode.Is(unicode.Cf, r):
+		case unicode.IsLetter(r) || unicode.IsMark(r):
+			b.WriteRune(r)
+			letters = true
+		case unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// Term is a term and how often it was used.
+type Term struct {
+	Term  string `json:"term"`
+	Count int    `json:"count"`
+}
+
+// Counter tallies the terms of the texts added to it. The zero value is
+// ready to use.
+type Counter struct {
+	counts map[string]int
+}
+
+// Add counts the terms of text.
+func (c *Counter) Add(text string) {
+	if c.counts == nil {
+		c.counts = map[string]int{}
+	}
+	for _, term := range Tokens(text) {
+		c.counts[term]++
+	}
+}
+
+// Top returns the n most used terms, most used first and ties in
+// alphabetical order.
+func (c *Counter) Top(n int) []Term {
+	terms := make([]Term, 0, len(c.counts))
+	for term, count := range c.counts {
+		terms = append(terms, Term{Term: term, Count: count})
+	}
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].Count != terms[j].Count {
+			return terms[i].Count > terms[j].Count
+		}
+		return terms[i].Term < terms[j].Term
+	})
+	if len(terms) > n {
+		terms = terms[:n]
+	}
+	return terms
+}
@@ -0,0 +1,64 @@
+package words_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sujalbistaa/whispr/internal/words"
+)
+
+func TestTokens(t *testing.T) {
+	for _, tc := range []struct {
+		name, text string
+		want       []string
+	}{
+		{"english", "The Library is CLOSED again, exams are coming!", []string{"library", "closed", "exams", "coming"}},
+		{"apostrophes", "Don't skip Ram’s party; it's the canteen's fault", []string{"skip", "rams", "party", "canteens", "fault"}},
+		{"numbers and short words", "Room 204 at 9am, x y z", []string{"room", "9am"}},
+		{"urls", "see https://www.example.com/notes", []string{"see", "example", "notes"}},
+		{"romanized nepali", "aaja ta canteen ma momo khatam bhayo", []string{"aaja", "canteen", "momo", "khatam"}},
+		{"devanagari", "आज कलेजको क्यान्टिनमा मम सकियो।", []string{"आज", "कलेजको", "क्यान्टिनमा", "मम", "सकियो"}},
+		{"devanagari stop words", "म पनि परीक्षा को तयारी गर्दै छु", []string{"परीक्षा", "तयारी", "गर्दै"}},
+		// Vowel signs and virama are marks, kept in their word.
+		{"devanagari marks", "विद्यार्थी हड्ताल", []string{"विद्यार्थी", "हड्ताल"}},
+		// A zero-width joiner doesn't split or change the word.
+		{"zero-width joiner", "विद्‍यार्थी", []string{"विद्यार्थी"}},
+		{"devanagari digits", "२०८२ साल", []string{"साल"}},
+		{"mixed scripts", "Exam को result आयो!!", []string{"exam", "result", "आयो"}},
+		{"nothing left", "the and is को मा", nil},
+		{"empty", "", nil},
+	} {
+		if got := words.Tokens(tc.text); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: Tokens(%q) = %q, want %q", tc.name, tc.text, got, tc.want)
+		}
+	}
+}
+
+func TestCounterTop(t *testing.T) {
+	var counter words.Counter
+	if got := counter.Top(10); len(got) != 0 {
+		t.Errorf("empty Counter.Top = %v, want none", got)
+	}
+	for _, text := range []string{
+		"Exams exams EXAMS",
+		"canteen momo, canteen",
+		"परीक्षा परीक्षा",
+		"बिदा momo",
+	} {
+		counter.Add(text)
+	}
+	want := []words.Term{
+		{Term: "exams", Count: 3},
+		// Ties in alphabetical order, which puts Latin before Devanagari.
+		{Term: "canteen", Count: 2},
+		{Term: "momo", Count: 2},
+		{Term: "परीक्षा", Count: 2},
+		{Term: "बिदा", Count: 1},
+	}
+	if got := counter.Top(10); !reflect.DeepEqual(got, want) {
+		t.Errorf("Top(10) = %v, want %v", got, want)
+	}
+	if got := counter.Top(2); !reflect.DeepEqual(got, want[:2]) {
+		t.Errorf("Top(2) = %v, want %v", got, want[:2])
+	}
+}
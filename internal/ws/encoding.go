@@ -0,0 +1,96 @@
+package ws
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gorilla/websocket"
+	"github.com/ugorji/go/codec"
+)
+
+// Encoding is how messages are encoded for a client.
+type Encoding uint8
+
+const (
+	// JSON sends each message as it was published, in text frames.
+	JSON Encoding = iota
+	// MessagePack sends each message converted to MessagePack, one per
+	// binary frame.
+	MessagePack
+
+	encodings = iota
+)
+
+// ParseEncoding returns the encoding named s: "json" or "" for JSON, or
+// "msgpack".
+func ParseEncoding(s string) (Encoding, bool) {
+	switch s {
+	case "", "json":
+		return JSON, true
+	case "msgpack":
+		return MessagePack, true
+	}
+	return JSON, false
+}
+
+func (e Encoding) String() string {
+	if e == MessagePack {
+		return "msgpack"
+	}
+	return "json"
+}
+
+// frameType is the WebSocket frame type messages are sent in.
+func (e Encoding) frameType() int {
+	if e == MessagePack {
+		return websocket.BinaryMessage
+	}
+	return websocket.TextMessage
+}
+
+// msgpackHandle writes the current MessagePack spec (str and bin types)
+// with map keys sorted, so equal messages encode the same.
+var msgpackHandle = &codec.MsgpackHandle{WriteExt: true, BasicHandle: codec.BasicHandle{EncodeOptions: codec.EncodeOptions{Canonical: true}}}
+
+// encode converts the published JSON data to e. MessagePack gets the same
+// structure: objects become maps, integers stay integers and other numbers
+// become float64.
+func (e Encoding) encode(data []byte) ([]byte, error) {
+	if e == JSON {
+		return data, nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("decoding message: %w", err)
+	}
+	var out []byte
+	if err := codec.NewEncoderBytes(&out, msgpackHandle).Encode(numbers(value)); err != nil {
+		return nil, fmt.Errorf("encoding %s: %w", e, err)
+	}
+	return out, nil
+}
+
+// numbers replaces the json.Numbers in v with int64s, or float64s when
+// they aren't integers.
+func numbers(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for key, value := range v {
+			v[key] = numbers(value)
+		}
+	case []any:
+		for i, value := range v {
+			v[i] = numbers(value)
+		}
+	}
+	return v
+}
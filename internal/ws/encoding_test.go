@@ -0,0 +1,130 @@
+package ws_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/ugorji/go/codec"
+
+	"github.com/sujalbistaa/whispr/internal/testutil"
+)
+
+// dial connects to /ws with query and waits for the hub to register it.
+func dial(t *testing.T, ts *testutil.TestServer, query string) *websocket.Conn {
+	t.Helper()
+	before := ts.Hub.ClientCount()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws"+query, nil)
+	if err != nil {
+		t.Fatalf("dialing /ws%s: %v", query, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	for deadline := time.Now().Add(2 * time.Second); ts.Hub.ClientCount() <= before; {
+		if time.Now().After(deadline) {
+			t.Fatalf("/ws%s was never registered", query)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return conn
+}
+
+// next reads one frame, which must be of type frame.
+func next(t *testing.T, conn *websocket.Conn, frame int) []byte {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	typ, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("reading a message: %v", err)
+	}
+	if typ != frame {
+		t.Fatalf("message in frame type %d, want %d: %q", typ, frame, data)
+	}
+	return data
+}
+
+// TestMessagePackRoundTrip sends every message type the server
+// broadcasts to a client of each encoding and checks the MessagePack
+// decodes to the same message as the JSON.
+func TestMessagePackRoundTrip(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	plain := dial(t, ts, "")
+	explicit := dial(t, ts, "?encoding=json")
+	packed := dial(t, ts, "?encoding=msgpack")
+
+	admin := func(method, path string, body any) {
+		t.Helper()
+		if status, resp := ts.Do(t, ts.AdminRequest(t, method, path, body)); status >= 300 {
+			t.Fatalf("%s %s: status %d: %s", method, path, status, resp)
+		}
+	}
+	var post uint
+	for _, step := range []struct {
+		typ string
+		do  func()
+	}{
+		{"new_post", func() { post = ts.CreatePost(t, "sent to every encoding").ID }},
+		{"vote", func() { ts.Vote(t, post, 1) }},
+		{"content_warning", func() {
+			admin(http.MethodPatch, fmt.Sprintf("/api/v1/admin/posts/%d/content-warning", post), map[string]string{"contentWarning": "substances"})
+		}},
+		{"delete", func() { ts.Hide(t, post) }},
+		{"announcement", func() {
+			admin(http.MethodPost, "/api/v1/admin/announce", map[string]any{"message": "exams postponed", "level": "warning", "ttlSeconds": 60})
+		}},
+		{"maintenance", func() {
+			admin(http.MethodPost, "/api/v1/admin/maintenance", map[string]string{"mode": "readonly"})
+		}},
+	} {
+		step.do()
+
+		raw := next(t, plain, websocket.TextMessage)
+		if other := next(t, explicit, websocket.TextMessage); string(other) != string(raw) {
+			t.Errorf("%s: ?encoding=json sent %s, the default sent %s", step.typ, other, raw)
+		}
+		var want map[string]any
+		if err := json.Unmarshal(raw, &want); err != nil {
+			t.Fatalf("%s: decoding JSON %s: %v", step.typ, raw, err)
+		}
+		if want["type"] != step.typ {
+			t.Fatalf("JSON client got %s, want a %s message", raw, step.typ)
+		}
+
+		handle := &codec.MsgpackHandle{}
+		handle.RawToString = true
+		handle.MapType = reflect.TypeOf(map[string]any(nil))
+		var got map[string]any
+		if err := codec.NewDecoderBytes(next(t, packed, websocket.BinaryMessage), handle).Decode(&got); err != nil {
+			t.Fatalf("%s: decoding MessagePack: %v", step.typ, err)
+		}
+		if id, ok := got["data"].(map[string]any)["id"]; ok {
+			switch id.(type) {
+			case int64, uint64:
+			default:
+				t.Errorf("%s: id decoded as %T, want an integer", step.typ, id)
+			}
+		}
+		// Compare through JSON, which has one number type.
+		repacked, err := json.Marshal(got)
+		if err != nil {
+			t.Fatalf("%s: %v", step.typ, err)
+		}
+		var roundTripped map[string]any
+		json.Unmarshal(repacked, &roundTripped)
+		if !reflect.DeepEqual(roundTripped, want) {
+			t.Errorf("%s: MessagePack decodes to\n%s\nwant\n%s", step.typ, repacked, raw)
+		}
+	}
+}
+
+func TestUnknownEncoding(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	_, resp, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(ts.URL, "http")+"/ws?encoding=protobuf", nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("?encoding=protobuf: %v, %+v; want a 400 instead of an upgrade", err, resp)
+	}
+}
@@ -1,11 +1,22 @@
 package ws
 
 import (
-	"log"
+	"context"
+	"errors"
+	"log/slog"
 	"net/http"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/sujalbistaa/whispr/internal/logging"
+	"github.com/sujalbistaa/whispr/internal/reporting"
+	"github.com/sujalbistaa/whispr/internal/supervisor"
 )
 
 const (
@@ -17,8 +28,20 @@ const (
 	pingPeriod = (pongWait * 9) / 10
 	// Maximum message size allowed from peer.
 	maxMessageSize = 512
+	// Messages queued for the Run loop before Publish starts dropping them.
+	broadcastQueue = 256
+	// Recent messages kept for clients that poll instead of holding a
+	// WebSocket open.
+	replaySize = 256
 )
 
+// tracerName identifies the hub's spans.
+const tracerName = "github.com/sujalbistaa/whispr/internal/ws"
+
+// ErrBroadcastFull is returned by Publish when the hub is too far behind
+// (or not running) to take another message.
+var ErrBroadcastFull = errors.New("ws: broadcast queue is full")
+
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
@@ -35,6 +58,31 @@ type Client struct {
 	conn *websocket.Conn
 	// Buffered channel of outbound messages.
 	Send chan []byte
+	// Rooms the client receives messages for, besides those sent to
+	// everyone; nil means every room.
+	rooms map[string]bool
+	// How messages are encoded for the client.
+	encoding Encoding
+}
+
+// Message is a message queued for the clients in Room, or for every
+// client when Room is empty.
+type Message struct {
+	Room string
+	Data []byte
+}
+
+// wants reports whether the client receives msg.
+func (c *Client) wants(msg Message) bool {
+	return msg.Room == "" || c.rooms == nil || c.rooms[msg.Room]
+}
+
+// Event is a message the hub has fanned out, numbered in the order it was
+// sent. Numbers start at 1 and restart with the process.
+type Event struct {
+	Seq  uint64
+	Room string
+	Data []byte
 }
 
 // readPump pumps messages from the websocket connection to the hub.
@@ -53,7 +101,7 @@ func (c *Client) readPump() {
 		_, _, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("error: %v", err)
+				slog.Warn("unexpected WS close", "err", err)
 			}
 			break
 		}
@@ -77,16 +125,19 @@ func (c *Client) writePump() {
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
+			w, err := c.conn.NextWriter(c.encoding.frameType())
 			if err != nil {
 				return
 			}
 			w.Write(message)
 
 			// Add queued chat messages to the current websocket message.
-			n := len(c.Send)
-			for i := 0; i < n; i++ {
-				w.Write(<-c.Send)
+			// MessagePack clients get one message per frame.
+			if c.encoding == JSON {
+				n := len(c.Send)
+				for i := 0; i < n; i++ {
+					w.Write(<-c.Send)
+				}
 			}
 
 			if err := w.Close(); err != nil {
@@ -106,62 +157,246 @@ func (c *Client) writePump() {
 type Hub struct {
 	// Registered clients.
 	Clients map[*Client]bool
-	// Inbound messages from the clients. (We'll use this later)
-	Broadcast chan []byte
+	// Messages for the Run loop to fan out.
+	Broadcast chan Message
 	// Register requests from the clients.
 	Register chan *Client
 	// Unregister requests from clients.
 	Unregister chan *Client
+	// Number of registered clients, readable from any goroutine.
+	clientCount atomic.Int64
+	// Registered clients per room, "" counting those in every room.
+	roomsMu    sync.Mutex
+	roomCounts map[string]int
+	// The last replaySize messages fanned out, oldest overwritten first,
+	// and a channel closed (then replaced) whenever one is added.
+	replayMu sync.Mutex
+	replay   [replaySize]Event
+	seq      uint64
+	recorded chan struct{}
+	// Whether the Run loop is active.
+	running atomic.Bool
+	// Receives panics from the Run loop; they are logged if nil. Set it
+	// before calling Run.
+	Reporter reporting.ErrorReporter
+	// Restarts the Run loop after a panic; one with
+	// supervisor.DefaultConfig and Reporter if nil. Set it before calling
+	// Run.
+	Supervisor *supervisor.Supervisor
 }
 
 // NewHub creates a new Hub.
 func NewHub() *Hub {
 	return &Hub{
-		Broadcast:  make(chan []byte),
+		Broadcast:  make(chan Message, broadcastQueue),
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
 		Clients:    make(map[*Client]bool),
+		roomCounts: make(map[string]int),
+		recorded:   make(chan struct{}),
 	}
 }
 
-// Run starts the hub's event loop.
+// Run starts the hub's event loop. A panic in the loop is reported and
+// the loop restarted, so one bad message can't take the live feed down.
+// If it keeps panicking the supervisor gives up, Run returns and Running
+// reports false, failing readiness.
 func (h *Hub) Run() {
+	sup := h.Supervisor
+	if sup == nil {
+		reporter := h.Reporter
+		if reporter == nil {
+			reporter = reporting.LogReporter{}
+		}
+		sup = supervisor.New(supervisor.DefaultConfig, reporter)
+	}
+	h.running.Store(true)
+	defer h.running.Store(false)
+	sup.Run(context.Background(), "ws.hub", h.loop)
+}
+
+func (h *Hub) loop(context.Context) {
 	for {
 		select {
 		case client := <-h.Register:
 			h.Clients[client] = true
-			log.Println("WS Client registered. Total clients:", len(h.Clients))
+			h.countRooms(client, 1)
+			h.clientCount.Store(int64(len(h.Clients)))
+			slog.Debug("WS client registered", "clients", len(h.Clients))
 		case client := <-h.Unregister:
 			if _, ok := h.Clients[client]; ok {
 				delete(h.Clients, client)
+				h.countRooms(client, -1)
 				close(client.Send)
-				log.Println("WS Client unregistered. Total clients:", len(h.Clients))
+				h.clientCount.Store(int64(len(h.Clients)))
+				slog.Debug("WS client unregistered", "clients", len(h.Clients))
 			}
 		case message := <-h.Broadcast:
-			for client := range h.Clients {
-				select {
-				case client.Send <- message:
-				default:
-					close(client.Send)
-					delete(h.Clients, client)
-				}
+			h.fanOut(message)
+		}
+	}
+}
+
+// fanOut queues message for every client in its room, dropping those too
+// slow to keep up. It is encoded once for each encoding its recipients
+// use. Each fan-out is a span of its own when tracing is enabled.
+func (h *Hub) fanOut(message Message) {
+	h.record(message)
+	_, span := otel.Tracer(tracerName).Start(context.Background(), "ws.broadcast")
+	defer span.End()
+	var encoded [encodings][]byte
+	var failed [encodings]bool
+	clients, recipients := len(h.Clients), 0
+	for client := range h.Clients {
+		if !client.wants(message) || failed[client.encoding] {
+			continue
+		}
+		data := encoded[client.encoding]
+		if data == nil {
+			var err error
+			if data, err = client.encoding.encode(message.Data); err != nil {
+				slog.Error("encoding WS message", "encoding", client.encoding, "err", err)
+				failed[client.encoding] = true
+				continue
 			}
+			encoded[client.encoding] = data
+		}
+		recipients++
+		select {
+		case client.Send <- data:
+		default:
+			close(client.Send)
+			delete(h.Clients, client)
+			h.countRooms(client, -1)
 		}
 	}
+	h.clientCount.Store(int64(len(h.Clients)))
+	span.SetAttributes(
+		attribute.String("ws.room", message.Room),
+		attribute.Int("ws.clients", recipients),
+		attribute.Int("ws.dropped_clients", clients-len(h.Clients)),
+		attribute.Int("ws.message_bytes", len(message.Data)),
+	)
+}
+
+// record numbers message, keeps it for Since and wakes its waiters.
+func (h *Hub) record(message Message) {
+	h.replayMu.Lock()
+	defer h.replayMu.Unlock()
+	h.seq++
+	h.replay[h.seq%replaySize] = Event{Seq: h.seq, Room: message.Room, Data: message.Data}
+	close(h.recorded)
+	h.recorded = make(chan struct{})
+}
+
+// Since returns the kept messages numbered after seq for rooms (every room
+// when rooms is empty), oldest first, and a channel that is closed when
+// the next message is fanned out. A seq ahead of the hub's is from before
+// a restart and is treated as 0. Messages older than the last replaySize
+// are gone. It is safe to call from any goroutine.
+func (h *Hub) Since(seq uint64, rooms []string) ([]Event, <-chan struct{}) {
+	h.replayMu.Lock()
+	defer h.replayMu.Unlock()
+	if seq > h.seq {
+		seq = 0
+	}
+	first := seq + 1
+	if h.seq >= replaySize && first <= h.seq-replaySize {
+		first = h.seq - replaySize + 1
+	}
+	var events []Event
+	for n := first; n <= h.seq; n++ {
+		event := h.replay[n%replaySize]
+		if event.Room == "" || len(rooms) == 0 || slices.Contains(rooms, event.Room) {
+			events = append(events, event)
+		}
+	}
+	return events, h.recorded
+}
+
+// countRooms adds delta to the counts of client's rooms.
+func (h *Hub) countRooms(client *Client, delta int) {
+	h.roomsMu.Lock()
+	defer h.roomsMu.Unlock()
+	if client.rooms == nil {
+		h.roomCounts[""] += delta
+		return
+	}
+	for room := range client.rooms {
+		if h.roomCounts[room] += delta; h.roomCounts[room] == 0 {
+			delete(h.roomCounts, room)
+		}
+	}
+}
+
+// Running reports whether the Run loop is active.
+func (h *Hub) Running() bool {
+	return h.running.Load()
+}
+
+// ClientCount returns the number of currently connected clients.
+// It is safe to call from any goroutine.
+func (h *Hub) ClientCount() int {
+	return int(h.clientCount.Load())
+}
+
+// RoomCount returns the number of connected clients that receive
+// messages for room. It is safe to call from any goroutine.
+func (h *Hub) RoomCount(room string) int {
+	h.roomsMu.Lock()
+	defer h.roomsMu.Unlock()
+	return h.roomCounts[room] + h.roomCounts[""]
+}
+
+// Publish queues msg for every connected client. It never blocks: if the
+// queue is full the message is dropped and ErrBroadcastFull returned.
+func (h *Hub) Publish(msg []byte) error {
+	return h.PublishTo("", msg)
+}
+
+// PublishTo queues msg for the clients in room, or for every client when
+// room is empty. Like Publish, it never blocks.
+func (h *Hub) PublishTo(room string, msg []byte) error {
+	select {
+	case h.Broadcast <- Message{Room: room, Data: msg}:
+		return nil
+	default:
+		return ErrBroadcastFull
+	}
+}
+
+// ServeHTTP upgrades the request to a WebSocket and registers the client
+// in every room.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ServeWs(h, w, r)
+}
+
+// ServeRooms upgrades the request to a WebSocket and registers the client
+// in rooms only, receiving messages in encoding.
+func (h *Hub) ServeRooms(w http.ResponseWriter, r *http.Request, rooms []string, encoding Encoding) {
+	set := make(map[string]bool, len(rooms))
+	for _, room := range rooms {
+		set[room] = true
+	}
+	serve(h, w, r, set, encoding)
 }
 
 // ServeWs handles websocket requests from the peer.
 func ServeWs(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	serve(hub, w, r, nil, JSON)
+}
+
+func serve(hub *Hub, w http.ResponseWriter, r *http.Request, rooms map[string]bool, encoding Encoding) {
 	conn, err := upgrader.Upgrade(w, r, nil)
-	if err !=  nil {
-		log.Println("Failed to upgrade WS:", err)
+	if err != nil {
+		logging.FromContext(r.Context()).Warn("upgrading WS", "err", err)
 		return
 	}
-	client := &Client{Hub: hub, conn: conn, Send: make(chan []byte, 256)}
+	client := &Client{Hub: hub, conn: conn, Send: make(chan []byte, 256), rooms: rooms, encoding: encoding}
 	client.Hub.Register <- client
 
 	// Allow collection of memory referenced by the caller by executing
 	// all work in new goroutines.
 	go client.writePump()
 	go client.readPump()
-}
\ No newline at end of file
+}
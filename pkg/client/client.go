@@ -0,0 +1,308 @@
+// Package client is a Go client for the whispr API (/api/v1) and its
+// WebSocket feed.
+//
+//	c := client.New("https://whispr.example")
+//	posts, err := c.ListPosts(ctx)
+//	post, err := c.CreatePost(ctx, "hello")
+//	events, err := c.Subscribe(ctx)
+//
+// Failed requests return an *APIError carrying the API's error code;
+// match it with errors.Is against ErrNotFound, ErrConflict and the other
+// sentinels. Rate-limited requests are retried after the server's
+// Retry-After, twice by default (see WithMaxRetries).
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// apiPrefix is where the versioned API is mounted.
+const apiPrefix = "/api/v1"
+
+// defaultMaxRetries is how often a rate-limited request is retried.
+const defaultMaxRetries = 2
+
+// Post is a post as the API returns it.
+type Post struct {
+	ID             uint       `json:"id"`
+	BoardID        uint       `json:"boardId"`
+	Content        string     `json:"content"`
+	Lang           string     `json:"lang"`           // Detected language: "en", "ne" or "und"
+	ContentWarning string     `json:"contentWarning"` // E.g. "self_harm"; empty for none
+	Score          int        `json:"score"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	UpdatedAt      time.Time  `json:"updatedAt"`
+	LastActivityAt time.Time  `json:"lastActivityAt"` // Latest vote, or CreatedAt
+	VotesLastHour  int64      `json:"votesLastHour"`  // Only filled in by Trending and Rising
+	ArchivedAt     *time.Time `json:"archivedAt"`     // When it was archived out of the feeds; nil while it's in them
+}
+
+// Board is a board posts are made on. Posts made without naming a board
+// go to the default board, "general".
+type Board struct {
+	ID        uint      `json:"id"`
+	Slug      string    `json:"slug"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Me is this client's karma and posting streak.
+type Me struct {
+	Karma      int `json:"karma"`
+	Streak     int `json:"streak"` // Consecutive UTC days posted, up to today or yesterday
+	BestStreak int `json:"bestStreak"`
+}
+
+// Client calls one whispr server. It is safe for concurrent use.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	maxRetries int
+	language   string
+}
+
+// Option customizes a Client built by New.
+type Option func(*Client)
+
+// WithHTTPClient sends requests through hc instead of http.DefaultClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.httpClient = hc }
+}
+
+// WithMaxRetries sets how many times a rate-limited request is retried
+// (2 by default; 0 disables retries).
+func WithMaxRetries(n int) Option {
+	return func(c *Client) { c.maxRetries = n }
+}
+
+// WithLanguage asks for error messages in lang (Accept-Language).
+func WithLanguage(lang string) Option {
+	return func(c *Client) { c.language = lang }
+}
+
+// New returns a client for the server at baseURL, e.g.
+// "https://whispr.example".
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+		maxRetries: defaultMaxRetries,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// ListPosts returns every visible post, newest first. The API has no
+// pagination; the whole feed comes back in one response.
+func (c *Client) ListPosts(ctx context.Context) ([]Post, error) {
+	var posts []Post
+	err := c.do(ctx, http.MethodGet, "/posts", nil, &posts)
+	return posts, err
+}
+
+// ListPostsIn is ListPosts limited to posts in langs ("en", "ne"). Posts
+// whose language couldn't be determined are always included.
+func (c *Client) ListPostsIn(ctx context.Context, langs ...string) ([]Post, error) {
+	var posts []Post
+	err := c.do(ctx, http.MethodGet, "/posts?lang="+url.QueryEscape(strings.Join(langs, ",")), nil, &posts)
+	return posts, err
+}
+
+// Boards lists every board, by slug.
+func (c *Client) Boards(ctx context.Context) ([]Board, error) {
+	var boards []Board
+	err := c.do(ctx, http.MethodGet, "/boards", nil, &boards)
+	return boards, err
+}
+
+// ListBoardPosts is ListPosts for the board with slug. An unknown board
+// fails with ErrNotFound.
+func (c *Client) ListBoardPosts(ctx context.Context, slug string) ([]Post, error) {
+	var posts []Post
+	err := c.do(ctx, http.MethodGet, boardPath(slug)+"/posts", nil, &posts)
+	return posts, err
+}
+
+// Trending returns the highest-scoring posts.
+func (c *Client) Trending(ctx context.Context) ([]Post, error) {
+	var posts []Post
+	err := c.do(ctx, http.MethodGet, "/trending", nil, &posts)
+	return posts, err
+}
+
+// Rising returns the posts with the most votes in the last hour.
+func (c *Client) Rising(ctx context.Context) ([]Post, error) {
+	var posts []Post
+	err := c.do(ctx, http.MethodGet, "/trending?sort=rising", nil, &posts)
+	return posts, err
+}
+
+// GetPosts fetches several posts by ID in one request. Posts come back in
+// the order asked for; IDs that don't exist or aren't visible are
+// returned in missing.
+func (c *Client) GetPosts(ctx context.Context, ids ...uint) (posts []Post, missing []uint, err error) {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.FormatUint(uint64(id), 10)
+	}
+	var result struct {
+		Posts   []Post `json:"posts"`
+		Missing []uint `json:"missing"`
+	}
+	err = c.do(ctx, http.MethodGet, "/posts?ids="+url.QueryEscape(strings.Join(parts, ",")), nil, &result)
+	return result.Posts, result.Missing, err
+}
+
+// GetPost returns one post.
+func (c *Client) GetPost(ctx context.Context, id uint) (Post, error) {
+	var post Post
+	err := c.do(ctx, http.MethodGet, postPath(id), nil, &post)
+	return post, err
+}
+
+// CreatePost publishes content and returns the stored post, which starts
+// with its author's upvote. A near-duplicate of a recent post is stored
+// but held for moderation, and stays out of the feeds until approved.
+func (c *Client) CreatePost(ctx context.Context, content string) (Post, error) {
+	var post Post
+	err := c.do(ctx, http.MethodPost, "/posts", map[string]string{"content": content}, &post)
+	return post, err
+}
+
+// CreateBoardPost is CreatePost on the board with slug.
+func (c *Client) CreateBoardPost(ctx context.Context, slug, content string) (Post, error) {
+	var post Post
+	err := c.do(ctx, http.MethodPost, boardPath(slug)+"/posts", map[string]string{"content": content}, &post)
+	return post, err
+}
+
+// Bookmark saves post id for this client. Clients are told apart by IP,
+// so every program behind one address shares its bookmarks.
+func (c *Client) Bookmark(ctx context.Context, id uint) error {
+	return c.do(ctx, http.MethodPut, postPath(id)+"/bookmark", nil, nil)
+}
+
+// Unbookmark removes post id from this client's bookmarks.
+func (c *Client) Unbookmark(ctx context.Context, id uint) error {
+	return c.do(ctx, http.MethodDelete, postPath(id)+"/bookmark", nil, nil)
+}
+
+// Bookmarks returns page (from 1) of this client's saved posts, limit at
+// a time and most recently saved first, and how many there are. Posts
+// hidden since they were saved are left out.
+func (c *Client) Bookmarks(ctx context.Context, page, limit int) (posts []Post, total int64, err error) {
+	var result struct {
+		Posts []Post `json:"posts"`
+		Total int64  `json:"total"`
+	}
+	err = c.do(ctx, http.MethodGet, fmt.Sprintf("/bookmarks?page=%d&limit=%d", page, limit), nil, &result)
+	return result.Posts, result.Total, err
+}
+
+// Archive returns page (from 1) of the posts archived out of the feeds,
+// limit at a time and most recently archived first, and how many there
+// are.
+func (c *Client) Archive(ctx context.Context, page, limit int) (posts []Post, total int64, err error) {
+	var result struct {
+		Posts []Post `json:"posts"`
+		Total int64  `json:"total"`
+	}
+	err = c.do(ctx, http.MethodGet, fmt.Sprintf("/archive/posts?page=%d&limit=%d", page, limit), nil, &result)
+	return result.Posts, result.Total, err
+}
+
+// Me returns this client's karma and posting streak. Like bookmarks,
+// they belong to the client's IP.
+func (c *Client) Me(ctx context.Context) (Me, error) {
+	var me Me
+	err := c.do(ctx, http.MethodGet, "/me", nil, &me)
+	return me, err
+}
+
+// Vote casts value (1 or -1) on post id and returns its new score. A
+// second vote on the same post fails with ErrConflict.
+func (c *Client) Vote(ctx context.Context, id uint, value int) (int, error) {
+	var result struct {
+		Score int `json:"score"`
+	}
+	err := c.do(ctx, http.MethodPost, postPath(id)+"/vote", map[string]int{"value": value}, &result)
+	return result.Score, err
+}
+
+func postPath(id uint) string {
+	return "/posts/" + strconv.FormatUint(uint64(id), 10)
+}
+
+func boardPath(slug string) string {
+	return "/boards/" + url.PathEscape(slug)
+}
+
+// do sends a request to the API and decodes a 2xx response into out.
+// Rate-limited requests are retried after Retry-After, which also covers
+// POSTs: a 429 is sent before the request is acted on.
+func (c *Client) do(ctx context.Context, method, path string, body, out any) error {
+	var encoded []byte
+	if body != nil {
+		var err error
+		if encoded, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("encoding request: %w", err)
+		}
+	}
+	for attempt := 0; ; attempt++ {
+		err := c.once(ctx, method, path, encoded, out)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || apiErr.Code != CodeRateLimited || attempt >= c.maxRetries {
+			return err
+		}
+		select {
+		case <-time.After(apiErr.RetryAfter):
+		case <-ctx.Done():
+			return err
+		}
+	}
+}
+
+func (c *Client) once(ctx context.Context, method, path string, body []byte, out any) error {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+apiPrefix+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.language != "" {
+		req.Header.Set("Accept-Language", c.language)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return readAPIError(resp)
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("decoding %s %s response: %w", method, path, err)
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package client_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sujalbistaa/whispr/internal/testutil"
+	"github.com/sujalbistaa/whispr/pkg/client"
+)
+
+// forwardedFor sends every request as coming from ip, which the test
+// server trusts from loopback, so each client gets rate limits and votes
+// of its own.
+type forwardedFor struct {
+	ip string
+}
+
+func (f forwardedFor) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("X-Forwarded-For", f.ip)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newClient(ts *testutil.TestServer, opts ...client.Option) *client.Client {
+	hc := &http.Client{Transport: forwardedFor{ip: ts.ClientIP()}}
+	return client.New(ts.URL, append([]client.Option{client.WithHTTPClient(hc)}, opts...)...)
+}
+
+func TestPosts(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	c := newClient(ts)
+	ctx := context.Background()
+
+	created, err := c.CreatePost(ctx, "hello from the Go client")
+	if err != nil {
+		t.Fatalf("CreatePost: %v", err)
+	}
+	if created.ID == 0 || created.Content != "hello from the Go client" || created.Score != 1 {
+		t.Errorf("CreatePost = %+v", created)
+	}
+
+	got, err := c.GetPost(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetPost: %v", err)
+	}
+	if got.ID != created.ID || got.Content != created.Content {
+		t.Errorf("GetPost = %+v, want %+v", got, created)
+	}
+
+	posts, err := c.ListPosts(ctx)
+	if err != nil {
+		t.Fatalf("ListPosts: %v", err)
+	}
+	if len(posts) != 1 || posts[0].ID != created.ID {
+		t.Errorf("ListPosts = %+v, want just post %d", posts, created.ID)
+	}
+
+	found, missing, err := c.GetPosts(ctx, created.ID, created.ID+100)
+	if err != nil {
+		t.Fatalf("GetPosts: %v", err)
+	}
+	if len(found) != 1 || len(missing) != 1 || missing[0] != created.ID+100 {
+		t.Errorf("GetPosts = %+v, missing %v", found, missing)
+	}
+
+	if _, err := c.GetPost(ctx, created.ID+100); !errors.Is(err, client.ErrNotFound) {
+		t.Errorf("GetPost of a missing post = %v, want ErrNotFound", err)
+	}
+}
+
+func TestVote(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	ctx := context.Background()
+	post, err := newClient(ts).CreatePost(ctx, "a post to vote on")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	voter := newClient(ts)
+	score, err := voter.Vote(ctx, post.ID, 1)
+	if err != nil {
+		t.Fatalf("Vote: %v", err)
+	}
+	if score != post.Score+1 {
+		t.Errorf("Vote = %d, want %d", score, post.Score+1)
+	}
+	if _, err := voter.Vote(ctx, post.ID, 1); !errors.Is(err, client.ErrConflict) {
+		t.Errorf("second Vote = %v, want ErrConflict", err)
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	_, err := newClient(ts).CreatePost(context.Background(), "")
+	if !errors.Is(err, client.ErrValidation) {
+		t.Fatalf("CreatePost(\"\") = %v, want ErrValidation", err)
+	}
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadRequest {
+		t.Fatalf("error %v isn't a 400 *APIError", err)
+	}
+	fields := apiErr.FieldErrors()
+	if len(fields) == 0 || fields[0].Field == "" || fields[0].Rule == "" {
+		t.Errorf("FieldErrors = %+v, want the failed field", fields)
+	}
+}
+
+func TestRateLimitRetry(t *testing.T) {
+	t.Setenv("POST_RATE_RPS", "5")
+	t.Setenv("POST_RATE_BURST", "1")
+	ts := testutil.NewTestServer(t)
+	ctx := context.Background()
+
+	impatient := newClient(ts, client.WithMaxRetries(0))
+	if _, err := impatient.CreatePost(ctx, "the first post goes through"); err != nil {
+		t.Fatal(err)
+	}
+	_, err := impatient.CreatePost(ctx, "the second one is too soon")
+	var apiErr *client.APIError
+	if !errors.Is(err, client.ErrRateLimited) || !errors.As(err, &apiErr) || apiErr.RetryAfter <= 0 {
+		t.Fatalf("CreatePost without retries = %v, want ErrRateLimited with a Retry-After", err)
+	}
+
+	patient := newClient(ts)
+	if _, err := patient.CreatePost(ctx, "patience is a virtue"); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := patient.CreatePost(ctx, "and it gets you there in the end"); err != nil {
+		t.Fatalf("CreatePost with retries: %v", err)
+	}
+	if waited := time.Since(start); waited < time.Second/2 {
+		t.Errorf("retried after %s, before Retry-After", waited)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	c := newClient(ts)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := c.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	post := ts.CreatePost(t, "announced to subscribers")
+	if event := nextEvent(t, events, client.EventNewPost); event.Post == nil || event.Post.ID != post.ID || event.Board != "general" {
+		t.Errorf("new_post event = %+v, want post %d on general", event, post.ID)
+	}
+	score := ts.Vote(t, post.ID, 1)
+	if event := nextEvent(t, events, client.EventVote); event.PostID != post.ID || event.Score != score {
+		t.Errorf("vote event = %+v, want post %d at %d", event, post.ID, score)
+	}
+	ts.Hide(t, post.ID)
+	if event := nextEvent(t, events, client.EventDelete); event.PostID != post.ID {
+		t.Errorf("delete event = %+v, want post %d", event, post.ID)
+	}
+
+	cancel()
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("events weren't closed after canceling")
+		}
+	}
+}
+
+// nextEvent skips events until one of type typ arrives and returns it,
+// failing the test after a few seconds.
+func nextEvent(t *testing.T, events <-chan client.Event, typ string) client.Event {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-events:
+			if event.Type == typ {
+				return event
+			}
+		case <-timeout:
+			t.Fatalf("no %s event", typ)
+		}
+	}
+}
+
+// dropProxy forwards TCP connections to a server and can cut them all,
+// like a flaky network between the client and the server.
+type dropProxy struct {
+	net.Listener
+	target string
+
+	mu    sync.Mutex
+	conns []net.Conn
+}
+
+func newDropProxy(t *testing.T, target string) *dropProxy {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &dropProxy{Listener: ln, target: target}
+	t.Cleanup(func() {
+		ln.Close()
+		p.drop()
+	})
+	go p.serve()
+	return p
+}
+
+func (p *dropProxy) serve() {
+	for {
+		conn, err := p.Accept()
+		if err != nil {
+			return
+		}
+		upstream, err := net.Dial("tcp", p.target)
+		if err != nil {
+			conn.Close()
+			continue
+		}
+		p.mu.Lock()
+		p.conns = append(p.conns, conn, upstream)
+		p.mu.Unlock()
+		go io.Copy(upstream, conn)
+		go io.Copy(conn, upstream)
+	}
+}
+
+// drop closes every connection made so far.
+func (p *dropProxy) drop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, conn := range p.conns {
+		conn.Close()
+	}
+	p.conns = nil
+}
+
+func TestSubscribeReconnects(t *testing.T) {
+	ts := testutil.NewTestServer(t)
+	proxy := newDropProxy(t, strings.TrimPrefix(ts.URL, "http://"))
+	c := client.New("http://" + proxy.Addr().String())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := c.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	first := ts.CreatePost(t, "before the connection drops")
+	if event := nextEvent(t, events, client.EventNewPost); event.Post.ID != first.ID {
+		t.Errorf("new_post for %d, want %d", event.Post.ID, first.ID)
+	}
+	proxy.drop()
+	nextEvent(t, events, client.EventReconnected)
+	// The hub registers the new connection a moment after the dial.
+	deadline := time.Now().Add(2 * time.Second)
+	for ts.Hub.ClientCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	second := ts.CreatePost(t, "after the client came back")
+	if event := nextEvent(t, events, client.EventNewPost); event.Post.ID != second.ID {
+		t.Errorf("new_post for %d after reconnecting, want %d", event.Post.ID, second.ID)
+	}
+}
@@ -0,0 +1,126 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Error codes the API returns. They are stable; messages are not.
+const (
+	CodeBadRequest    = "bad_request"
+	CodeValidation    = "validation_failed"
+	CodeContent       = "content_rejected"
+	CodeUnauthorized  = "unauthorized"
+	CodeForbidden     = "forbidden"
+	CodeBanned        = "banned"
+	CodeChallenge     = "challenge_required"
+	CodeNotFound      = "not_found"
+	CodeConflict      = "conflict"
+	CodeReadOnly      = "read_only"
+	CodeTooLarge      = "payload_too_large"
+	CodeRateLimited   = "rate_limited"
+	CodeQuotaExceeded = "quota_exceeded"
+	CodeOverloaded    = "overloaded"
+	CodeUnavailable   = "unavailable"
+	CodeMaintenance   = "maintenance"
+	CodeAdminDisabled = "admin_disabled"
+	CodeInternal      = "internal_error"
+)
+
+// Sentinels for errors.Is: an *APIError matches the one with its code.
+var (
+	ErrBadRequest  = &APIError{Code: CodeBadRequest}
+	ErrValidation  = &APIError{Code: CodeValidation}
+	ErrContent     = &APIError{Code: CodeContent}
+	ErrBanned      = &APIError{Code: CodeBanned}
+	ErrChallenge   = &APIError{Code: CodeChallenge}
+	ErrNotFound    = &APIError{Code: CodeNotFound}
+	ErrConflict    = &APIError{Code: CodeConflict}
+	ErrReadOnly    = &APIError{Code: CodeReadOnly} // Sent to a read-only mirror
+	ErrTooLarge    = &APIError{Code: CodeTooLarge}
+	ErrRateLimited = &APIError{Code: CodeRateLimited}
+	ErrQuota       = &APIError{Code: CodeQuotaExceeded}
+	ErrOverloaded  = &APIError{Code: CodeOverloaded}
+	ErrUnavailable = &APIError{Code: CodeUnavailable}
+	ErrMaintenance = &APIError{Code: CodeMaintenance}
+)
+
+// APIError is an error response from the API.
+type APIError struct {
+	Status     int             // HTTP status
+	Code       string          // One of the Code constants
+	Message    string          // Human-readable, in the requested language
+	Details    json.RawMessage // Extra detail, e.g. the failed fields of a validation error
+	RetryAfter time.Duration   // From Retry-After, when the server sent one
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("whispr: %s (%d): %s", e.Code, e.Status, e.Message)
+}
+
+// Is reports whether target is an *APIError with the same code.
+func (e *APIError) Is(target error) bool {
+	t, ok := target.(*APIError)
+	return ok && t.Code == e.Code
+}
+
+// FieldErrors returns the failed fields of a validation error.
+func (e *APIError) FieldErrors() []FieldError {
+	var fields []FieldError
+	if e.Code == CodeValidation {
+		json.Unmarshal(e.Details, &fields)
+	}
+	return fields
+}
+
+// FieldError is one failed rule in a validation error.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// readAPIError decodes an error response. A body that isn't the API's
+// error envelope, e.g. from a proxy, still yields an APIError, with the
+// code guessed from the status.
+func readAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{Status: resp.StatusCode}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+		apiErr.RetryAfter = time.Duration(secs) * time.Second
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	var envelope struct {
+		Error struct {
+			Code    string          `json:"code"`
+			Message string          `json:"message"`
+			Details json.RawMessage `json:"details"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(body, &envelope) == nil && envelope.Error.Code != "" {
+		apiErr.Code = envelope.Error.Code
+		apiErr.Message = envelope.Error.Message
+		apiErr.Details = envelope.Error.Details
+		return apiErr
+	}
+	apiErr.Message = http.StatusText(resp.StatusCode)
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		apiErr.Code = CodeNotFound
+	case http.StatusTooManyRequests:
+		apiErr.Code = CodeRateLimited
+	case http.StatusServiceUnavailable:
+		apiErr.Code = CodeUnavailable
+	default:
+		if resp.StatusCode >= 500 {
+			apiErr.Code = CodeInternal
+		} else {
+			apiErr.Code = CodeBadRequest
+		}
+	}
+	return apiErr
+}
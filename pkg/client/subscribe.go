@@ -0,0 +1,151 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Event types delivered by Subscribe.
+const (
+	EventNewPost      = "new_post"
+	EventVote         = "vote"
+	EventDelete       = "delete"
+	EventAnnouncement = "announcement"
+	EventMaintenance  = "maintenance"
+	// EventReconnected follows a dropped connection that has been
+	// re-established. The server doesn't replay missed events, so refetch
+	// anything that must be current.
+	EventReconnected = "reconnected"
+)
+
+const (
+	// reconnectMin and reconnectMax bound the backoff between dials.
+	reconnectMin = 500 * time.Millisecond
+	reconnectMax = 30 * time.Second
+)
+
+// Event is one message from the live feed.
+type Event struct {
+	Type   string
+	Board  string          // Slug of the board it concerns; empty for every board
+	Post   *Post           // EventNewPost
+	PostID uint            // EventVote and EventDelete
+	Score  int             // EventVote
+	Data   json.RawMessage // The payload as sent, for every type
+}
+
+// Subscribe connects to the live feed of the default board and delivers
+// its events until ctx is canceled, when the channel is closed. A dropped
+// connection is redialed with backoff and announced with an
+// EventReconnected. The first dial's error is returned directly.
+func (c *Client) Subscribe(ctx context.Context) (<-chan Event, error) {
+	return c.SubscribeBoards(ctx)
+}
+
+// SubscribeBoards is Subscribe for the boards with the given slugs, or
+// the default board if there are none. Events for every board, such as
+// announcements, arrive either way.
+func (c *Client) SubscribeBoards(ctx context.Context, slugs ...string) (<-chan Event, error) {
+	path := "/ws"
+	if len(slugs) > 0 {
+		path += "?board=" + url.QueryEscape(strings.Join(slugs, ","))
+	}
+	conn, err := c.dial(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	events := make(chan Event, 64)
+	go c.subscribe(ctx, conn, path, events)
+	return events, nil
+}
+
+func (c *Client) subscribe(ctx context.Context, conn *websocket.Conn, path string, events chan<- Event) {
+	defer close(events)
+	for {
+		c.read(ctx, conn, events)
+		conn.Close()
+
+		backoff := reconnectMin
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			var err error
+			if conn, err = c.dial(ctx, path); err == nil {
+				break
+			}
+			backoff = min(backoff*2, reconnectMax)
+		}
+		select {
+		case events <- Event{Type: EventReconnected}:
+		case <-ctx.Done():
+			conn.Close()
+			return
+		}
+	}
+}
+
+// read delivers events from conn until it fails or ctx is canceled.
+func (c *Client) read(ctx context.Context, conn *websocket.Conn, events chan<- Event) {
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		event, ok := parseEvent(data)
+		if !ok {
+			continue
+		}
+		select {
+		case events <- event:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (c *Client) dial(ctx context.Context, path string) (*websocket.Conn, error) {
+	wsURL := "ws" + strings.TrimPrefix(c.baseURL, "http") + path
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, wsURL, nil)
+	return conn, err
+}
+
+// parseEvent decodes a feed message; malformed ones are skipped.
+func parseEvent(data []byte) (Event, bool) {
+	var msg struct {
+		Type  string          `json:"type"`
+		Data  json.RawMessage `json:"data"`
+		Board string          `json:"board"`
+	}
+	if json.Unmarshal(data, &msg) != nil || msg.Type == "" {
+		return Event{}, false
+	}
+	event := Event{Type: msg.Type, Board: msg.Board, Data: msg.Data}
+	switch msg.Type {
+	case EventNewPost:
+		var post Post
+		if json.Unmarshal(msg.Data, &post) != nil {
+			return Event{}, false
+		}
+		event.Post = &post
+	case EventVote, EventDelete:
+		var ref struct {
+			ID    uint `json:"id"`
+			Score int  `json:"score"`
+		}
+		if json.Unmarshal(msg.Data, &ref) != nil {
+			return Event{}, false
+		}
+		event.PostID, event.Score = ref.ID, ref.Score
+	}
+	return event, true
+}
@@ -0,0 +1,10 @@
+// Package public holds the frontend, embedded so the server binary can be
+// deployed on its own.
+package public
+
+import "embed"
+
+// Files is the frontend. Add patterns here when new asset types appear.
+//
+//go:embed *.html *.js
+var Files embed.FS
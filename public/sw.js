@@ -0,0 +1,16 @@
+// Service worker for Web Push: shows trending-post notifications from the
+// server and opens the site when one is clicked.
+self.addEventListener('push', (event) => {
+    if (!event.data) return;
+    const data = event.data.json();
+    event.waitUntil(self.registration.showNotification(data.title, {
+        body: data.body,
+        tag: `post-${data.postId}`,
+        data: { url: data.url },
+    }));
+});
+
+self.addEventListener('notificationclick', (event) => {
+    event.notification.close();
+    event.waitUntil(clients.openWindow(event.notification.data.url));
+});